@@ -1,19 +1,41 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/katana-stuidio/access-control/internal/config"
 	"github.com/katana-stuidio/access-control/internal/config/logger"
+	hand_authz "github.com/katana-stuidio/access-control/internal/handler/authz"
+	hand_mfa "github.com/katana-stuidio/access-control/internal/handler/mfa"
+	hand_oauth "github.com/katana-stuidio/access-control/internal/handler/oauth"
+	hand_passkey "github.com/katana-stuidio/access-control/internal/handler/passkey"
+	hand_password "github.com/katana-stuidio/access-control/internal/handler/password"
+	hand_role "github.com/katana-stuidio/access-control/internal/handler/role"
 	hand_ten "github.com/katana-stuidio/access-control/internal/handler/tenant"
 	hand_ten_group "github.com/katana-stuidio/access-control/internal/handler/tenant_group"
 	hand_usr "github.com/katana-stuidio/access-control/internal/handler/user"
+	hand_wk "github.com/katana-stuidio/access-control/internal/handler/wellknown"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/adapter/mailer"
 	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
 	"github.com/katana-stuidio/access-control/pkg/adapter/redisdb"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/permission"
 	"github.com/katana-stuidio/access-control/pkg/server"
+	service_authcode "github.com/katana-stuidio/access-control/pkg/service/authcode"
+	service_lockout "github.com/katana-stuidio/access-control/pkg/service/lockout"
+	service_mfa "github.com/katana-stuidio/access-control/pkg/service/mfa"
+	service_oauth "github.com/katana-stuidio/access-control/pkg/service/oauth_client"
+	service_passkey "github.com/katana-stuidio/access-control/pkg/service/passkey"
+	service_password_policy "github.com/katana-stuidio/access-control/pkg/service/password_policy"
+	service_password_reset "github.com/katana-stuidio/access-control/pkg/service/password_reset"
+	service_role "github.com/katana-stuidio/access-control/pkg/service/role"
 	service_ten "github.com/katana-stuidio/access-control/pkg/service/tenant"
 	service_ten_group "github.com/katana-stuidio/access-control/pkg/service/tenant_group"
 	service_token "github.com/katana-stuidio/access-control/pkg/service/token"
@@ -42,10 +64,133 @@ func main() {
 	tenat_service := service_ten.NewTenantService(conn_pg)
 	tenant_group_service := service_ten_group.NewTenantGroupService(conn_pg)
 	token_service := service_token.NewTokenService(conn_redis, conf)
+	oauth_client_service := service_oauth.NewOAuthClientService(conn_pg)
+	authcode_service := service_authcode.NewAuthCodeService(conn_redis)
+	role_service := service_role.NewRoleService(conn_pg)
+
+	// Segredos TOTP cifrados com SRV_MFA_ENCRYPTION_KEY, obrigatória
+	mfa_cipher, err := service_mfa.NewSecretCipherFromKey(conf.MFAEncryptionKey)
+	if err != nil {
+		log.Fatalf("MFA encryption key could not be loaded: %v", err)
+	}
+	mfa_service := service_mfa.NewMFAService(conn_pg, mfa_cipher)
+	mfa_challenge_service := service_mfa.NewChallengeService(conn_redis)
+
+	// Proteção do login contra força bruta, com os contadores no Redis
+	lockout_service := service_lockout.NewLockoutService(conn_redis, service_lockout.Policy{
+		MaxAttempts:   conf.LoginMaxAttempts,
+		Lockout:       time.Duration(conf.LoginLockout) * time.Minute,
+		MaxDelay:      time.Duration(conf.LoginMaxDelay) * time.Second,
+		IPMaxAttempts: conf.LoginIPMaxAttempts,
+		Window:        time.Duration(conf.LoginAttemptWindow) * time.Minute,
+	})
+
+	// Esqueci minha senha: tokens em tb_password_reset, enviados por email
+	mail_sender, err := mailer.New(conf)
+	if err != nil {
+		log.Fatalf("Mail sender could not be created: %v", err)
+	}
+	password_reset_service := service_password_reset.NewPasswordResetService(conn_pg, time.Duration(conf.PasswordResetTTL)*time.Minute)
+
+	// Política de senhas de cada grupo de tenants
+	password_policy_service := service_password_policy.NewPasswordPolicyService(conn_pg)
+
+	// Passkeys (WebAuthn) em tb_user_credential, desafios das cerimônias no Redis
+	passkey_service := service_passkey.NewPasskeyService(conn_pg)
+	passkey_ceremony_service := service_passkey.NewCeremonyService(conn_redis)
+
+	// Assinatura assimétrica dos tokens; com HS256 os tokens seguem assinados com SRV_JWT_SECRET_KEY
+	if conf.JWTSigningAlg != jwt.AlgHS256 {
+		var key_source jwt.KeySource
+		switch conf.JWTKeySource {
+		case "postgres":
+			// Chaves privadas cifradas com SRV_JWT_KEY_ENCRYPTION_KEY, obrigatória com o postgres
+			key_cipher, err := jwt.NewAESKeyCipherFromKey(conf.JWTKeyEncryptionKey)
+			if err != nil {
+				log.Fatalf("JWT key encryption key could not be loaded: %v", err)
+			}
+			key_source = jwt.NewPostgresKeyStore(conn_pg, jwt.KeyRotation{
+				Algorithm: conf.JWTSigningAlg,
+				Interval:  time.Duration(conf.JWTKeyRotation) * time.Minute,
+				Overlap:   time.Duration(conf.JWTKeyOverlap) * time.Minute,
+			}, key_cipher)
+		case "file":
+			key_source = jwt.NewPEMKeySource(conf.JWTKeyDir, conf.JWTSigningKID, conf.JWTSigningAlg)
+		default:
+			log.Fatalf("Unknown JWT key source: %s", conf.JWTKeySource)
+		}
+
+		key_ring, err := jwt.LoadKeyRing(context.Background(), key_source)
+		if err != nil {
+			log.Fatalf("JWT signing keys could not be loaded: %v", err)
+		}
+		jwt.SetKeyRing(key_ring)
+		go jwt.WatchKeys(context.Background(), key_ring, key_source)
+		logger.Info("Tokens assinados com " + conf.JWTSigningAlg)
+	}
+	if !jwt.IDTokensSupported(conf) {
+		logger.Info("OpenID Connect desativado: o escopo openid exige SRV_JWT_SIGNING_ALG assimétrico e SRV_JWT_ISSUER https")
+	}
+
+	// Inicializa o serviço de permissão (OpenFGA ou motor local), opcional
+	var permission_service permission.PermissionServiceInterface
+	var model_manager permission.ModelManagerInterface
+	model_pins := permission.NewModelPinStore(conn_pg)
+	switch {
+	case conf.FGA_ENGINE == "local":
+		modelJSON := permission.GetDefaultEducationalModel()
+		if conf.FGA_LOCAL_MODEL_FILE != "" {
+			content, err := os.ReadFile(conf.FGA_LOCAL_MODEL_FILE)
+			if err != nil {
+				log.Fatalf("Authorization model file could not be read: %v", err)
+			}
+			modelJSON = string(content)
+		}
+
+		local_service, err := permission.NewLocalPermissionService(permission.NewPostgresTupleStore(conn_pg), modelJSON)
+		if err != nil {
+			log.Fatalf("Permission service failed to start: %v", err)
+		}
+		permission_service = local_service
+		logger.Info("Usando o motor de autorização local")
+	case conf.FGA_API_URL != "":
+		fga_service, err := permission.NewPermissionService(context.Background(), conf.FGA_API_URL, conf.FGA_STORE_ID, conf.FGA_API_TOKEN)
+		if err != nil {
+			log.Fatalf("Permission service failed to start: %v", err)
+		}
+		permission_service = fga_service
+		model_manager = fga_service
+
+		// Avalia o modelo fixado em tb_fga_model_pin (ou SRV_FGA_MODEL_ID, se nenhum foi fixado),
+		// aplicado antes de subir as rotas
+		if err := model_pins.Apply(context.Background(), fga_service, conf.FGA_MODEL_ID); err != nil {
+			log.Fatalf("Pinned authorization model could not be read: %v", err)
+		}
+		go model_pins.Watch(context.Background(), fga_service, conf.FGA_MODEL_ID)
+	default:
+		logger.Info("SRV_FGA_API_URL não configurada, rotas com relação OpenFGA serão negadas")
+	}
+
+	// Sem OpenFGA as permissões do token são apenas as dos roles
+	scope_resolver := permission.NewScopeResolver(nil)
+	if permission_service != nil {
+		// Aplica as tuplas de role gravadas na outbox pelo serviço de usuário
+		go permission.NewOutboxWorker(conn_pg, permission_service).Run(context.Background())
+
+		// Permissões do token a partir do role e das relações do OpenFGA
+		if conf.FGA_TOKEN_PERMISSIONS {
+			scope_resolver = permission.NewScopeResolver(permission_service)
+		}
+	}
 
 	// Criação do router com Gin
 	router := gin.Default()
 
+	// O IP do cliente (limite de logins por IP) só vem do X-Forwarded-For dos proxies configurados
+	if err := router.SetTrustedProxies(conf.TrustedProxies); err != nil {
+		log.Fatalf("Invalid SRV_TRUSTED_PROXIES: %v", err)
+	}
+
 	// Configure CORS with more explicit settings
 	corsConfig := cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
@@ -67,13 +212,44 @@ func main() {
 		})
 	})
 
+	// Política de acesso aplicada a cada rota registrada
+	guard := middleware.NewGuard(conf, permission_service, token_service)
+
 	// Registra handlers do módulo user
-	hand_usr.RegisterUserAPIHandlers(router, usr_service, tenat_service, tenant_group_service, conf, token_service)
-	hand_ten.RegisterTenantAPIHandlers(router, tenat_service)
+	hand_usr.RegisterUserAPIHandlers(router, guard, usr_service, tenat_service, tenant_group_service, role_service, mfa_service, mfa_challenge_service, lockout_service, password_reset_service, conf, token_service, scope_resolver)
+	hand_ten.RegisterTenantAPIHandlers(router, guard, tenat_service, token_service)
 
 	// Registra handlers do módulo tenant group
 	tenant_group_handler := hand_ten_group.NewTenantGroupHandler(tenant_group_service)
-	hand_ten_group.SetupRoutes(router, tenant_group_handler)
+	hand_ten_group.SetupRoutes(router, guard, tenant_group_handler)
+
+	// Registra handlers de autenticação em dois fatores (TOTP)
+	mfa_handler := hand_mfa.NewMFAHandler(conf, mfa_service, mfa_challenge_service, usr_service, tenat_service, tenant_group_service, token_service, scope_resolver)
+	hand_mfa.SetupRoutes(router, guard, mfa_handler)
+
+	// Registra handlers de redefinição de senha por email
+	password_handler := hand_password.NewPasswordHandler(conf, password_reset_service, password_policy_service, usr_service, tenant_group_service, token_service, mail_sender)
+	hand_password.SetupRoutes(router, guard, password_handler)
+
+	// Registra handlers de login sem senha com passkeys (WebAuthn)
+	passkey_handler := hand_passkey.NewPasskeyHandler(conf, passkey_service, passkey_ceremony_service, mfa_service, mfa_challenge_service, lockout_service, usr_service, tenat_service, tenant_group_service, token_service, scope_resolver)
+	hand_passkey.SetupRoutes(router, guard, passkey_handler)
+
+	// Registra handlers do catálogo de roles
+	role_handler := hand_role.NewRoleHandler(role_service, tenant_group_service)
+	hand_role.SetupRoutes(router, guard, role_handler)
+
+	// Registra handlers de verificação de permissão para outros serviços
+	authz_handler := hand_authz.NewAuthzHandler(permission_service)
+	model_handler := hand_authz.NewModelHandler(model_manager, model_pins)
+	hand_authz.SetupRoutes(router, guard, authz_handler, model_handler)
+
+	// Registra o servidor de autorização OAuth 2.0 (tokens para serviços e aplicações)
+	oauth_handler := hand_oauth.NewOAuthHandler(conf, oauth_client_service, authcode_service, usr_service, tenat_service, tenant_group_service, token_service, scope_resolver, mfa_service, mfa_challenge_service, lockout_service)
+	hand_oauth.SetupRoutes(router, guard, oauth_handler)
+
+	// Publica as chaves públicas e o documento de descoberta para validar os tokens offline
+	hand_wk.SetupRoutes(router, guard, hand_wk.NewWellKnownHandler(conf))
 
 	// Cria servidor HTTP
 	srv := server.NewHTTPServer(router, conf)
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -17,8 +18,69 @@ type Config struct {
 	JWTSecretKey  string `json:"jwt_secret_key"`
 	JWTTokenExp   int    `json:"jwt_token_exp"`
 	JWTRefreshExp int    `json:"jwt_refresh_exp"`
+	// JWTSigningAlg é HS256 (JWTSecretKey) ou RS256, ES256, EdDSA com as chaves de JWTKeySource
+	JWTSigningAlg string `json:"jwt_signing_alg"`
+	// JWTKeySource é file (PEMs em JWTKeyDir) ou postgres (tb_jwt_signing_key, com rotação)
+	JWTKeySource string `json:"jwt_key_source"`
+	JWTKeyDir    string `json:"jwt_key_dir"`
+	// JWTSigningKID fixa a chave de assinatura entre os PEMs; vazio usa o arquivo mais recente
+	JWTSigningKID string `json:"jwt_signing_kid"`
+	// JWTKeyRotation e JWTKeyOverlap, em minutos, controlam a rotação das chaves no Postgres:
+	// uma chave nova é gerada a cada JWTKeyRotation e a anterior segue válida por JWTKeyOverlap
+	JWTKeyRotation int `json:"jwt_key_rotation"`
+	JWTKeyOverlap  int `json:"jwt_key_overlap"`
+	// JWTKeyEncryptionKey, em base64 (32 bytes), cifra as chaves privadas em tb_jwt_signing_key;
+	// obrigatória com JWTKeySource postgres
+	JWTKeyEncryptionKey string `json:"-"`
+	// JWTIssuer (iss) e JWTAudience (aud) são gravados nos tokens e exigidos na validação;
+	// vazios desativam a verificação
+	JWTIssuer   string `json:"jwt_issuer"`
+	JWTAudience string `json:"jwt_audience"`
+	// PublicURL é a URL pela qual os clientes acessam o serviço, base dos endpoints publicados
+	// na descoberta do OpenID Connect (os cabeçalhos Host e X-Forwarded-* podem ser forjados)
+	PublicURL string `json:"public_url"`
+	// JWTClientAudiences são as audiências extras dos tokens emitidos para cada client_id
+	JWTClientAudiences map[string][]string `json:"jwt_client_audiences"`
+	// JWTLeeway, em segundos, é a tolerância de relógio na validação de exp, nbf e iat
+	JWTLeeway int `json:"jwt_leeway"`
+	// MFAEncryptionKey, em base64 (32 bytes), cifra os segredos TOTP gravados no Postgres;
+	// obrigatória, a API não inicia sem ela
+	MFAEncryptionKey string `json:"-"`
+	// MFAIssuer é o emissor exibido no aplicativo autenticador
+	MFAIssuer string `json:"mfa_issuer"`
+	// WebAuthnRPID é o domínio ao qual as passkeys ficam vinculadas e WebAuthnOrigins
+	// as origens (scheme://host[:porta]) das páginas que fazem o registro e o login
+	WebAuthnRPID    string   `json:"webauthn_rp_id"`
+	WebAuthnRPName  string   `json:"webauthn_rp_name"`
+	WebAuthnOrigins []string `json:"webauthn_origins"`
+	// LoginMaxAttempts senhas erradas seguidas bloqueiam o usuário por LoginLockout minutos (0 desativa);
+	// cada erro atrasa a próxima tentativa em 1s, 2s, 4s... até LoginMaxDelay segundos
+	LoginMaxAttempts int `json:"login_max_attempts"`
+	LoginLockout     int `json:"login_lockout"`
+	LoginMaxDelay    int `json:"login_max_delay"`
+	// LoginIPMaxAttempts senhas erradas de um mesmo IP em LoginAttemptWindow minutos suspendem
+	// os logins desse IP até o fim da janela (0 desativa)
+	LoginIPMaxAttempts int `json:"login_ip_max_attempts"`
+	LoginAttemptWindow int `json:"login_attempt_window"`
+	// TrustedProxies são os IPs ou redes (CIDR) dos proxies cujo X-Forwarded-For é aceito
+	// como IP do cliente; vazio usa o IP da conexão, pois o cabeçalho pode ser forjado
+	TrustedProxies []string `json:"trusted_proxies"`
+	// MailDriver é smtp ou log, sem padrão: log grava as mensagens em MailDir sem enviá-las
+	// (ou, se vazio, só os cabeçalhos no log, pois o corpo leva o token de redefinição de senha)
+	MailDriver string `json:"mail_driver"`
+	MailFrom   string `json:"mail_from"`
+	MailDir    string `json:"mail_dir"`
+	SMTPHost   string `json:"smtp_host"`
+	SMTPPort   string `json:"smtp_port"`
+	SMTPUser   string `json:"smtp_user"`
+	SMTPPass   string `json:"-"`
+	// PasswordResetURL é a página que recebe o token de redefinição de senha (?token=...)
+	// e PasswordResetTTL, em minutos, a validade do token
+	PasswordResetURL string `json:"password_reset_url"`
+	PasswordResetTTL int    `json:"password_reset_ttl"`
 	*PGSQLConfig
 	*RedisDBConfig
+	*FGAConfig
 }
 
 type PGSQLConfig struct {
@@ -45,6 +107,22 @@ type RedisDBConfig struct {
 	PUBSUB_CHANNEL string `json:"-"`
 }
 
+type FGAConfig struct {
+	// FGA_ENGINE escolhe o motor de autorização: openfga (padrão) ou local
+	FGA_ENGINE string `json:"fga_engine"`
+	// FGA_LOCAL_MODEL_FILE é o modelo JSON do motor local; vazio usa o modelo padrão
+	FGA_LOCAL_MODEL_FILE string `json:"fga_local_model_file"`
+
+	FGA_API_URL   string `json:"fga_api_url"`
+	FGA_STORE_ID  string `json:"fga_store_id"`
+	FGA_API_TOKEN string `json:"-"`
+	// FGA_MODEL_ID é o modelo usado enquanto nenhum modelo for fixado em tb_fga_model_pin
+	FGA_MODEL_ID string `json:"fga_model_id"`
+	// FGA_TOKEN_PERMISSIONS soma às permissões do token as relações concedidas no OpenFGA,
+	// além das derivadas do role; cada emissão de token passa a consultar o OpenFGA
+	FGA_TOKEN_PERMISSIONS bool `json:"fga_token_permissions"`
+}
+
 func NewConfig() *Config {
 	conf := defaultConf()
 
@@ -73,11 +151,206 @@ func NewConfig() *Config {
 		conf.JWTRefreshExp, _ = strconv.Atoi(SRV_JWT_REFRESH_EXP)
 	}
 
+	SRV_JWT_SIGNING_ALG := os.Getenv("SRV_JWT_SIGNING_ALG")
+	if SRV_JWT_SIGNING_ALG != "" {
+		conf.JWTSigningAlg = SRV_JWT_SIGNING_ALG
+	}
+
+	SRV_JWT_KEY_SOURCE := os.Getenv("SRV_JWT_KEY_SOURCE")
+	if SRV_JWT_KEY_SOURCE != "" {
+		conf.JWTKeySource = SRV_JWT_KEY_SOURCE
+	}
+
+	SRV_JWT_KEY_DIR := os.Getenv("SRV_JWT_KEY_DIR")
+	if SRV_JWT_KEY_DIR != "" {
+		conf.JWTKeyDir = SRV_JWT_KEY_DIR
+	}
+
+	SRV_JWT_SIGNING_KID := os.Getenv("SRV_JWT_SIGNING_KID")
+	if SRV_JWT_SIGNING_KID != "" {
+		conf.JWTSigningKID = SRV_JWT_SIGNING_KID
+	}
+
+	SRV_JWT_KEY_ROTATION := os.Getenv("SRV_JWT_KEY_ROTATION")
+	if SRV_JWT_KEY_ROTATION != "" {
+		conf.JWTKeyRotation, _ = strconv.Atoi(SRV_JWT_KEY_ROTATION)
+	}
+
+	SRV_JWT_KEY_OVERLAP := os.Getenv("SRV_JWT_KEY_OVERLAP")
+	if SRV_JWT_KEY_OVERLAP != "" {
+		conf.JWTKeyOverlap, _ = strconv.Atoi(SRV_JWT_KEY_OVERLAP)
+	}
+
+	SRV_JWT_KEY_ENCRYPTION_KEY := os.Getenv("SRV_JWT_KEY_ENCRYPTION_KEY")
+	if SRV_JWT_KEY_ENCRYPTION_KEY != "" {
+		conf.JWTKeyEncryptionKey = SRV_JWT_KEY_ENCRYPTION_KEY
+	}
+
+	SRV_JWT_ISSUER := os.Getenv("SRV_JWT_ISSUER")
+	if SRV_JWT_ISSUER != "" {
+		conf.JWTIssuer = SRV_JWT_ISSUER
+	}
+
+	SRV_PUBLIC_URL := os.Getenv("SRV_PUBLIC_URL")
+	if SRV_PUBLIC_URL != "" {
+		conf.PublicURL = SRV_PUBLIC_URL
+	}
+
+	SRV_JWT_AUDIENCE := os.Getenv("SRV_JWT_AUDIENCE")
+	if SRV_JWT_AUDIENCE != "" {
+		conf.JWTAudience = SRV_JWT_AUDIENCE
+	}
+
+	SRV_JWT_CLIENT_AUDIENCES := os.Getenv("SRV_JWT_CLIENT_AUDIENCES")
+	if SRV_JWT_CLIENT_AUDIENCES != "" {
+		conf.JWTClientAudiences = parseClientAudiences(SRV_JWT_CLIENT_AUDIENCES)
+	}
+
+	SRV_JWT_LEEWAY := os.Getenv("SRV_JWT_LEEWAY")
+	if SRV_JWT_LEEWAY != "" {
+		conf.JWTLeeway, _ = strconv.Atoi(SRV_JWT_LEEWAY)
+	}
+
+	SRV_MFA_ENCRYPTION_KEY := os.Getenv("SRV_MFA_ENCRYPTION_KEY")
+	if SRV_MFA_ENCRYPTION_KEY != "" {
+		conf.MFAEncryptionKey = SRV_MFA_ENCRYPTION_KEY
+	}
+
+	SRV_MFA_ISSUER := os.Getenv("SRV_MFA_ISSUER")
+	if SRV_MFA_ISSUER != "" {
+		conf.MFAIssuer = SRV_MFA_ISSUER
+	}
+
+	SRV_WEBAUTHN_RP_ID := os.Getenv("SRV_WEBAUTHN_RP_ID")
+	if SRV_WEBAUTHN_RP_ID != "" {
+		conf.WebAuthnRPID = SRV_WEBAUTHN_RP_ID
+	}
+
+	SRV_WEBAUTHN_RP_NAME := os.Getenv("SRV_WEBAUTHN_RP_NAME")
+	if SRV_WEBAUTHN_RP_NAME != "" {
+		conf.WebAuthnRPName = SRV_WEBAUTHN_RP_NAME
+	}
+
+	SRV_WEBAUTHN_ORIGINS := os.Getenv("SRV_WEBAUTHN_ORIGINS")
+	if SRV_WEBAUTHN_ORIGINS != "" {
+		conf.WebAuthnOrigins = parseList(SRV_WEBAUTHN_ORIGINS)
+	}
+
+	SRV_LOGIN_MAX_ATTEMPTS := os.Getenv("SRV_LOGIN_MAX_ATTEMPTS")
+	if SRV_LOGIN_MAX_ATTEMPTS != "" {
+		conf.LoginMaxAttempts, _ = strconv.Atoi(SRV_LOGIN_MAX_ATTEMPTS)
+	}
+
+	SRV_LOGIN_LOCKOUT := os.Getenv("SRV_LOGIN_LOCKOUT")
+	if SRV_LOGIN_LOCKOUT != "" {
+		conf.LoginLockout, _ = strconv.Atoi(SRV_LOGIN_LOCKOUT)
+	}
+
+	SRV_LOGIN_MAX_DELAY := os.Getenv("SRV_LOGIN_MAX_DELAY")
+	if SRV_LOGIN_MAX_DELAY != "" {
+		conf.LoginMaxDelay, _ = strconv.Atoi(SRV_LOGIN_MAX_DELAY)
+	}
+
+	SRV_LOGIN_IP_MAX_ATTEMPTS := os.Getenv("SRV_LOGIN_IP_MAX_ATTEMPTS")
+	if SRV_LOGIN_IP_MAX_ATTEMPTS != "" {
+		conf.LoginIPMaxAttempts, _ = strconv.Atoi(SRV_LOGIN_IP_MAX_ATTEMPTS)
+	}
+
+	SRV_LOGIN_ATTEMPT_WINDOW := os.Getenv("SRV_LOGIN_ATTEMPT_WINDOW")
+	if SRV_LOGIN_ATTEMPT_WINDOW != "" {
+		conf.LoginAttemptWindow, _ = strconv.Atoi(SRV_LOGIN_ATTEMPT_WINDOW)
+	}
+
+	SRV_TRUSTED_PROXIES := os.Getenv("SRV_TRUSTED_PROXIES")
+	if SRV_TRUSTED_PROXIES != "" {
+		conf.TrustedProxies = parseList(SRV_TRUSTED_PROXIES)
+	}
+
+	SRV_MAIL_DRIVER := os.Getenv("SRV_MAIL_DRIVER")
+	if SRV_MAIL_DRIVER != "" {
+		conf.MailDriver = SRV_MAIL_DRIVER
+	}
+
+	SRV_MAIL_FROM := os.Getenv("SRV_MAIL_FROM")
+	if SRV_MAIL_FROM != "" {
+		conf.MailFrom = SRV_MAIL_FROM
+	}
+
+	SRV_MAIL_DIR := os.Getenv("SRV_MAIL_DIR")
+	if SRV_MAIL_DIR != "" {
+		conf.MailDir = SRV_MAIL_DIR
+	}
+
+	SRV_SMTP_HOST := os.Getenv("SRV_SMTP_HOST")
+	if SRV_SMTP_HOST != "" {
+		conf.SMTPHost = SRV_SMTP_HOST
+	}
+
+	SRV_SMTP_PORT := os.Getenv("SRV_SMTP_PORT")
+	if SRV_SMTP_PORT != "" {
+		conf.SMTPPort = SRV_SMTP_PORT
+	}
+
+	SRV_SMTP_USER := os.Getenv("SRV_SMTP_USER")
+	if SRV_SMTP_USER != "" {
+		conf.SMTPUser = SRV_SMTP_USER
+	}
+
+	SRV_SMTP_PASS := os.Getenv("SRV_SMTP_PASS")
+	if SRV_SMTP_PASS != "" {
+		conf.SMTPPass = SRV_SMTP_PASS
+	}
+
+	SRV_PASSWORD_RESET_URL := os.Getenv("SRV_PASSWORD_RESET_URL")
+	if SRV_PASSWORD_RESET_URL != "" {
+		conf.PasswordResetURL = SRV_PASSWORD_RESET_URL
+	}
+
+	SRV_PASSWORD_RESET_TTL := os.Getenv("SRV_PASSWORD_RESET_TTL")
+	if SRV_PASSWORD_RESET_TTL != "" {
+		conf.PasswordResetTTL, _ = strconv.Atoi(SRV_PASSWORD_RESET_TTL)
+	}
+
 	SRV_DB_SSL_MODE := os.Getenv("SRV_DB_SSL_MODE")
 	if SRV_DB_SSL_MODE != "" {
 		conf.PGSQLConfig.SRV_DB_SSL_MODE = SRV_DB_SSL_MODE
 	}
 
+	SRV_FGA_TOKEN_PERMISSIONS := os.Getenv("SRV_FGA_TOKEN_PERMISSIONS")
+	if SRV_FGA_TOKEN_PERMISSIONS != "" {
+		conf.FGAConfig.FGA_TOKEN_PERMISSIONS, _ = strconv.ParseBool(SRV_FGA_TOKEN_PERMISSIONS)
+	}
+
+	SRV_FGA_ENGINE := os.Getenv("SRV_FGA_ENGINE")
+	if SRV_FGA_ENGINE != "" {
+		conf.FGAConfig.FGA_ENGINE = SRV_FGA_ENGINE
+	}
+
+	SRV_FGA_LOCAL_MODEL_FILE := os.Getenv("SRV_FGA_LOCAL_MODEL_FILE")
+	if SRV_FGA_LOCAL_MODEL_FILE != "" {
+		conf.FGAConfig.FGA_LOCAL_MODEL_FILE = SRV_FGA_LOCAL_MODEL_FILE
+	}
+
+	SRV_FGA_API_URL := os.Getenv("SRV_FGA_API_URL")
+	if SRV_FGA_API_URL != "" {
+		conf.FGAConfig.FGA_API_URL = SRV_FGA_API_URL
+	}
+
+	SRV_FGA_STORE_ID := os.Getenv("SRV_FGA_STORE_ID")
+	if SRV_FGA_STORE_ID != "" {
+		conf.FGAConfig.FGA_STORE_ID = SRV_FGA_STORE_ID
+	}
+
+	SRV_FGA_API_TOKEN := os.Getenv("SRV_FGA_API_TOKEN")
+	if SRV_FGA_API_TOKEN != "" {
+		conf.FGAConfig.FGA_API_TOKEN = SRV_FGA_API_TOKEN
+	}
+
+	SRV_FGA_MODEL_ID := os.Getenv("SRV_FGA_MODEL_ID")
+	if SRV_FGA_MODEL_ID != "" {
+		conf.FGAConfig.FGA_MODEL_ID = SRV_FGA_MODEL_ID
+	}
+
 	return conf
 }
 
@@ -89,6 +362,34 @@ func defaultConf() *Config {
 		JWTTokenExp:   150,             // 15 minutes for access token
 		JWTRefreshExp: 10080,           // 7 days for refresh token (7 * 24 * 60 = 10080 minutes)
 
+		JWTSigningAlg:  "HS256",
+		JWTKeySource:   "file",
+		JWTKeyRotation: 43200, // 30 days
+		JWTKeyOverlap:  10080, // the refresh token lifetime, so rotated keys still validate live sessions
+
+		JWTIssuer:   "access-control",
+		JWTAudience: "access-control",
+		JWTLeeway:   30,
+
+		PublicURL: "http://localhost:8080",
+
+		MFAIssuer: "access-control",
+
+		WebAuthnRPID:    "localhost",
+		WebAuthnRPName:  "access-control",
+		WebAuthnOrigins: []string{"http://localhost:8080"},
+
+		LoginMaxAttempts:   5,
+		LoginLockout:       15,
+		LoginMaxDelay:      30,
+		LoginIPMaxAttempts: 50,
+		LoginAttemptWindow: 15,
+
+		MailFrom:         "access-control <no-reply@localhost>",
+		SMTPPort:         "587",
+		PasswordResetURL: "http://localhost:8080/reset-password",
+		PasswordResetTTL: 30,
+
 		PGSQLConfig: &PGSQLConfig{
 			DB_DRIVE: "postgres",
 			DB_PORT:  "5432",
@@ -98,7 +399,40 @@ func defaultConf() *Config {
 			RDB_PORT: "6379",
 			RDB_DB:   0,
 		},
+
+		FGAConfig: &FGAConfig{
+			FGA_ENGINE: "openfga",
+		},
 	}
 
 	return &default_conf
 }
+
+// parseList lê uma lista separada por vírgulas
+func parseList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseClientAudiences lê o formato client=aud1,aud2;outro=aud3
+func parseClientAudiences(value string) map[string][]string {
+	audiences := map[string][]string{}
+	for _, entry := range strings.Split(value, ";") {
+		clientID, list, ok := strings.Cut(entry, "=")
+		clientID = strings.TrimSpace(clientID)
+		if !ok || clientID == "" {
+			continue
+		}
+		for _, audience := range strings.Split(list, ",") {
+			if audience = strings.TrimSpace(audience); audience != "" {
+				audiences[clientID] = append(audiences[clientID], audience)
+			}
+		}
+	}
+	return audiences
+}
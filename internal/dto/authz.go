@@ -0,0 +1,133 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuthzTuple is a relationship tuple in OpenFGA format (ex: user:123 estudante tenant:abc)
+type AuthzTuple struct {
+	User      string          `json:"user" binding:"required"`
+	Relation  string          `json:"relation" binding:"required"`
+	Object    string          `json:"object" binding:"required"`
+	Condition *AuthzCondition `json:"condition,omitempty"`
+}
+
+// AuthzCondition is the condition attached to a tuple
+type AuthzCondition struct {
+	Name    string                 `json:"name" binding:"required"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// AuthzCheckRequest asks if a user has a relation on an object (ex: can_read on quiz:123).
+// User accepts a bare user ID or a reference such as user:123 or tenant:abc#admin.
+type AuthzCheckRequest struct {
+	User             string                 `json:"user" binding:"required"`
+	Relation         string                 `json:"relation" binding:"required"`
+	ObjectType       string                 `json:"object_type" binding:"required"`
+	ObjectID         string                 `json:"object_id" binding:"required"`
+	ContextualTuples []AuthzTuple           `json:"contextual_tuples,omitempty" binding:"omitempty,max=20,dive"`
+	Context          map[string]interface{} `json:"context,omitempty"`
+}
+
+// AuthzCheckResponse represents the result of a single check
+type AuthzCheckResponse struct {
+	Allowed              bool   `json:"allowed"`
+	AuthorizationModelID string `json:"authorization_model_id"`
+}
+
+// AuthzBatchCheckRequest groups up to 50 checks in a single call
+type AuthzBatchCheckRequest struct {
+	Checks []AuthzCheckRequest `json:"checks" binding:"required,min=1,max=50,dive"`
+}
+
+// AuthzBatchCheckResult is the result of one check of the batch, in request order
+type AuthzBatchCheckResult struct {
+	AuthzCheckRequest
+	Allowed              bool   `json:"allowed"`
+	AuthorizationModelID string `json:"authorization_model_id,omitempty"`
+	Error                string `json:"error,omitempty"`
+}
+
+// AuthzBatchCheckResponse represents the response of the batch check
+type AuthzBatchCheckResponse struct {
+	Results []AuthzBatchCheckResult `json:"results"`
+}
+
+// AuthzListObjectsRequest asks which objects of a type the user has a relation on
+// (ex: which quizzes can this student read)
+type AuthzListObjectsRequest struct {
+	User             string                 `json:"user" binding:"required"`
+	Relation         string                 `json:"relation" binding:"required"`
+	ObjectType       string                 `json:"object_type" binding:"required"`
+	ContextualTuples []AuthzTuple           `json:"contextual_tuples,omitempty" binding:"omitempty,max=20,dive"`
+	Context          map[string]interface{} `json:"context,omitempty"`
+}
+
+// AuthzListObjectsResponse lists the objects found, as type:id
+type AuthzListObjectsResponse struct {
+	Objects []string `json:"objects"`
+}
+
+// AuthzListUsersRequest asks which users of a type have a relation on an object.
+// UserRelation filters usersets, ex: user_type tenant and user_relation admin.
+type AuthzListUsersRequest struct {
+	ObjectType       string                 `json:"object_type" binding:"required"`
+	ObjectID         string                 `json:"object_id" binding:"required"`
+	Relation         string                 `json:"relation" binding:"required"`
+	UserType         string                 `json:"user_type" binding:"required"`
+	UserRelation     string                 `json:"user_relation,omitempty"`
+	ContextualTuples []AuthzTuple           `json:"contextual_tuples,omitempty" binding:"omitempty,max=20,dive"`
+	Context          map[string]interface{} `json:"context,omitempty"`
+}
+
+// AuthzListUsersResponse lists the users found, as type:id or type:id#relation
+type AuthzListUsersResponse struct {
+	Users []string `json:"users"`
+}
+
+// AuthzModelUploadRequest uploads a new model version, as JSON (model) or OpenFGA DSL (dsl)
+type AuthzModelUploadRequest struct {
+	Model json.RawMessage `json:"model,omitempty" swaggertype:"object"`
+	DSL   string          `json:"dsl,omitempty"`
+	Pin   bool            `json:"pin"`
+}
+
+// AuthzModelUploadResponse represents the created model version
+type AuthzModelUploadResponse struct {
+	AuthorizationModelID string `json:"authorization_model_id"`
+	Pinned               bool   `json:"pinned"`
+}
+
+// AuthzModelSummary describes one model version in the listing
+type AuthzModelSummary struct {
+	ID            string   `json:"id"`
+	SchemaVersion string   `json:"schema_version"`
+	Types         []string `json:"types"`
+	Active        bool     `json:"active"`
+}
+
+// AuthzModelListResponse lists model versions, newest first
+type AuthzModelListResponse struct {
+	Models            []AuthzModelSummary `json:"models"`
+	ContinuationToken string              `json:"continuation_token,omitempty"`
+	ActiveModelID     string              `json:"active_model_id"`
+}
+
+// AuthzModelPinRequest pins the model evaluated by every check
+type AuthzModelPinRequest struct {
+	ModelID string `json:"model_id" binding:"required"`
+}
+
+// AuthzModelPin is one entry of the pin history
+type AuthzModelPin struct {
+	ModelID  string    `json:"model_id"`
+	PinnedBy string    `json:"pinned_by,omitempty"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// AuthzModelActiveResponse represents the active model and the pin history
+type AuthzModelActiveResponse struct {
+	ActiveModelID string          `json:"active_model_id"`
+	History       []AuthzModelPin `json:"history"`
+}
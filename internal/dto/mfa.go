@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAChallengeResponse is returned by getjwt instead of the tokens when the login needs a second factor.
+// With EnrollmentRequired the tenant policy requires MFA and the user enrolls a secret with the token first.
+type MFAChallengeResponse struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	MFAToken           string `json:"mfa_token"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+// MFAChallengeRequest identifies the login waiting for the second factor
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// MFAVerifyRequest completes the login with a TOTP code or a recovery code
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAVerifyResponse carries the tokens of the login, and the recovery codes when the login confirmed the enrollment
+type MFAVerifyResponse struct {
+	AccessToken   string   `json:"accessToken"`
+	RefreshToken  string   `json:"refreshToken,omitempty"`
+	TokenID       string   `json:"tokenId,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// MFACodeRequest confirms an action of the user with a TOTP code or a recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAEnrollResponse is the secret to add to the authenticator app, OTPAuthURI is usually shown as a QR code
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// MFARecoveryCodesResponse lists new recovery codes, shown only once
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAPolicyRequest replaces the roles that must log in with a second factor in a tenant
+type MFAPolicyRequest struct {
+	RequiredRoles []string `json:"required_roles" binding:"required"`
+}
+
+// MFAPolicyResponse represents the MFA policy of a tenant
+type MFAPolicyResponse struct {
+	TenantID      uuid.UUID `json:"tenant_id"`
+	RequiredRoles []string  `json:"required_roles"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthTokenResponse is the successful response of /oauth/token (RFC 6749, section 5.1)
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// OAuthErrorResponse is the error response of the OAuth endpoints (RFC 6749, section 5.2)
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthClientCreateRequest registers a client bound to a tenant
+type OAuthClientCreateRequest struct {
+	Name         string    `json:"name" binding:"required"`
+	Scopes       []string  `json:"scopes"`
+	TenantID     uuid.UUID `json:"tenant_id" binding:"required"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	FirstParty   bool      `json:"first_party"`
+	Public       bool      `json:"public"`
+}
+
+// OAuthClientResponse represents a registered client. ClientSecret is only
+// returned when the client is created.
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	Scopes       []string  `json:"scopes"`
+	TenantID     uuid.UUID `json:"tenant_id"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	FirstParty   bool      `json:"first_party"`
+	Public       bool      `json:"public"`
+	Enable       bool      `json:"enable"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthIntrospectionResponse is the response of /oauth/introspect (RFC 7662, section 2.2).
+// Inactive tokens carry only Active; the tenant and user fields extend the standard members.
+type OAuthIntrospectionResponse struct {
+	Active      bool     `json:"active"`
+	Scope       string   `json:"scope,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Username    string   `json:"username,omitempty"`
+	TokenType   string   `json:"token_type,omitempty"`
+	Exp         int64    `json:"exp,omitempty"`
+	Iat         int64    `json:"iat,omitempty"`
+	Nbf         int64    `json:"nbf,omitempty"`
+	Sub         string   `json:"sub,omitempty"`
+	Aud         []string `json:"aud,omitempty"`
+	Iss         string   `json:"iss,omitempty"`
+	Jti         string   `json:"jti,omitempty"`
+	UserID      string   `json:"user_id,omitempty"`
+	TenantID    string   `json:"tenant_id,omitempty"`
+	GroupID     string   `json:"group_id,omitempty"`
+	Role        string   `json:"role,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
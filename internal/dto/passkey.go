@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"github.com/katana-stuidio/access-control/pkg/webauthn"
+)
+
+// PasskeyRegisterBeginRequest confirms the user before a passkey is added: the TOTP code when
+// MFA is enabled, the password otherwise
+type PasskeyRegisterBeginRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// PasskeyRegisterRequest completes the registration with the response of navigator.credentials.create
+type PasskeyRegisterRequest struct {
+	// Name tells the passkeys of the user apart, e.g. "Notebook da escola"
+	Name       string                       `json:"name" binding:"max=100"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+// PasskeyLoginBeginRequest starts a passkey login. Without Username the browser offers
+// the discoverable credentials saved for this service.
+type PasskeyLoginBeginRequest struct {
+	Username string `json:"username"`
+	ClientID string `json:"client_id"`
+}
+
+// PasskeyLoginRequest completes the login with the response of navigator.credentials.get
+type PasskeyLoginRequest struct {
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
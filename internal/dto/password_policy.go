@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordPolicyRequest replaces the password policy of a tenant group.
+// history_size and max_age_days equal to 0 disable the rules; min_length is capped
+// at model.PasswordMaxBytes, the longest password bcrypt hashes
+type PasswordPolicyRequest struct {
+	MinLength            int  `json:"min_length" binding:"min=8,max=72"`
+	RequireUppercase     bool `json:"require_uppercase"`
+	RequireLowercase     bool `json:"require_lowercase"`
+	RequireNumber        bool `json:"require_number"`
+	RequireSymbol        bool `json:"require_symbol"`
+	DisallowPersonalData bool `json:"disallow_personal_data"`
+	HistorySize          int  `json:"history_size" binding:"min=0,max=24"`
+	MaxAgeDays           int  `json:"max_age_days" binding:"min=0,max=3650"`
+}
+
+// PasswordPolicyResponse represents the password policy of a tenant group
+type PasswordPolicyResponse struct {
+	GroupID              uuid.UUID `json:"group_id"`
+	MinLength            int       `json:"min_length"`
+	RequireUppercase     bool      `json:"require_uppercase"`
+	RequireLowercase     bool      `json:"require_lowercase"`
+	RequireNumber        bool      `json:"require_number"`
+	RequireSymbol        bool      `json:"require_symbol"`
+	DisallowPersonalData bool      `json:"disallow_personal_data"`
+	HistorySize          int       `json:"history_size"`
+	MaxAgeDays           int       `json:"max_age_days"`
+	UpdatedAt            time.Time `json:"updated_at,omitempty"`
+}
+
+// PasswordViolation is a rule of the password policy the password breaks
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordRejectedResponse lists every rule of the password policy the new password breaks
+type PasswordRejectedResponse struct {
+	Error      string              `json:"error"`
+	Violations []PasswordViolation `json:"violations"`
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoleCreateRequest creates a role in the global catalog or, with GroupID, in the catalog of a tenant group.
+// A group role with the name of a global role overrides it for the tenants of the group.
+type RoleCreateRequest struct {
+	Name        string    `json:"name" binding:"required"`
+	Description string    `json:"description"`
+	GroupID     uuid.UUID `json:"group_id"`
+	Enable      *bool     `json:"enable"`
+}
+
+// RoleUpdateRequest changes a role of the catalog, the name cannot be changed
+type RoleUpdateRequest struct {
+	Description string `json:"description"`
+	Enable      bool   `json:"enable"`
+}
+
+// RoleResponse represents a role of the catalog, GroupID is empty for global roles
+type RoleResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	GroupID     *uuid.UUID `json:"group_id,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enable      bool       `json:"enable"`
+	System      bool       `json:"system"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// UserRolesRequest replaces the roles of a user in a tenant, the user tenant when TenantID is empty
+type UserRolesRequest struct {
+	TenantID uuid.UUID `json:"tenant_id"`
+	Roles    []string  `json:"roles" binding:"required"`
+}
+
+// UserRolesResponse lists the roles of a user in a tenant
+type UserRolesResponse struct {
+	UserID   uuid.UUID `json:"user_id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+	Roles    []string  `json:"roles"`
+}
@@ -9,6 +9,8 @@ import (
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// ClientID adds the audiences configured for the client to the tokens (SRV_JWT_CLIENT_AUDIENCES)
+	ClientID string `json:"client_id,omitempty"`
 }
 
 type UserRequestDtoInput struct {
@@ -18,6 +20,8 @@ type UserRequestDtoInput struct {
 	CNPJ     string `json:"cnpj"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
+	// Roles are the other roles of the user, the first one is the main role when Role is empty
+	Roles []string `json:"roles,omitempty"`
 }
 
 type UserRequestDtoOutPut struct {
@@ -27,6 +31,7 @@ type UserRequestDtoOutPut struct {
 	Username  string    `json:"username"`
 	Enable    bool      `json:"enable"`
 	Role      string    `json:"role"`
+	Roles     []string  `json:"roles,omitempty"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
@@ -41,3 +46,29 @@ type UserChangePasswordOutPut struct {
 	NewPassowrd string `json:"new_password"`
 	OldPassowrd string `json:"old_password"`
 }
+
+// RevokeTokenRequest identifies the session to revoke (TokenID of the JWT)
+type RevokeTokenRequest struct {
+	TokenID string `json:"token_id" binding:"required"`
+}
+
+// PasswordForgotRequest asks for a password reset link sent to the email of the user
+type PasswordForgotRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// PasswordExpiredResponse is returned by getjwt for a password older than the maximum age of the
+// policy. PasswordChangeToken is a token of /password/reset valid for ExpiresIn seconds.
+type PasswordExpiredResponse struct {
+	Error               string `json:"error"`
+	PasswordExpired     bool   `json:"password_expired"`
+	PasswordChangeToken string `json:"password_change_token"`
+	ExpiresIn           int    `json:"expires_in"`
+}
+
+// PasswordResetRequest sets a new password with the token of the reset link, or the
+// password_change_token of an expired password
+type PasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
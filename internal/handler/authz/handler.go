@@ -0,0 +1,314 @@
+package authz
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+)
+
+// maxParallelChecks limits the concurrent calls to the permission service in a batch
+const maxParallelChecks = 10
+
+type AuthzHandler struct {
+	permissionService permission.PermissionServiceInterface
+}
+
+func NewAuthzHandler(permissionService permission.PermissionServiceInterface) *AuthzHandler {
+	return &AuthzHandler{
+		permissionService: permissionService,
+	}
+}
+
+// @Summary Check permission
+// @Description Check if a user has a relation on an object
+// @Tags authz
+// @Accept json
+// @Produce json
+// @Param request body dto.AuthzCheckRequest true "Check details"
+// @Success 200 {object} dto.AuthzCheckResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 503 {object} handler.HttpMsg
+// @Router /api/v1/authz/check [post]
+func (h *AuthzHandler) Check(c *gin.Context) {
+	if h.permissionService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	var request dto.AuthzCheckRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if !contextualTuplesAllowed(c, request.ContextualTuples) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Contextual tuples are only accepted from service clients"})
+		return
+	}
+
+	checkRequest, err := toCheckRequest(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.permissionService.Check(c.Request.Context(), checkRequest)
+	if err != nil {
+		logger.Error("Error checking permission", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission check failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.AuthzCheckResponse{
+		Allowed:              result.Allowed,
+		AuthorizationModelID: result.AuthorizationModelID,
+	})
+}
+
+// @Summary Batch check permissions
+// @Description Run up to 50 checks in a single call, results keep the request order
+// @Tags authz
+// @Accept json
+// @Produce json
+// @Param request body dto.AuthzBatchCheckRequest true "Checks"
+// @Success 200 {object} dto.AuthzBatchCheckResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 503 {object} handler.HttpMsg
+// @Router /api/v1/authz/check/batch [post]
+func (h *AuthzHandler) BatchCheck(c *gin.Context) {
+	if h.permissionService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	var request dto.AuthzBatchCheckRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	for _, check := range request.Checks {
+		if !contextualTuplesAllowed(c, check.ContextualTuples) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Contextual tuples are only accepted from service clients"})
+			return
+		}
+	}
+
+	results := make([]dto.AuthzBatchCheckResult, len(request.Checks))
+	sem := make(chan struct{}, maxParallelChecks)
+	var wg sync.WaitGroup
+
+	for i, check := range request.Checks {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, check dto.AuthzCheckRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i].AuthzCheckRequest = check
+
+			checkRequest, err := toCheckRequest(check)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+
+			result, err := h.permissionService.Check(c.Request.Context(), checkRequest)
+			if err != nil {
+				logger.Error("Error checking permission", err)
+				results[i].Error = "Permission check failed"
+				return
+			}
+
+			results[i].Allowed = result.Allowed
+			results[i].AuthorizationModelID = result.AuthorizationModelID
+		}(i, check)
+	}
+
+	wg.Wait()
+
+	c.JSON(http.StatusOK, dto.AuthzBatchCheckResponse{Results: results})
+}
+
+// @Summary List objects
+// @Description List the objects of a type on which the user has a relation (ex: which quizzes can this student read)
+// @Tags authz
+// @Accept json
+// @Produce json
+// @Param request body dto.AuthzListObjectsRequest true "Query"
+// @Success 200 {object} dto.AuthzListObjectsResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 503 {object} handler.HttpMsg
+// @Router /api/v1/authz/list-objects [post]
+func (h *AuthzHandler) ListObjects(c *gin.Context) {
+	if h.permissionService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	var request dto.AuthzListObjectsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if !contextualTuplesAllowed(c, request.ContextualTuples) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Contextual tuples are only accepted from service clients"})
+		return
+	}
+
+	user, err := parseUser(request.User)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tuples, err := toTuples(request.ContextualTuples)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	objects, err := h.permissionService.ListObjects(c.Request.Context(), permission.ListObjectsRequest{
+		User:             user,
+		Relation:         request.Relation,
+		ObjectType:       request.ObjectType,
+		ContextualTuples: tuples,
+		Context:          request.Context,
+	})
+	if err != nil {
+		logger.Error("Error listing objects", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission check failed"})
+		return
+	}
+
+	response := dto.AuthzListObjectsResponse{Objects: make([]string, len(objects))}
+	for i, object := range objects {
+		response.Objects[i] = object.String()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary List users
+// @Description List the users (or usersets) of a type that have a relation on an object
+// @Tags authz
+// @Accept json
+// @Produce json
+// @Param request body dto.AuthzListUsersRequest true "Query"
+// @Success 200 {object} dto.AuthzListUsersResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 503 {object} handler.HttpMsg
+// @Router /api/v1/authz/list-users [post]
+func (h *AuthzHandler) ListUsers(c *gin.Context) {
+	if h.permissionService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	var request dto.AuthzListUsersRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if !contextualTuplesAllowed(c, request.ContextualTuples) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Contextual tuples are only accepted from service clients"})
+		return
+	}
+
+	tuples, err := toTuples(request.ContextualTuples)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := h.permissionService.ListUsers(c.Request.Context(), permission.ListUsersRequest{
+		Object:           permission.NewObject(request.ObjectType, request.ObjectID),
+		Relation:         request.Relation,
+		UserType:         request.UserType,
+		UserRelation:     request.UserRelation,
+		ContextualTuples: tuples,
+		Context:          request.Context,
+	})
+	if err != nil {
+		logger.Error("Error listing users", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission check failed"})
+		return
+	}
+
+	response := dto.AuthzListUsersResponse{Users: make([]string, len(users))}
+	for i, user := range users {
+		response.Users[i] = user.String()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseUser aceita o ID do usuário ou uma referência completa (user:123, tenant:abc#admin)
+func parseUser(user string) (permission.Object, error) {
+	if !strings.Contains(user, ":") {
+		return permission.UserObject(user), nil
+	}
+	return permission.ParseObject(user)
+}
+
+// contextualTuplesAllowed: contextual tuples are taken as true by the check, a caller sending
+// "user:me admin tenant:x" would be allowed anything, so only service clients may send them
+func contextualTuplesAllowed(c *gin.Context, tuples []dto.AuthzTuple) bool {
+	return len(tuples) == 0 || middleware.ServiceClient(c)
+}
+
+func toTuples(tuples []dto.AuthzTuple) ([]permission.Tuple, error) {
+	result := make([]permission.Tuple, 0, len(tuples))
+	for _, t := range tuples {
+		user, err := parseUser(t.User)
+		if err != nil {
+			return nil, err
+		}
+		object, err := permission.ParseObject(t.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		tuple := permission.NewTuple(user, t.Relation, object)
+		if t.Condition != nil {
+			tuple.Condition = &permission.Condition{Name: t.Condition.Name, Context: t.Condition.Context}
+		}
+		result = append(result, tuple)
+	}
+	return result, nil
+}
+
+func toCheckRequest(request dto.AuthzCheckRequest) (permission.CheckRequest, error) {
+	user, err := parseUser(request.User)
+	if err != nil {
+		return permission.CheckRequest{}, err
+	}
+	tuples, err := toTuples(request.ContextualTuples)
+	if err != nil {
+		return permission.CheckRequest{}, err
+	}
+
+	return permission.CheckRequest{
+		User:             user,
+		Relation:         request.Relation,
+		Object:           permission.NewObject(request.ObjectType, request.ObjectID),
+		ContextualTuples: tuples,
+		Context:          request.Context,
+	}, nil
+}
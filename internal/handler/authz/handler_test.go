@@ -0,0 +1,220 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+)
+
+// fakePermissionService allows every relation named "can_read"
+type fakePermissionService struct{}
+
+func (f *fakePermissionService) AddRelation(ctx context.Context, userID, tenantID, relation string) error {
+	return nil
+}
+
+func (f *fakePermissionService) RemoveRelation(ctx context.Context, userID, tenantID, relation string) error {
+	return nil
+}
+
+func (f *fakePermissionService) CheckPermission(ctx context.Context, userID, tenantID, relation string) (bool, error) {
+	return relation == "can_read", nil
+}
+
+func (f *fakePermissionService) Write(ctx context.Context, writes, deletes []permission.Tuple) error {
+	return nil
+}
+
+func (f *fakePermissionService) Check(ctx context.Context, req permission.CheckRequest) (*permission.CheckResult, error) {
+	if req.Object.Type == "broken" {
+		return nil, errors.New("openfga offline")
+	}
+	return &permission.CheckResult{Allowed: req.Relation == "can_read", AuthorizationModelID: "model-1"}, nil
+}
+
+// ListObjects devolve os quizzes q1 e q2 para user:u1, e q3 também quando há tupla contextual
+func (f *fakePermissionService) ListObjects(ctx context.Context, req permission.ListObjectsRequest) ([]permission.Object, error) {
+	if req.User != permission.UserObject("u1") {
+		return nil, nil
+	}
+	objects := []permission.Object{permission.NewObject(req.ObjectType, "q1"), permission.NewObject(req.ObjectType, "q2")}
+	for _, t := range req.ContextualTuples {
+		objects = append(objects, t.Object)
+	}
+	return objects, nil
+}
+
+func (f *fakePermissionService) ListUsers(ctx context.Context, req permission.ListUsersRequest) ([]permission.Object, error) {
+	return []permission.Object{permission.TenantObject("t1").Userset("admin")}, nil
+}
+
+func newTestRouter(service permission.PermissionServiceInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// As rotas exigem um cliente de serviço, ver SetupRoutes
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") != "" {
+			c.Set("user_id", c.GetHeader("X-Test-User"))
+			return
+		}
+		c.Set("client_id", "quiz-service")
+	})
+	handler := NewAuthzHandler(service)
+	router.POST("/check", handler.Check)
+	router.POST("/check/batch", handler.BatchCheck)
+	router.POST("/list-objects", handler.ListObjects)
+	router.POST("/list-users", handler.ListUsers)
+	return router
+}
+
+func TestCheck(t *testing.T) {
+	router := newTestRouter(&fakePermissionService{})
+
+	body := `{"user":"u1","relation":"can_read","object_type":"quiz","object_id":"q1"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	var response dto.AuthzCheckResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.Allowed || response.AuthorizationModelID != "model-1" {
+		t.Errorf("resposta inesperada %+v", response)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(`{"user":"u1"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("requisição incompleta: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestBatchCheckKeepsOrder(t *testing.T) {
+	router := newTestRouter(&fakePermissionService{})
+
+	body := `{"checks":[
+		{"user":"u1","relation":"can_read","object_type":"quiz","object_id":"q1"},
+		{"user":"u1","relation":"can_delete","object_type":"quiz","object_id":"q1"},
+		{"user":"u1","relation":"can_read","object_type":"broken","object_id":"q2"}
+	]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/check/batch", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	var response dto.AuthzBatchCheckResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Results) != 3 {
+		t.Fatalf("esperado 3 resultados, mas obteve %d", len(response.Results))
+	}
+	if !response.Results[0].Allowed || response.Results[1].Allowed {
+		t.Errorf("resultados fora de ordem: %+v", response.Results)
+	}
+	if response.Results[2].Error == "" || response.Results[2].ObjectID != "q2" {
+		t.Errorf("erro do terceiro item não reportado: %+v", response.Results[2])
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	router := newTestRouter(&fakePermissionService{})
+
+	body := `{"user":"user:u1","relation":"can_read","object_type":"quiz",
+		"contextual_tuples":[{"user":"u1","relation":"estudante","object":"quiz:q3"}]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/list-objects", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	var response dto.AuthzListObjectsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	expected := []string{"quiz:q1", "quiz:q2", "quiz:q3"}
+	if len(response.Objects) != len(expected) {
+		t.Fatalf("esperado %v, mas obteve %v", expected, response.Objects)
+	}
+	for i := range expected {
+		if response.Objects[i] != expected[i] {
+			t.Errorf("esperado %v, mas obteve %v", expected, response.Objects)
+		}
+	}
+
+	body = `{"user":"user:","relation":"can_read","object_type":"quiz"}`
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/list-objects", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("referência inválida: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestContextualTuplesOnlyFromServiceClients(t *testing.T) {
+	router := newTestRouter(&fakePermissionService{})
+
+	tuples := `"contextual_tuples":[{"user":"u1","relation":"admin","object":"tenant:t1"}]`
+	requests := map[string]string{
+		"/check":        `{"user":"u1","relation":"can_read","object_type":"quiz","object_id":"q1",` + tuples + `}`,
+		"/check/batch":  `{"checks":[{"user":"u1","relation":"can_read","object_type":"quiz","object_id":"q1",` + tuples + `}]}`,
+		"/list-objects": `{"user":"u1","relation":"can_read","object_type":"quiz",` + tuples + `}`,
+		"/list-users":   `{"object_type":"quiz","object_id":"q1","relation":"can_read","user_type":"user",` + tuples + `}`,
+	}
+
+	for path, body := range requests {
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		request.Header.Set("X-Test-User", "u1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s com usuário: esperado %d, mas obteve %d", path, http.StatusForbidden, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s com cliente de serviço: esperado %d, mas obteve %d", path, http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	router := newTestRouter(&fakePermissionService{})
+
+	body := `{"object_type":"quiz","object_id":"q1","relation":"can_read","user_type":"tenant","user_relation":"admin"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/list-users", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	var response dto.AuthzListUsersResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Users) != 1 || response.Users[0] != "tenant:t1#admin" {
+		t.Errorf("resposta inesperada %v", response.Users)
+	}
+}
+
+func TestCheckWithoutPermissionService(t *testing.T) {
+	router := newTestRouter(nil)
+
+	body := `{"user":"u1","relation":"can_read","object_type":"quiz","object_id":"q1"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(body)))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
@@ -0,0 +1,288 @@
+package authz
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+)
+
+const (
+	defaultModelPageSize = 20
+	modelPinHistorySize  = 20
+)
+
+// ModelHandler manages the versions of the authorization model
+type ModelHandler struct {
+	manager permission.ModelManagerInterface
+	pins    permission.ModelPinStoreInterface
+}
+
+func NewModelHandler(manager permission.ModelManagerInterface, pins permission.ModelPinStoreInterface) *ModelHandler {
+	return &ModelHandler{
+		manager: manager,
+		pins:    pins,
+	}
+}
+
+// @Summary Upload authorization model
+// @Description Register a new model version from JSON or OpenFGA DSL, optionally pinning it
+// @Tags authz
+// @Accept json
+// @Produce json
+// @Param request body dto.AuthzModelUploadRequest true "Model"
+// @Success 201 {object} dto.AuthzModelUploadResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 503 {object} handler.HttpMsg
+// @Router /api/v1/authz/models [post]
+func (h *ModelHandler) Upload(c *gin.Context) {
+	if h.manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	var request dto.AuthzModelUploadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if (len(request.Model) == 0) == (request.DSL == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either model or dsl"})
+		return
+	}
+
+	modelJSON := string(request.Model)
+	if request.DSL != "" {
+		var err error
+		if modelJSON, err = permission.ModelJSONFromDSL(request.DSL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	modelID, err := h.manager.RegisterModelFromJSON(c.Request.Context(), modelJSON)
+	if err != nil {
+		logger.Error("Error registering authorization model", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Pin {
+		if err := h.pin(c, modelID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Model registered but could not be pinned", "authorization_model_id": modelID})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, dto.AuthzModelUploadResponse{
+		AuthorizationModelID: modelID,
+		Pinned:               request.Pin,
+	})
+}
+
+// @Summary List authorization models
+// @Description List the model versions, newest first
+// @Tags authz
+// @Produce json
+// @Param page_size query int false "Page size"
+// @Param continuation_token query string false "Continuation token"
+// @Success 200 {object} dto.AuthzModelListResponse
+// @Failure 503 {object} handler.HttpMsg
+// @Router /api/v1/authz/models [get]
+func (h *ModelHandler) List(c *gin.Context) {
+	if h.manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultModelPageSize)))
+	if err != nil || pageSize < 1 || pageSize > 50 {
+		pageSize = defaultModelPageSize
+	}
+
+	models, continuationToken, err := h.manager.ListModels(c.Request.Context(), int32(pageSize), c.Query("continuation_token"))
+	if err != nil {
+		logger.Error("Error listing authorization models", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not list models"})
+		return
+	}
+
+	activeModelID, err := h.manager.ActiveModelID(c.Request.Context())
+	if err != nil {
+		logger.Error("Error reading active authorization model", err)
+	}
+
+	response := dto.AuthzModelListResponse{
+		Models:            make([]dto.AuthzModelSummary, len(models)),
+		ContinuationToken: continuationToken,
+		ActiveModelID:     activeModelID,
+	}
+	for i, model := range models {
+		types := make([]string, len(model.TypeDefinitions))
+		for j, typeDefinition := range model.TypeDefinitions {
+			types[j] = typeDefinition.Type
+		}
+		response.Models[i] = dto.AuthzModelSummary{
+			ID:            model.GetId(),
+			SchemaVersion: model.GetSchemaVersion(),
+			Types:         types,
+			Active:        model.GetId() == activeModelID,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get authorization model
+// @Description Get a model version in JSON format
+// @Tags authz
+// @Produce json
+// @Param id path string true "Model ID"
+// @Success 200 {object} object
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/authz/models/{id} [get]
+func (h *ModelHandler) GetByID(c *gin.Context) {
+	if h.manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	model, err := h.manager.ReadModel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		logger.Error("Error reading authorization model", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Model not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model)
+}
+
+// @Summary Diff authorization models
+// @Description Compare types, relations and conditions of two model versions
+// @Tags authz
+// @Produce json
+// @Param from query string true "Base model ID"
+// @Param to query string true "Target model ID"
+// @Success 200 {object} permission.ModelDiff
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/authz/models/diff [get]
+func (h *ModelHandler) Diff(c *gin.Context) {
+	if h.manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	fromID, toID := c.Query("from"), c.Query("to")
+	if fromID == "" || toID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameters from and to are required"})
+		return
+	}
+
+	from, err := h.manager.ReadModel(c.Request.Context(), fromID)
+	if err != nil {
+		logger.Error("Error reading authorization model", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Model not found: " + fromID})
+		return
+	}
+
+	to, err := h.manager.ReadModel(c.Request.Context(), toID)
+	if err != nil {
+		logger.Error("Error reading authorization model", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Model not found: " + toID})
+		return
+	}
+
+	c.JSON(http.StatusOK, permission.DiffModels(*from, *to))
+}
+
+// @Summary Get active authorization model
+// @Description Get the model evaluated by every check and the pin history
+// @Tags authz
+// @Produce json
+// @Success 200 {object} dto.AuthzModelActiveResponse
+// @Failure 503 {object} handler.HttpMsg
+// @Router /api/v1/authz/models/active [get]
+func (h *ModelHandler) GetActive(c *gin.Context) {
+	if h.manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	activeModelID, err := h.manager.ActiveModelID(c.Request.Context())
+	if err != nil {
+		logger.Error("Error reading active authorization model", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not read active model"})
+		return
+	}
+
+	history, err := h.pins.History(c.Request.Context(), modelPinHistorySize)
+	if err != nil {
+		logger.Error("Error reading authorization model pins", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read pin history"})
+		return
+	}
+
+	response := dto.AuthzModelActiveResponse{
+		ActiveModelID: activeModelID,
+		History:       make([]dto.AuthzModelPin, len(history)),
+	}
+	for i, pin := range history {
+		response.History[i] = dto.AuthzModelPin{ModelID: pin.ModelID, PinnedBy: pin.PinnedBy, PinnedAt: pin.PinnedAt}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Pin authorization model
+// @Description Pin the model version evaluated by every check
+// @Tags authz
+// @Accept json
+// @Produce json
+// @Param request body dto.AuthzModelPinRequest true "Model"
+// @Success 200 {object} dto.AuthzModelActiveResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/authz/models/active [put]
+func (h *ModelHandler) Pin(c *gin.Context) {
+	if h.manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+		return
+	}
+
+	var request dto.AuthzModelPinRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if _, err := h.manager.ReadModel(c.Request.Context(), request.ModelID); err != nil {
+		logger.Error("Error reading authorization model", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Model not found"})
+		return
+	}
+
+	if err := h.pin(c, request.ModelID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not pin model"})
+		return
+	}
+
+	h.GetActive(c)
+}
+
+// pin grava o modelo em tb_fga_model_pin e passa a avaliá-lo imediatamente nesta instância;
+// as demais instâncias o aplicam na próxima leitura de ModelPinStore.Watch
+func (h *ModelHandler) pin(c *gin.Context, modelID string) error {
+	if err := h.pins.Pin(c.Request.Context(), modelID, c.GetString("user_id")); err != nil {
+		return err
+	}
+
+	h.manager.SetModelID(modelID)
+	return nil
+}
@@ -0,0 +1,146 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+	openfga "github.com/openfga/go-sdk"
+)
+
+type fakeModelManager struct {
+	models  map[string]string
+	active  string
+	counter int
+}
+
+func (f *fakeModelManager) RegisterModelFromJSON(ctx context.Context, modelJSON string) (string, error) {
+	if !json.Valid([]byte(modelJSON)) {
+		return "", errors.New("modelo inválido")
+	}
+	f.counter++
+	id := fmt.Sprintf("model-%d", f.counter)
+	f.models[id] = modelJSON
+	return id, nil
+}
+
+func (f *fakeModelManager) ListModels(ctx context.Context, pageSize int32, continuationToken string) ([]openfga.AuthorizationModel, string, error) {
+	return nil, "", nil
+}
+
+func (f *fakeModelManager) ReadModel(ctx context.Context, modelID string) (*openfga.AuthorizationModel, error) {
+	if _, ok := f.models[modelID]; !ok {
+		return nil, errors.New("not found")
+	}
+	return &openfga.AuthorizationModel{Id: modelID}, nil
+}
+
+func (f *fakeModelManager) ActiveModelID(ctx context.Context) (string, error) {
+	return f.active, nil
+}
+
+func (f *fakeModelManager) SetModelID(modelID string) {
+	f.active = modelID
+}
+
+type fakeModelPinStore struct {
+	history []permission.ModelPin
+}
+
+func (f *fakeModelPinStore) Pin(ctx context.Context, modelID, pinnedBy string) error {
+	f.history = append([]permission.ModelPin{{ModelID: modelID, PinnedBy: pinnedBy, PinnedAt: time.Now()}}, f.history...)
+	return nil
+}
+
+func (f *fakeModelPinStore) Active(ctx context.Context) (string, error) {
+	if len(f.history) == 0 {
+		return "", nil
+	}
+	return f.history[0].ModelID, nil
+}
+
+func (f *fakeModelPinStore) History(ctx context.Context, limit int) ([]permission.ModelPin, error) {
+	return f.history, nil
+}
+
+func newModelTestRouter(manager *fakeModelManager, pins *fakeModelPinStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewModelHandler(manager, pins)
+	router.Use(func(c *gin.Context) { c.Set("user_id", "admin-1") })
+	router.POST("/models", handler.Upload)
+	router.PUT("/models/active", handler.Pin)
+	return router
+}
+
+func TestUploadModel(t *testing.T) {
+	manager := &fakeModelManager{models: map[string]string{}}
+	pins := &fakeModelPinStore{}
+	router := newModelTestRouter(manager, pins)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{name: "sem modelo", body: `{}`, expected: http.StatusBadRequest},
+		{name: "json e dsl", body: `{"model":{"schema_version":"1.1"},"dsl":"model"}`, expected: http.StatusBadRequest},
+		{name: "dsl inválida", body: `{"dsl":"model\n  schema 1.1\ntype"}`, expected: http.StatusBadRequest},
+		{name: "json", body: `{"model":{"schema_version":"1.1","type_definitions":[{"type":"user"}]}}`, expected: http.StatusCreated},
+		{name: "dsl fixada", body: `{"dsl":"model\n  schema 1.1\n\ntype user\n","pin":true}`, expected: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/models", strings.NewReader(tt.body)))
+		if w.Code != tt.expected {
+			t.Errorf("%s: esperado %d, mas obteve %d (%s)", tt.name, tt.expected, w.Code, w.Body.String())
+		}
+	}
+
+	if manager.active != "model-2" {
+		t.Errorf("modelo fixado esperado model-2, mas obteve %q", manager.active)
+	}
+	if len(pins.history) != 1 || pins.history[0].PinnedBy != "admin-1" {
+		t.Errorf("fixação não registrada: %+v", pins.history)
+	}
+}
+
+func TestPinModel(t *testing.T) {
+	manager := &fakeModelManager{models: map[string]string{"model-1": "{}", "model-2": "{}"}}
+	pins := &fakeModelPinStore{}
+	router := newModelTestRouter(manager, pins)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/models/active", strings.NewReader(`{"model_id":"unknown"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("modelo inexistente: esperado %d, mas obteve %d", http.StatusNotFound, w.Code)
+	}
+
+	for _, id := range []string{"model-1", "model-2"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/models/active", strings.NewReader(`{"model_id":"`+id+`"}`)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+		}
+	}
+
+	var response dto.AuthzModelActiveResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if response.ActiveModelID != "model-2" || manager.active != "model-2" {
+		t.Errorf("modelo ativo esperado model-2, mas obteve %q", response.ActiveModelID)
+	}
+	if len(response.History) != 2 || response.History[1].ModelID != "model-1" {
+		t.Errorf("histórico inesperado %+v", response.History)
+	}
+}
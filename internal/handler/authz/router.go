@@ -0,0 +1,37 @@
+package authz
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// SetupRoutes configures the authorization check routes used by downstream services.
+// The checks answer for any user and object, so they are limited to service clients
+// granted the authz:check scope and to the Admin
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *AuthzHandler, modelHandler *ModelHandler) {
+	authzRoutes := router.Group("/api/v1/authz")
+	{
+		guard.Register(authzRoutes, []middleware.Route{
+			{Method: http.MethodPost, Path: "/check", Access: middleware.Service, Scopes: []string{model.ScopeAuthz}, Roles: []string{model.RoleAdmin}, Handler: handler.Check},
+			{Method: http.MethodPost, Path: "/check/batch", Access: middleware.Service, Scopes: []string{model.ScopeAuthz}, Roles: []string{model.RoleAdmin}, Handler: handler.BatchCheck},
+			{Method: http.MethodPost, Path: "/list-objects", Access: middleware.Service, Scopes: []string{model.ScopeAuthz}, Roles: []string{model.RoleAdmin}, Handler: handler.ListObjects},
+			{Method: http.MethodPost, Path: "/list-users", Access: middleware.Service, Scopes: []string{model.ScopeAuthz}, Roles: []string{model.RoleAdmin}, Handler: handler.ListUsers},
+		})
+	}
+
+	// Gestão das versões do modelo de autorização, restrita ao Admin
+	modelRoutes := router.Group("/api/v1/authz/models")
+	{
+		guard.Register(modelRoutes, []middleware.Route{
+			{Method: http.MethodPost, Path: "", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: modelHandler.Upload},
+			{Method: http.MethodGet, Path: "", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: modelHandler.List},
+			{Method: http.MethodGet, Path: "/diff", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: modelHandler.Diff},
+			{Method: http.MethodGet, Path: "/active", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: modelHandler.GetActive},
+			{Method: http.MethodPut, Path: "/active", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: modelHandler.Pin},
+			{Method: http.MethodGet, Path: "/:id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: modelHandler.GetByID},
+		})
+	}
+}
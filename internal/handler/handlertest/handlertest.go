@@ -0,0 +1,64 @@
+// Package handlertest has the helpers shared by the router tests of the handlers: a router with
+// the guard of the configuration and access tokens signed with its HS256 secret
+package handlertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+)
+
+// Identifiers of the user and the tenant of the tokens of Claims
+const (
+	UserID   = "00000000-0000-0000-0000-000000000001"
+	TenantID = "00000000-0000-0000-0000-000000000002"
+)
+
+// NewRouter returns a router in test mode with the routes registered by setup, guarded by the
+// guard of conf without permission service nor token service
+func NewRouter(conf *config.Config, setup func(router *gin.Engine, guard *middleware.Guard)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setup(router, middleware.NewGuard(conf, nil, nil))
+	return router
+}
+
+// Claims returns the claims of an access token of the test user with role, valid for a minute
+func Claims(conf *config.Config, role string) *jwt.Claims {
+	return &jwt.Claims{
+		Username: "tester",
+		UserID:   UserID,
+		TenantID: TenantID,
+		Role:     role,
+		TokenID:  "test-token",
+		RegisteredClaims: jwtlib.RegisteredClaims{
+			ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(time.Minute)),
+			Issuer:    conf.JWTIssuer,
+			Audience:  jwtlib.ClaimStrings{conf.JWTAudience},
+			IssuedAt:  jwtlib.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+// Sign signs the claims with the HS256 secret of conf
+func Sign(t testing.TB, conf *config.Config, claims *jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwtlib.NewWithClaims(jwtlib.SigningMethodHS256, claims).SignedString([]byte(conf.JWTSecretKey))
+	if err != nil {
+		t.Fatalf("Erro ao assinar token de teste: %v", err)
+	}
+
+	return token
+}
+
+// SignToken signs an access token of the test user with role
+func SignToken(t testing.TB, conf *config.Config, role string) string {
+	t.Helper()
+	return Sign(t, conf, Claims(conf, role))
+}
@@ -0,0 +1,546 @@
+package mfa
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	service_ten "github.com/katana-stuidio/access-control/pkg/service/tenant"
+	service_ten_group "github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+)
+
+// MFAHandler implements the second step of the login, the TOTP self-service of the users
+// and the MFA policy of the tenants
+type MFAHandler struct {
+	conf               *config.Config
+	mfaService         mfa.MFAServiceInterface
+	challenges         mfa.ChallengeServiceInterface
+	userService        user.UserServiceInterface
+	tenantService      service_ten.TenantServiceInterface
+	tenantGroupService service_ten_group.TenantGroupServiceInterface
+	tokenService       token.TokenServiceInterface
+	permissions        jwt.PermissionResolver
+}
+
+func NewMFAHandler(conf *config.Config, mfaService mfa.MFAServiceInterface, challenges mfa.ChallengeServiceInterface,
+	userService user.UserServiceInterface, tenantService service_ten.TenantServiceInterface,
+	tenantGroupService service_ten_group.TenantGroupServiceInterface, tokenService token.TokenServiceInterface, permissions jwt.PermissionResolver) *MFAHandler {
+	return &MFAHandler{
+		conf:               conf,
+		mfaService:         mfaService,
+		challenges:         challenges,
+		userService:        userService,
+		tenantService:      tenantService,
+		tenantGroupService: tenantGroupService,
+		tokenService:       tokenService,
+		permissions:        permissions,
+	}
+}
+
+// @Summary Complete the login with MFA
+// @Description Check the TOTP code (or a recovery code) of the challenge returned by getjwt and issue the tokens.
+// @Description When the challenge required enrollment the code confirms the enrolled secret and the recovery codes are returned once.
+// @Description After 5 codes in 15 minutes, across every challenge of the user, the second factor is locked until the window ends
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body dto.MFAVerifyRequest true "Challenge and code"
+// @Success 200 {object} dto.MFAVerifyResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 401 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 423 {object} handler.HttpMsg
+// @Router /api/v1/user/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var request dto.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	challenge, usr, ok := h.challengeUser(c, request.MFAToken)
+	if !ok {
+		return
+	}
+
+	remaining, ok := h.attempt(c, challenge.UserID)
+	if !ok {
+		return
+	}
+
+	var recoveryCodes []string
+	err := h.mfaService.Verify(c.Request.Context(), usr.ID, request.Code)
+	if errors.Is(err, mfa.ErrMFANotEnabled) && challenge.EnrollmentRequired {
+		recoveryCodes, err = h.mfaService.Confirm(c.Request.Context(), usr.ID, request.Code)
+	}
+
+	switch {
+	case errors.Is(err, mfa.ErrMFANotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Enroll a secret with /api/v1/user/mfa/challenge/enroll first"})
+		return
+	case errors.Is(err, mfa.ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code", "attempts_remaining": remaining})
+		return
+	case err != nil:
+		logger.Error("Error verifying MFA code", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify MFA code"})
+		return
+	}
+	h.challenges.Succeed(c.Request.Context(), challenge.UserID)
+
+	// The challenge is single-use: a second request with the same token fails here
+	if _, err := h.challenges.Consume(c.Request.Context(), request.MFAToken); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	tenant := h.tenantService.GetByID(c.Request.Context(), model.GlobalScope(), usr.TenantID)
+	if tenant == nil || tenant.ID == uuid.Nil || !usr.Enable || !tenant.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User or tenant is disabled"})
+		return
+	}
+
+	tenantGroup := h.tenantGroupService.GetByID(c.Request.Context(), tenant.GroupID)
+
+	tokenDetails, err := jwt.GenerateToken(c.Request.Context(), usr, tenant, tenantGroup, challenge.ClientID, h.conf, h.tokenService, h.permissions)
+	if err != nil {
+		logger.Error("Failed to generate JWT: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MFAVerifyResponse{
+		AccessToken:   tokenDetails.AccessToken,
+		RefreshToken:  tokenDetails.RefreshToken,
+		TokenID:       tokenDetails.TokenID,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// @Summary Enroll MFA during the login
+// @Description Create the TOTP secret of a user whose tenant policy requires MFA, with the challenge returned by getjwt.
+// @Description The login completes with the first code of the authenticator app in /api/v1/user/mfa/verify
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body dto.MFAChallengeRequest true "Challenge"
+// @Success 200 {object} dto.MFAEnrollResponse
+// @Failure 401 {object} handler.HttpMsg
+// @Failure 409 {object} handler.HttpMsg
+// @Router /api/v1/user/mfa/challenge/enroll [post]
+func (h *MFAHandler) ChallengeEnroll(c *gin.Context) {
+	var request dto.MFAChallengeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	challenge, usr, ok := h.challengeUser(c, request.MFAToken)
+	if !ok {
+		return
+	}
+
+	if !challenge.EnrollmentRequired {
+		c.JSON(http.StatusConflict, gin.H{"error": "MFA is already enabled"})
+		return
+	}
+
+	h.enroll(c, usr)
+}
+
+// @Summary Get MFA status
+// @Description Second factor state of the authenticated user
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} model.MFAStatus
+// @Failure 401 {object} handler.HttpMsg
+// @Router /api/v1/user/mfa [get]
+func (h *MFAHandler) Status(c *gin.Context) {
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfaService.Status(c.Request.Context(), usr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read MFA status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// @Summary Enroll MFA
+// @Description Create a TOTP secret for the authenticated user, replacing a secret not confirmed yet.
+// @Description MFA is enabled by POST /api/v1/user/mfa/confirm with the first code of the authenticator app
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} dto.MFAEnrollResponse
+// @Failure 409 {object} handler.HttpMsg
+// @Router /api/v1/user/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	h.enroll(c, usr)
+}
+
+// @Summary Confirm MFA
+// @Description Enable the enrolled TOTP secret with a code of the authenticator app. The recovery codes are returned only once
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body dto.MFACodeRequest true "TOTP code"
+// @Success 200 {object} dto.MFARecoveryCodesResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 401 {object} handler.HttpMsg
+// @Router /api/v1/user/mfa/confirm [post]
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	var request dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfaService.Confirm(c.Request.Context(), usr.ID, request.Code)
+	switch {
+	case errors.Is(err, mfa.ErrMFANotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No pending MFA enrollment"})
+		return
+	case errors.Is(err, mfa.ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not confirm MFA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replace the recovery codes of the authenticated user, the previous codes stop working
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body dto.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} dto.MFARecoveryCodesResponse
+// @Failure 401 {object} handler.HttpMsg
+// @Router /api/v1/user/mfa/recovery-codes [post]
+func (h *MFAHandler) RecoveryCodes(c *gin.Context) {
+	usr, ok := h.verifiedUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), usr.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary Disable MFA
+// @Description Remove the TOTP secret and the recovery codes of the authenticated user.
+// @Description Not allowed when the tenant policy requires MFA for one of the user roles
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body dto.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} handler.HttpMsg
+// @Failure 401 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Router /api/v1/user/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	usr, ok := h.verifiedUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfaService.Status(c.Request.Context(), usr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read MFA status"})
+		return
+	}
+	if status.Required {
+		c.JSON(http.StatusForbidden, gin.H{"error": "MFA is required by the tenant policy"})
+		return
+	}
+
+	if _, err := h.mfaService.Disable(c.Request.Context(), usr.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not disable MFA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "MFA disabled"})
+}
+
+// @Summary Reset user MFA
+// @Description Remove the second factor of a user who lost the authenticator and the recovery codes.
+// @Description When the tenant policy requires MFA the user enrolls again at the next login.
+// @Description Users with a role of a wider scope than the caller (ex: Admin) cannot be reset
+// @Tags mfa
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/user/{id}/mfa [delete]
+func (h *MFAHandler) Reset(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	scope := model.TenantScopeFromContext(c.Request.Context())
+
+	usr := h.userService.GetByID(c.Request.Context(), scope, id)
+	if usr == nil || usr.ID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// As in the user updates, an Instituicao cannot reset the second factor of an Admin of its tenant
+	if model.ScopeLevelForRoles(usr.AllRoles()...) > scope.Level {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Role is outside the caller scope"})
+		return
+	}
+
+	rowsAffected, err := h.mfaService.Disable(c.Request.Context(), usr.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset MFA"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "MFA is not enabled for the user"})
+		return
+	}
+
+	logger.Info("MFA of user " + usr.ID.String() + " reset by user " + c.GetString("user_id"))
+	c.JSON(http.StatusOK, gin.H{"message": "MFA reset"})
+}
+
+// @Summary Get tenant MFA policy
+// @Description Roles that must log in with a second factor in the tenant
+// @Tags mfa
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} dto.MFAPolicyResponse
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/Tenant/{id}/mfa-policy [get]
+func (h *MFAHandler) GetPolicy(c *gin.Context) {
+	tenantID, ok := h.policyTenant(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.mfaService.GetPolicy(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read MFA policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policyResponse(policy))
+}
+
+// @Summary Set tenant MFA policy
+// @Description Replace the roles that must log in with a second factor in the tenant.
+// @Description Users of these roles without MFA enroll a secret at the next login
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant ID"
+// @Param policy body dto.MFAPolicyRequest true "Required roles"
+// @Success 200 {object} dto.MFAPolicyResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/Tenant/{id}/mfa-policy [put]
+func (h *MFAHandler) SetPolicy(c *gin.Context) {
+	var request dto.MFAPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	tenantID, ok := h.policyTenant(c)
+	if !ok {
+		return
+	}
+
+	policy := &model.MFAPolicy{TenantID: tenantID, RequiredRoles: []string{}}
+	for _, role := range request.RequiredRoles {
+		if !model.ValidRoleName(role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role name: " + role})
+			return
+		}
+		if !policy.Requires([]string{role}) {
+			policy.RequiredRoles = append(policy.RequiredRoles, role)
+		}
+	}
+
+	if err := h.mfaService.SetPolicy(c.Request.Context(), policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save MFA policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policyResponse(policy))
+}
+
+func (h *MFAHandler) enroll(c *gin.Context, usr *model.User) {
+	secret, err := h.mfaService.Enroll(c.Request.Context(), usr.ID)
+	if errors.Is(err, mfa.ErrMFAAlreadyEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "MFA is already enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not enroll MFA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: mfa.ProvisioningURI(h.conf.MFAIssuer, usr.Username, secret),
+	})
+}
+
+// challengeUser reads the challenge of the token and its user, writing the error response when they are not valid
+func (h *MFAHandler) challengeUser(c *gin.Context, mfaToken string) (*mfa.Challenge, *model.User, bool) {
+	challenge, err := h.challenges.Get(c.Request.Context(), mfaToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return nil, nil, false
+	}
+
+	id, err := uuid.Parse(challenge.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return nil, nil, false
+	}
+
+	usr := h.userService.GetByID(c.Request.Context(), model.GlobalScope(), id)
+	if usr == nil || usr.ID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return nil, nil, false
+	}
+
+	return challenge, usr, true
+}
+
+// currentUser reads the authenticated user
+func (h *MFAHandler) currentUser(c *gin.Context) (*model.User, bool) {
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return nil, false
+	}
+
+	usr := h.userService.GetByID(c.Request.Context(), model.GlobalScope(), id)
+	if usr == nil || usr.ID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return nil, false
+	}
+
+	return usr, true
+}
+
+// verifiedUser reads the authenticated user and checks the code of the request, so a stolen
+// access token alone cannot turn MFA off nor read new recovery codes
+func (h *MFAHandler) verifiedUser(c *gin.Context) (*model.User, bool) {
+	var request dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return nil, false
+	}
+
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	remaining, ok := h.attempt(c, usr.ID.String())
+	if !ok {
+		return nil, false
+	}
+
+	err := h.mfaService.Verify(c.Request.Context(), usr.ID, request.Code)
+	switch {
+	case errors.Is(err, mfa.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "MFA is not enabled"})
+		return nil, false
+	case errors.Is(err, mfa.ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code", "attempts_remaining": remaining})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify MFA code"})
+		return nil, false
+	}
+	h.challenges.Succeed(c.Request.Context(), usr.ID.String())
+
+	return usr, true
+}
+
+// attempt counts a code of the user before it is checked, so concurrent requests cannot exceed
+// the limit, and writes 423 once the user has no attempt left
+func (h *MFAHandler) attempt(c *gin.Context, userID string) (remaining int, ok bool) {
+	remaining, retryAfter, err := h.challenges.Attempt(c.Request.Context(), userID)
+	if errors.Is(err, mfa.ErrLocked) {
+		seconds := int(retryAfter.Seconds())
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusLocked, gin.H{"error": "MFA temporarily locked after too many wrong codes", "retry_after": seconds})
+		return 0, false
+	}
+	if err != nil {
+		logger.Error("Error counting MFA attempt", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not verify MFA code"})
+		return 0, false
+	}
+
+	return remaining, true
+}
+
+// policyTenant reads the tenant of the path inside the caller scope
+func (h *MFAHandler) policyTenant(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return uuid.Nil, false
+	}
+
+	scope := model.TenantScopeFromContext(c.Request.Context())
+
+	tenant := h.tenantService.GetByID(c.Request.Context(), scope, id)
+	if tenant == nil || tenant.ID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return uuid.Nil, false
+	}
+
+	return tenant.ID, true
+}
+
+func policyResponse(policy *model.MFAPolicy) dto.MFAPolicyResponse {
+	return dto.MFAPolicyResponse{
+		TenantID:      policy.TenantID,
+		RequiredRoles: policy.RequiredRoles,
+		UpdatedAt:     policy.UpdatedAt,
+	}
+}
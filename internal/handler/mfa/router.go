@@ -0,0 +1,37 @@
+package mfa
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// SetupRoutes configures the MFA routes of the users and the MFA policy of the tenants
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *MFAHandler) {
+	userRoutes := router.Group("/api/v1/user")
+	{
+		guard.Register(userRoutes, []middleware.Route{
+			// Second step of the login, authenticated by the mfa_token of getjwt
+			{Method: http.MethodPost, Path: "/mfa/verify", Access: middleware.Public, Handler: handler.Verify},
+			{Method: http.MethodPost, Path: "/mfa/challenge/enroll", Access: middleware.Public, Handler: handler.ChallengeEnroll},
+
+			{Method: http.MethodGet, Path: "/mfa", Access: middleware.Authenticated, Handler: handler.Status},
+			{Method: http.MethodPost, Path: "/mfa/enroll", Access: middleware.Authenticated, Handler: handler.Enroll},
+			{Method: http.MethodPost, Path: "/mfa/confirm", Access: middleware.Authenticated, Handler: handler.Confirm},
+			{Method: http.MethodPost, Path: "/mfa/recovery-codes", Access: middleware.Authenticated, Handler: handler.RecoveryCodes},
+			{Method: http.MethodPost, Path: "/mfa/disable", Access: middleware.Authenticated, Handler: handler.Disable},
+			{Method: http.MethodDelete, Path: "/:id/mfa", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: handler.Reset},
+		})
+	}
+
+	tenantRoutes := router.Group("/api/v1/Tenant")
+	{
+		managers := []string{model.RoleAdmin, model.RoleGrupoEducacional, model.RoleInstituicao}
+		guard.Register(tenantRoutes, []middleware.Route{
+			{Method: http.MethodGet, Path: "/:id/mfa-policy", Access: middleware.Restricted, Roles: managers, Handler: handler.GetPolicy},
+			{Method: http.MethodPut, Path: "/:id/mfa-policy", Access: middleware.Restricted, Roles: managers, Handler: handler.SetPolicy},
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package mfa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/handler/handlertest"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+)
+
+var (
+	testUserID   = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	testTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	testAdminID  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+// fakeMFAService accepts only validCode; a user without MFA confirms the enrollment with it
+type fakeMFAService struct {
+	mfa.MFAServiceInterface
+	enabled  bool
+	enrolled bool
+}
+
+const validCode = "123456"
+
+func (f *fakeMFAService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	if !f.enabled {
+		return mfa.ErrMFANotEnabled
+	}
+	if code != validCode {
+		return mfa.ErrInvalidCode
+	}
+	return nil
+}
+
+func (f *fakeMFAService) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if !f.enrolled {
+		return nil, mfa.ErrMFANotEnrolled
+	}
+	if code != validCode {
+		return nil, mfa.ErrInvalidCode
+	}
+	f.enabled = true
+	return []string{"aaaaa-bbbbb"}, nil
+}
+
+func (f *fakeMFAService) Disable(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeMFAService) Enroll(ctx context.Context, userID uuid.UUID) (string, error) {
+	if f.enabled {
+		return "", mfa.ErrMFAAlreadyEnabled
+	}
+	f.enrolled = true
+	return "JBSWY3DPEHPK3PXP", nil
+}
+
+// fakeChallengeService keeps the challenges in memory
+type fakeChallengeService struct {
+	challenges map[string]*mfa.Challenge
+	attempts   map[string]int
+}
+
+func (f *fakeChallengeService) Issue(ctx context.Context, challenge *mfa.Challenge) (string, error) {
+	token := uuid.NewString()
+	f.challenges[token] = challenge
+	return token, nil
+}
+
+func (f *fakeChallengeService) Get(ctx context.Context, token string) (*mfa.Challenge, error) {
+	challenge, ok := f.challenges[token]
+	if !ok {
+		return nil, mfa.ErrChallengeNotFound
+	}
+	return challenge, nil
+}
+
+func (f *fakeChallengeService) Attempt(ctx context.Context, userID string) (int, time.Duration, error) {
+	f.attempts[userID]++
+	if f.attempts[userID] > mfa.MaxAttempts {
+		return 0, time.Minute, mfa.ErrLocked
+	}
+	return mfa.MaxAttempts - f.attempts[userID], 0, nil
+}
+
+func (f *fakeChallengeService) Succeed(ctx context.Context, userID string) {
+	delete(f.attempts, userID)
+}
+
+func (f *fakeChallengeService) Consume(ctx context.Context, token string) (*mfa.Challenge, error) {
+	challenge, ok := f.challenges[token]
+	if !ok {
+		return nil, mfa.ErrChallengeNotFound
+	}
+	delete(f.challenges, token)
+	return challenge, nil
+}
+
+type fakeUserService struct {
+	user.UserServiceInterface
+}
+
+func (f *fakeUserService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	if ID == testAdminID {
+		return &model.User{ID: testAdminID, TenantID: testTenantID, Username: "admin", Role: model.RoleAdmin, Enable: true}
+	}
+	if ID != testUserID {
+		return &model.User{}
+	}
+	return &model.User{ID: testUserID, TenantID: testTenantID, Username: "maria", Role: model.RoleInstituicao, Enable: true}
+}
+
+type fakeTenantService struct {
+	tenant.TenantServiceInterface
+}
+
+func (f *fakeTenantService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Tenant {
+	if ID != testTenantID {
+		return &model.Tenant{}
+	}
+	return &model.Tenant{ID: testTenantID, Name: "Escola", IsActive: true}
+}
+
+type fakeTenantGroupService struct {
+	tenant_group.TenantGroupServiceInterface
+}
+
+func (f *fakeTenantGroupService) GetByID(ctx context.Context, ID uuid.UUID) *model.TenantGroup {
+	return &model.TenantGroup{}
+}
+
+type fakeTokenService struct {
+	token.TokenServiceInterface
+}
+
+func (f *fakeTokenService) SaveRefreshToken(ctx context.Context, tokenID, familyID, userID, username, tenantID, role string, issuedAt, exp time.Time) error {
+	return nil
+}
+
+type testServer struct {
+	router     *gin.Engine
+	mfa        *fakeMFAService
+	challenges *fakeChallengeService
+}
+
+func newTestServer(conf *config.Config) *testServer {
+	gin.SetMode(gin.TestMode)
+
+	server := &testServer{
+		router:     gin.New(),
+		mfa:        &fakeMFAService{},
+		challenges: &fakeChallengeService{challenges: map[string]*mfa.Challenge{}, attempts: map[string]int{}},
+	}
+	handler := NewMFAHandler(conf, server.mfa, server.challenges, &fakeUserService{}, &fakeTenantService{}, &fakeTenantGroupService{}, &fakeTokenService{}, nil)
+	SetupRoutes(server.router, middleware.NewGuard(conf, nil, nil), handler)
+	return server
+}
+
+func request(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRoutesRequireToken(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(conf)
+
+	public := map[string]bool{
+		"POST /api/v1/user/mfa/verify":           true,
+		"POST /api/v1/user/mfa/challenge/enroll": true,
+	}
+
+	for _, route := range server.router.Routes() {
+		if public[route.Method+" "+route.Path] {
+			continue
+		}
+		if w := request(server.router, route.Method, route.Path, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s sem token: esperado %d, mas obteve %d", route.Method, route.Path, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
+
+func TestVerifyIssuesTokens(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(conf)
+	server.mfa.enabled = true
+
+	mfaToken, _ := server.challenges.Issue(context.Background(), &mfa.Challenge{UserID: testUserID.String()})
+
+	w := request(server.router, http.MethodPost, "/api/v1/user/mfa/verify", `{"mfa_token":"`+mfaToken+`","code":"000000"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("código errado: esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"attempts_remaining":4`) {
+		t.Errorf("tentativas restantes ausentes: %s", w.Body.String())
+	}
+
+	w = request(server.router, http.MethodPost, "/api/v1/user/mfa/verify", `{"mfa_token":"`+mfaToken+`","code":"`+validCode+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response dto.MFAVerifyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.AccessToken == "" {
+		t.Fatalf("tokens ausentes: %s", w.Body.String())
+	}
+	if len(response.RecoveryCodes) != 0 {
+		t.Error("códigos de recuperação só são devolvidos no cadastro")
+	}
+
+	// O desafio é de uso único
+	w = request(server.router, http.MethodPost, "/api/v1/user/mfa/verify", `{"mfa_token":"`+mfaToken+`","code":"`+validCode+`"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("desafio reutilizado: esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestVerifyLocksAfterAttemptsAcrossChallenges(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(conf)
+	server.mfa.enabled = true
+
+	// Um novo desafio a cada tentativa não renova o limite do usuário
+	for i := 0; i < mfa.MaxAttempts; i++ {
+		mfaToken, _ := server.challenges.Issue(context.Background(), &mfa.Challenge{UserID: testUserID.String()})
+		w := request(server.router, http.MethodPost, "/api/v1/user/mfa/verify", `{"mfa_token":"`+mfaToken+`","code":"000000"}`)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("tentativa %d: esperado %d, mas obteve %d", i+1, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	mfaToken, _ := server.challenges.Issue(context.Background(), &mfa.Challenge{UserID: testUserID.String()})
+	w := request(server.router, http.MethodPost, "/api/v1/user/mfa/verify", `{"mfa_token":"`+mfaToken+`","code":"`+validCode+`"}`)
+	if w.Code != http.StatusLocked || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("limite esgotado: esperado %d com Retry-After, mas obteve %d %q", http.StatusLocked, w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestResetRespectsCallerScope(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(conf)
+	token := handlertest.SignToken(t, conf, model.RoleInstituicao)
+
+	cases := map[uuid.UUID]int{
+		testUserID:  http.StatusOK,
+		testAdminID: http.StatusForbidden,
+	}
+
+	for id, expected := range cases {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/user/"+id.String()+"/mfa", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != expected {
+			t.Errorf("usuário %s: esperado %d, mas obteve %d", id, expected, w.Code)
+		}
+	}
+}
+
+func TestEnrollmentDuringLogin(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(conf)
+
+	mfaToken, _ := server.challenges.Issue(context.Background(), &mfa.Challenge{UserID: testUserID.String(), EnrollmentRequired: true})
+	body := `{"mfa_token":"` + mfaToken + `","code":"` + validCode + `"}`
+
+	// Sem segredo cadastrado o código não pode ser conferido
+	if w := request(server.router, http.MethodPost, "/api/v1/user/mfa/verify", body); w.Code != http.StatusBadRequest {
+		t.Fatalf("sem cadastro: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+
+	w := request(server.router, http.MethodPost, "/api/v1/user/mfa/challenge/enroll", `{"mfa_token":"`+mfaToken+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("cadastro: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "otpauth://totp/") {
+		t.Errorf("URI de cadastro ausente: %s", w.Body.String())
+	}
+
+	w = request(server.router, http.MethodPost, "/api/v1/user/mfa/verify", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("confirmação: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response dto.MFAVerifyResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.AccessToken == "" || len(response.RecoveryCodes) == 0 {
+		t.Errorf("esperados tokens e códigos de recuperação: %s", w.Body.String())
+	}
+}
+
+func TestChallengeEnrollRejectsEnabledUser(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(conf)
+	server.mfa.enabled = true
+
+	mfaToken, _ := server.challenges.Issue(context.Background(), &mfa.Challenge{UserID: testUserID.String()})
+
+	// Com MFA habilitado a senha não basta para trocar o segredo
+	if w := request(server.router, http.MethodPost, "/api/v1/user/mfa/challenge/enroll", `{"mfa_token":"`+mfaToken+`"}`); w.Code != http.StatusConflict {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusConflict, w.Code)
+	}
+}
@@ -0,0 +1,374 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/authcode"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+)
+
+const pkceMethodS256 = "S256"
+
+// authorizeRequest is a validated authorization request (RFC 6749, section 4.1.1)
+type authorizeRequest struct {
+	Client              *model.OAuthClient
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+
+	// RequestedRedirectURI is the redirect_uri parameter as sent, empty when the
+	// registered URI was used, and is kept by the login form
+	RequestedRedirectURI string
+}
+
+// Scope is the space separated list of the granted scopes
+func (r *authorizeRequest) Scope() string {
+	return strings.Join(r.Scopes, " ")
+}
+
+// loginPage is the data of the login and consent page
+type loginPage struct {
+	*authorizeRequest
+	Username string
+	Error    string
+	// MFARequired shows the field of the second factor code
+	MFARequired bool
+}
+
+// @Summary OAuth 2.0 authorization endpoint
+// @Description Show the login page of the authorization code flow. PKCE with S256 is required.
+// @Tags oauth
+// @Produce html
+// @Param response_type query string true "code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string false "Registered redirect URI"
+// @Param scope query string false "Space separated scopes"
+// @Param state query string false "Opaque value returned to the client"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "S256"
+// @Param nonce query string false "OpenID Connect nonce, returned in the ID token"
+// @Success 200
+// @Failure 302
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	request, ok := h.authorizeRequest(c)
+	if !ok {
+		return
+	}
+
+	renderLogin(c, http.StatusOK, loginPage{authorizeRequest: request})
+}
+
+// @Summary OAuth 2.0 authorization decision
+// @Description Authenticate the user of the login page and redirect to the client with the authorization code.
+// @Description First-party clients are authorized at login, the other clients need the user consent (decision=allow).
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param username formData string true "Username"
+// @Param password formData string true "Password"
+// @Param otp formData string false "TOTP or recovery code, when the user has MFA"
+// @Param decision formData string false "allow or deny"
+// @Success 303
+// @Failure 401
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) AuthorizeDecision(c *gin.Context) {
+	request, ok := h.authorizeRequest(c)
+	if !ok {
+		return
+	}
+
+	if c.PostForm("decision") == "deny" {
+		redirectAuthorizeError(c, request, errAccessDenied, "the user denied the request")
+		return
+	}
+
+	page := loginPage{authorizeRequest: request, Username: c.PostForm("username")}
+
+	// The login page has the same brute-force protection as getjwt
+	if h.lockoutService != nil {
+		if retryAfter, err := h.lockoutService.Check(c.Request.Context(), page.Username, c.ClientIP()); err != nil {
+			renderLoginLimited(c, page, retryAfter, err)
+			return
+		}
+	}
+
+	usr, err := h.userService.Authenticate(c.PostForm("username"), c.PostForm("password"))
+	if err != nil {
+		if h.lockoutService != nil {
+			if retryAfter, err := h.lockoutService.Fail(c.Request.Context(), page.Username, c.ClientIP()); err != nil {
+				renderLoginLimited(c, page, retryAfter, err)
+				return
+			}
+		}
+		page.Error = "Usuário ou senha inválidos"
+		renderLogin(c, http.StatusUnauthorized, page)
+		return
+	}
+	if h.lockoutService != nil {
+		h.lockoutService.Succeed(c.Request.Context(), page.Username)
+	}
+
+	tenant := h.tenantService.GetByID(c.Request.Context(), model.GlobalScope(), usr.TenantID)
+	if !usr.Enable || tenant == nil || tenant.ID == uuid.Nil || !tenant.IsActive {
+		page.Error = "Usuário ou instituição desativados"
+		renderLogin(c, http.StatusForbidden, page)
+		return
+	}
+
+	// As in getjwt, an expired password opens no session
+	expired, err := h.userService.PasswordExpired(c.Request.Context(), usr)
+	if err != nil {
+		page.Error = "Não foi possível verificar a senha, tente novamente"
+		renderLogin(c, http.StatusInternalServerError, page)
+		return
+	}
+	if expired {
+		page.Error = "Sua senha expirou: use a opção de esqueci minha senha para cadastrar uma nova"
+		renderLogin(c, http.StatusForbidden, page)
+		return
+	}
+
+	if !h.secondFactor(c, usr, &page) {
+		return
+	}
+
+	// Only first-party clients serve every tenant, the others only their own users
+	if !request.Client.FirstParty {
+		if usr.TenantID != request.Client.TenantID {
+			page.Error = "Este aplicativo não está disponível para a sua instituição"
+			renderLogin(c, http.StatusForbidden, page)
+			return
+		}
+		if c.PostForm("decision") != "allow" {
+			page.Error = "Autorize o acesso do aplicativo para continuar"
+			renderLogin(c, http.StatusBadRequest, page)
+			return
+		}
+	}
+
+	code, err := h.codes.Issue(c.Request.Context(), &authcode.AuthorizationCode{
+		ClientID:            request.Client.ClientID,
+		RedirectURI:         request.RedirectURI,
+		RedirectURIExplicit: request.RequestedRedirectURI != "",
+		UserID:              usr.ID.String(),
+		TenantID:            usr.TenantID.String(),
+		Scopes:              request.Scopes,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		Nonce:               request.Nonce,
+		AuthTime:            time.Now(),
+	})
+	if err != nil {
+		logger.Error("Error issuing authorization code", err)
+		redirectAuthorizeError(c, request, errServerError, "")
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	c.Redirect(http.StatusSeeOther, withQuery(request.RedirectURI, params))
+}
+
+// secondFactor checks the otp field of the login form when the user has MFA or the tenant policy
+// requires it, and renders the login page again otherwise. Enrollment is not offered here, the
+// user enrolls through the login of the API (POST /api/v1/user/getjwt) first. The codes count
+// against the same per-user limit as /api/v1/user/mfa/verify: the correct password already
+// cleared the login failures, so they are not counted by the lockout service.
+func (h *OAuthHandler) secondFactor(c *gin.Context, usr *model.User, page *loginPage) bool {
+	if h.mfaService == nil {
+		return true
+	}
+
+	status, err := h.mfaService.Status(c.Request.Context(), usr)
+	if err != nil {
+		logger.Error("Error reading MFA status", err)
+		page.Error = "Não foi possível verificar o segundo fator, tente novamente"
+		renderLogin(c, http.StatusInternalServerError, *page)
+		return false
+	}
+	if !status.ChallengeRequired() {
+		return true
+	}
+
+	if !status.Enabled {
+		page.Error = "Sua instituição exige autenticação em dois fatores: cadastre o aplicativo autenticador antes de continuar"
+		renderLogin(c, http.StatusForbidden, *page)
+		return false
+	}
+
+	page.MFARequired = true
+	code := c.PostForm("otp")
+	if code == "" {
+		page.Error = "Informe o código do aplicativo autenticador"
+		renderLogin(c, http.StatusUnauthorized, *page)
+		return false
+	}
+
+	_, retryAfter, err := h.challenges.Attempt(c.Request.Context(), usr.ID.String())
+	if errors.Is(err, mfa.ErrLocked) {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		page.Error = fmt.Sprintf("Segundo fator bloqueado por excesso de códigos inválidos. Tente novamente em %d minutos", int(math.Ceil(retryAfter.Minutes())))
+		renderLogin(c, http.StatusLocked, *page)
+		return false
+	}
+	if err != nil {
+		logger.Error("Error counting MFA attempt", err)
+		page.Error = "Não foi possível verificar o segundo fator, tente novamente"
+		renderLogin(c, http.StatusServiceUnavailable, *page)
+		return false
+	}
+
+	if err := h.mfaService.Verify(c.Request.Context(), usr.ID, code); err != nil {
+		if !errors.Is(err, mfa.ErrInvalidCode) {
+			logger.Error("Error verifying MFA code", err)
+		}
+		page.Error = "Código inválido"
+		renderLogin(c, http.StatusUnauthorized, *page)
+		return false
+	}
+	h.challenges.Succeed(c.Request.Context(), usr.ID.String())
+
+	return true
+}
+
+// authorizeRequest validates the parameters of the query (GET) or of the login form (POST).
+// Errors in client_id or redirect_uri are shown to the user: redirecting to an unverified
+// URI would make this an open redirector (RFC 6749, section 4.1.2.1). The others go back to the client.
+func (h *OAuthHandler) authorizeRequest(c *gin.Context) (*authorizeRequest, bool) {
+	param := c.Query
+	if c.Request.Method == http.MethodPost {
+		param = c.PostForm
+	}
+
+	client, err := h.clients.GetByClientID(c.Request.Context(), param("client_id"))
+	if err != nil || client == nil || !client.Enable {
+		renderAuthorizeError(c, "Aplicativo desconhecido")
+		return nil, false
+	}
+
+	redirectURI := param("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.ValidRedirectURI(redirectURI) {
+		renderAuthorizeError(c, "Endereço de retorno não registrado para o aplicativo")
+		return nil, false
+	}
+
+	request := &authorizeRequest{
+		Client:              client,
+		RedirectURI:         redirectURI,
+		State:               param("state"),
+		CodeChallenge:       param("code_challenge"),
+		CodeChallengeMethod: param("code_challenge_method"),
+		Nonce:               param("nonce"),
+
+		RequestedRedirectURI: param("redirect_uri"),
+	}
+
+	if param("response_type") != "code" {
+		redirectAuthorizeError(c, request, errUnsupportedResponseType, "only the code response type is supported")
+		return nil, false
+	}
+
+	// PKCE is required of every client and the plain method is not accepted
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != pkceMethodS256 {
+		redirectAuthorizeError(c, request, errInvalidRequest, "code_challenge with code_challenge_method S256 is required")
+		return nil, false
+	}
+
+	scopes, ok := client.GrantUserScopes(param("scope"))
+	if !ok {
+		redirectAuthorizeError(c, request, errInvalidScope, "scope not allowed for this client")
+		return nil, false
+	}
+	request.Scopes = scopes
+
+	// Without a KeyRing and an https issuer the client could not verify the ID token
+	if model.HasScope(request.Scope(), model.ScopeOpenID) && !jwt.IDTokensSupported(h.conf) {
+		redirectAuthorizeError(c, request, errInvalidScope, "the openid scope is not enabled on this server")
+		return nil, false
+	}
+
+	return request, true
+}
+
+func redirectAuthorizeError(c *gin.Context, request *authorizeRequest, code, description string) {
+	params := url.Values{"error": {code}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	c.Redirect(http.StatusSeeOther, withQuery(request.RedirectURI, params))
+}
+
+// withQuery adds params to the query of the registered redirect URI, keeping its own parameters
+func withQuery(uri string, params url.Values) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func renderAuthorizeError(c *gin.Context, message string) {
+	renderPage(c, http.StatusBadRequest, authorizeErrorTemplate, message)
+}
+
+// renderLoginLimited shows the login refused by the brute-force protection
+func renderLoginLimited(c *gin.Context, page loginPage, retryAfter time.Duration, err error) {
+	c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+
+	if errors.Is(err, lockout.ErrLocked) {
+		page.Error = fmt.Sprintf("Acesso bloqueado por excesso de tentativas. Tente novamente em %d minutos", int(math.Ceil(retryAfter.Minutes())))
+		renderLogin(c, http.StatusLocked, page)
+		return
+	}
+	page.Error = fmt.Sprintf("Muitas tentativas de login. Aguarde %d segundos", int(retryAfter.Seconds()))
+	renderLogin(c, http.StatusTooManyRequests, page)
+}
+
+func renderLogin(c *gin.Context, status int, page loginPage) {
+	renderPage(c, status, loginTemplate, page)
+}
+
+func renderPage(c *gin.Context, status int, tmpl *template.Template, data interface{}) {
+	// The page takes credentials: it must not be cached nor framed by another site (clickjacking)
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		logger.Error("Error rendering authorization page", err)
+	}
+}
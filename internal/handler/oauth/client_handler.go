@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// @Summary Register OAuth client
+// @Description Register a client bound to a tenant. The client secret is only returned in this response
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Param request body dto.OAuthClientCreateRequest true "Client"
+// @Success 201 {object} dto.OAuthClientResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Router /api/v1/oauth/clients [post]
+func (h *OAuthHandler) CreateClient(c *gin.Context) {
+	var request dto.OAuthClientCreateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	tenant := h.tenantService.GetByID(c.Request.Context(), model.GlobalScope(), request.TenantID)
+	if tenant == nil || tenant.ID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tenant not found"})
+		return
+	}
+
+	for _, uri := range request.RedirectURIs {
+		if !validRedirectURI(uri) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect URI: " + uri})
+			return
+		}
+	}
+
+	client, secret, err := model.NewOAuthClient(&model.OAuthClient{
+		Name:         request.Name,
+		Scopes:       request.Scopes,
+		TenantID:     request.TenantID,
+		RedirectURIs: request.RedirectURIs,
+		FirstParty:   request.FirstParty,
+		Public:       request.Public,
+	})
+	if err != nil {
+		logger.Error("Error creating oauth client", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if _, err := h.clients.Create(c.Request.Context(), client); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := clientResponse(client)
+	response.ClientSecret = secret
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Summary List OAuth clients
+// @Description List the registered clients with pagination
+// @Tags oauth
+// @Produce json
+// @Param limit query int false "Limit"
+// @Param page query int false "Page"
+// @Success 200 {object} model.Paginate
+// @Router /api/v1/oauth/clients [get]
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+		return
+	}
+
+	paginate, err := h.clients.GetAll(c.Request.Context(), limit, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	clients := []dto.OAuthClientResponse{}
+	if clientList, ok := paginate.Data.(*model.OAuthClientList); ok {
+		for i := range clientList.List {
+			clients = append(clients, clientResponse(&clientList.List[i]))
+		}
+	}
+	paginate.Data = clients
+
+	c.JSON(http.StatusOK, paginate)
+}
+
+// @Summary Delete OAuth client
+// @Description Delete a client; tokens already issued expire normally
+// @Tags oauth
+// @Param client_id path string true "Client ID"
+// @Success 204
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/oauth/clients/{client_id} [delete]
+func (h *OAuthHandler) DeleteClient(c *gin.Context) {
+	if h.clients.Delete(c.Request.Context(), c.Param("client_id")) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func clientResponse(client *model.OAuthClient) dto.OAuthClientResponse {
+	return dto.OAuthClientResponse{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		Scopes:       client.Scopes,
+		TenantID:     client.TenantID,
+		RedirectURIs: client.RedirectURIs,
+		FirstParty:   client.FirstParty,
+		Public:       client.Public,
+		Enable:       client.Enable,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// validRedirectURI accepts absolute URIs without fragment (RFC 6749, section 3.1.2).
+// Plain http is only allowed for loopback, custom schemes serve the mobile apps (RFC 8252).
+func validRedirectURI(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || strings.ContainsAny(uri, " #") {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		return parsed.Host != ""
+	case "http":
+		host := parsed.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		// Private-use schemes such as com.katana.app:/callback
+		return strings.Contains(parsed.Scheme, ".")
+	}
+}
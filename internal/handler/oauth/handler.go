@@ -0,0 +1,212 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/authcode"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	"github.com/katana-stuidio/access-control/pkg/service/oauth_client"
+	service_ten "github.com/katana-stuidio/access-control/pkg/service/tenant"
+	service_ten_group "github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+)
+
+// Error codes of RFC 6749, sections 4.1.2.1 and 5.2
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errUnauthorizedClient      = "unauthorized_client"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errInvalidScope            = "invalid_scope"
+	errAccessDenied            = "access_denied"
+	errServerError             = "server_error"
+)
+
+const (
+	grantClientCredentials = "client_credentials"
+	grantAuthorizationCode = "authorization_code"
+	grantRefreshToken      = "refresh_token"
+)
+
+// OAuthHandler implements the OAuth 2.0 authorization server endpoints
+type OAuthHandler struct {
+	conf               *config.Config
+	clients            oauth_client.OAuthClientServiceInterface
+	codes              authcode.AuthCodeServiceInterface
+	userService        user.UserServiceInterface
+	tenantService      service_ten.TenantServiceInterface
+	tenantGroupService service_ten_group.TenantGroupServiceInterface
+	tokenService       token.TokenServiceInterface
+	permissions        jwt.PermissionResolver
+	mfaService         mfa.MFAServiceInterface
+	challenges         mfa.ChallengeServiceInterface
+	lockoutService     lockout.LockoutServiceInterface
+}
+
+func NewOAuthHandler(conf *config.Config, clients oauth_client.OAuthClientServiceInterface, codes authcode.AuthCodeServiceInterface,
+	userService user.UserServiceInterface, tenantService service_ten.TenantServiceInterface,
+	tenantGroupService service_ten_group.TenantGroupServiceInterface, tokenService token.TokenServiceInterface, permissions jwt.PermissionResolver,
+	mfaService mfa.MFAServiceInterface, challenges mfa.ChallengeServiceInterface, lockoutService lockout.LockoutServiceInterface) *OAuthHandler {
+	return &OAuthHandler{
+		conf:               conf,
+		clients:            clients,
+		codes:              codes,
+		userService:        userService,
+		tenantService:      tenantService,
+		tenantGroupService: tenantGroupService,
+		tokenService:       tokenService,
+		permissions:        permissions,
+		mfaService:         mfaService,
+		challenges:         challenges,
+		lockoutService:     lockoutService,
+	}
+}
+
+// @Summary OAuth 2.0 token endpoint
+// @Description Issue an access token. Supports the client_credentials, authorization_code (with PKCE) and refresh_token grants.
+// @Description Confidential clients authenticate with HTTP Basic or client_id and client_secret in the form, public clients send only client_id
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Grant type"
+// @Param scope formData string false "Space separated scopes"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI of the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Success 200 {object} dto.OAuthTokenResponse
+// @Failure 400 {object} dto.OAuthErrorResponse
+// @Failure 401 {object} dto.OAuthErrorResponse
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	// Token responses must never be cached (RFC 6749, section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	switch c.PostForm("grant_type") {
+	case grantClientCredentials:
+		h.clientCredentials(c, client)
+	case grantAuthorizationCode:
+		h.authorizationCode(c, client)
+	case grantRefreshToken:
+		h.refreshToken(c, client)
+	case "":
+		oauthError(c, http.StatusBadRequest, errInvalidRequest, "grant_type is required")
+	default:
+		oauthError(c, http.StatusBadRequest, errUnsupportedGrantType, "")
+	}
+}
+
+// clientCredentials issues a token on behalf of the client itself, bound to its tenant
+func (h *OAuthHandler) clientCredentials(c *gin.Context, client *model.OAuthClient) {
+	// Public clients have no secret to prove they are the client
+	if client.Public {
+		oauthError(c, http.StatusBadRequest, errUnauthorizedClient, "public clients cannot use client_credentials")
+		return
+	}
+
+	scopes, ok := client.GrantScopes(c.PostForm("scope"))
+	if !ok {
+		oauthError(c, http.StatusBadRequest, errInvalidScope, "scope not allowed for this client")
+		return
+	}
+
+	// Clients of inactive tenants lose access like the tenant users
+	tenant := h.tenantService.GetByID(c.Request.Context(), model.GlobalScope(), client.TenantID)
+	if tenant == nil || tenant.ID == uuid.Nil || !tenant.IsActive {
+		oauthError(c, http.StatusBadRequest, errUnauthorizedClient, "client tenant is disabled")
+		return
+	}
+
+	tokenDetails, err := jwt.GenerateClientToken(client, tenant, scopes, h.conf)
+	if err != nil {
+		logger.Error("Failed to generate client token: ", err)
+		oauthError(c, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(tokenDetails, strings.Join(scopes, " "), h.conf.JWTTokenExp))
+}
+
+// authenticateClient reads the client credentials from HTTP Basic (client_secret_basic) or
+// from the form (client_secret_post) and writes the error response when they are not valid.
+// Public clients are identified by client_id alone, PKCE binds their codes instead of a secret.
+func (h *OAuthHandler) authenticateClient(c *gin.Context) (*model.OAuthClient, bool) {
+	clientID, secret, basic := c.Request.BasicAuth()
+	if basic {
+		// Basic credentials are form encoded (RFC 6749, section 2.3.1)
+		var errID, errSecret error
+		clientID, errID = url.QueryUnescape(clientID)
+		secret, errSecret = url.QueryUnescape(secret)
+		if errID != nil || errSecret != nil || c.PostForm("client_secret") != "" {
+			oauthError(c, http.StatusBadRequest, errInvalidRequest, "malformed client credentials")
+			return nil, false
+		}
+	} else {
+		clientID, secret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+
+	if clientID != "" && secret == "" {
+		return h.publicClient(c, clientID)
+	}
+
+	if clientID == "" || secret == "" {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		oauthError(c, http.StatusUnauthorized, errInvalidClient, "client authentication required")
+		return nil, false
+	}
+
+	client, err := h.clients.Authenticate(c.Request.Context(), clientID, secret)
+	if err != nil {
+		if !errors.Is(err, oauth_client.ErrInvalidClient) {
+			logger.Error("Client authentication failed: ", err)
+			oauthError(c, http.StatusInternalServerError, errServerError, "")
+			return nil, false
+		}
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		oauthError(c, http.StatusUnauthorized, errInvalidClient, "")
+		return nil, false
+	}
+
+	return client, true
+}
+
+func (h *OAuthHandler) publicClient(c *gin.Context, clientID string) (*model.OAuthClient, bool) {
+	client, err := h.clients.GetByClientID(c.Request.Context(), clientID)
+	if err != nil || client == nil || !client.Public || !client.Enable {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Error("Client authentication failed: ", err)
+		}
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		oauthError(c, http.StatusUnauthorized, errInvalidClient, "client authentication required")
+		return nil, false
+	}
+
+	return client, true
+}
+
+func oauthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, dto.OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,920 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/authcode"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	"github.com/katana-stuidio/access-control/pkg/service/oauth_client"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+)
+
+type fakeClientService struct {
+	clients map[string]*model.OAuthClient
+	secrets map[string]string
+}
+
+func (f *fakeClientService) GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error) {
+	return model.NewPaginate(limit, page, int64(len(f.clients))), nil
+}
+
+func (f *fakeClientService) GetByClientID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	return f.clients[clientID], nil
+}
+
+func (f *fakeClientService) Create(ctx context.Context, client *model.OAuthClient) (*model.OAuthClient, error) {
+	f.clients[client.ClientID] = client
+	return client, nil
+}
+
+func (f *fakeClientService) Delete(ctx context.Context, clientID string) int64 {
+	if _, ok := f.clients[clientID]; !ok {
+		return 0
+	}
+	delete(f.clients, clientID)
+	return 1
+}
+
+func (f *fakeClientService) Authenticate(ctx context.Context, clientID, secret string) (*model.OAuthClient, error) {
+	client, ok := f.clients[clientID]
+	if !ok || f.secrets[clientID] != secret {
+		return nil, oauth_client.ErrInvalidClient
+	}
+	return client, nil
+}
+
+type fakeTenantService struct {
+	tenant *model.Tenant
+}
+
+func (f *fakeTenantService) GetAll(ctx context.Context, scope model.TenantScope, limit, page int64) (*model.Paginate, error) {
+	return nil, nil
+}
+
+func (f *fakeTenantService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Tenant {
+	if f.tenant == nil || f.tenant.ID != ID {
+		return nil
+	}
+	return f.tenant
+}
+
+func (f *fakeTenantService) GetByCNPJ(ctx context.Context, CNPJ string) (*model.Tenant, error) {
+	return nil, nil
+}
+
+func (f *fakeTenantService) Create(ctx context.Context, tenant *model.Tenant) (*model.Tenant, error) {
+	return tenant, nil
+}
+
+func (f *fakeTenantService) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, tenant *model.Tenant) int64 {
+	return 0
+}
+
+func (f *fakeTenantService) Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) int64 {
+	return 0
+}
+
+func (f *fakeTenantService) GetExistCNPJ(ctx context.Context, cnpj string) (bool, error) {
+	return false, nil
+}
+
+type fakeUserService struct {
+	user     *model.User
+	password string
+	expired  bool
+}
+
+func (f *fakeUserService) GetAll(ctx context.Context, scope model.TenantScope, limit, page int64) (*model.Paginate, error) {
+	return nil, nil
+}
+
+func (f *fakeUserService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	if f.user.ID != ID {
+		return &model.User{}
+	}
+	return f.user
+}
+
+func (f *fakeUserService) GetByUserName(ctx context.Context, userName string) (*model.User, error) {
+	return f.user, nil
+}
+
+func (f *fakeUserService) Create(ctx context.Context, User *model.User) (*model.User, error) {
+	return User, nil
+}
+
+func (f *fakeUserService) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, User *model.User) int64 {
+	return 0
+}
+
+func (f *fakeUserService) Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) int64 {
+	return 0
+}
+
+func (f *fakeUserService) GetExistUserName(ctx context.Context, userName string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeUserService) Authenticate(username, password string) (*model.User, error) {
+	if username != f.user.Username || password != f.password {
+		return nil, errors.New("invalid credentials")
+	}
+	return f.user, nil
+}
+
+func (f *fakeUserService) GetByCNPJ(ctx context.Context, scope model.TenantScope, CNPJ string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeUserService) ChangePassword(ctx context.Context, userName, currentPassword, newPassword string) error {
+	return nil
+}
+
+func (f *fakeUserService) UpdatePassword(ctx context.Context, userName, newPassword string) int64 {
+	return 0
+}
+
+func (f *fakeUserService) EmailExists(ctx context.Context, email string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeUserService) ValidatePassword(ctx context.Context, User *model.User, password string) error {
+	return nil
+}
+
+func (f *fakeUserService) PasswordExpired(ctx context.Context, User *model.User) (bool, error) {
+	return f.expired, nil
+}
+
+type fakeTenantGroupService struct{}
+
+func (f *fakeTenantGroupService) GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error) {
+	return nil, nil
+}
+
+func (f *fakeTenantGroupService) GetByID(ctx context.Context, ID uuid.UUID) *model.TenantGroup {
+	return &model.TenantGroup{ID: ID, Name: "Rede"}
+}
+
+func (f *fakeTenantGroupService) GetByCNPJ(ctx context.Context, CNPJ string) (*model.TenantGroup, error) {
+	return nil, nil
+}
+
+func (f *fakeTenantGroupService) Create(ctx context.Context, tenantGroup *model.TenantGroup) (*model.TenantGroup, error) {
+	return tenantGroup, nil
+}
+
+func (f *fakeTenantGroupService) Update(ctx context.Context, ID uuid.UUID, tenantGroup *model.TenantGroup) int64 {
+	return 0
+}
+
+func (f *fakeTenantGroupService) Delete(ctx context.Context, ID uuid.UUID) int64 {
+	return 0
+}
+
+func (f *fakeTenantGroupService) GetExistCNPJ(ctx context.Context, cnpj string) (bool, error) {
+	return false, nil
+}
+
+// fakeTokenService keeps the refresh tokens in memory, consuming them once.
+// storeErr makes the token store checks fail, as with Redis unavailable.
+type fakeTokenService struct {
+	refresh  map[string]*token.RefreshTokenData
+	revoked  map[string]bool
+	denied   map[string]bool
+	storeErr error
+}
+
+func (f *fakeTokenService) SaveRefreshToken(ctx context.Context, tokenID, familyID, userID, username, tenantID, role string, issuedAt, exp time.Time) error {
+	f.refresh[tokenID] = &token.RefreshTokenData{FamilyID: familyID, UserID: userID, Username: username, TenantID: tenantID, Role: role, IssuedAt: issuedAt, ExpiresAt: exp}
+	return nil
+}
+
+func (f *fakeTokenService) GetRefreshToken(ctx context.Context, tokenID string) (*token.RefreshTokenData, error) {
+	data, ok := f.refresh[tokenID]
+	if !ok {
+		return nil, token.ErrRefreshTokenNotFound
+	}
+	return data, nil
+}
+
+func (f *fakeTokenService) ConsumeRefreshToken(ctx context.Context, tokenID string) (*token.RefreshTokenData, error) {
+	data, ok := f.refresh[tokenID]
+	if !ok {
+		return nil, token.ErrRefreshTokenNotFound
+	}
+	delete(f.refresh, tokenID)
+	return data, nil
+}
+
+func (f *fakeTokenService) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	f.revoked[familyID] = true
+	for tokenID, data := range f.refresh {
+		if data.FamilyID == familyID {
+			delete(f.refresh, tokenID)
+			f.denied[tokenID] = true
+		}
+	}
+	return nil
+}
+
+func (f *fakeTokenService) DeleteRefreshToken(ctx context.Context, tokenID string) error {
+	delete(f.refresh, tokenID)
+	return nil
+}
+
+func (f *fakeTokenService) DeleteAllUserTokens(ctx context.Context, userID string) error { return nil }
+
+func (f *fakeTokenService) DeleteAllTenantTokens(ctx context.Context, tenantID string) error {
+	return nil
+}
+
+func (f *fakeTokenService) IsTokenValid(ctx context.Context, tokenID string) (bool, error) {
+	if f.storeErr != nil {
+		return false, f.storeErr
+	}
+	_, ok := f.refresh[tokenID]
+	return ok, nil
+}
+
+func (f *fakeTokenService) RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	f.denied[tokenID] = true
+	return nil
+}
+
+func (f *fakeTokenService) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if f.storeErr != nil {
+		return false, f.storeErr
+	}
+	return f.denied[tokenID], nil
+}
+
+// fakeCodeService keeps the authorization codes in memory
+type fakeCodeService struct {
+	codes map[string]*authcode.AuthorizationCode
+	used  map[string]*authcode.AuthorizationCode
+}
+
+func (f *fakeCodeService) Issue(ctx context.Context, grant *authcode.AuthorizationCode) (string, error) {
+	code := uuid.NewString()
+	f.codes[code] = grant
+	return code, nil
+}
+
+func (f *fakeCodeService) Consume(ctx context.Context, code string) (*authcode.AuthorizationCode, error) {
+	if grant, ok := f.used[code]; ok {
+		return grant, authcode.ErrCodeReused
+	}
+	grant, ok := f.codes[code]
+	if !ok {
+		return nil, authcode.ErrCodeNotFound
+	}
+	delete(f.codes, code)
+	f.used[code] = grant
+	return grant, nil
+}
+
+func (f *fakeCodeService) RecordFamily(ctx context.Context, code, familyID string) error {
+	f.used[code].FamilyID = familyID
+	return nil
+}
+
+// fakeMFAService requires the second factor when required is set, accepting only code
+type fakeMFAService struct {
+	mfa.MFAServiceInterface
+	required bool
+	code     string
+}
+
+func (f *fakeMFAService) Status(ctx context.Context, user *model.User) (*model.MFAStatus, error) {
+	return &model.MFAStatus{Enabled: f.code != "", Required: f.required}, nil
+}
+
+func (f *fakeMFAService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	if f.code == "" || code != f.code {
+		return mfa.ErrInvalidCode
+	}
+	return nil
+}
+
+// fakeChallengeService counts the MFA attempts of each user
+type fakeChallengeService struct {
+	mfa.ChallengeServiceInterface
+	attempts map[string]int
+}
+
+func (f *fakeChallengeService) Attempt(ctx context.Context, userID string) (int, time.Duration, error) {
+	f.attempts[userID]++
+	if f.attempts[userID] > mfa.MaxAttempts {
+		return 0, 10 * time.Minute, mfa.ErrLocked
+	}
+	return mfa.MaxAttempts - f.attempts[userID], 0, nil
+}
+
+func (f *fakeChallengeService) Succeed(ctx context.Context, userID string) {
+	delete(f.attempts, userID)
+}
+
+// fakeLockoutService locks a username after maxAttempts wrong passwords, without delays
+type fakeLockoutService struct {
+	maxAttempts int
+	failures    map[string]int
+}
+
+func (f *fakeLockoutService) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	if f.failures[username] >= f.maxAttempts {
+		return 15 * time.Minute, lockout.ErrLocked
+	}
+	return 0, nil
+}
+
+func (f *fakeLockoutService) Fail(ctx context.Context, username, ip string) (time.Duration, error) {
+	f.failures[username]++
+	return f.Check(ctx, username, ip)
+}
+
+func (f *fakeLockoutService) Succeed(ctx context.Context, username string) {
+	delete(f.failures, username)
+}
+
+func (f *fakeLockoutService) Unlock(ctx context.Context, username string) bool {
+	locked := f.failures[username] >= f.maxAttempts
+	delete(f.failures, username)
+	return locked
+}
+
+type testServer struct {
+	router   *gin.Engine
+	tenants  *fakeTenantService
+	users    *fakeUserService
+	tokens   *fakeTokenService
+	mfa      *fakeMFAService
+	lockouts *fakeLockoutService
+}
+
+func newTestRouter(t *testing.T, conf *config.Config) (*gin.Engine, *fakeTenantService) {
+	t.Helper()
+	server := newTestServer(t, conf)
+	return server.router, server.tenants
+}
+
+func newTestServer(t *testing.T, conf *config.Config) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	tenants := &fakeTenantService{tenant: &model.Tenant{
+		ID:       uuid.New(),
+		GroupID:  uuid.New(),
+		Name:     "Escola",
+		IsActive: true,
+	}}
+	clients := &fakeClientService{
+		clients: map[string]*model.OAuthClient{
+			"reports": {ClientID: "reports", Scopes: []string{"users:read", "tenants:read"}, TenantID: tenants.tenant.ID, Enable: true},
+			"portal": {ClientID: "portal", Name: "Portal", Scopes: []string{"users:read"}, TenantID: tenants.tenant.ID,
+				RedirectURIs: []string{"https://portal.katana.com/callback"}, FirstParty: true, Public: true, Enable: true},
+			"parceiro": {ClientID: "parceiro", Name: "Parceiro", Scopes: []string{"users:read"}, TenantID: tenants.tenant.ID,
+				RedirectURIs: []string{"https://parceiro.com/cb", "https://parceiro.com/cb2"}, Enable: true},
+		},
+		secrets: map[string]string{"reports": "s3cr3t", "parceiro": "p4rc31r0"},
+	}
+	users := &fakeUserService{
+		user: &model.User{
+			ID:       uuid.New(),
+			TenantID: tenants.tenant.ID,
+			Username: "maria",
+			Name:     "Maria Silva",
+			Email:    "maria@escola.com",
+			Role:     model.RoleProfessor,
+			Enable:   true,
+		},
+		password: "senha",
+	}
+	tokens := &fakeTokenService{refresh: map[string]*token.RefreshTokenData{}, revoked: map[string]bool{}, denied: map[string]bool{}}
+	codes := &fakeCodeService{codes: map[string]*authcode.AuthorizationCode{}, used: map[string]*authcode.AuthorizationCode{}}
+	mfaService := &fakeMFAService{}
+	lockouts := &fakeLockoutService{maxAttempts: 3, failures: map[string]int{}}
+
+	router := gin.New()
+	challenges := &fakeChallengeService{attempts: map[string]int{}}
+	handler := NewOAuthHandler(conf, clients, codes, users, tenants, &fakeTenantGroupService{}, tokens, nil, mfaService, challenges, lockouts)
+	SetupRoutes(router, middleware.NewGuard(conf, nil, nil), handler)
+	return &testServer{router: router, tenants: tenants, users: users, tokens: tokens, mfa: mfaService, lockouts: lockouts}
+}
+
+func tokenRequest(form url.Values, clientID, secret string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	return req
+}
+
+func TestTokenClientCredentials(t *testing.T) {
+	conf := config.NewConfig()
+	router, tenants := newTestRouter(t, conf)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, tokenRequest(url.Values{"grant_type": {"client_credentials"}, "scope": {"users:read"}}, "reports", "s3cr3t"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("resposta do token sem Cache-Control no-store")
+	}
+
+	var response dto.OAuthTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.TokenType != "Bearer" || response.Scope != "users:read" || response.RefreshToken != "" {
+		t.Errorf("resposta inesperada %+v", response)
+	}
+
+	claims, err := jwt.ValidateToken(response.AccessToken, conf)
+	if err != nil {
+		t.Fatalf("token emitido rejeitado: %v", err)
+	}
+	if claims.ClientID != "reports" || claims.Subject != "reports" || claims.Scope != "users:read" {
+		t.Errorf("claims inesperadas %+v", claims)
+	}
+	if claims.Role != "" || claims.UserID != "" || claims.TenantID != tenants.tenant.ID.String() {
+		t.Errorf("token de cliente com usuário ou tenant inesperado %+v", claims)
+	}
+}
+
+func TestTokenClientSecretPost(t *testing.T) {
+	router, _ := newTestRouter(t, config.NewConfig())
+
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {"reports"}, "client_secret": {"s3cr3t"}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, tokenRequest(form, "", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	var response dto.OAuthTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Scope != "users:read tenants:read" {
+		t.Errorf("esperados todos os escopos do cliente, mas obteve %q", response.Scope)
+	}
+}
+
+func TestTokenErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     url.Values
+		clientID string
+		secret   string
+		status   int
+		code     string
+	}{
+		{"segredo incorreto", url.Values{"grant_type": {"client_credentials"}}, "reports", "errado", http.StatusUnauthorized, errInvalidClient},
+		{"cliente desconhecido", url.Values{"grant_type": {"client_credentials"}}, "outro", "s3cr3t", http.StatusUnauthorized, errInvalidClient},
+		{"sem credenciais", url.Values{"grant_type": {"client_credentials"}}, "", "", http.StatusUnauthorized, errInvalidClient},
+		{"escopo não permitido", url.Values{"grant_type": {"client_credentials"}, "scope": {"users:write"}}, "reports", "s3cr3t", http.StatusBadRequest, errInvalidScope},
+		{"grant não suportado", url.Values{"grant_type": {"password"}}, "reports", "s3cr3t", http.StatusBadRequest, errUnsupportedGrantType},
+		{"sem grant_type", url.Values{}, "reports", "s3cr3t", http.StatusBadRequest, errInvalidRequest},
+	}
+
+	router, _ := newTestRouter(t, config.NewConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tokenRequest(tt.form, tt.clientID, tt.secret))
+			if w.Code != tt.status {
+				t.Fatalf("esperado %d, mas obteve %d", tt.status, w.Code)
+			}
+
+			var response dto.OAuthErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.Error != tt.code {
+				t.Errorf("esperado %q, mas obteve %q", tt.code, response.Error)
+			}
+		})
+	}
+}
+
+func TestTokenDisabledTenant(t *testing.T) {
+	router, tenants := newTestRouter(t, config.NewConfig())
+	tenants.tenant.IsActive = false
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, tokenRequest(url.Values{"grant_type": {"client_credentials"}}, "reports", "s3cr3t"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestClientRoutesRequireAdmin(t *testing.T) {
+	router, _ := newTestRouter(t, config.NewConfig())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/oauth/clients", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+const testVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func testChallenge() string {
+	sum := sha256.Sum256([]byte(testVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizeForm(clientID, redirectURI string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"state":                 {"xyz"},
+		"code_challenge":        {testChallenge()},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func postAuthorize(router *gin.Engine, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// authorize faz o login e retorna os parâmetros do redirecionamento ao cliente
+func authorize(t *testing.T, router *gin.Engine, form url.Values) url.Values {
+	t.Helper()
+	w := postAuthorize(router, form)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusSeeOther, w.Code, w.Body.String())
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("redirecionamento inválido: %v", err)
+	}
+	return location.Query()
+}
+
+func TestAuthorizeShowsLoginPage(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+authorizeForm("portal", "").Encode(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("X-Frame-Options") != "DENY" || !strings.Contains(w.Body.String(), `name="password"`) {
+		t.Errorf("página de login inesperada")
+	}
+	// Cliente próprio não pede consentimento
+	if strings.Contains(w.Body.String(), "Autorizar") {
+		t.Errorf("cliente próprio com tela de consentimento")
+	}
+}
+
+func TestAuthorizeRejectsUnregisteredRedirect(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	for _, redirectURI := range []string{"https://evil.com/callback", "https://portal.katana.com/callback/../x"} {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+authorizeForm("portal", redirectURI).Encode(), nil))
+		if w.Code != http.StatusBadRequest || w.Header().Get("Location") != "" {
+			t.Errorf("%s: esperado %d sem redirecionamento, mas obteve %d", redirectURI, http.StatusBadRequest, w.Code)
+		}
+	}
+
+	// Mais de uma URI registrada exige redirect_uri
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+authorizeForm("parceiro", "").Encode(), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAuthorizeRequiresSecondFactor(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+
+	// Política exige MFA e o usuário ainda não cadastrou o autenticador
+	server.mfa.required = true
+	if w := postAuthorize(server.router, form); w.Code != http.StatusForbidden {
+		t.Fatalf("sem cadastro: esperado %d, mas obteve %d", http.StatusForbidden, w.Code)
+	}
+
+	server.mfa.code = "123456"
+	w := postAuthorize(server.router, form)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `name="otp"`) {
+		t.Fatalf("sem código: esperado %d com o campo otp, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+
+	form.Set("otp", "000000")
+	if w := postAuthorize(server.router, form); w.Code != http.StatusUnauthorized {
+		t.Fatalf("código errado: esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+
+	form.Set("otp", "123456")
+	if params := authorize(t, server.router, form); params.Get("code") == "" {
+		t.Errorf("código de autorização ausente: %v", params)
+	}
+}
+
+func TestAuthorizeLimitsSecondFactorCodes(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	server.mfa.required = true
+	server.mfa.code = "123456"
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+	form.Set("otp", "000000")
+
+	// A senha certa limpa as falhas de login, os códigos errados continuam contando
+	for i := 0; i < mfa.MaxAttempts; i++ {
+		if w := postAuthorize(server.router, form); w.Code != http.StatusUnauthorized {
+			t.Fatalf("código errado %d: esperado %d, mas obteve %d", i+1, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	form.Set("otp", "123456")
+	w := postAuthorize(server.router, form)
+	if w.Code != http.StatusLocked || w.Header().Get("Retry-After") != "600" {
+		t.Errorf("limite esgotado: esperado %d com Retry-After 600, mas obteve %d %q", http.StatusLocked, w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestAuthorizeLocksAfterFailedLogins(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("username", "maria")
+	form.Set("password", "errada")
+
+	for i := 1; i < server.lockouts.maxAttempts; i++ {
+		if w := postAuthorize(server.router, form); w.Code != http.StatusUnauthorized {
+			t.Fatalf("tentativa %d: esperado %d, mas obteve %d", i, http.StatusUnauthorized, w.Code)
+		}
+	}
+	if w := postAuthorize(server.router, form); w.Code != http.StatusLocked {
+		t.Fatalf("última tentativa: esperado %d, mas obteve %d", http.StatusLocked, w.Code)
+	}
+
+	// Bloqueado, nem a senha certa abre a sessão
+	form.Set("password", "senha")
+	w := postAuthorize(server.router, form)
+	if w.Code != http.StatusLocked || w.Header().Get("Retry-After") != "900" {
+		t.Errorf("senha certa com bloqueio: esperado %d com Retry-After 900, mas obteve %d %q", http.StatusLocked, w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestAuthorizeRejectsExpiredPassword(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	server.users.expired = true
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+
+	w := postAuthorize(server.router, form)
+	if w.Code != http.StatusForbidden || w.Header().Get("Location") != "" {
+		t.Errorf("senha expirada: esperado %d sem redirecionamento, mas obteve %d %q", http.StatusForbidden, w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestAuthorizeRequiresPKCE(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("code_challenge_method", "plain")
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+form.Encode(), nil))
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusSeeOther || location.Query().Get("error") != errInvalidRequest || location.Query().Get("state") != "xyz" {
+		t.Errorf("esperado redirecionamento com invalid_request, mas obteve %d %s", w.Code, location)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(t, conf)
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("username", "maria")
+	form.Set("password", "errada")
+	if w := postAuthorize(server.router, form); w.Code != http.StatusUnauthorized {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+
+	form.Set("password", "senha")
+	params := authorize(t, server.router, form)
+	if params.Get("state") != "xyz" || params.Get("code") == "" {
+		t.Fatalf("redirecionamento inesperado %v", params)
+	}
+
+	// Cliente público: apenas client_id e o code_verifier
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"portal"},
+		"code":          {params.Get("code")},
+		"redirect_uri":  {"https://portal.katana.com/callback"},
+		"code_verifier": {testVerifier},
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, tokenRequest(exchange, "", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response dto.OAuthTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	claims, err := jwt.ValidateToken(response.AccessToken, conf)
+	if err != nil {
+		t.Fatalf("token emitido rejeitado: %v", err)
+	}
+	if claims.UserID != server.users.user.ID.String() || claims.ClientID != "portal" || claims.Scope != "users:read" || claims.Role != model.RoleProfessor {
+		t.Errorf("claims inesperadas %+v", claims)
+	}
+
+	// O código vale uma única vez e a reutilização revoga os tokens emitidos
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, tokenRequest(exchange, "", ""))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if !server.tokens.revoked[claims.TokenID] {
+		t.Error("tokens do código reutilizado não foram revogados")
+	}
+}
+
+func TestAuthorizationCodeWithRegisteredRedirectURI(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	// Sem redirect_uri na autorização vale a única URI registrada, e o formulário não a inclui
+	form := authorizeForm("portal", "")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+form.Encode(), nil))
+	if !strings.Contains(w.Body.String(), `name="redirect_uri" value=""`) {
+		t.Errorf("o formulário deve repetir a redirect_uri enviada")
+	}
+
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+	params := authorize(t, server.router, form)
+
+	// Então a redirect_uri não é exigida na troca do código (RFC 6749, seção 4.1.3)
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"portal"},
+		"code":          {params.Get("code")},
+		"code_verifier": {testVerifier},
+	}
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, tokenRequest(exchange, "", ""))
+	if w.Code != http.StatusOK {
+		t.Errorf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestAuthorizationCodeExchangeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(url.Values)
+	}{
+		{"verificador incorreto", func(v url.Values) { v.Set("code_verifier", strings.Repeat("x", 43)) }},
+		{"sem verificador", func(v url.Values) { v.Del("code_verifier") }},
+		{"redirect_uri diferente", func(v url.Values) { v.Set("redirect_uri", "https://portal.katana.com/outro") }},
+		{"sem redirect_uri enviada na autorização", func(v url.Values) { v.Del("redirect_uri") }},
+		{"código de outro cliente", func(v url.Values) { v.Set("client_id", "parceiro"); v.Set("client_secret", "p4rc31r0") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, config.NewConfig())
+
+			form := authorizeForm("portal", "https://portal.katana.com/callback")
+			form.Set("username", "maria")
+			form.Set("password", "senha")
+			params := authorize(t, server.router, form)
+
+			exchange := url.Values{
+				"grant_type":    {"authorization_code"},
+				"client_id":     {"portal"},
+				"code":          {params.Get("code")},
+				"redirect_uri":  {"https://portal.katana.com/callback"},
+				"code_verifier": {testVerifier},
+			}
+			tt.change(exchange)
+
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, tokenRequest(exchange, "", ""))
+
+			var response dto.OAuthErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != http.StatusBadRequest || response.Error != errInvalidGrant {
+				t.Errorf("esperado %d %s, mas obteve %d %s", http.StatusBadRequest, errInvalidGrant, w.Code, response.Error)
+			}
+		})
+	}
+}
+
+func TestAuthorizeThirdPartyConsent(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	form := authorizeForm("parceiro", "https://parceiro.com/cb2")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+
+	// Sem a decisão do usuário o código não é emitido
+	if w := postAuthorize(server.router, form); w.Code != http.StatusBadRequest {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+
+	form.Set("decision", "deny")
+	if params := authorize(t, server.router, form); params.Get("error") != errAccessDenied || params.Get("code") != "" {
+		t.Errorf("esperado access_denied, mas obteve %v", params)
+	}
+
+	form.Set("decision", "allow")
+	if params := authorize(t, server.router, form); params.Get("code") == "" {
+		t.Errorf("código não emitido após o consentimento: %v", params)
+	}
+
+	// Usuário de outra instituição
+	server.users.user.TenantID = uuid.New()
+	if w := postAuthorize(server.router, form); w.Code != http.StatusForbidden {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestRefreshTokenGrant(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(t, conf)
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+	params := authorize(t, server.router, form)
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, tokenRequest(url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"portal"},
+		"code":          {params.Get("code")},
+		"redirect_uri":  {"https://portal.katana.com/callback"},
+		"code_verifier": {testVerifier},
+	}, "", ""))
+	var issued dto.OAuthTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &issued)
+
+	// Outro cliente não pode usar o refresh token
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, tokenRequest(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {issued.RefreshToken}}, "reports", "s3cr3t"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, tokenRequest(url.Values{"grant_type": {"refresh_token"}, "client_id": {"portal"}, "refresh_token": {issued.RefreshToken}}, "", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var rotated dto.OAuthTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	claims, err := jwt.ValidateToken(rotated.AccessToken, conf)
+	if err != nil || claims.ClientID != "portal" || rotated.Scope != "users:read" {
+		t.Errorf("token renovado inesperado %+v (%v)", claims, err)
+	}
+}
+
+func TestPublicClientCannotUseClientCredentials(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, tokenRequest(url.Values{"grant_type": {"client_credentials"}, "client_id": {"portal"}}, "", ""))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,205 @@
+package oauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// Token type hints of RFC 7009, section 2.1, also used as token_type in the introspection
+const (
+	tokenTypeAccess  = "access_token"
+	tokenTypeRefresh = "refresh_token"
+)
+
+// @Summary OAuth 2.0 token introspection
+// @Description Report whether an access or refresh token is active and its claims (RFC 7662). Requires a confidential client.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} dto.OAuthIntrospectionResponse
+// @Failure 400 {object} dto.OAuthErrorResponse
+// @Failure 401 {object} dto.OAuthErrorResponse
+// @Router /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	// Public clients cannot keep a secret, anyone could use them to probe tokens
+	if client.Public {
+		oauthError(c, http.StatusBadRequest, errUnauthorizedClient, "public clients cannot introspect tokens")
+		return
+	}
+
+	tokenStr := c.PostForm("token")
+	if tokenStr == "" {
+		oauthError(c, http.StatusBadRequest, errInvalidRequest, "token is required")
+		return
+	}
+
+	// The hint only saves a lookup (RFC 7662, section 2.1), the token itself tells its type
+	claims, active, err := h.activeToken(c, tokenStr)
+	if err != nil {
+		logger.Error("Token introspection failed: ", err)
+		oauthError(c, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+	if !active {
+		c.JSON(http.StatusOK, dto.OAuthIntrospectionResponse{Active: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, introspectionResponse(claims))
+}
+
+// @Summary OAuth 2.0 token revocation
+// @Description Revoke an access or refresh token (RFC 7009). Revoking a refresh token also revokes the access tokens of its session.
+// @Description Unknown, invalid or already revoked tokens are answered with 200 as well.
+// @Description Tokens of the password login, issued to no client, require a confidential client.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200
+// @Failure 400 {object} dto.OAuthErrorResponse
+// @Failure 401 {object} dto.OAuthErrorResponse
+// @Router /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	tokenStr := c.PostForm("token")
+	if tokenStr == "" {
+		oauthError(c, http.StatusBadRequest, errInvalidRequest, "token is required")
+		return
+	}
+
+	claims, err := jwt.ValidateToken(tokenStr, h.conf)
+	if err != nil {
+		// An invalid token has nothing left to revoke (RFC 7009, section 2.2)
+		c.Status(http.StatusOK)
+		return
+	}
+
+	// Tokens issued to a client can only be revoked by that client; tokens of the
+	// password login carry no client and need a confidential client, a public one is
+	// identified by its client_id alone
+	if claims.ClientID != client.ClientID && (claims.ClientID != "" || client.Public) {
+		oauthError(c, http.StatusBadRequest, errUnauthorizedClient, "token was issued to another client")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if claims.Renew {
+		err = h.revokeRefreshToken(c, claims)
+	} else {
+		var expiresAt time.Time
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+		err = h.tokenService.RevokeAccessToken(ctx, claims.TokenID, expiresAt)
+	}
+	if err != nil {
+		logger.Error("Token revocation failed: ", err)
+		// The client may retry, the token was not revoked (RFC 7009, section 2.2.1)
+		oauthError(c, http.StatusServiceUnavailable, errServerError, "")
+		return
+	}
+
+	logger.Info("Token revoked through /oauth/revoke",
+		zap.String("token_id", claims.TokenID),
+		zap.String("client_id", client.ClientID),
+	)
+	c.Status(http.StatusOK)
+}
+
+// revokeRefreshToken revokes the whole session of the refresh token, access tokens included
+func (h *OAuthHandler) revokeRefreshToken(c *gin.Context, claims *jwt.Claims) error {
+	ctx := c.Request.Context()
+
+	session, err := h.tokenService.GetRefreshToken(ctx, claims.TokenID)
+	if err != nil {
+		// Already consumed or revoked, its access token may still be alive
+		return h.tokenService.RevokeAccessToken(ctx, claims.TokenID, time.Time{})
+	}
+
+	familyID := session.FamilyID
+	if familyID == "" {
+		familyID = claims.TokenID
+	}
+	return h.tokenService.RevokeTokenFamily(ctx, familyID)
+}
+
+// activeToken validates the token and checks it against the Redis token store:
+// refresh tokens must still be stored, access tokens must not be in the denylist
+func (h *OAuthHandler) activeToken(c *gin.Context, tokenStr string) (*jwt.Claims, bool, error) {
+	claims, err := jwt.ValidateToken(tokenStr, h.conf)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	ctx := c.Request.Context()
+	if claims.Renew {
+		valid, err := h.tokenService.IsTokenValid(ctx, claims.TokenID)
+		if err != nil {
+			return nil, false, err
+		}
+		return claims, valid, nil
+	}
+
+	revoked, err := h.tokenService.IsAccessTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		return nil, false, err
+	}
+	return claims, !revoked, nil
+}
+
+func introspectionResponse(claims *jwt.Claims) dto.OAuthIntrospectionResponse {
+	tokenType := tokenTypeAccess
+	if claims.Renew {
+		tokenType = tokenTypeRefresh
+	}
+
+	return dto.OAuthIntrospectionResponse{
+		Active:      true,
+		Scope:       claims.Scope,
+		ClientID:    claims.ClientID,
+		Username:    claims.Username,
+		TokenType:   tokenType,
+		Exp:         unixTime(claims.ExpiresAt),
+		Iat:         unixTime(claims.IssuedAt),
+		Nbf:         unixTime(claims.NotBefore),
+		Sub:         claims.Subject,
+		Aud:         claims.Audience,
+		Iss:         claims.Issuer,
+		Jti:         claims.ID,
+		UserID:      claims.UserID,
+		TenantID:    claims.TenantID,
+		GroupID:     claims.GroupID,
+		Role:        claims.Role,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+	}
+}
+
+func unixTime(date *jwtlib.NumericDate) int64 {
+	if date == nil {
+		return 0
+	}
+	return date.Unix()
+}
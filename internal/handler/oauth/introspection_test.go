@@ -0,0 +1,186 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+)
+
+func postForm(server *testServer, path string, form url.Values, clientID, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+// userTokens obtém os tokens da usuária pelo fluxo authorization code do cliente portal
+func userTokens(t *testing.T, server *testServer) dto.OAuthTokenResponse {
+	t.Helper()
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+	params := authorize(t, server.router, form)
+
+	w := postForm(server, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"portal"},
+		"code":          {params.Get("code")},
+		"redirect_uri":  {"https://portal.katana.com/callback"},
+		"code_verifier": {testVerifier},
+	}, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var tokens dto.OAuthTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+	return tokens
+}
+
+func introspect(t *testing.T, server *testServer, tokenStr string) dto.OAuthIntrospectionResponse {
+	t.Helper()
+	w := postForm(server, "/oauth/introspect", url.Values{"token": {tokenStr}}, "reports", "s3cr3t")
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response dto.OAuthIntrospectionResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response
+}
+
+func TestIntrospect(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	tokens := userTokens(t, server)
+
+	access := introspect(t, server, tokens.AccessToken)
+	if !access.Active || access.TokenType != tokenTypeAccess || access.ClientID != "portal" ||
+		access.Username != "maria" || access.Scope != "users:read" || access.Exp == 0 {
+		t.Errorf("introspecção do access token inesperada %+v", access)
+	}
+
+	refresh := introspect(t, server, tokens.RefreshToken)
+	if !refresh.Active || refresh.TokenType != tokenTypeRefresh {
+		t.Errorf("introspecção do refresh token inesperada %+v", refresh)
+	}
+
+	if response := introspect(t, server, "invalido"); response.Active || response.Username != "" {
+		t.Errorf("token inválido reportado como ativo %+v", response)
+	}
+}
+
+func TestIntrospectRequiresConfidentialClient(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	tokens := userTokens(t, server)
+
+	if w := postForm(server, "/oauth/introspect", url.Values{"token": {tokens.AccessToken}}, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+
+	form := url.Values{"token": {tokens.AccessToken}, "client_id": {"portal"}}
+	if w := postForm(server, "/oauth/introspect", form, "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestIntrospectReportsTokenStoreErrors(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	tokens := userTokens(t, server)
+
+	// Sem o Redis o estado do token é desconhecido, nos dois tipos de token
+	server.tokens.storeErr = errors.New("redis indisponível")
+	for _, tokenStr := range []string{tokens.AccessToken, tokens.RefreshToken} {
+		w := postForm(server, "/oauth/introspect", url.Values{"token": {tokenStr}}, "reports", "s3cr3t")
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("esperado %d, mas obteve %d: %s", http.StatusInternalServerError, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	tokens := userTokens(t, server)
+
+	w := postForm(server, "/oauth/revoke", url.Values{"token": {tokens.AccessToken}, "client_id": {"portal"}}, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	if introspect(t, server, tokens.AccessToken).Active {
+		t.Error("access token revogado continua ativo")
+	}
+}
+
+func TestRevokeRefreshTokenRevokesSession(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	tokens := userTokens(t, server)
+
+	form := url.Values{"token": {tokens.RefreshToken}, "token_type_hint": {tokenTypeRefresh}, "client_id": {"portal"}}
+	if w := postForm(server, "/oauth/revoke", form, "", ""); w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	if introspect(t, server, tokens.RefreshToken).Active || introspect(t, server, tokens.AccessToken).Active {
+		t.Error("tokens da sessão continuam ativos após revogar o refresh token")
+	}
+}
+
+func TestRevokeChecksClient(t *testing.T) {
+	server := newTestServer(t, config.NewConfig())
+	tokens := userTokens(t, server)
+
+	// Token emitido ao portal não pode ser revogado por outro cliente
+	w := postForm(server, "/oauth/revoke", url.Values{"token": {tokens.AccessToken}}, "reports", "s3cr3t")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if !introspect(t, server, tokens.AccessToken).Active {
+		t.Error("token revogado por outro cliente")
+	}
+
+	// Token inválido é respondido como revogado
+	if w := postForm(server, "/oauth/revoke", url.Values{"token": {"invalido"}}, "reports", "s3cr3t"); w.Code != http.StatusOK {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRevokePasswordLoginTokenRequiresConfidentialClient(t *testing.T) {
+	conf := config.NewConfig()
+	server := newTestServer(t, conf)
+
+	// Token do login por senha, sem client_id
+	tokenDetails, err := jwt.GenerateToken(context.Background(), server.users.user, server.tenants.tenant, nil, "", conf, server.tokens, nil)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	accessToken := strings.TrimPrefix(tokenDetails.AccessToken, "Bearer ")
+
+	// Um cliente público se identifica só pelo client_id, qualquer um poderia usá-lo
+	w := postForm(server, "/oauth/revoke", url.Values{"token": {accessToken}, "client_id": {"portal"}}, "", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if !introspect(t, server, accessToken).Active {
+		t.Error("token do login revogado por um cliente público")
+	}
+
+	if w := postForm(server, "/oauth/revoke", url.Values{"token": {accessToken}}, "reports", "s3cr3t"); w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+	if introspect(t, server, accessToken).Active {
+		t.Error("access token revogado continua ativo")
+	}
+}
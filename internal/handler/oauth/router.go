@@ -0,0 +1,43 @@
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// SetupRoutes configures the OAuth 2.0 endpoints and the client registry
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *OAuthHandler) {
+	oauthRoutes := router.Group("/oauth")
+	{
+		guard.Register(oauthRoutes, []middleware.Route{
+			{Method: http.MethodGet, Path: "/authorize", Access: middleware.Public, Handler: handler.Authorize},
+			{Method: http.MethodPost, Path: "/authorize", Access: middleware.Public, Handler: handler.AuthorizeDecision},
+			{Method: http.MethodPost, Path: "/token", Access: middleware.Public, Handler: handler.Token},
+			// Introspection and revocation authenticate the calling client instead of a bearer token
+			{Method: http.MethodPost, Path: "/introspect", Access: middleware.Public, Handler: handler.Introspect},
+			{Method: http.MethodPost, Path: "/revoke", Access: middleware.Public, Handler: handler.Revoke},
+		})
+	}
+
+	// OpenID Connect UserInfo, protegido pelo access token
+	userInfoRoutes := router.Group("/userinfo")
+	{
+		guard.Register(userInfoRoutes, []middleware.Route{
+			{Method: http.MethodGet, Path: "", Access: middleware.Authenticated, Handler: handler.UserInfo},
+			{Method: http.MethodPost, Path: "", Access: middleware.Authenticated, Handler: handler.UserInfo},
+		})
+	}
+
+	// Registro dos clientes OAuth, restrito ao Admin
+	clientRoutes := router.Group("/api/v1/oauth/clients")
+	{
+		guard.Register(clientRoutes, []middleware.Route{
+			{Method: http.MethodPost, Path: "", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: handler.CreateClient},
+			{Method: http.MethodGet, Path: "", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: handler.ListClients},
+			{Method: http.MethodDelete, Path: "/:client_id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: handler.DeleteClient},
+		})
+	}
+}
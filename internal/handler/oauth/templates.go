@@ -0,0 +1,72 @@
+package oauth
+
+import "html/template"
+
+const pageStyle = `<style>
+body { font-family: sans-serif; background: #f4f5f7; display: flex; justify-content: center; padding-top: 10vh; }
+main { background: #fff; padding: 2rem; border-radius: 8px; width: 20rem; box-shadow: 0 1px 4px rgba(0,0,0,.15); }
+label, input, button { display: block; width: 100%; box-sizing: border-box; }
+input { margin: .25rem 0 1rem; padding: .5rem; }
+button { padding: .6rem; margin-top: .5rem; cursor: pointer; }
+.error { color: #b00020; }
+</style>`
+
+var loginTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Entrar - {{.Client.Name}}</title>
+` + pageStyle + `
+</head>
+<body>
+<main>
+<h1>Entrar</h1>
+<p>Acesse sua conta para continuar em <strong>{{.Client.Name}}</strong>.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+<input type="hidden" name="response_type" value="code">
+<input type="hidden" name="client_id" value="{{.Client.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.RequestedRedirectURI}}">
+<input type="hidden" name="scope" value="{{.Scope}}">
+<input type="hidden" name="state" value="{{.State}}">
+<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.CodeChallengeMethod}}">
+<input type="hidden" name="nonce" value="{{.Nonce}}">
+<label for="username">Usuário</label>
+<input id="username" name="username" value="{{.Username}}" autocomplete="username" required autofocus>
+<label for="password">Senha</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+{{if .MFARequired}}
+<label for="otp">Código do aplicativo autenticador ou de recuperação</label>
+<input id="otp" name="otp" inputmode="numeric" autocomplete="one-time-code" required>
+{{end}}
+{{if .Client.FirstParty}}
+<button type="submit">Entrar</button>
+{{else}}
+{{if .Scopes}}<p>O aplicativo terá acesso a:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<button type="submit" name="decision" value="allow">Autorizar</button>
+<button type="submit" name="decision" value="deny" formnovalidate>Negar</button>
+{{end}}
+</form>
+</main>
+</body>
+</html>
+`))
+
+var authorizeErrorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<title>Erro de autorização</title>
+` + pageStyle + `
+</head>
+<body>
+<main>
+<h1>Não foi possível continuar</h1>
+<p class="error">{{.}}</p>
+</main>
+</body>
+</html>
+`))
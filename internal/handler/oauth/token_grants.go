@@ -0,0 +1,132 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/authcode"
+	"go.uber.org/zap"
+)
+
+// authorizationCode exchanges a code issued by /oauth/authorize for the user tokens
+func (h *OAuthHandler) authorizationCode(c *gin.Context, client *model.OAuthClient) {
+	ctx := c.Request.Context()
+	code := c.PostForm("code")
+	if code == "" {
+		oauthError(c, http.StatusBadRequest, errInvalidRequest, "code is required")
+		return
+	}
+
+	grant, err := h.codes.Consume(ctx, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, authcode.ErrCodeReused):
+			// The tokens issued for a replayed code are revoked (RFC 6749, section 4.1.2)
+			if grant != nil && grant.FamilyID != "" {
+				if err := h.tokenService.RevokeTokenFamily(ctx, grant.FamilyID); err != nil {
+					logger.Error("Error revoking tokens of a reused authorization code", err, zap.String("family_id", grant.FamilyID))
+				}
+			}
+			oauthError(c, http.StatusBadRequest, errInvalidGrant, "authorization code already used")
+		case errors.Is(err, authcode.ErrCodeNotFound):
+			oauthError(c, http.StatusBadRequest, errInvalidGrant, "invalid or expired authorization code")
+		default:
+			logger.Error("Error consuming authorization code", err)
+			oauthError(c, http.StatusInternalServerError, errServerError, "")
+		}
+		return
+	}
+
+	// redirect_uri is required only if the authorization request sent it, and must match when present
+	redirectURI := c.PostForm("redirect_uri")
+	if grant.ClientID != client.ClientID || ((grant.RedirectURIExplicit || redirectURI != "") && grant.RedirectURI != redirectURI) {
+		oauthError(c, http.StatusBadRequest, errInvalidGrant, "code was issued to another client or redirect_uri")
+		return
+	}
+
+	if !authcode.VerifyPKCE(c.PostForm("code_verifier"), grant.CodeChallenge, grant.CodeChallengeMethod) {
+		oauthError(c, http.StatusBadRequest, errInvalidGrant, "code_verifier does not match the code_challenge")
+		return
+	}
+
+	// The user or the tenant may have been disabled since the code was issued
+	userID, _ := uuid.Parse(grant.UserID)
+	usr := h.userService.GetByID(ctx, model.GlobalScope(), userID)
+	if usr == nil || usr.ID == uuid.Nil || !usr.Enable {
+		oauthError(c, http.StatusBadRequest, errInvalidGrant, "user is disabled")
+		return
+	}
+
+	tenant := h.tenantService.GetByID(ctx, model.GlobalScope(), usr.TenantID)
+	if tenant == nil || tenant.ID == uuid.Nil || !tenant.IsActive {
+		oauthError(c, http.StatusBadRequest, errInvalidGrant, "user tenant is disabled")
+		return
+	}
+	tenantGroup := h.tenantGroupService.GetByID(ctx, tenant.GroupID)
+
+	tokenDetails, err := jwt.GenerateAuthorizationCodeToken(c.Request.Context(), usr, tenant, tenantGroup, client, grant.Scopes, h.conf, h.tokenService, h.permissions)
+	if err != nil {
+		logger.Error("Failed to generate JWT: ", err)
+		oauthError(c, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	if err := h.codes.RecordFamily(ctx, code, tokenDetails.TokenID); err != nil {
+		logger.Error("Error recording tokens of authorization code", err)
+	}
+
+	scope := strings.Join(grant.Scopes, " ")
+	response := tokenResponse(tokenDetails, scope, h.conf.JWTTokenExp)
+
+	// OpenID Connect: the openid scope adds the ID token of the user
+	if model.HasScope(scope, model.ScopeOpenID) {
+		response.IDToken, err = jwt.GenerateIDToken(usr, tenant, tenantGroup, client.ClientID, scope, grant.Nonce, grant.AuthTime, h.conf)
+		if err != nil {
+			logger.Error("Failed to generate ID token: ", err)
+			oauthError(c, http.StatusInternalServerError, errServerError, "")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// refreshToken rotates a refresh token issued to the same client by the authorization code grant
+func (h *OAuthHandler) refreshToken(c *gin.Context, client *model.OAuthClient) {
+	refreshToken := c.PostForm("refresh_token")
+	if refreshToken == "" {
+		oauthError(c, http.StatusBadRequest, errInvalidRequest, "refresh_token is required")
+		return
+	}
+
+	claims, err := jwt.ValidateToken(refreshToken, h.conf)
+	if err != nil || !claims.Renew || claims.ClientID != client.ClientID {
+		oauthError(c, http.StatusBadRequest, errInvalidGrant, "invalid refresh token")
+		return
+	}
+
+	tokenDetails, ok := jwt.RefreshJWT(c.Request.Context(), refreshToken, h.conf, h.tokenService, h.permissions)
+	if !ok {
+		oauthError(c, http.StatusBadRequest, errInvalidGrant, "invalid refresh token")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(tokenDetails, claims.Scope, h.conf.JWTTokenExp))
+}
+
+func tokenResponse(tokenDetails *jwt.TokenDetails, scope string, tokenExp int) dto.OAuthTokenResponse {
+	return dto.OAuthTokenResponse{
+		AccessToken:  strings.TrimPrefix(tokenDetails.AccessToken, "Bearer "),
+		TokenType:    "Bearer",
+		ExpiresIn:    tokenExp * 60,
+		RefreshToken: strings.TrimPrefix(tokenDetails.RefreshToken, "Bearer "),
+		Scope:        scope,
+	}
+}
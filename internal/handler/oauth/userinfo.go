@@ -0,0 +1,51 @@
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// @Summary OpenID Connect UserInfo
+// @Description Claims of the user of the access token. Requires the openid scope; profile and email release the name and the email
+// @Tags oauth
+// @Produce json
+// @Param Authorization header string true "Bearer {token}"
+// @Success 200 {object} jwt.UserInfo
+// @Failure 401 {object} handler.HttpMsg
+// @Failure 403 {object} dto.OAuthErrorResponse
+// @Router /userinfo [get]
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	// scope and user_id are set by AuthMiddleware from the validated token
+	scope := c.GetString("scope")
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if !model.HasScope(scope, model.ScopeOpenID) || err != nil {
+		// RFC 6750, section 3.1
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		oauthError(c, http.StatusForbidden, "insufficient_scope", "the openid scope is required")
+		return
+	}
+
+	ctx := c.Request.Context()
+	usr := h.userService.GetByID(ctx, model.GlobalScope(), userID)
+	if usr == nil || usr.ID == uuid.Nil || !usr.Enable {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		oauthError(c, http.StatusUnauthorized, "invalid_token", "user not found")
+		return
+	}
+
+	tenant := h.tenantService.GetByID(ctx, model.GlobalScope(), usr.TenantID)
+	var tenantGroup *model.TenantGroup
+	if tenant != nil && tenant.ID != uuid.Nil {
+		tenantGroup = h.tenantGroupService.GetByID(ctx, tenant.GroupID)
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, jwt.UserInfo{
+		Subject:     usr.ID.String(),
+		UserProfile: jwt.NewUserProfile(usr, tenant, tenantGroup, scope),
+	})
+}
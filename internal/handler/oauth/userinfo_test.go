@@ -0,0 +1,136 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+)
+
+// openIDTokens obtém os tokens da usuária com os escopos informados
+func openIDTokens(t *testing.T, server *testServer, scope string) (accessToken, idToken string) {
+	t.Helper()
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("scope", scope)
+	form.Set("nonce", "n-0S6")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+	params := authorize(t, server.router, form)
+
+	w := postForm(server, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {"portal"},
+		"code":          {params.Get("code")},
+		"redirect_uri":  {"https://portal.katana.com/callback"},
+		"code_verifier": {testVerifier},
+	}, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.AccessToken, response.IDToken
+}
+
+// openIDConfig assina os tokens com uma chave ES256 e usa uma URL https como issuer,
+// as condições para o servidor emitir ID tokens
+func openIDConfig(t *testing.T) (*config.Config, jwt.SigningKey) {
+	t.Helper()
+	key, err := jwt.GenerateSigningKey(jwt.AlgES256, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("erro ao gerar chave: %v", err)
+	}
+	jwt.SetKeyRing(jwt.NewKeyRing([]jwt.SigningKey{key}))
+	t.Cleanup(func() { jwt.SetKeyRing(nil) })
+
+	conf := config.NewConfig()
+	conf.JWTSigningAlg = jwt.AlgES256
+	conf.JWTIssuer = "https://auth.katana.com"
+	return conf, key
+}
+
+func getUserInfo(server *testServer, accessToken string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOpenIDConnectIDToken(t *testing.T) {
+	conf, key := openIDConfig(t)
+	server := newTestServer(t, conf)
+
+	_, idToken := openIDTokens(t, server, "openid profile")
+	if idToken == "" {
+		t.Fatal("ID token não emitido com o escopo openid")
+	}
+
+	claims := &jwt.IDTokenClaims{}
+	_, err := jwtlib.ParseWithClaims(idToken, claims, func(*jwtlib.Token) (interface{}, error) {
+		return key.PublicKey, nil
+	}, jwtlib.WithAudience("portal"), jwtlib.WithIssuer(conf.JWTIssuer))
+	if err != nil {
+		t.Fatalf("ID token inválido: %v", err)
+	}
+	if claims.Subject != server.users.user.ID.String() || claims.Nonce != "n-0S6" || claims.Name != "Maria Silva" ||
+		claims.Email != "" || claims.TenantID != server.tenants.tenant.ID.String() || claims.AuthTime == nil {
+		t.Errorf("claims inesperadas %+v", claims)
+	}
+
+	// Sem openid não há ID token
+	if _, idToken := openIDTokens(t, server, "users:read"); idToken != "" {
+		t.Error("ID token emitido sem o escopo openid")
+	}
+}
+
+func TestOpenIDScopeRequiresVerifiableIDTokens(t *testing.T) {
+	// Com HS256 o ID token seria assinado com o segredo do servidor, que o cliente não tem
+	server := newTestServer(t, config.NewConfig())
+
+	form := authorizeForm("portal", "https://portal.katana.com/callback")
+	form.Set("scope", "openid")
+	form.Set("username", "maria")
+	form.Set("password", "senha")
+	if params := authorize(t, server.router, form); params.Get("error") != errInvalidScope || params.Get("code") != "" {
+		t.Errorf("esperado %s, mas obteve %v", errInvalidScope, params)
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	conf, _ := openIDConfig(t)
+	server := newTestServer(t, conf)
+
+	accessToken, _ := openIDTokens(t, server, "openid email")
+	w := getUserInfo(server, accessToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var info jwt.UserInfo
+	json.Unmarshal(w.Body.Bytes(), &info)
+	if info.Subject != server.users.user.ID.String() || info.Email != "maria@escola.com" || info.Name != "" ||
+		info.TenantID != server.tenants.tenant.ID.String() {
+		t.Errorf("claims inesperadas %+v", info)
+	}
+
+	// O access token sem openid não libera o UserInfo
+	accessToken, _ = openIDTokens(t, server, "users:read")
+	if w := getUserInfo(server, accessToken); w.Code != http.StatusForbidden {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusForbidden, w.Code)
+	}
+
+	if w := getUserInfo(server, "invalido"); w.Code != http.StatusUnauthorized {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+}
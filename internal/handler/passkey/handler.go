@@ -0,0 +1,528 @@
+package passkey
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	"github.com/katana-stuidio/access-control/pkg/service/passkey"
+	service_ten "github.com/katana-stuidio/access-control/pkg/service/tenant"
+	service_ten_group "github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+	"github.com/katana-stuidio/access-control/pkg/webauthn"
+)
+
+// PasskeyHandler implements the WebAuthn ceremonies: the registration of passkeys by a logged in
+// user and the passwordless login, which issues the same tokens as getjwt
+type PasskeyHandler struct {
+	conf               *config.Config
+	rp                 *webauthn.RelyingParty
+	credentials        passkey.PasskeyServiceInterface
+	ceremonies         passkey.CeremonyServiceInterface
+	mfaService         mfa.MFAServiceInterface
+	challenges         mfa.ChallengeServiceInterface
+	lockoutService     lockout.LockoutServiceInterface
+	userService        user.UserServiceInterface
+	tenantService      service_ten.TenantServiceInterface
+	tenantGroupService service_ten_group.TenantGroupServiceInterface
+	tokenService       token.TokenServiceInterface
+	permissions        jwt.PermissionResolver
+}
+
+func NewPasskeyHandler(conf *config.Config, credentials passkey.PasskeyServiceInterface, ceremonies passkey.CeremonyServiceInterface,
+	mfaService mfa.MFAServiceInterface, challenges mfa.ChallengeServiceInterface, lockoutService lockout.LockoutServiceInterface,
+	userService user.UserServiceInterface, tenantService service_ten.TenantServiceInterface,
+	tenantGroupService service_ten_group.TenantGroupServiceInterface, tokenService token.TokenServiceInterface, permissions jwt.PermissionResolver) *PasskeyHandler {
+	return &PasskeyHandler{
+		conf: conf,
+		rp: &webauthn.RelyingParty{
+			ID:      conf.WebAuthnRPID,
+			Name:    conf.WebAuthnRPName,
+			Origins: conf.WebAuthnOrigins,
+		},
+		credentials:        credentials,
+		ceremonies:         ceremonies,
+		mfaService:         mfaService,
+		challenges:         challenges,
+		lockoutService:     lockoutService,
+		userService:        userService,
+		tenantService:      tenantService,
+		tenantGroupService: tenantGroupService,
+		tokenService:       tokenService,
+		permissions:        permissions,
+	}
+}
+
+// @Summary Start a passkey registration
+// @Description Return the options of navigator.credentials.create for the logged in user.
+// @Description The passkeys already registered are excluded, so an authenticator is not registered twice.
+// @Description The user confirms with the TOTP code when MFA is enabled and with the password otherwise
+// @Tags passkey
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyRegisterBeginRequest true "Password or TOTP code"
+// @Success 200 {object} webauthn.CreationOptions
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 401 {object} handler.HttpMsg
+// @Failure 423 {object} handler.HttpMsg
+// @Failure 429 {object} handler.HttpMsg
+// @Router /api/v1/user/passkey/register/begin [post]
+func (h *PasskeyHandler) RegisterBegin(c *gin.Context) {
+	usr, ok := h.verifiedUser(c)
+	if !ok {
+		return
+	}
+
+	registered, err := h.credentials.GetByUser(c.Request.Context(), usr.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read passkeys"})
+		return
+	}
+
+	challenge, ok := h.begin(c, &passkey.Ceremony{Type: passkey.CeremonyRegistration, UserID: usr.ID.String()})
+	if !ok {
+		return
+	}
+
+	// The user handle is the user ID, returned by the authenticator at login
+	entity := webauthn.UserEntity{ID: usr.ID[:], Name: usr.Username, DisplayName: usr.Name}
+	c.JSON(http.StatusOK, h.rp.CreationOptions(challenge, entity, toWebAuthn(registered)))
+}
+
+// @Summary Complete a passkey registration
+// @Description Verify the response of navigator.credentials.create and save the passkey of the logged in user
+// @Tags passkey
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyRegisterRequest true "Authenticator response"
+// @Success 201 {object} model.UserCredential
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 401 {object} handler.HttpMsg
+// @Failure 409 {object} handler.HttpMsg
+// @Router /api/v1/user/passkey/register/finish [post]
+func (h *PasskeyHandler) RegisterFinish(c *gin.Context) {
+	var request dto.PasskeyRegisterRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	ceremony, challenge, ok := h.consume(c, request.Credential.Response.ClientDataJSON, passkey.CeremonyRegistration)
+	if !ok {
+		return
+	}
+	if ceremony.UserID != usr.ID.String() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired WebAuthn challenge"})
+		return
+	}
+
+	credential, err := h.rp.VerifyRegistration(&request.Credential, challenge)
+	if err != nil {
+		logger.Error("Passkey registration rejected", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey registration"})
+		return
+	}
+
+	userCredential, _ := model.NewUserCredential(&model.UserCredential{
+		UserID:       usr.ID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		Transports:   credential.Transports,
+		Name:         request.Name,
+	})
+	if aaguid, err := uuid.FromBytes(credential.AAGUID); err == nil {
+		userCredential.AAGUID = aaguid
+	}
+
+	userCredential, err = h.credentials.Create(c.Request.Context(), userCredential)
+	if errors.Is(err, passkey.ErrCredentialExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Passkey already registered"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save passkey"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, userCredential)
+}
+
+// @Summary List passkeys
+// @Description List the passkeys of the logged in user
+// @Tags passkey
+// @Produce json
+// @Success 200 {array} model.UserCredential
+// @Failure 401 {object} handler.HttpMsg
+// @Router /api/v1/user/passkey [get]
+func (h *PasskeyHandler) List(c *gin.Context) {
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	credentials, err := h.credentials.GetByUser(c.Request.Context(), usr.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read passkeys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// @Summary Delete a passkey
+// @Description Delete a passkey of the logged in user
+// @Tags passkey
+// @Param id path string true "Passkey ID"
+// @Success 204
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/user/passkey/{id} [delete]
+func (h *PasskeyHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passkey ID"})
+		return
+	}
+
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if h.credentials.Delete(c.Request.Context(), usr.ID, id) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Start a passkey login
+// @Description Return the options of navigator.credentials.get. With a username only its passkeys are allowed;
+// @Description without one the browser offers the discoverable credentials saved for this service
+// @Tags passkey
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyLoginBeginRequest false "Username and client"
+// @Success 200 {object} webauthn.RequestOptions
+// @Failure 400 {object} handler.HttpMsg
+// @Router /api/v1/user/passkey/login/begin [post]
+func (h *PasskeyHandler) LoginBegin(c *gin.Context) {
+	var request dto.PasskeyLoginBeginRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+	}
+
+	if _, err := jwt.Audience(h.conf, request.ClientID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown client"})
+		return
+	}
+
+	ceremony := &passkey.Ceremony{Type: passkey.CeremonyLogin, ClientID: request.ClientID}
+
+	// An unknown username gets options with no credentials, like a user without passkeys,
+	// so the response does not tell which usernames exist
+	var allowed []model.UserCredential
+	if request.Username != "" {
+		if usr, err := h.userService.GetByUserName(c.Request.Context(), request.Username); err == nil && usr.ID != uuid.Nil {
+			ceremony.UserID = usr.ID.String()
+			allowed, err = h.credentials.GetByUser(c.Request.Context(), usr.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read passkeys"})
+				return
+			}
+		}
+	}
+
+	challenge, ok := h.begin(c, ceremony)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.rp.RequestOptions(challenge, toWebAuthn(allowed)))
+}
+
+// @Summary Complete a passkey login
+// @Description Verify the response of navigator.credentials.get and issue the tokens, as getjwt does after the password.
+// @Description Passkeys require user verification, so the login does not ask for a second factor
+// @Tags passkey
+// @Accept json
+// @Produce json
+// @Param request body dto.PasskeyLoginRequest true "Authenticator response"
+// @Success 200 {object} jwt.TokenDetails
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 401 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Router /api/v1/user/passkey/login/finish [post]
+func (h *PasskeyHandler) LoginFinish(c *gin.Context) {
+	var request dto.PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	ceremony, challenge, ok := h.consume(c, request.Credential.Response.ClientDataJSON, passkey.CeremonyLogin)
+	if !ok {
+		return
+	}
+
+	userCredential, err := h.credentials.GetByCredentialID(c.Request.Context(), request.Credential.RawID)
+	if err != nil {
+		if !errors.Is(err, passkey.ErrCredentialNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read passkey"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	// The login started for a username only accepts its passkeys, and the user handle
+	// returned by the authenticator must be the owner of the credential
+	if ceremony.UserID != "" && ceremony.UserID != userCredential.UserID.String() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+	if handle := request.Credential.Response.UserHandle; handle != "" {
+		if decoded, err := base64.RawURLEncoding.DecodeString(handle); err != nil || !bytes.Equal(decoded, userCredential.UserID[:]) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+	}
+
+	credential, err := credentialOf(userCredential)
+	if err != nil {
+		logger.Error("Invalid stored passkey "+userCredential.ID.String(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	signCount, err := h.rp.VerifyAssertion(&request.Credential, challenge, credential)
+	if err == nil {
+		err = h.credentials.UpdateSignCount(c.Request.Context(), userCredential.ID, signCount)
+	}
+	if err != nil {
+		// A counter that did not increase may mean a cloned authenticator
+		logger.Error("Passkey assertion rejected for credential "+userCredential.ID.String(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	usr := h.userService.GetByID(c.Request.Context(), model.GlobalScope(), userCredential.UserID)
+	if usr == nil || usr.ID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	tenant := h.tenantService.GetByID(c.Request.Context(), model.GlobalScope(), usr.TenantID)
+	if tenant == nil || tenant.ID == uuid.Nil || !usr.Enable || !tenant.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User or tenant is disabled"})
+		return
+	}
+
+	tenantGroup := h.tenantGroupService.GetByID(c.Request.Context(), tenant.GroupID)
+
+	tokenDetails, err := jwt.GenerateToken(c.Request.Context(), usr, tenant, tenantGroup, ceremony.ClientID, h.conf, h.tokenService, h.permissions)
+	if err != nil {
+		logger.Error("Failed to generate JWT: ", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenDetails)
+}
+
+// begin stores a new ceremony and returns its challenge
+func (h *PasskeyHandler) begin(c *gin.Context, ceremony *passkey.Ceremony) (string, bool) {
+	challenge, err := webauthn.NewChallenge()
+	if err == nil {
+		err = h.ceremonies.Begin(c.Request.Context(), challenge, ceremony)
+	}
+	if err != nil {
+		logger.Error("Failed to start WebAuthn ceremony", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start passkey ceremony"})
+		return "", false
+	}
+
+	return challenge, true
+}
+
+// consume finds the ceremony answered by clientDataJSON and invalidates it, so a response is accepted once
+func (h *PasskeyHandler) consume(c *gin.Context, clientDataJSON, ceremonyType string) (*passkey.Ceremony, string, bool) {
+	challenge, err := webauthn.ClientChallenge(clientDataJSON)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid clientDataJSON"})
+		return nil, "", false
+	}
+
+	ceremony, err := h.ceremonies.Consume(c.Request.Context(), challenge)
+	if err != nil || ceremony.Type != ceremonyType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired WebAuthn challenge"})
+		return nil, "", false
+	}
+
+	return ceremony, challenge, true
+}
+
+func (h *PasskeyHandler) currentUser(c *gin.Context) (*model.User, bool) {
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return nil, false
+	}
+
+	usr := h.userService.GetByID(c.Request.Context(), model.GlobalScope(), id)
+	if usr == nil || usr.ID == uuid.Nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return nil, false
+	}
+
+	return usr, true
+}
+
+// verifiedUser reads the authenticated user and checks the password or the TOTP code of the
+// request. A passkey logs in without password nor second factor, so a stolen access token
+// alone must not add one that outlives the token and a password reset.
+func (h *PasskeyHandler) verifiedUser(c *gin.Context) (*model.User, bool) {
+	var request dto.PasskeyRegisterBeginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return nil, false
+	}
+
+	usr, ok := h.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	status, err := h.mfaService.Status(c.Request.Context(), usr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read MFA status"})
+		return nil, false
+	}
+	if status.Enabled {
+		return usr, h.verifyCode(c, usr, request.Code)
+	}
+	return usr, h.verifyPassword(c, usr, request.Password)
+}
+
+// verifyCode checks the TOTP or recovery code against the MFA attempts of the user
+func (h *PasskeyHandler) verifyCode(c *gin.Context, usr *model.User, code string) bool {
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "MFA code is required"})
+		return false
+	}
+
+	remaining, retryAfter, err := h.challenges.Attempt(c.Request.Context(), usr.ID.String())
+	if errors.Is(err, mfa.ErrLocked) {
+		locked(c, retryAfter, "MFA temporarily locked after too many wrong codes")
+		return false
+	}
+	if err != nil {
+		logger.Error("Error counting MFA attempt", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not verify MFA code"})
+		return false
+	}
+
+	err = h.mfaService.Verify(c.Request.Context(), usr.ID, code)
+	if errors.Is(err, mfa.ErrInvalidCode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code", "attempts_remaining": remaining})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify MFA code"})
+		return false
+	}
+	h.challenges.Succeed(c.Request.Context(), usr.ID.String())
+
+	return true
+}
+
+// verifyPassword checks the password with the brute-force protection of getjwt
+func (h *PasskeyHandler) verifyPassword(c *gin.Context, usr *model.User, password string) bool {
+	if password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return false
+	}
+
+	if retryAfter, err := h.lockoutService.Check(c.Request.Context(), usr.Username, c.ClientIP()); err != nil {
+		loginLimited(c, retryAfter, err)
+		return false
+	}
+
+	if _, err := h.userService.Authenticate(usr.Username, password); err != nil {
+		if retryAfter, err := h.lockoutService.Fail(c.Request.Context(), usr.Username, c.ClientIP()); err != nil {
+			loginLimited(c, retryAfter, err)
+			return false
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return false
+	}
+	h.lockoutService.Succeed(c.Request.Context(), usr.Username)
+
+	return true
+}
+
+// loginLimited answers a password refused by the brute-force protection, as getjwt does
+func loginLimited(c *gin.Context, retryAfter time.Duration, err error) {
+	if errors.Is(err, lockout.ErrLocked) {
+		locked(c, retryAfter, "Account temporarily locked after too many failed logins")
+		return
+	}
+	seconds := int(retryAfter.Seconds())
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many login attempts", "retry_after": seconds})
+}
+
+func locked(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(retryAfter.Seconds())
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusLocked, gin.H{"error": message, "retry_after": seconds})
+}
+
+// toWebAuthn converts the stored passkeys to the credentials checked by the relying party
+func toWebAuthn(credentials []model.UserCredential) []webauthn.Credential {
+	converted := make([]webauthn.Credential, 0, len(credentials))
+	for i := range credentials {
+		if credential, err := credentialOf(&credentials[i]); err == nil {
+			converted = append(converted, *credential)
+		}
+	}
+	return converted
+}
+
+func credentialOf(credential *model.UserCredential) (*webauthn.Credential, error) {
+	id, err := base64.RawURLEncoding.DecodeString(credential.CredentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webauthn.Credential{
+		ID:         id,
+		PublicKey:  credential.PublicKey,
+		SignCount:  credential.SignCount,
+		AAGUID:     credential.AAGUID[:],
+		Transports: credential.Transports,
+	}, nil
+}
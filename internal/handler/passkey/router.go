@@ -0,0 +1,25 @@
+package passkey
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+)
+
+// SetupRoutes configures the passkey registration of the users and the passwordless login
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *PasskeyHandler) {
+	userRoutes := router.Group("/api/v1/user")
+	{
+		guard.Register(userRoutes, []middleware.Route{
+			// Login with a passkey instead of the password of getjwt
+			{Method: http.MethodPost, Path: "/passkey/login/begin", Access: middleware.Public, Handler: handler.LoginBegin},
+			{Method: http.MethodPost, Path: "/passkey/login/finish", Access: middleware.Public, Handler: handler.LoginFinish},
+
+			{Method: http.MethodGet, Path: "/passkey", Access: middleware.Authenticated, Handler: handler.List},
+			{Method: http.MethodPost, Path: "/passkey/register/begin", Access: middleware.Authenticated, Handler: handler.RegisterBegin},
+			{Method: http.MethodPost, Path: "/passkey/register/finish", Access: middleware.Authenticated, Handler: handler.RegisterFinish},
+			{Method: http.MethodDelete, Path: "/passkey/:id", Access: middleware.Authenticated, Handler: handler.Delete},
+		})
+	}
+}
@@ -0,0 +1,532 @@
+package passkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/handler/handlertest"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	"github.com/katana-stuidio/access-control/pkg/service/passkey"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+	"github.com/katana-stuidio/access-control/pkg/webauthn"
+	"github.com/katana-stuidio/access-control/pkg/webauthn/webauthntest"
+)
+
+var (
+	testUserID   = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	testTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	otherUserID  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+const (
+	testPassword = "Senha#Forte2024"
+	testMFACode  = "123456"
+)
+
+// reauth is the body of register/begin that confirms maria with the password
+var reauth = map[string]string{"password": testPassword}
+
+// fakePasskeyService keeps the passkeys in memory
+type fakePasskeyService struct {
+	passkey.PasskeyServiceInterface
+	credentials map[string]*model.UserCredential
+}
+
+func (f *fakePasskeyService) GetByUser(ctx context.Context, userID uuid.UUID) ([]model.UserCredential, error) {
+	credentials := []model.UserCredential{}
+	for _, credential := range f.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, *credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (f *fakePasskeyService) GetByCredentialID(ctx context.Context, credentialID string) (*model.UserCredential, error) {
+	credential, ok := f.credentials[credentialID]
+	if !ok {
+		return nil, passkey.ErrCredentialNotFound
+	}
+	copied := *credential
+	return &copied, nil
+}
+
+func (f *fakePasskeyService) Create(ctx context.Context, credential *model.UserCredential) (*model.UserCredential, error) {
+	if _, ok := f.credentials[credential.CredentialID]; ok {
+		return credential, passkey.ErrCredentialExists
+	}
+	f.credentials[credential.CredentialID] = credential
+	return credential, nil
+}
+
+func (f *fakePasskeyService) UpdateSignCount(ctx context.Context, ID uuid.UUID, signCount uint32) error {
+	for _, credential := range f.credentials {
+		if credential.ID == ID {
+			credential.SignCount = signCount
+			return nil
+		}
+	}
+	return passkey.ErrCredentialNotFound
+}
+
+// fakeCeremonyService keeps the ceremonies in memory
+type fakeCeremonyService struct {
+	ceremonies map[string]*passkey.Ceremony
+}
+
+func (f *fakeCeremonyService) Begin(ctx context.Context, challenge string, ceremony *passkey.Ceremony) error {
+	f.ceremonies[challenge] = ceremony
+	return nil
+}
+
+func (f *fakeCeremonyService) Consume(ctx context.Context, challenge string) (*passkey.Ceremony, error) {
+	ceremony, ok := f.ceremonies[challenge]
+	if !ok {
+		return nil, passkey.ErrCeremonyNotFound
+	}
+	delete(f.ceremonies, challenge)
+	return ceremony, nil
+}
+
+type fakeUserService struct {
+	user.UserServiceInterface
+	enabled bool
+}
+
+func (f *fakeUserService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	switch ID {
+	case testUserID:
+		return &model.User{ID: testUserID, TenantID: testTenantID, Username: "maria", Name: "Maria", Role: model.RoleProfessor, Enable: f.enabled}
+	case otherUserID:
+		return &model.User{ID: otherUserID, TenantID: testTenantID, Username: "joao", Name: "João", Role: model.RoleProfessor, Enable: true}
+	}
+	return &model.User{}
+}
+
+func (f *fakeUserService) GetByUserName(ctx context.Context, username string) (*model.User, error) {
+	switch username {
+	case "maria":
+		return f.GetByID(ctx, model.GlobalScope(), testUserID), nil
+	case "joao":
+		return f.GetByID(ctx, model.GlobalScope(), otherUserID), nil
+	}
+	return &model.User{}, errors.New("sql: no rows in result set")
+}
+
+func (f *fakeUserService) Authenticate(username, password string) (*model.User, error) {
+	if username != "maria" || password != testPassword {
+		return nil, errors.New("invalid credentials")
+	}
+	return f.GetByUserName(context.Background(), username)
+}
+
+// fakeMFAService accepts testMFACode of the users with MFA enabled
+type fakeMFAService struct {
+	mfa.MFAServiceInterface
+	enabled bool
+}
+
+func (f *fakeMFAService) Status(ctx context.Context, usr *model.User) (*model.MFAStatus, error) {
+	return &model.MFAStatus{Enabled: f.enabled}, nil
+}
+
+func (f *fakeMFAService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	if !f.enabled {
+		return mfa.ErrMFANotEnabled
+	}
+	if code != testMFACode {
+		return mfa.ErrInvalidCode
+	}
+	return nil
+}
+
+type fakeChallengeService struct {
+	mfa.ChallengeServiceInterface
+	attempts map[string]int
+}
+
+func (f *fakeChallengeService) Attempt(ctx context.Context, userID string) (int, time.Duration, error) {
+	f.attempts[userID]++
+	if f.attempts[userID] > mfa.MaxAttempts {
+		return 0, 10 * time.Minute, mfa.ErrLocked
+	}
+	return mfa.MaxAttempts - f.attempts[userID], 0, nil
+}
+
+func (f *fakeChallengeService) Succeed(ctx context.Context, userID string) {
+	delete(f.attempts, userID)
+}
+
+type fakeLockoutService struct {
+	lockout.LockoutServiceInterface
+	failures int
+}
+
+func (f *fakeLockoutService) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (f *fakeLockoutService) Fail(ctx context.Context, username, ip string) (time.Duration, error) {
+	f.failures++
+	return 0, nil
+}
+
+func (f *fakeLockoutService) Succeed(ctx context.Context, username string) {
+	f.failures = 0
+}
+
+type fakeTenantService struct {
+	tenant.TenantServiceInterface
+}
+
+func (f *fakeTenantService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Tenant {
+	if ID != testTenantID {
+		return &model.Tenant{}
+	}
+	return &model.Tenant{ID: testTenantID, Name: "Escola", IsActive: true}
+}
+
+type fakeTenantGroupService struct {
+	tenant_group.TenantGroupServiceInterface
+}
+
+func (f *fakeTenantGroupService) GetByID(ctx context.Context, ID uuid.UUID) *model.TenantGroup {
+	return &model.TenantGroup{}
+}
+
+type fakeTokenService struct {
+	token.TokenServiceInterface
+}
+
+func (f *fakeTokenService) SaveRefreshToken(ctx context.Context, tokenID, familyID, userID, username, tenantID, role string, issuedAt, exp time.Time) error {
+	return nil
+}
+
+type testServer struct {
+	conf          *config.Config
+	router        *gin.Engine
+	users         *fakeUserService
+	mfa           *fakeMFAService
+	lockout       *fakeLockoutService
+	passkeys      *fakePasskeyService
+	authenticator *webauthntest.Authenticator
+}
+
+func newTestServer() *testServer {
+	gin.SetMode(gin.TestMode)
+
+	conf := config.NewConfig()
+	server := &testServer{
+		conf:          conf,
+		router:        gin.New(),
+		users:         &fakeUserService{enabled: true},
+		mfa:           &fakeMFAService{},
+		lockout:       &fakeLockoutService{},
+		passkeys:      &fakePasskeyService{credentials: map[string]*model.UserCredential{}},
+		authenticator: webauthntest.New(conf.WebAuthnOrigins[0]),
+	}
+	ceremonies := &fakeCeremonyService{ceremonies: map[string]*passkey.Ceremony{}}
+	challenges := &fakeChallengeService{attempts: map[string]int{}}
+	handler := NewPasskeyHandler(conf, server.passkeys, ceremonies, server.mfa, challenges, server.lockout, server.users, &fakeTenantService{}, &fakeTenantGroupService{}, &fakeTokenService{}, nil)
+	SetupRoutes(server.router, middleware.NewGuard(conf, nil, nil), handler)
+	return server
+}
+
+func (s *testServer) request(method, path, bearer string, body interface{}) *httptest.ResponseRecorder {
+	var payload string
+	switch b := body.(type) {
+	case string:
+		payload = b
+	case nil:
+	default:
+		data, _ := json.Marshal(b)
+		payload = string(data)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *testServer) signToken(t *testing.T, userID uuid.UUID) string {
+	claims := handlertest.Claims(s.conf, model.RoleProfessor)
+	claims.UserID = userID.String()
+	claims.TenantID = testTenantID.String()
+	return handlertest.Sign(t, s.conf, claims)
+}
+
+// register runs the registration ceremony of the user with the software authenticator
+func (s *testServer) register(t *testing.T, userID uuid.UUID) {
+	t.Helper()
+	bearer := s.signToken(t, userID)
+
+	w := s.request(http.MethodPost, "/api/v1/user/passkey/register/begin", bearer, reauth)
+	if w.Code != http.StatusOK {
+		t.Fatalf("início do cadastro: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var options webauthn.CreationOptions
+	if err := json.Unmarshal(w.Body.Bytes(), &options); err != nil {
+		t.Fatalf("opções inválidas: %v", err)
+	}
+
+	response, err := s.authenticator.Create(&options)
+	if err != nil {
+		t.Fatalf("autenticador: %v", err)
+	}
+
+	w = s.request(http.MethodPost, "/api/v1/user/passkey/register/finish", bearer, map[string]interface{}{"name": "Notebook", "credential": response})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("fim do cadastro: esperado %d, mas obteve %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+}
+
+// assert runs the login ceremony and returns the response of finish
+func (s *testServer) assert(t *testing.T, begin string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := s.request(http.MethodPost, "/api/v1/user/passkey/login/begin", "", begin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("início do login: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var options webauthn.RequestOptions
+	if err := json.Unmarshal(w.Body.Bytes(), &options); err != nil {
+		t.Fatalf("opções inválidas: %v", err)
+	}
+
+	response, err := s.authenticator.Get(&options)
+	if err != nil {
+		t.Fatalf("autenticador: %v", err)
+	}
+
+	return s.request(http.MethodPost, "/api/v1/user/passkey/login/finish", "", map[string]interface{}{"credential": response})
+}
+
+func TestRoutesRequireToken(t *testing.T) {
+	server := newTestServer()
+
+	public := map[string]bool{
+		"POST /api/v1/user/passkey/login/begin":  true,
+		"POST /api/v1/user/passkey/login/finish": true,
+	}
+
+	for _, route := range server.router.Routes() {
+		if public[route.Method+" "+route.Path] {
+			continue
+		}
+		if w := server.request(route.Method, route.Path, "", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s sem token: esperado %d, mas obteve %d", route.Method, route.Path, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
+
+func TestPasskeyLoginIssuesTokens(t *testing.T) {
+	server := newTestServer()
+	server.register(t, testUserID)
+
+	if len(server.passkeys.credentials) != 1 {
+		t.Fatalf("esperada 1 passkey gravada, mas obteve %d", len(server.passkeys.credentials))
+	}
+
+	// Login com credencial descoberta, sem informar o usuário
+	w := server.assert(t, `{}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var tokens jwt.TokenDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("tokens ausentes: %s", w.Body.String())
+	}
+
+	claims, err := jwt.ValidateToken(strings.TrimPrefix(tokens.AccessToken, "Bearer "), server.conf)
+	if err != nil {
+		t.Fatalf("token de acesso inválido: %v", err)
+	}
+	if claims.UserID != testUserID.String() || claims.TenantID != testTenantID.String() {
+		t.Errorf("token emitido para %s/%s, esperado %s/%s", claims.UserID, claims.TenantID, testUserID, testTenantID)
+	}
+
+	// Login informando o usuário
+	if w := server.assert(t, `{"username":"maria"}`); w.Code != http.StatusOK {
+		t.Errorf("com usuário: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestPasskeyLoginRejectsReplay(t *testing.T) {
+	server := newTestServer()
+	server.register(t, testUserID)
+
+	w := server.request(http.MethodPost, "/api/v1/user/passkey/login/begin", "", `{}`)
+	var options webauthn.RequestOptions
+	json.Unmarshal(w.Body.Bytes(), &options)
+	response, _ := server.authenticator.Get(&options)
+	body := map[string]interface{}{"credential": response}
+
+	if w := server.request(http.MethodPost, "/api/v1/user/passkey/login/finish", "", body); w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// O desafio é de uso único
+	if w := server.request(http.MethodPost, "/api/v1/user/passkey/login/finish", "", body); w.Code != http.StatusBadRequest {
+		t.Errorf("resposta reutilizada: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPasskeyLoginRejectsOtherUser(t *testing.T) {
+	server := newTestServer()
+	server.register(t, testUserID)
+
+	// O login iniciado para joao não aceita a passkey de maria
+	if w := server.assert(t, `{"username":"joao"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestPasskeyLoginUnknownUsername(t *testing.T) {
+	server := newTestServer()
+
+	w := server.request(http.MethodPost, "/api/v1/user/passkey/login/begin", "", `{"username":"ninguem"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	known := server.request(http.MethodPost, "/api/v1/user/passkey/login/begin", "", `{"username":"joao"}`)
+	var unknownOptions, knownOptions webauthn.RequestOptions
+	json.Unmarshal(w.Body.Bytes(), &unknownOptions)
+	json.Unmarshal(known.Body.Bytes(), &knownOptions)
+	if len(unknownOptions.AllowCredentials) != len(knownOptions.AllowCredentials) {
+		t.Error("usuário inexistente não deve ser distinguível de usuário sem passkeys")
+	}
+}
+
+func TestPasskeyLoginRejectsDisabledUser(t *testing.T) {
+	server := newTestServer()
+	server.register(t, testUserID)
+	server.users.enabled = false
+
+	if w := server.assert(t, `{}`); w.Code != http.StatusForbidden {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestPasskeyLoginRejectsClonedAuthenticator(t *testing.T) {
+	server := newTestServer()
+	server.authenticator.Counter = true
+	server.register(t, testUserID)
+
+	if w := server.assert(t, `{}`); w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Um clone com o contador atrasado não passa
+	for _, credential := range server.passkeys.credentials {
+		credential.SignCount = 10
+	}
+	if w := server.assert(t, `{}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("contador não incrementado: esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestPasskeyLoginRejectsUnknownClient(t *testing.T) {
+	server := newTestServer()
+
+	if w := server.request(http.MethodPost, "/api/v1/user/passkey/login/begin", "", `{"client_id":"desconhecido"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestRegisterRejectsOtherUserCeremony(t *testing.T) {
+	server := newTestServer()
+
+	// A cerimônia iniciada por maria não pode ser concluída por joao
+	w := server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", server.signToken(t, testUserID), reauth)
+	var options webauthn.CreationOptions
+	json.Unmarshal(w.Body.Bytes(), &options)
+	response, _ := server.authenticator.Create(&options)
+
+	w = server.request(http.MethodPost, "/api/v1/user/passkey/register/finish", server.signToken(t, otherUserID), map[string]interface{}{"credential": response})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if len(server.passkeys.credentials) != 0 {
+		t.Error("passkey não deveria ser gravada")
+	}
+}
+
+func TestRegisterExcludesExistingPasskeys(t *testing.T) {
+	server := newTestServer()
+	server.register(t, testUserID)
+
+	w := server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", server.signToken(t, testUserID), reauth)
+	var options webauthn.CreationOptions
+	json.Unmarshal(w.Body.Bytes(), &options)
+
+	if len(options.ExcludeCredentials) != 1 {
+		t.Fatalf("esperada 1 credencial excluída, mas obteve %d", len(options.ExcludeCredentials))
+	}
+	if _, err := server.authenticator.Create(&options); err == nil {
+		t.Error("o autenticador não deveria cadastrar a mesma passkey duas vezes")
+	}
+}
+
+func TestRegisterRequiresPassword(t *testing.T) {
+	server := newTestServer()
+	bearer := server.signToken(t, testUserID)
+
+	// Só o token de acesso não basta para cadastrar uma passkey
+	if w := server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", bearer, `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("sem senha: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if w := server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", bearer, map[string]string{"password": "errada"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("senha errada: esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+	if server.lockout.failures != 1 {
+		t.Errorf("esperada 1 falha contada no bloqueio, mas obteve %d", server.lockout.failures)
+	}
+}
+
+func TestRegisterRequiresMFACodeWhenEnabled(t *testing.T) {
+	server := newTestServer()
+	server.mfa.enabled = true
+	bearer := server.signToken(t, testUserID)
+
+	// Com MFA ligado a senha não basta
+	if w := server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", bearer, reauth); w.Code != http.StatusBadRequest {
+		t.Errorf("só a senha: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	for i := 0; i < mfa.MaxAttempts; i++ {
+		server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", bearer, map[string]string{"code": "000000"})
+	}
+	w := server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", bearer, map[string]string{"code": testMFACode})
+	if w.Code != http.StatusLocked {
+		t.Errorf("após %d códigos errados: esperado %d, mas obteve %d", mfa.MaxAttempts, http.StatusLocked, w.Code)
+	}
+
+	server = newTestServer()
+	server.mfa.enabled = true
+	w = server.request(http.MethodPost, "/api/v1/user/passkey/register/begin", server.signToken(t, testUserID), map[string]string{"code": testMFACode})
+	if w.Code != http.StatusOK {
+		t.Errorf("código válido: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/service/password_policy"
+)
+
+// PasswordRejected converts the error of a password rejected by the password policy; ok is false for other errors
+func PasswordRejected(err error) (dto.PasswordRejectedResponse, bool) {
+	var policyErr *password_policy.PolicyError
+	if !errors.As(err, &policyErr) {
+		return dto.PasswordRejectedResponse{}, false
+	}
+
+	rejected := dto.PasswordRejectedResponse{Error: "Password does not meet the password policy"}
+	for _, violation := range policyErr.Violations {
+		rejected.Violations = append(rejected.Violations, dto.PasswordViolation{Rule: violation.Rule, Message: violation.Message})
+	}
+	return rejected, true
+}
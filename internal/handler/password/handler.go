@@ -0,0 +1,296 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/handler"
+	"github.com/katana-stuidio/access-control/pkg/adapter/mailer"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/password_policy"
+	"github.com/katana-stuidio/access-control/pkg/service/password_reset"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+)
+
+// PasswordHandler implements the forgot-password flow: a reset link sent by email and the
+// new password set with its token; and the password policy of the tenant groups
+type PasswordHandler struct {
+	conf               *config.Config
+	resets             password_reset.PasswordResetServiceInterface
+	policies           password_policy.PasswordPolicyServiceInterface
+	userService        user.UserServiceInterface
+	tenantGroupService tenant_group.TenantGroupServiceInterface
+	tokenService       token.TokenServiceInterface
+	mailer             mailer.SenderInterface
+}
+
+func NewPasswordHandler(conf *config.Config, resets password_reset.PasswordResetServiceInterface, policies password_policy.PasswordPolicyServiceInterface,
+	userService user.UserServiceInterface, tenantGroupService tenant_group.TenantGroupServiceInterface, tokenService token.TokenServiceInterface,
+	sender mailer.SenderInterface) *PasswordHandler {
+	return &PasswordHandler{
+		conf:               conf,
+		resets:             resets,
+		policies:           policies,
+		userService:        userService,
+		tenantGroupService: tenantGroupService,
+		tokenService:       tokenService,
+		mailer:             sender,
+	}
+}
+
+// @Summary Forgot password
+// @Description Send a password reset link to the email of the user. The response is the same whether the user
+// @Description exists or not, so it does not tell which usernames exist
+// @Tags password
+// @Accept json
+// @Produce json
+// @Param request body dto.PasswordForgotRequest true "Username"
+// @Success 202 {object} handler.HttpMsg
+// @Failure 400 {object} handler.HttpMsg
+// @Router /api/v1/user/password/forgot [post]
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	var request dto.PasswordForgotRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	accepted := gin.H{"message": "If the user exists, a password reset link was sent to its email"}
+
+	usr, err := h.userService.GetByUserName(c.Request.Context(), request.Username)
+	if err != nil || usr == nil || usr.ID == uuid.Nil || !usr.Enable || usr.Email == "" {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	resetToken, err := h.resets.Issue(c.Request.Context(), usr.ID)
+	if err != nil {
+		if !errors.Is(err, password_reset.ErrTooSoon) {
+			logger.Error("Failed to issue password reset token for user "+usr.ID.String(), err)
+		}
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	// Sent after the response, so its time does not tell that the user exists
+	message := h.resetMessage(usr, resetToken)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailer.Timeout)
+		defer cancel()
+
+		if err := h.mailer.Send(ctx, message); err != nil {
+			logger.Error("Failed to send password reset email to user "+usr.ID.String(), err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// @Summary Reset password
+// @Description Set a new password with the token of the reset link, or with the password_change_token returned by
+// @Description getjwt for an expired password. The token is single-use and every session of the user is revoked
+// @Tags password
+// @Accept json
+// @Produce json
+// @Param request body dto.PasswordResetRequest true "Token and new password"
+// @Success 200 {object} handler.HttpMsg
+// @Failure 400 {object} handler.HttpMsg
+// @Router /api/v1/user/password/reset [post]
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var request dto.PasswordResetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	userID, err := h.resets.Verify(c.Request.Context(), request.Token)
+	if err != nil {
+		h.invalidToken(c, err)
+		return
+	}
+
+	usr := h.userService.GetByID(c.Request.Context(), model.GlobalScope(), userID)
+	if usr == nil || usr.ID == uuid.Nil || !usr.Enable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+		return
+	}
+
+	// Checked before the token is consumed, so a rejected password does not spend the link
+	if err := h.userService.ValidatePassword(c.Request.Context(), usr, request.NewPassword); err != nil {
+		if rejected, ok := handler.PasswordRejected(err); ok {
+			c.JSON(http.StatusBadRequest, rejected)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not validate password"})
+		return
+	}
+
+	consumedBy, err := h.resets.Consume(c.Request.Context(), request.Token)
+	if err != nil {
+		h.invalidToken(c, err)
+		return
+	}
+	if consumedBy != usr.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+		return
+	}
+
+	if h.userService.UpdatePassword(c.Request.Context(), usr.Username, request.NewPassword) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset password"})
+		return
+	}
+
+	// Whoever knew the old password loses the sessions opened with it
+	if err := jwt.RevokeAllUserTokens(usr.ID.String(), h.tokenService); err != nil {
+		logger.Error("Failed to revoke tokens after password reset of user "+usr.ID.String(), err)
+	}
+
+	logger.Info("Password reset for user " + usr.ID.String())
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset"})
+}
+
+// @Summary Get tenant group password policy
+// @Description Rules of the passwords of the users of the tenant group, the default ones when none was saved
+// @Tags password
+// @Produce json
+// @Param id path string true "Tenant group ID"
+// @Success 200 {object} dto.PasswordPolicyResponse
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/tenant-groups/{id}/password-policy [get]
+func (h *PasswordHandler) GetPolicy(c *gin.Context) {
+	groupID, ok := h.policyGroup(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.policies.GetPolicy(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read password policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policyResponse(policy))
+}
+
+// @Summary Set tenant group password policy
+// @Description Replace the rules of the passwords of the users of the tenant group. The rules apply to the
+// @Description next password set; a max_age_days shorter than the age of a password expires it at the next login
+// @Tags password
+// @Accept json
+// @Produce json
+// @Param id path string true "Tenant group ID"
+// @Param policy body dto.PasswordPolicyRequest true "Password policy"
+// @Success 200 {object} dto.PasswordPolicyResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/tenant-groups/{id}/password-policy [put]
+func (h *PasswordHandler) SetPolicy(c *gin.Context) {
+	var request dto.PasswordPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	groupID, ok := h.policyGroup(c)
+	if !ok {
+		return
+	}
+
+	policy := &model.PasswordPolicy{
+		GroupID:              groupID,
+		MinLength:            request.MinLength,
+		RequireUppercase:     request.RequireUppercase,
+		RequireLowercase:     request.RequireLowercase,
+		RequireNumber:        request.RequireNumber,
+		RequireSymbol:        request.RequireSymbol,
+		DisallowPersonalData: request.DisallowPersonalData,
+		HistorySize:          request.HistorySize,
+		MaxAgeDays:           request.MaxAgeDays,
+	}
+
+	if err := h.policies.SetPolicy(c.Request.Context(), policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save password policy"})
+		return
+	}
+
+	logger.Info("Password policy of tenant group " + groupID.String() + " changed by user " + c.GetString("user_id"))
+	c.JSON(http.StatusOK, policyResponse(policy))
+}
+
+// invalidToken writes the response of a reset token that could not be verified or consumed
+func (h *PasswordHandler) invalidToken(c *gin.Context, err error) {
+	if !errors.Is(err, password_reset.ErrInvalidToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset password"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+}
+
+// policyGroup reads the tenant group of the path, writing the error response when it is outside the scope of the caller
+func (h *PasswordHandler) policyGroup(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return uuid.Nil, false
+	}
+
+	scope := model.TenantScopeFromContext(c.Request.Context())
+	if !scope.AllowsGroup(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant group not found"})
+		return uuid.Nil, false
+	}
+
+	group := h.tenantGroupService.GetByID(c.Request.Context(), id)
+	if group == nil || group.ID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant group not found"})
+		return uuid.Nil, false
+	}
+
+	return group.ID, true
+}
+
+func policyResponse(policy *model.PasswordPolicy) dto.PasswordPolicyResponse {
+	return dto.PasswordPolicyResponse{
+		GroupID:              policy.GroupID,
+		MinLength:            policy.MinLength,
+		RequireUppercase:     policy.RequireUppercase,
+		RequireLowercase:     policy.RequireLowercase,
+		RequireNumber:        policy.RequireNumber,
+		RequireSymbol:        policy.RequireSymbol,
+		DisallowPersonalData: policy.DisallowPersonalData,
+		HistorySize:          policy.HistorySize,
+		MaxAgeDays:           policy.MaxAgeDays,
+		UpdatedAt:            policy.UpdatedAt,
+	}
+}
+
+func (h *PasswordHandler) resetMessage(usr *model.User, resetToken string) *mailer.Message {
+	link := h.conf.PasswordResetURL
+	if parsed, err := url.Parse(link); err == nil {
+		query := parsed.Query()
+		query.Set("token", resetToken)
+		parsed.RawQuery = query.Encode()
+		link = parsed.String()
+	}
+
+	return &mailer.Message{
+		To:      usr.Email,
+		Subject: "Redefinição de senha",
+		Body: fmt.Sprintf("Olá, %s.\n\n"+
+			"Recebemos um pedido para redefinir a sua senha. Use o link abaixo em até %d minutos:\n\n"+
+			"%s\n\n"+
+			"Se você não fez o pedido, ignore este email: a sua senha continua a mesma.\n",
+			usr.Name, h.conf.PasswordResetTTL, link),
+	}
+}
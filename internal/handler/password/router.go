@@ -0,0 +1,30 @@
+package password
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// SetupRoutes configures the forgot-password routes, public as the user cannot log in,
+// and the password policy of the tenant groups
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *PasswordHandler) {
+	userRoutes := router.Group("/api/v1/user")
+	{
+		guard.Register(userRoutes, []middleware.Route{
+			{Method: http.MethodPost, Path: "/password/forgot", Access: middleware.Public, Handler: handler.Forgot},
+			{Method: http.MethodPost, Path: "/password/reset", Access: middleware.Public, Handler: handler.Reset},
+		})
+	}
+
+	tenantGroupRoutes := router.Group("/api/v1/tenant-groups")
+	{
+		managers := []string{model.RoleAdmin, model.RoleGrupoEducacional}
+		guard.Register(tenantGroupRoutes, []middleware.Route{
+			{Method: http.MethodGet, Path: "/:id/password-policy", Access: middleware.Restricted, Roles: managers, Handler: handler.GetPolicy},
+			{Method: http.MethodPut, Path: "/:id/password-policy", Access: middleware.Restricted, Roles: managers, Handler: handler.SetPolicy},
+		})
+	}
+}
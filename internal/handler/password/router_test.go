@@ -0,0 +1,354 @@
+package password
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/handler/handlertest"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/adapter/mailer"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/password_policy"
+	"github.com/katana-stuidio/access-control/pkg/service/password_reset"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+)
+
+var (
+	testUserID  = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	testGroupID = uuid.MustParse("00000000-0000-0000-0000-000000000004")
+)
+
+// fakeResetService keeps the tokens in memory; a token is valid once
+type fakeResetService struct {
+	tokens map[string]uuid.UUID
+}
+
+func (f *fakeResetService) Issue(ctx context.Context, userID uuid.UUID) (string, error) {
+	resetToken := uuid.NewString()
+	f.tokens[resetToken] = userID
+	return resetToken, nil
+}
+
+func (f *fakeResetService) IssueChange(ctx context.Context, userID uuid.UUID) (string, error) {
+	return f.Issue(ctx, userID)
+}
+
+func (f *fakeResetService) Verify(ctx context.Context, resetToken string) (uuid.UUID, error) {
+	userID, ok := f.tokens[resetToken]
+	if !ok {
+		return uuid.Nil, password_reset.ErrInvalidToken
+	}
+	return userID, nil
+}
+
+func (f *fakeResetService) Consume(ctx context.Context, resetToken string) (uuid.UUID, error) {
+	userID, ok := f.tokens[resetToken]
+	if !ok {
+		return uuid.Nil, password_reset.ErrInvalidToken
+	}
+	delete(f.tokens, resetToken)
+	return userID, nil
+}
+
+type fakeUserService struct {
+	user.UserServiceInterface
+	passwords map[string]string
+}
+
+func (f *fakeUserService) GetByUserName(ctx context.Context, userName string) (*model.User, error) {
+	if userName != "maria" {
+		return &model.User{}, errors.New("sql: no rows in result set")
+	}
+	return f.GetByID(ctx, model.GlobalScope(), testUserID), nil
+}
+
+func (f *fakeUserService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	if ID != testUserID {
+		return &model.User{}
+	}
+	return &model.User{ID: testUserID, Username: "maria", Name: "Maria", Email: "maria@escola.br", Enable: true}
+}
+
+// ValidatePassword checks the password against the default policy, forbidding personal data
+func (f *fakeUserService) ValidatePassword(ctx context.Context, User *model.User, password string) error {
+	policy := model.DefaultPasswordPolicy(testGroupID)
+	policy.DisallowPersonalData = true
+	if violations := policy.Check(User, password); len(violations) > 0 {
+		return &password_policy.PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (f *fakeUserService) UpdatePassword(ctx context.Context, userName, newPassword string) int64 {
+	f.passwords[userName] = newPassword
+	return 1
+}
+
+type fakeTokenService struct {
+	token.TokenServiceInterface
+	revoked []string
+}
+
+func (f *fakeTokenService) DeleteAllUserTokens(ctx context.Context, userID string) error {
+	f.revoked = append(f.revoked, userID)
+	return nil
+}
+
+// fakePolicyService keeps the saved policies in memory
+type fakePolicyService struct {
+	policies map[uuid.UUID]*model.PasswordPolicy
+}
+
+func (f *fakePolicyService) GetPolicy(ctx context.Context, groupID uuid.UUID) (*model.PasswordPolicy, error) {
+	if policy, ok := f.policies[groupID]; ok {
+		return policy, nil
+	}
+	return model.DefaultPasswordPolicy(groupID), nil
+}
+
+func (f *fakePolicyService) SetPolicy(ctx context.Context, policy *model.PasswordPolicy) error {
+	f.policies[policy.GroupID] = policy
+	return nil
+}
+
+type fakeTenantGroupService struct {
+	tenant_group.TenantGroupServiceInterface
+}
+
+func (f *fakeTenantGroupService) GetByID(ctx context.Context, ID uuid.UUID) *model.TenantGroup {
+	if ID != testGroupID {
+		return &model.TenantGroup{}
+	}
+	return &model.TenantGroup{ID: testGroupID, Name: "Rede", IsActive: true}
+}
+
+type testServer struct {
+	router   *gin.Engine
+	mailDir  string
+	resets   *fakeResetService
+	users    *fakeUserService
+	sessions *fakeTokenService
+	policies *fakePolicyService
+	conf     *config.Config
+}
+
+func newTestServer(t *testing.T) *testServer {
+	gin.SetMode(gin.TestMode)
+
+	conf := config.NewConfig()
+	conf.PasswordResetURL = "https://portal.escola.br/redefinir?origem=email"
+	server := &testServer{
+		router:   gin.New(),
+		mailDir:  t.TempDir(),
+		resets:   &fakeResetService{tokens: map[string]uuid.UUID{}},
+		users:    &fakeUserService{passwords: map[string]string{}},
+		sessions: &fakeTokenService{},
+		policies: &fakePolicyService{policies: map[uuid.UUID]*model.PasswordPolicy{}},
+		conf:     conf,
+	}
+	handler := NewPasswordHandler(conf, server.resets, server.policies, server.users, &fakeTenantGroupService{}, server.sessions,
+		mailer.NewLogSender(server.mailDir, conf.MailFrom))
+	SetupRoutes(server.router, middleware.NewGuard(conf, nil, nil), handler)
+	return server
+}
+
+func (s *testServer) request(path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// authorized sends a request with the token of a user of role in the tenant group groupID
+func (s *testServer) authorized(t *testing.T, method, path, body, role string, groupID uuid.UUID) *httptest.ResponseRecorder {
+	claims := handlertest.Claims(s.conf, role)
+	claims.GroupID = groupID.String()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+handlertest.Sign(t, s.conf, claims))
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// resetToken waits for the email sent after the response and returns the token of its link
+func (s *testServer) resetToken(t *testing.T) string {
+	t.Helper()
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		files, _ := filepath.Glob(filepath.Join(s.mailDir, "*.eml"))
+		if len(files) == 0 {
+			continue
+		}
+
+		data, _ := os.ReadFile(files[0])
+		if !strings.Contains(string(data), "To: maria@escola.br") {
+			t.Fatalf("email enviado ao destinatário errado:\n%s", data)
+		}
+		match := regexp.MustCompile(`https://portal\.escola\.br/redefinir\?origem=email&token=([\w-]+)`).FindSubmatch(data)
+		if match == nil {
+			t.Fatalf("link de redefinição ausente:\n%s", data)
+		}
+		return string(match[1])
+	}
+
+	t.Fatal("email de redefinição não enviado")
+	return ""
+}
+
+func TestForgotAndReset(t *testing.T) {
+	server := newTestServer(t)
+
+	if w := server.request("/api/v1/user/password/forgot", `{"username":"maria"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	resetToken := server.resetToken(t)
+
+	w := server.request("/api/v1/user/password/reset", `{"token":"`+resetToken+`","new_password":"Nova#Senha1"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("redefinição: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if server.users.passwords["maria"] != "Nova#Senha1" {
+		t.Error("senha não atualizada")
+	}
+	if len(server.sessions.revoked) != 1 || server.sessions.revoked[0] != testUserID.String() {
+		t.Errorf("sessões do usuário deveriam ser revogadas, obteve %v", server.sessions.revoked)
+	}
+
+	// O token é de uso único
+	w = server.request("/api/v1/user/password/reset", `{"token":"`+resetToken+`","new_password":"Outra#Senha2"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("token reutilizado: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestForgotDoesNotRevealUsers(t *testing.T) {
+	server := newTestServer(t)
+
+	known := server.request("/api/v1/user/password/forgot", `{"username":"maria"}`)
+	unknown := server.request("/api/v1/user/password/forgot", `{"username":"ninguem"}`)
+
+	if known.Code != unknown.Code || known.Body.String() != unknown.Body.String() {
+		t.Errorf("respostas diferentes: %d %s / %d %s", known.Code, known.Body.String(), unknown.Code, unknown.Body.String())
+	}
+}
+
+func TestResetRejectsWeakPasswordWithoutSpendingToken(t *testing.T) {
+	server := newTestServer(t)
+	resetToken, _ := server.resets.Issue(context.Background(), testUserID)
+
+	if w := server.request("/api/v1/user/password/reset", `{"token":"`+resetToken+`","new_password":"fraca"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("senha fraca: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if len(server.users.passwords) != 0 {
+		t.Error("senha fraca não deveria ser gravada")
+	}
+
+	if w := server.request("/api/v1/user/password/reset", `{"token":"`+resetToken+`","new_password":"Nova#Senha1"}`); w.Code != http.StatusOK {
+		t.Errorf("o token deveria continuar válido: esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestResetRejectsUnknownToken(t *testing.T) {
+	server := newTestServer(t)
+
+	if w := server.request("/api/v1/user/password/reset", `{"token":"desconhecido","new_password":"Nova#Senha1"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if len(server.sessions.revoked) != 0 {
+		t.Error("nenhuma sessão deveria ser revogada")
+	}
+}
+
+func TestResetListsEveryViolation(t *testing.T) {
+	server := newTestServer(t)
+	resetToken, _ := server.resets.Issue(context.Background(), testUserID)
+
+	w := server.request("/api/v1/user/password/reset", `{"token":"`+resetToken+`","new_password":"maria2024"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	var response dto.PasswordRejectedResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	rules := []string{}
+	for _, violation := range response.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	expected := []string{model.PasswordRuleUppercase, model.PasswordRuleSymbol, model.PasswordRulePersonalData}
+	if strings.Join(rules, " ") != strings.Join(expected, " ") {
+		t.Errorf("esperadas as regras %v, mas obteve %v", expected, rules)
+	}
+	if _, ok := server.resets.tokens[resetToken]; !ok {
+		t.Error("o token não deveria ser usado")
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	server := newTestServer(t)
+	path := "/api/v1/tenant-groups/" + testGroupID.String() + "/password-policy"
+
+	w := server.authorized(t, http.MethodGet, path, "", model.RoleGrupoEducacional, testGroupID)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"min_length":8`) {
+		t.Fatalf("política padrão: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	policy := `{"min_length":12,"require_uppercase":true,"require_lowercase":true,"require_number":true,"require_symbol":false,` +
+		`"disallow_personal_data":true,"history_size":5,"max_age_days":90}`
+	w = server.authorized(t, http.MethodPut, path, policy, model.RoleGrupoEducacional, testGroupID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("gravação: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	saved := server.policies.policies[testGroupID]
+	if saved == nil || saved.MinLength != 12 || !saved.RequireLowercase || saved.RequireSymbol || saved.HistorySize != 5 || saved.MaxAgeDays != 90 {
+		t.Errorf("política gravada incorretamente: %+v", saved)
+	}
+}
+
+func TestPasswordPolicyRejectsInvalidRequests(t *testing.T) {
+	server := newTestServer(t)
+	path := "/api/v1/tenant-groups/" + testGroupID.String() + "/password-policy"
+	otherGroup := uuid.MustParse("00000000-0000-0000-0000-000000000005")
+
+	cases := []struct {
+		name    string
+		body    string
+		role    string
+		groupID uuid.UUID
+		status  int
+	}{
+		{"tamanho mínimo abaixo de 8", `{"min_length":4}`, model.RoleAdmin, uuid.Nil, http.StatusBadRequest},
+		{"tamanho mínimo acima do bcrypt", `{"min_length":73}`, model.RoleAdmin, uuid.Nil, http.StatusBadRequest},
+		{"histórico acima do limite", `{"min_length":8,"history_size":100}`, model.RoleAdmin, uuid.Nil, http.StatusBadRequest},
+		{"grupo de outro gestor", `{"min_length":8}`, model.RoleGrupoEducacional, otherGroup, http.StatusNotFound},
+		{"role sem permissão", `{"min_length":8}`, model.RoleInstituicao, testGroupID, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		if w := server.authorized(t, http.MethodPut, path, tc.body, tc.role, tc.groupID); w.Code != tc.status {
+			t.Errorf("%s: esperado %d, mas obteve %d: %s", tc.name, tc.status, w.Code, w.Body.String())
+		}
+	}
+	if len(server.policies.policies) != 0 {
+		t.Error("nenhuma política deveria ser gravada")
+	}
+}
@@ -0,0 +1,292 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/role"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+)
+
+type RoleHandler struct {
+	roleService        role.RoleServiceInterface
+	tenantGroupService tenant_group.TenantGroupServiceInterface
+}
+
+func NewRoleHandler(roleService role.RoleServiceInterface, tenantGroupService tenant_group.TenantGroupServiceInterface) *RoleHandler {
+	return &RoleHandler{
+		roleService:        roleService,
+		tenantGroupService: tenantGroupService,
+	}
+}
+
+// @Summary List roles
+// @Description Role catalog of a tenant group: the group roles and the global roles it did not override.
+// @Description Without group_id the catalog of the caller group, or the global catalog for Admin
+// @Tags roles
+// @Produce json
+// @Param group_id query string false "Tenant group ID"
+// @Success 200 {array} dto.RoleResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Router /api/v1/roles/ [get]
+func (h *RoleHandler) GetAll(c *gin.Context) {
+	scope := model.TenantScopeFromContext(c.Request.Context())
+
+	groupID := scope.GroupID
+	if scope.Level == model.ScopeGlobal {
+		groupID = uuid.Nil
+	}
+	if groupStr := c.Query("group_id"); groupStr != "" {
+		id, err := uuid.Parse(groupStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group_id parameter"})
+			return
+		}
+		if !scope.AllowsGroup(id) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tenant group not found"})
+			return
+		}
+		groupID = id
+	}
+
+	roles, err := h.roleService.GetAll(c.Request.Context(), groupID)
+	if err != nil {
+		logger.Error("Error getting roles", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	response := []dto.RoleResponse{}
+	for i := range roles {
+		response = append(response, roleResponse(&roles[i]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Create role
+// @Description Create a role in the global catalog (Admin) or in the catalog of a tenant group.
+// @Description A group role with the name of a global role overrides it for the tenants of the group
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param role body dto.RoleCreateRequest true "Role"
+// @Success 201 {object} dto.RoleResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 409 {object} handler.HttpMsg
+// @Router /api/v1/roles/ [post]
+func (h *RoleHandler) Create(c *gin.Context) {
+	var request dto.RoleCreateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	if !model.ValidRoleName(request.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role name, use letters, digits and _ (2 to 50 characters)"})
+		return
+	}
+
+	// Group level callers create roles in their own group
+	scope := model.TenantScopeFromContext(c.Request.Context())
+	if request.GroupID == uuid.Nil && scope.Level != model.ScopeGlobal {
+		request.GroupID = scope.GroupID
+	}
+
+	if request.GroupID == uuid.Nil {
+		if scope.Level != model.ScopeGlobal {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only Admin manages the global roles"})
+			return
+		}
+	} else {
+		if !scope.AllowsGroup(request.GroupID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Tenant group outside of the caller scope"})
+			return
+		}
+		group := h.tenantGroupService.GetByID(c.Request.Context(), request.GroupID)
+		if group == nil || group.ID == uuid.Nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tenant group not found"})
+			return
+		}
+	}
+
+	enable := true
+	if request.Enable != nil {
+		enable = *request.Enable
+	}
+
+	newRole, err := model.NewRole(&model.Role{
+		GroupID:     request.GroupID,
+		Name:        request.Name,
+		Description: request.Description,
+		Enable:      enable,
+	})
+	if err != nil {
+		logger.Error("Error creating role", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	created, err := h.roleService.Create(c.Request.Context(), newRole)
+	if err != nil {
+		if errors.Is(err, role.ErrRoleExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Role already exists in this catalog"})
+			return
+		}
+		logger.Error("Error saving role", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, roleResponse(created))
+}
+
+// @Summary Get role
+// @Tags roles
+// @Produce json
+// @Param id path string true "Role ID"
+// @Success 200 {object} dto.RoleResponse
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/roles/{id} [get]
+func (h *RoleHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	scope := model.TenantScopeFromContext(c.Request.Context())
+
+	found := h.roleService.GetByID(c.Request.Context(), scope, id)
+	if found.ID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roleResponse(found))
+}
+
+// @Summary Update role
+// @Description Change the description and the enabled flag of a role. Tenant groups change
+// @Description a global role by creating a role with the same name in the group
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Param role body dto.RoleUpdateRequest true "Role"
+// @Success 200 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/roles/{id} [put]
+func (h *RoleHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var request dto.RoleUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Error binding JSON", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	scope := model.TenantScopeFromContext(c.Request.Context())
+	if !h.writable(c, scope, id) {
+		return
+	}
+
+	rowsAffected := h.roleService.Update(c.Request.Context(), scope, id, &model.Role{
+		Description: request.Description,
+		Enable:      request.Enable,
+	})
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+}
+
+// @Summary Delete role
+// @Description Delete a role of the catalog. Deleting the override of a global role restores the global role.
+// @Description System roles and roles granted to users cannot be deleted
+// @Tags roles
+// @Produce json
+// @Param id path string true "Role ID"
+// @Success 200 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Failure 409 {object} handler.HttpMsg
+// @Router /api/v1/roles/{id} [delete]
+func (h *RoleHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	scope := model.TenantScopeFromContext(c.Request.Context())
+	if !h.writable(c, scope, id) {
+		return
+	}
+
+	rowsAffected, err := h.roleService.Delete(c.Request.Context(), scope, id)
+	switch {
+	case errors.Is(err, role.ErrSystemRole):
+		c.JSON(http.StatusConflict, gin.H{"error": "System roles cannot be deleted"})
+		return
+	case errors.Is(err, role.ErrRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is granted to users"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	case rowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// writable checks that the caller may change the role and writes the error response otherwise:
+// global roles belong to Admin, group roles to the callers of the group
+func (h *RoleHandler) writable(c *gin.Context, scope model.TenantScope, id uuid.UUID) bool {
+	found := h.roleService.GetByID(c.Request.Context(), scope, id)
+	if found.ID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return false
+	}
+
+	if found.Global() && scope.Level != model.ScopeGlobal {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Global roles are overridden by creating a role with the same name in the tenant group"})
+		return false
+	}
+
+	return true
+}
+
+func roleResponse(r *model.Role) dto.RoleResponse {
+	response := dto.RoleResponse{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		Enable:      r.Enable,
+		System:      r.System,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+	if !r.Global() {
+		groupID := r.GroupID
+		response.GroupID = &groupID
+	}
+	return response
+}
@@ -0,0 +1,26 @@
+package role
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// SetupRoutes configures the role catalog routes
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *RoleHandler) {
+	readers := []string{model.RoleAdmin, model.RoleGrupoEducacional, model.RoleInstituicao}
+	managers := []string{model.RoleAdmin, model.RoleGrupoEducacional}
+
+	roleRoutes := router.Group("/api/v1/roles")
+	{
+		guard.Register(roleRoutes, []middleware.Route{
+			{Method: http.MethodGet, Path: "/", Access: middleware.Restricted, Roles: readers, Handler: handler.GetAll},
+			{Method: http.MethodGet, Path: "/:id", Access: middleware.Restricted, Roles: readers, Handler: handler.GetByID},
+			{Method: http.MethodPost, Path: "/", Access: middleware.Restricted, Roles: managers, Handler: handler.Create},
+			{Method: http.MethodPut, Path: "/:id", Access: middleware.Restricted, Roles: managers, Handler: handler.Update},
+			{Method: http.MethodDelete, Path: "/:id", Access: middleware.Restricted, Roles: managers, Handler: handler.Delete},
+		})
+	}
+}
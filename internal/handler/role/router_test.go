@@ -0,0 +1,196 @@
+package role
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/handler/handlertest"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/role"
+	"github.com/katana-stuidio/access-control/pkg/service/tenant_group"
+)
+
+var (
+	testGroupID  = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	otherGroupID = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+)
+
+// fakeRoleService keeps the catalog in memory
+type fakeRoleService struct {
+	role.RoleServiceInterface
+	roles map[uuid.UUID]*model.Role
+}
+
+func newFakeRoleService() *fakeRoleService {
+	return &fakeRoleService{roles: map[uuid.UUID]*model.Role{}}
+}
+
+func (f *fakeRoleService) add(groupID uuid.UUID, name string) *model.Role {
+	r := &model.Role{ID: uuid.New(), GroupID: groupID, Name: name, Enable: true}
+	f.roles[r.ID] = r
+	return r
+}
+
+func (f *fakeRoleService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Role {
+	r, ok := f.roles[ID]
+	if !ok || (!r.Global() && scope.Level != model.ScopeGlobal && r.GroupID != scope.GroupID) {
+		return &model.Role{}
+	}
+	return r
+}
+
+func (f *fakeRoleService) Create(ctx context.Context, r *model.Role) (*model.Role, error) {
+	for _, existing := range f.roles {
+		if existing.Name == r.Name && existing.GroupID == r.GroupID {
+			return r, role.ErrRoleExists
+		}
+	}
+	f.roles[r.ID] = r
+	return r, nil
+}
+
+func (f *fakeRoleService) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, r *model.Role) int64 {
+	existing, ok := f.roles[ID]
+	if !ok {
+		return 0
+	}
+	existing.Description, existing.Enable = r.Description, r.Enable
+	return 1
+}
+
+// fakeTenantGroupService knows only the test groups
+type fakeTenantGroupService struct {
+	tenant_group.TenantGroupServiceInterface
+}
+
+func (f *fakeTenantGroupService) GetByID(ctx context.Context, ID uuid.UUID) *model.TenantGroup {
+	if ID != testGroupID && ID != otherGroupID {
+		return &model.TenantGroup{}
+	}
+	return &model.TenantGroup{ID: ID, IsActive: true}
+}
+
+// signGroupToken signs a token of the test user in the tenant group testGroupID
+func signGroupToken(t *testing.T, conf *config.Config, role string) string {
+	claims := handlertest.Claims(conf, role)
+	claims.GroupID = testGroupID.String()
+	return handlertest.Sign(t, conf, claims)
+}
+
+func newTestRouter(conf *config.Config, service role.RoleServiceInterface) *gin.Engine {
+	return handlertest.NewRouter(conf, func(router *gin.Engine, guard *middleware.Guard) {
+		SetupRoutes(router, guard, NewRoleHandler(service, &fakeTenantGroupService{}))
+	})
+}
+
+func request(router *gin.Engine, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRoutesRejectMissingToken(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf, newFakeRoleService())
+
+	for _, route := range router.Routes() {
+		if w := request(router, "", route.Method, route.Path, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s sem token: esperado %d, mas obteve %d", route.Method, route.Path, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
+
+func TestRoutesRejectOtherRoles(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf, newFakeRoleService())
+
+	cases := []struct {
+		role         string
+		method, path string
+	}{
+		{model.RoleProfessor, http.MethodGet, "/api/v1/roles/"},
+		{model.RoleEstudante, http.MethodGet, "/api/v1/roles/00000000-0000-0000-0000-000000000003"},
+		{model.RoleInstituicao, http.MethodPost, "/api/v1/roles/"},
+		{model.RoleInstituicao, http.MethodPut, "/api/v1/roles/00000000-0000-0000-0000-000000000003"},
+		{model.RoleInstituicao, http.MethodDelete, "/api/v1/roles/00000000-0000-0000-0000-000000000003"},
+	}
+
+	for _, tc := range cases {
+		if w := request(router, signGroupToken(t, conf, tc.role), tc.method, tc.path, ""); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s com role %s: esperado %d, mas obteve %d", tc.method, tc.path, tc.role, http.StatusForbidden, w.Code)
+		}
+	}
+}
+
+func TestCreateRoleInCallerGroup(t *testing.T) {
+	conf := config.NewConfig()
+	service := newFakeRoleService()
+	router := newTestRouter(conf, service)
+	token := signGroupToken(t, conf, model.RoleGrupoEducacional)
+
+	w := request(router, token, http.MethodPost, "/api/v1/roles/", `{"name":"Coordenador","description":"Coordenação pedagógica"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), testGroupID.String()) {
+		t.Errorf("role criado fora do grupo do usuário: %s", w.Body.String())
+	}
+
+	if w := request(router, token, http.MethodPost, "/api/v1/roles/", `{"name":"Coordenador"}`); w.Code != http.StatusConflict {
+		t.Errorf("nome repetido: esperado %d, mas obteve %d", http.StatusConflict, w.Code)
+	}
+
+	if w := request(router, token, http.MethodPost, "/api/v1/roles/", `{"name":"Outro","group_id":"`+otherGroupID.String()+`"}`); w.Code != http.StatusForbidden {
+		t.Errorf("outro grupo: esperado %d, mas obteve %d", http.StatusForbidden, w.Code)
+	}
+
+	if w := request(router, token, http.MethodPost, "/api/v1/roles/", `{"name":"com espaço"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("nome inválido: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+
+	// Sem grupo o role é global, reservado ao Admin
+	admin := signGroupToken(t, conf, model.RoleAdmin)
+	if w := request(router, admin, http.MethodPost, "/api/v1/roles/", `{"name":"Monitor"}`); w.Code != http.StatusCreated {
+		t.Errorf("role global pelo Admin: esperado %d, mas obteve %d", http.StatusCreated, w.Code)
+	}
+}
+
+func TestUpdateGlobalRoleRequiresAdmin(t *testing.T) {
+	conf := config.NewConfig()
+	service := newFakeRoleService()
+	router := newTestRouter(conf, service)
+
+	global := service.add(uuid.Nil, model.RoleProfessor)
+	own := service.add(testGroupID, "Coordenador")
+	other := service.add(otherGroupID, "Tutor")
+
+	token := signGroupToken(t, conf, model.RoleGrupoEducacional)
+	body := `{"description":"alterado","enable":true}`
+
+	if w := request(router, token, http.MethodPut, "/api/v1/roles/"+global.ID.String(), body); w.Code != http.StatusForbidden {
+		t.Errorf("role global: esperado %d, mas obteve %d", http.StatusForbidden, w.Code)
+	}
+	if w := request(router, token, http.MethodPut, "/api/v1/roles/"+own.ID.String(), body); w.Code != http.StatusOK {
+		t.Errorf("role do grupo: esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+	if w := request(router, token, http.MethodPut, "/api/v1/roles/"+other.ID.String(), body); w.Code != http.StatusNotFound {
+		t.Errorf("role de outro grupo: esperado %d, mas obteve %d", http.StatusNotFound, w.Code)
+	}
+
+	admin := signGroupToken(t, conf, model.RoleAdmin)
+	if w := request(router, admin, http.MethodPut, "/api/v1/roles/"+global.ID.String(), body); w.Code != http.StatusOK {
+		t.Errorf("role global pelo Admin: esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+}
@@ -9,8 +9,10 @@ import (
 
 	"github.com/katana-stuidio/access-control/internal/config/logger"
 	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
 	"github.com/katana-stuidio/access-control/pkg/model"
 	"github.com/katana-stuidio/access-control/pkg/service/tenant"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
 )
 
 // @Summary Get all tenants
@@ -44,7 +46,9 @@ func getAllTenant(service tenant.TenantServiceInterface) http.Handler {
 			}
 		}
 
-		result, err := service.GetAll(r.Context(), limit, page)
+		scope := model.TenantScopeFromContext(r.Context())
+
+		result, err := service.GetAll(r.Context(), scope, limit, page)
 		if err != nil {
 			ErroHttpMsgToConvertingResponseTenantListToJson.Write(w)
 			return
@@ -82,7 +86,9 @@ func getTenant(service tenant.TenantServiceInterface) http.HandlerFunc {
 			return
 		}
 
-		tenant := service.GetByID(r.Context(), id)
+		scope := model.TenantScopeFromContext(r.Context())
+
+		tenant := service.GetByID(r.Context(), scope, id)
 		if tenant.ID == uuid.Nil {
 			ErroHttpMsgTenantNotFound.Write(w)
 			return
@@ -194,7 +200,7 @@ func createTenant(service tenant.TenantServiceInterface) http.HandlerFunc {
 // @Failure 404 {object} handler.HttpMsg
 // @Failure 500 {object} handler.HttpMsg
 // @Router /api/v1/tenant/{id} [patch]
-func updateTenant(service tenant.TenantServiceInterface) http.HandlerFunc {
+func updateTenant(service tenant.TenantServiceInterface, tokenService token.TokenServiceInterface) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		externalID := r.URL.Query().Get("id")
 		id, err := uuid.Parse(externalID)
@@ -230,12 +236,27 @@ func updateTenant(service tenant.TenantServiceInterface) http.HandlerFunc {
 			return
 		}
 
-		rowsAffected := service.Update(r.Context(), id, &request_to_update_tenant)
+		scope := model.TenantScopeFromContext(r.Context())
+
+		tenant := service.GetByID(r.Context(), scope, id)
+		if tenant.ID == uuid.Nil {
+			ErroHttpMsgTenantNotFound.Write(w)
+			return
+		}
+
+		rowsAffected := service.Update(r.Context(), scope, id, &request_to_update_tenant)
 		if rowsAffected == 0 {
 			ErroHttpMsgToUpdateTenant.Write(w)
 			return
 		}
 
+		// Deactivating a tenant ends the sessions of all its users
+		if !request_to_update_tenant.IsActive {
+			if err := jwt.RevokeAllTenantTokens(id.String(), tokenService); err != nil {
+				logger.Error("Failed to revoke tokens of inactive tenant: "+id.String(), err)
+			}
+		}
+
 		w.WriteHeader(http.StatusOK)
 	}
 }
@@ -251,7 +272,7 @@ func updateTenant(service tenant.TenantServiceInterface) http.HandlerFunc {
 // @Failure 404 {object} handler.HttpMsg
 // @Failure 500 {object} handler.HttpMsg
 // @Router /api/v1/tenant/{id} [delete]
-func deleteTenant(service tenant.TenantServiceInterface) http.HandlerFunc {
+func deleteTenant(service tenant.TenantServiceInterface, tokenService token.TokenServiceInterface) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		externalID := r.URL.Query().Get("id")
 		id, err := uuid.Parse(externalID)
@@ -265,12 +286,24 @@ func deleteTenant(service tenant.TenantServiceInterface) http.HandlerFunc {
 			return
 		}
 
-		rowsAffected := service.Delete(r.Context(), id)
+		scope := model.TenantScopeFromContext(r.Context())
+
+		tenant := service.GetByID(r.Context(), scope, id)
+		if tenant.ID == uuid.Nil {
+			ErroHttpMsgTenantNotFound.Write(w)
+			return
+		}
+
+		rowsAffected := service.Delete(r.Context(), scope, id)
 		if rowsAffected == 0 {
 			ErroHttpMsgToDeleteTenant.Write(w)
 			return
 		}
 
+		if err := jwt.RevokeAllTenantTokens(id.String(), tokenService); err != nil {
+			logger.Error("Failed to revoke tokens of deleted tenant: "+id.String(), err)
+		}
+
 		SuccessHttpMsgToDeleteTenant.Write(w)
 	}
 }
@@ -1,17 +1,24 @@
 package tenant
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
 	"github.com/katana-stuidio/access-control/pkg/service/tenant"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
 )
 
-func RegisterTenantAPIHandlers(r *gin.Engine, service tenant.TenantServiceInterface) {
+func RegisterTenantAPIHandlers(r *gin.Engine, guard *middleware.Guard, service tenant.TenantServiceInterface, tokenService token.TokenServiceInterface) {
 	tenantGroup := r.Group("/api/v1/Tenant")
 	{
-		tenantGroup.POST("/", gin.WrapH(createTenant(service)))
-		tenantGroup.GET("/:id", gin.WrapH(getTenant(service)))
-		tenantGroup.PATCH("/:id", gin.WrapH(updateTenant(service)))
-		tenantGroup.DELETE("/:id", gin.WrapH(deleteTenant(service)))
-		tenantGroup.GET("/", gin.WrapH(getAllTenant(service)))
+		guard.Register(tenantGroup, []middleware.Route{
+			{Method: http.MethodPost, Path: "/", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: gin.WrapH(createTenant(service))},
+			{Method: http.MethodGet, Path: "/:id", Access: middleware.Authenticated, Handler: gin.WrapH(getTenant(service))},
+			{Method: http.MethodPatch, Path: "/:id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: gin.WrapH(updateTenant(service, tokenService))},
+			{Method: http.MethodDelete, Path: "/:id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: gin.WrapH(deleteTenant(service, tokenService))},
+			{Method: http.MethodGet, Path: "/", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: gin.WrapH(getAllTenant(service))},
+		})
 	}
 }
@@ -0,0 +1,67 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/handler/handlertest"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+func newTestRouter(conf *config.Config) *gin.Engine {
+	return handlertest.NewRouter(conf, func(router *gin.Engine, guard *middleware.Guard) {
+		RegisterTenantAPIHandlers(router, guard, nil, nil)
+	})
+}
+
+func TestRoutesRejectMissingOrInvalidToken(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf)
+
+	otherConf := config.NewConfig()
+	otherConf.JWTSecretKey = "outra-chave"
+	forged := handlertest.SignToken(t, otherConf, model.RoleAdmin)
+
+	for _, route := range router.Routes() {
+		for _, header := range []string{"", "Bearer invalido", "Bearer " + forged} {
+			req := httptest.NewRequest(route.Method, route.Path, nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s com header %q: esperado %d, mas obteve %d", route.Method, route.Path, header, http.StatusUnauthorized, w.Code)
+			}
+		}
+	}
+}
+
+func TestAdminRoutesRejectOtherRoles(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf)
+	token := handlertest.SignToken(t, conf, model.RoleInstituicao)
+
+	restricted := []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/Tenant/"},
+		{http.MethodGet, "/api/v1/Tenant/"},
+		{http.MethodPatch, "/api/v1/Tenant/00000000-0000-0000-0000-000000000003"},
+		{http.MethodDelete, "/api/v1/Tenant/00000000-0000-0000-0000-000000000003"},
+	}
+
+	for _, route := range restricted {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s com role %s: esperado %d, mas obteve %d", route.method, route.path, model.RoleInstituicao, http.StatusForbidden, w.Code)
+		}
+	}
+}
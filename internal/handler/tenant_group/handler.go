@@ -140,6 +140,13 @@ func (h *TenantGroupHandler) GetByID(c *gin.Context) {
 		return
 	}
 
+	// Callers outside of a global scope only see their own tenant group
+	scope := model.TenantScopeFromContext(c.Request.Context())
+	if !scope.AllowsGroup(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant group not found"})
+		return
+	}
+
 	tenantGroup := h.tenantGroupService.GetByID(c.Request.Context(), id)
 	if tenantGroup.ID == uuid.Nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant group not found"})
@@ -1,18 +1,24 @@
 package tenant_group
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
 )
 
 // SetupRoutes configures the tenant group routes
-func SetupRoutes(router *gin.Engine, handler *TenantGroupHandler) {
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *TenantGroupHandler) {
 	// Tenant Group routes
 	tenantGroupRoutes := router.Group("/api/v1/tenant-groups")
 	{
-		tenantGroupRoutes.POST("/", handler.Create)
-		tenantGroupRoutes.GET("/", handler.GetAll)
-		tenantGroupRoutes.GET("/:id", handler.GetByID)
-		tenantGroupRoutes.PUT("/:id", handler.Update)
-		tenantGroupRoutes.DELETE("/:id", handler.Delete)
+		guard.Register(tenantGroupRoutes, []middleware.Route{
+			{Method: http.MethodPost, Path: "/", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: handler.Create},
+			{Method: http.MethodGet, Path: "/", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: handler.GetAll},
+			{Method: http.MethodGet, Path: "/:id", Access: middleware.Authenticated, Handler: handler.GetByID},
+			{Method: http.MethodPut, Path: "/:id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: handler.Update},
+			{Method: http.MethodDelete, Path: "/:id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: handler.Delete},
+		})
 	}
 }
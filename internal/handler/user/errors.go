@@ -95,6 +95,11 @@ var ErroHttpMsgCNPJNotFound handler.HttpMsg = handler.HttpMsg{
 }
 
 var ErroHttpMsgInvalidRole handler.HttpMsg = handler.HttpMsg{
-	Msg:  "Invalid role. Valid roles are: Professor, Estudante, Instituicao, Admin",
+	Msg:  "Invalid role. Roles must be enabled in the role catalog of the tenant group",
 	Code: http.StatusBadRequest,
 }
+
+var ErroHttpMsgRoleOutOfScope handler.HttpMsg = handler.HttpMsg{
+	Msg:  "Erro Role exceeds the permissions of the current user",
+	Code: http.StatusForbidden,
+}
@@ -2,16 +2,25 @@ package user
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/katana-stuidio/access-control/internal/config"
 	"github.com/katana-stuidio/access-control/internal/config/logger"
 	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/handler"
 	"github.com/katana-stuidio/access-control/pkg/jwt"
 	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	"github.com/katana-stuidio/access-control/pkg/service/password_reset"
+	service_role "github.com/katana-stuidio/access-control/pkg/service/role"
 	service_ten "github.com/katana-stuidio/access-control/pkg/service/tenant"
 	service_ten_group "github.com/katana-stuidio/access-control/pkg/service/tenant_group"
 	"github.com/katana-stuidio/access-control/pkg/service/token"
@@ -65,7 +74,9 @@ func getAllUser(service user.UserServiceInterface) gin.HandlerFunc {
 		limit := int64(10) // default limit
 		page := int64(1)   // default page
 
-		users, err := service.GetAll(c.Request.Context(), limit, page)
+		scope := model.TenantScopeFromContext(c.Request.Context())
+
+		users, err := service.GetAll(c.Request.Context(), scope, limit, page)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -93,7 +104,9 @@ func getUser(service user.UserServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		user := service.GetByID(c.Request.Context(), id)
+		scope := model.TenantScopeFromContext(c.Request.Context())
+
+		user := service.GetByID(c.Request.Context(), scope, id)
 		if user.ID == uuid.Nil {
 			ErroHttpMsgUserNotFound.Write(c.Writer)
 			return
@@ -104,7 +117,8 @@ func getUser(service user.UserServiceInterface) gin.HandlerFunc {
 }
 
 // @Summary Create a new user
-// @Description Create a new user with the provided details. Role must be one of: Professor, Estudante, Instituicao, Admin
+// @Description Create a new user with the provided details. Role is the main role and Roles the other roles of the user,
+// @Description all enabled in the role catalog of the tenant group (GET /api/v1/roles)
 // @Tags users
 // @Accept json
 // @Produce json
@@ -113,7 +127,7 @@ func getUser(service user.UserServiceInterface) gin.HandlerFunc {
 // @Failure 400 {object} handler.HttpMsg
 // @Failure 500 {object} handler.HttpMsg
 // @Router /api/v1/user/ [post]
-func createUser(service user.UserServiceInterface) gin.HandlerFunc {
+func createUser(service user.UserServiceInterface, roleService service_role.RoleServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var userDto dto.UserRequestDtoInput
 
@@ -130,6 +144,12 @@ func createUser(service user.UserServiceInterface) gin.HandlerFunc {
 			CNPJ:     userDto.CNPJ,
 			Email:    userDto.Email,
 			Role:     userDto.Role,
+			Roles:    userDto.Roles,
+		}
+
+		// Without a main role the first of the roles is the main role
+		if userModel.Role == "" && len(userModel.Roles) > 0 {
+			userModel.Role = userModel.Roles[0]
 		}
 
 		logger.Info("Received role from request: " + userDto.Role)
@@ -148,20 +168,16 @@ func createUser(service user.UserServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		if userDto.Role == "" {
+		if userModel.Role == "" {
 			ErroHttpMsgUserRoleIsRequired.Write(c.Writer)
 			return
 		}
 
-		validRoles := map[string]bool{
-			"Professor":   true,
-			"Estudante":   true,
-			"Instituicao": true,
-			"Admin":       true,
-		}
+		// Callers can only create users in tenants inside their own scope
+		scope := model.TenantScopeFromContext(c.Request.Context())
 
-		if !validRoles[userDto.Role] {
-			ErroHttpMsgInvalidRole.Write(c.Writer)
+		if model.ScopeLevelForRoles(userModel.AllRoles()...) > scope.Level {
+			ErroHttpMsgRoleOutOfScope.Write(c.Writer)
 			return
 		}
 
@@ -177,7 +193,7 @@ func createUser(service user.UserServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		tenant_id, err := service.GetByCNPJ(c.Request.Context(), userDto.CNPJ)
+		tenant_id, err := service.GetByCNPJ(c.Request.Context(), scope, userDto.CNPJ)
 		if err != nil {
 			ErroHttpMsgCNPJNotFound.Write(c.Writer)
 			return
@@ -194,6 +210,17 @@ func createUser(service user.UserServiceInterface) gin.HandlerFunc {
 			return
 		}
 
+		invalidRoles, err := roleService.InvalidRoles(c.Request.Context(), tenantUUID, userModel.AllRoles())
+		if err != nil {
+			ErroHttpMsgToInsertUser.Write(c.Writer)
+			return
+		}
+
+		if len(invalidRoles) > 0 {
+			ErroHttpMsgInvalidRole.Write(c.Writer)
+			return
+		}
+
 		usrCad, err := model.NewUser(&userModel)
 		if err != nil {
 			logger.Error("Invalid login request: ", err)
@@ -229,6 +256,15 @@ func createUser(service user.UserServiceInterface) gin.HandlerFunc {
 			return
 		}
 
+		if err := service.ValidatePassword(c.Request.Context(), usrCad, usrCad.Password); err != nil {
+			if rejected, ok := handler.PasswordRejected(err); ok {
+				c.JSON(http.StatusBadRequest, rejected)
+				return
+			}
+			ErroHttpMsgToInsertUser.Write(c.Writer)
+			return
+		}
+
 		result, err := service.Create(c.Request.Context(), usrCad)
 		if err != nil {
 			ErroHttpMsgToInsertUser.Write(c.Writer)
@@ -241,6 +277,7 @@ func createUser(service user.UserServiceInterface) gin.HandlerFunc {
 			Name:      result.Name,
 			Enable:    result.Enable,
 			Role:      result.Role,
+			Roles:     result.AllRoles(),
 			CreatedAt: result.CreatedAt,
 			UpdatedAt: result.UpdatedAt,
 		}
@@ -250,18 +287,19 @@ func createUser(service user.UserServiceInterface) gin.HandlerFunc {
 }
 
 // @Summary Update user
-// @Description Update an existing user's details
+// @Description Update an existing user's details. The password is optional; a new one must meet the password
+// @Description policy. A new password, role or tenant revokes the sessions of the user
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
 // @Param user body model.User true "User details"
 // @Success 200 {object} model.User
-// @Failure 400 {object} handler.HttpMsg
+// @Failure 400 {object} dto.PasswordRejectedResponse
 // @Failure 404 {object} handler.HttpMsg
 // @Failure 500 {object} handler.HttpMsg
 // @Router /api/v1/user/{id} [patch]
-func updateUser(service user.UserServiceInterface) gin.HandlerFunc {
+func updateUser(service user.UserServiceInterface, roleService service_role.RoleServiceInterface, tokenService token.TokenServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		externalID := c.Param("id")
 		id, err := uuid.Parse(externalID)
@@ -286,23 +324,78 @@ func updateUser(service user.UserServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		if strings.TrimSpace(requestToUpdate.Password) == "" {
-			ErroHttpMsgUserPasswordIsRequired.Write(c.Writer)
-			return
-		}
+		scope := model.TenantScopeFromContext(c.Request.Context())
 
-		user := service.GetByID(c.Request.Context(), id)
+		user := service.GetByID(c.Request.Context(), scope, id)
 		if user.ID == uuid.Nil {
 			ErroHttpMsgUserNotFound.Write(c.Writer)
 			return
 		}
 
-		rowsAffected := service.Update(c.Request.Context(), id, &requestToUpdate)
+		// The user itself must be inside the caller scope: an Instituicao cannot edit an Admin of its tenant
+		if model.ScopeLevelForRoles(user.AllRoles()...) > scope.Level {
+			ErroHttpMsgRoleOutOfScope.Write(c.Writer)
+			return
+		}
+
+		// The password is optional; a new one follows the policy of the user, as in changepassword
+		newPassword := requestToUpdate.Password
+		requestToUpdate.Password = ""
+		if newPassword != "" {
+			user.Username = requestToUpdate.Username
+			user.Email = requestToUpdate.Email
+			if err := service.ValidatePassword(c.Request.Context(), user, newPassword); err != nil {
+				if rejected, ok := handler.PasswordRejected(err); ok {
+					c.JSON(http.StatusBadRequest, rejected)
+					return
+				}
+				ErroHttpMsgToUpdateUser.Write(c.Writer)
+				return
+			}
+		}
+
+		if model.ScopeLevelForRole(requestToUpdate.Role) > scope.Level {
+			ErroHttpMsgRoleOutOfScope.Write(c.Writer)
+			return
+		}
+
+		if requestToUpdate.TenantID == uuid.Nil {
+			requestToUpdate.TenantID = user.TenantID
+		}
+
+		roleChanged := requestToUpdate.Role != user.Role || requestToUpdate.TenantID != user.TenantID
+		if roleChanged {
+			invalidRoles, err := roleService.InvalidRoles(c.Request.Context(), requestToUpdate.TenantID, []string{requestToUpdate.Role})
+			if err != nil {
+				ErroHttpMsgToUpdateUser.Write(c.Writer)
+				return
+			}
+
+			if len(invalidRoles) > 0 {
+				ErroHttpMsgInvalidRole.Write(c.Writer)
+				return
+			}
+		}
+
+		rowsAffected := service.Update(c.Request.Context(), scope, id, &requestToUpdate)
 		if rowsAffected == 0 {
 			ErroHttpMsgToUpdateUser.Write(c.Writer)
 			return
 		}
 
+		if newPassword != "" && service.UpdatePassword(c.Request.Context(), requestToUpdate.Username, newPassword) == 0 {
+			ErroHttpMsgToUpdateUser.Write(c.Writer)
+			return
+		}
+
+		// A disabled user, or one whose password was replaced, loses every open session. So does a
+		// user with a new role or tenant, the rotated tokens would keep the old ones as in setUserRoles
+		if !requestToUpdate.Enable || newPassword != "" || roleChanged {
+			if err := jwt.RevokeAllUserTokens(id.String(), tokenService); err != nil {
+				logger.Error("Failed to revoke tokens of updated user: "+id.String(), err)
+			}
+		}
+
 		c.JSON(http.StatusOK, requestToUpdate)
 	}
 }
@@ -318,7 +411,7 @@ func updateUser(service user.UserServiceInterface) gin.HandlerFunc {
 // @Failure 404 {object} handler.HttpMsg
 // @Failure 500 {object} handler.HttpMsg
 // @Router /api/v1/user/{id} [delete]
-func deleteUser(service user.UserServiceInterface) gin.HandlerFunc {
+func deleteUser(service user.UserServiceInterface, tokenService token.TokenServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		externalID := c.Param("id")
 		id, err := uuid.Parse(externalID)
@@ -327,24 +420,174 @@ func deleteUser(service user.UserServiceInterface) gin.HandlerFunc {
 			return
 		}
 
-		user := service.GetByID(c.Request.Context(), id)
+		scope := model.TenantScopeFromContext(c.Request.Context())
+
+		user := service.GetByID(c.Request.Context(), scope, id)
 		if user.ID == uuid.Nil {
 			ErroHttpMsgUserNotFound.Write(c.Writer)
 			return
 		}
 
-		rowsAffected := service.Delete(c.Request.Context(), id)
+		// As in updateUser, an Instituicao cannot delete an Admin of its tenant
+		if model.ScopeLevelForRoles(user.AllRoles()...) > scope.Level {
+			ErroHttpMsgRoleOutOfScope.Write(c.Writer)
+			return
+		}
+
+		rowsAffected := service.Delete(c.Request.Context(), scope, id)
 		if rowsAffected == 0 {
 			ErroHttpMsgToDeleteUser.Write(c.Writer)
 			return
 		}
 
+		if err := jwt.RevokeAllUserTokens(id.String(), tokenService); err != nil {
+			logger.Error("Failed to revoke tokens of deleted user: "+id.String(), err)
+		}
+
 		SuccessHttpMsgToDeleteUser.Write(c.Writer)
 	}
 }
 
+// @Summary Get user roles
+// @Description Roles of the user in a tenant, the user tenant when tenant_id is empty
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Param tenant_id query string false "Tenant ID"
+// @Success 200 {object} dto.UserRolesResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/user/{id}/roles [get]
+func getUserRoles(service user.UserServiceInterface, tenantService service_ten.TenantServiceInterface, roleService service_role.RoleServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		usr, tenantID, ok := userRolesTarget(c, service, tenantService, c.Query("tenant_id"))
+		if !ok {
+			return
+		}
+
+		roles, err := roleService.GetUserRoles(c.Request.Context(), usr.ID, tenantID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.UserRolesResponse{UserID: usr.ID, TenantID: tenantID, Roles: roles})
+	}
+}
+
+// @Summary Set user roles
+// @Description Replace the roles of the user in a tenant, the user tenant when tenant_id is empty.
+// @Description The roles must be enabled in the catalog of the tenant group. When the main role of the user
+// @Description is not among them the first role becomes the main role. The user logs in again to get the new roles.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param roles body dto.UserRolesRequest true "Roles"
+// @Success 200 {object} dto.UserRolesResponse
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 403 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/user/{id}/roles [put]
+func setUserRoles(service user.UserServiceInterface, tenantService service_ten.TenantServiceInterface, roleService service_role.RoleServiceInterface, tokenService token.TokenServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request dto.UserRolesRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			ErroHttpMsgToParseRequestUserToJson.Write(c.Writer)
+			return
+		}
+
+		tenantParam := ""
+		if request.TenantID != uuid.Nil {
+			tenantParam = request.TenantID.String()
+		}
+
+		usr, tenantID, ok := userRolesTarget(c, service, tenantService, tenantParam)
+		if !ok {
+			return
+		}
+
+		roles := (&model.User{Roles: request.Roles}).AllRoles()
+
+		// The user keeps at least the main role in its own tenant
+		if tenantID == usr.TenantID && len(roles) == 0 {
+			ErroHttpMsgUserRoleIsRequired.Write(c.Writer)
+			return
+		}
+
+		// Both the roles the user has and the new ones are inside the caller scope
+		scope := model.TenantScopeFromContext(c.Request.Context())
+		if model.ScopeLevelForRoles(append(usr.AllRoles(), roles...)...) > scope.Level {
+			ErroHttpMsgRoleOutOfScope.Write(c.Writer)
+			return
+		}
+
+		invalidRoles, err := roleService.InvalidRoles(c.Request.Context(), tenantID, roles)
+		if err != nil {
+			ErroHttpMsgToUpdateUser.Write(c.Writer)
+			return
+		}
+
+		if len(invalidRoles) > 0 {
+			ErroHttpMsgInvalidRole.Write(c.Writer)
+			return
+		}
+
+		if err := roleService.SetUserRoles(c.Request.Context(), usr.ID, tenantID, roles); err != nil {
+			ErroHttpMsgToUpdateUser.Write(c.Writer)
+			return
+		}
+
+		// The roles are in the tokens, the rotated tokens would keep the removed roles
+		if err := jwt.RevokeAllUserTokens(usr.ID.String(), tokenService); err != nil {
+			logger.Error("Failed to revoke tokens of user with new roles: "+usr.ID.String(), err)
+		}
+
+		c.JSON(http.StatusOK, dto.UserRolesResponse{UserID: usr.ID, TenantID: tenantID, Roles: roles})
+	}
+}
+
+// userRolesTarget reads the user of the path and the tenant of its roles, both inside the
+// caller scope, and writes the error response when they are not valid
+func userRolesTarget(c *gin.Context, service user.UserServiceInterface, tenantService service_ten.TenantServiceInterface, tenantParam string) (*model.User, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		ErroHttpMsgUserIdIsRequired.Write(c.Writer)
+		return nil, uuid.Nil, false
+	}
+
+	scope := model.TenantScopeFromContext(c.Request.Context())
+
+	usr := service.GetByID(c.Request.Context(), scope, id)
+	if usr.ID == uuid.Nil {
+		ErroHttpMsgUserNotFound.Write(c.Writer)
+		return nil, uuid.Nil, false
+	}
+
+	if tenantParam == "" {
+		return usr, usr.TenantID, true
+	}
+
+	tenantID, err := uuid.Parse(tenantParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant_id"})
+		return nil, uuid.Nil, false
+	}
+
+	tenant := tenantService.GetByID(c.Request.Context(), scope, tenantID)
+	if tenant == nil || tenant.ID == uuid.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return nil, uuid.Nil, false
+	}
+
+	return usr, tenantID, true
+}
+
 // @Summary Get JWT token
-// @Description Authenticate user and get JWT token
+// @Description Authenticate user and get JWT token. When the user has MFA, or the tenant policy requires it
+// @Description for one of the user roles, the response is a dto.MFAChallengeResponse and the tokens are
+// @Description issued by POST /api/v1/user/mfa/verify. An expired password answers 403 with a dto.PasswordExpiredResponse,
+// @Description whose token sets a new password with POST /api/v1/user/password/reset
 // @Tags users
 // @Accept json
 // @Produce json
@@ -352,9 +595,12 @@ func deleteUser(service user.UserServiceInterface) gin.HandlerFunc {
 // @Success 200 {object} jwt.TokenDetails
 // @Failure 400 {object} handler.HttpMsg
 // @Failure 401 {object} handler.HttpMsg
+// @Failure 403 {object} dto.PasswordExpiredResponse
+// @Failure 423 {object} handler.HttpMsg
+// @Failure 429 {object} handler.HttpMsg
 // @Failure 500 {object} handler.HttpMsg
 // @Router /api/v1/user/getjwt [post]
-func getJWT(service user.UserServiceInterface, tenantService service_ten.TenantServiceInterface, tenantGroupService service_ten_group.TenantGroupServiceInterface, conf *config.Config, tokenService token.TokenServiceInterface) gin.HandlerFunc {
+func getJWT(service user.UserServiceInterface, tenantService service_ten.TenantServiceInterface, tenantGroupService service_ten_group.TenantGroupServiceInterface, mfaService mfa.MFAServiceInterface, challenges mfa.ChallengeServiceInterface, lockoutService lockout.LockoutServiceInterface, resets password_reset.PasswordResetServiceInterface, conf *config.Config, tokenService token.TokenServiceInterface, permissions jwt.PermissionResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var loginRequest dto.LoginRequest
 
@@ -364,25 +610,106 @@ func getJWT(service user.UserServiceInterface, tenantService service_ten.TenantS
 			return
 		}
 
+		if _, err := jwt.Audience(conf, loginRequest.ClientID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown client"})
+			return
+		}
+
+		// Locked users and throttled clients are refused before the password is checked
+		if lockoutService != nil {
+			if retryAfter, err := lockoutService.Check(c.Request.Context(), loginRequest.Username, c.ClientIP()); err != nil {
+				loginLimited(c, retryAfter, err)
+				return
+			}
+		}
+
 		user, err := service.Authenticate(loginRequest.Username, loginRequest.Password)
 		if err != nil {
 			logger.Error("Authentication failed: ", err)
+			if lockoutService != nil {
+				if retryAfter, err := lockoutService.Fail(c.Request.Context(), loginRequest.Username, c.ClientIP()); err != nil {
+					loginLimited(c, retryAfter, err)
+					return
+				}
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
 		}
+		if lockoutService != nil {
+			lockoutService.Succeed(c.Request.Context(), loginRequest.Username)
+		}
 
 		// Fetch tenant information
-		tenant := tenantService.GetByID(c.Request.Context(), user.TenantID)
+		tenant := tenantService.GetByID(c.Request.Context(), model.GlobalScope(), user.TenantID)
 		if tenant.ID == uuid.Nil {
 			logger.Error("Tenant not found for user: "+user.ID.String(), nil)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Tenant information not found"})
 			return
 		}
 
+		// Disabled users and inactive tenants had their sessions revoked and cannot open new ones
+		if !user.Enable || !tenant.IsActive {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User or tenant is disabled"})
+			return
+		}
+
+		// A password older than the maximum age of the policy opens no session. As the password
+		// was just checked, the response carries a short-lived token of /password/reset, so users
+		// without email can set a new password too.
+		expired, err := service.PasswordExpired(c.Request.Context(), user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check password age"})
+			return
+		}
+		if expired {
+			changeToken, err := resets.IssueChange(c.Request.Context(), user.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start password change"})
+				return
+			}
+			c.JSON(http.StatusForbidden, dto.PasswordExpiredResponse{
+				Error:               "Password expired",
+				PasswordExpired:     true,
+				PasswordChangeToken: changeToken,
+				ExpiresIn:           int(password_reset.ChangeLifetime.Seconds()),
+			})
+			return
+		}
+
+		// With a second factor the password only opens a challenge, completed by /mfa/verify
+		if mfaService != nil {
+			status, err := mfaService.Status(c.Request.Context(), user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check MFA"})
+				return
+			}
+
+			if status.ChallengeRequired() {
+				mfaToken, err := challenges.Issue(c.Request.Context(), &mfa.Challenge{
+					UserID:             user.ID.String(),
+					ClientID:           loginRequest.ClientID,
+					EnrollmentRequired: !status.Enabled,
+				})
+				if err != nil {
+					logger.Error("Failed to issue MFA challenge: ", err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start MFA"})
+					return
+				}
+
+				c.JSON(http.StatusOK, dto.MFAChallengeResponse{
+					MFARequired:        true,
+					EnrollmentRequired: !status.Enabled,
+					MFAToken:           mfaToken,
+					ExpiresIn:          int(mfa.ChallengeLifetime.Seconds()),
+				})
+				return
+			}
+		}
+
 		// Fetch tenant group information (now mandatory)
 		tenantGroup := tenantGroupService.GetByID(c.Request.Context(), tenant.GroupID)
 
-		tokenDetails, err := jwt.GenerateToken(user, tenant, tenantGroup, conf, tokenService)
+		tokenDetails, err := jwt.GenerateToken(c.Request.Context(), user, tenant, tenantGroup, loginRequest.ClientID, conf, tokenService, permissions)
 		if err != nil {
 			logger.Error("Failed to generate JWT: ", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
@@ -393,6 +720,60 @@ func getJWT(service user.UserServiceInterface, tenantService service_ten.TenantS
 	}
 }
 
+// loginLimited answers a login refused by the brute-force protection: 423 while the user
+// is locked and 429 while the delay of the user or the limit of the IP is running
+func loginLimited(c *gin.Context, retryAfter time.Duration, err error) {
+	seconds := int(retryAfter.Seconds())
+	c.Header("Retry-After", strconv.Itoa(seconds))
+
+	if errors.Is(err, lockout.ErrLocked) {
+		c.JSON(http.StatusLocked, gin.H{"error": "Account temporarily locked after too many failed logins", "retry_after": seconds})
+		return
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many login attempts", "retry_after": seconds})
+}
+
+// @Summary Unlock user login
+// @Description Remove the temporary lock of a user after too many wrong passwords, and its failed attempts
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} handler.HttpMsg
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 404 {object} handler.HttpMsg
+// @Router /api/v1/user/{id}/lockout [delete]
+func unlockUser(service user.UserServiceInterface, lockoutService lockout.LockoutServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+			return
+		}
+
+		scope := model.TenantScopeFromContext(c.Request.Context())
+
+		usr := service.GetByID(c.Request.Context(), scope, id)
+		if usr == nil || usr.ID == uuid.Nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		// As in updateUser, the locked user must be inside the caller scope
+		if model.ScopeLevelForRoles(usr.AllRoles()...) > scope.Level {
+			ErroHttpMsgRoleOutOfScope.Write(c.Writer)
+			return
+		}
+
+		if !lockoutService.Unlock(c.Request.Context(), usr.Username) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User is not locked"})
+			return
+		}
+
+		logger.Info("Login of user " + usr.ID.String() + " unlocked by user " + c.GetString("user_id"))
+		c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+	}
+}
+
 // @Summary Validate JWT token
 // @Description Validate a JWT token
 // @Tags users
@@ -428,7 +809,7 @@ func validateToken(conf *config.Config) gin.HandlerFunc {
 }
 
 // @Summary Refresh JWT token
-// @Description Refresh an expired JWT token
+// @Description Exchange a refresh token for a new access and refresh token pair. The refresh token is single-use; reusing it revokes every token of the session
 // @Tags users
 // @Accept json
 // @Produce json
@@ -436,7 +817,7 @@ func validateToken(conf *config.Config) gin.HandlerFunc {
 // @Success 200 {object} jwt.TokenDetails
 // @Failure 401 {object} handler.HttpMsg
 // @Router /api/v1/user/refreshjwt [post]
-func refreshToken(conf *config.Config, tokenService token.TokenServiceInterface) gin.HandlerFunc {
+func refreshToken(conf *config.Config, tokenService token.TokenServiceInterface, permissions jwt.PermissionResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -450,7 +831,7 @@ func refreshToken(conf *config.Config, tokenService token.TokenServiceInterface)
 			return
 		}
 
-		tokenDetails, ok := jwt.RefreshJWT(refreshToken, conf, tokenService)
+		tokenDetails, ok := jwt.RefreshJWT(c.Request.Context(), refreshToken, conf, tokenService, permissions)
 		if !ok {
 			logger.Error("Token refresh failed: ", nil)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
@@ -462,7 +843,7 @@ func refreshToken(conf *config.Config, tokenService token.TokenServiceInterface)
 }
 
 // @Summary Logout user
-// @Description Logout user by revoking refresh token
+// @Description Logout user by revoking the refresh token and the access token
 // @Tags users
 // @Accept json
 // @Produce json
@@ -472,22 +853,23 @@ func refreshToken(conf *config.Config, tokenService token.TokenServiceInterface)
 // @Router /api/v1/user/logout [post]
 func logout(tokenService token.TokenServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
+		// token_id and token_expires_at are set by AuthMiddleware from the validated token
+		tokenID := c.GetString("token_id")
+		if tokenID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
 
-		tokenID, err := jwt.ExtractTokenID(authHeader)
+		err := jwt.RevokeToken(tokenID, tokenService)
 		if err != nil {
-			logger.Error("Failed to extract token ID: ", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			logger.Error("Failed to revoke token: ", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
 			return
 		}
 
-		err = jwt.RevokeToken(tokenID, tokenService)
+		err = jwt.RevokeAccessToken(tokenID, c.GetTime("token_expires_at"), tokenService)
 		if err != nil {
-			logger.Error("Failed to revoke token: ", err)
+			logger.Error("Failed to revoke access token: ", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
 			return
 		}
@@ -499,6 +881,46 @@ func logout(tokenService token.TokenServiceInterface) gin.HandlerFunc {
 	}
 }
 
+// @Summary Revoke token
+// @Description Revoke a session by token ID, the access token stops working immediately
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.RevokeTokenRequest true "Token ID"
+// @Success 200 {object} handler.HttpMsg
+// @Failure 400 {object} handler.HttpMsg
+// @Failure 500 {object} handler.HttpMsg
+// @Router /api/v1/user/revoketoken [post]
+func revokeToken(tokenService token.TokenServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request dto.RevokeTokenRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+
+		// The access token lifetime is unknown here, so the denylist keeps the maximum lifetime
+		if err := jwt.RevokeAccessToken(request.TokenID, time.Time{}, tokenService); err != nil {
+			logger.Error("Failed to revoke access token: ", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+
+		if err := jwt.RevokeToken(request.TokenID, tokenService); err != nil {
+			logger.Error("Failed to revoke refresh token: ", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+
+		logger.Info(fmt.Sprintf("Token %s revoked by user %s", request.TokenID, c.GetString("user_id")))
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Token revoked",
+			"code":    200,
+		})
+	}
+}
+
 // @Summary Change user password
 // @Description Change a user's password
 // @Tags users
@@ -506,7 +928,7 @@ func logout(tokenService token.TokenServiceInterface) gin.HandlerFunc {
 // @Produce json
 // @Param request body dto.UserChangePasswordOutPut true "Password change details"
 // @Success 200 {object} handler.HttpMsg
-// @Failure 400 {object} handler.HttpMsg
+// @Failure 400 {object} dto.PasswordRejectedResponse
 // @Failure 500 {object} handler.HttpMsg
 // @Router /api/v1/user/changepassword [patch]
 func changePassword(service user.UserServiceInterface) http.HandlerFunc {
@@ -532,23 +954,10 @@ func changePassword(service user.UserServiceInterface) http.HandlerFunc {
 		err = service.ChangePassword(r.Context(), userChange.Username, userChange.OldPassowrd, userChange.NewPassowrd)
 		if err != nil {
 			errorMsg := err.Error()
-			if errorMsg == "new password length is too short" ||
-				errorMsg == "new password must contain at least one uppercase letter" ||
-				errorMsg == "new password must contain at least one number" ||
-				errorMsg == "new password must contain at least one symbol" {
-
-				requirements := `Password must meet the following requirements:
-1. At least 8 characters long
-2. At least one uppercase letter
-3. At least one number
-4. At least one special symbol (!@#$%^&*()\-_+=)`
-
+			if rejected, ok := handler.PasswordRejected(err); ok {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(HttpMsg{
-					Message: requirements,
-					Code:    http.StatusBadRequest,
-				})
+				json.NewEncoder(w).Encode(rejected)
 				return
 			}
 			http.Error(w, errorMsg, http.StatusInternalServerError)
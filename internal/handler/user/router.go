@@ -1,26 +1,41 @@
 package user
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/mfa"
+	"github.com/katana-stuidio/access-control/pkg/service/password_reset"
+	service_role "github.com/katana-stuidio/access-control/pkg/service/role"
 	service_ten "github.com/katana-stuidio/access-control/pkg/service/tenant"
 	service_ten_group "github.com/katana-stuidio/access-control/pkg/service/tenant_group"
 	"github.com/katana-stuidio/access-control/pkg/service/token"
 	"github.com/katana-stuidio/access-control/pkg/service/user"
 )
 
-func RegisterUserAPIHandlers(r *gin.Engine, service user.UserServiceInterface, tenantService service_ten.TenantServiceInterface, tenantGroupService service_ten_group.TenantGroupServiceInterface, conf *config.Config, tokenService token.TokenServiceInterface) {
+func RegisterUserAPIHandlers(r *gin.Engine, guard *middleware.Guard, service user.UserServiceInterface, tenantService service_ten.TenantServiceInterface, tenantGroupService service_ten_group.TenantGroupServiceInterface, roleService service_role.RoleServiceInterface, mfaService mfa.MFAServiceInterface, challenges mfa.ChallengeServiceInterface, lockoutService lockout.LockoutServiceInterface, resets password_reset.PasswordResetServiceInterface, conf *config.Config, tokenService token.TokenServiceInterface, permissions jwt.PermissionResolver) {
 	userGroup := r.Group("/api/v1/user")
 	{
-		userGroup.POST("/", createUser(service))
-		userGroup.GET("/:id", getUser(service))
-		userGroup.POST("/getjwt", getJWT(service, tenantService, tenantGroupService, conf, tokenService))
-		userGroup.POST("/refreshjwt", refreshToken(conf, tokenService))
-		userGroup.POST("/validatejwt", validateToken(conf))
-		userGroup.POST("/logout", logout(tokenService))
-		userGroup.PATCH("/:id", updateUser(service))
-		userGroup.DELETE("/:id", deleteUser(service))
-		userGroup.GET("/", getAllUser(service))
-		userGroup.PATCH("/changepassword", gin.WrapH(changePassword(service)))
+		guard.Register(userGroup, []middleware.Route{
+			{Method: http.MethodPost, Path: "/getjwt", Access: middleware.Public, Handler: getJWT(service, tenantService, tenantGroupService, mfaService, challenges, lockoutService, resets, conf, tokenService, permissions)},
+			{Method: http.MethodPost, Path: "/refreshjwt", Access: middleware.Public, Handler: refreshToken(conf, tokenService, permissions)},
+			{Method: http.MethodPost, Path: "/validatejwt", Access: middleware.Authenticated, Handler: validateToken(conf)},
+			{Method: http.MethodPost, Path: "/logout", Access: middleware.Authenticated, Handler: logout(tokenService)},
+			{Method: http.MethodPost, Path: "/revoketoken", Access: middleware.Restricted, Roles: []string{model.RoleAdmin}, Handler: revokeToken(tokenService)},
+			{Method: http.MethodPatch, Path: "/changepassword", Access: middleware.Authenticated, Handler: gin.WrapH(changePassword(service))},
+			{Method: http.MethodGet, Path: "/:id", Access: middleware.Authenticated, Handler: getUser(service)},
+			{Method: http.MethodPost, Path: "/", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: createUser(service, roleService)},
+			{Method: http.MethodPatch, Path: "/:id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: updateUser(service, roleService, tokenService)},
+			{Method: http.MethodDelete, Path: "/:id", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: deleteUser(service, tokenService)},
+			{Method: http.MethodDelete, Path: "/:id/lockout", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: unlockUser(service, lockoutService)},
+			{Method: http.MethodGet, Path: "/:id/roles", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: getUserRoles(service, tenantService, roleService)},
+			{Method: http.MethodPut, Path: "/:id/roles", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: setUserRoles(service, tenantService, roleService, tokenService)},
+			{Method: http.MethodGet, Path: "/", Access: middleware.Restricted, Roles: []string{model.RoleAdmin, model.RoleInstituicao}, Handler: getAllUser(service)},
+		})
 	}
 }
@@ -0,0 +1,588 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/handler/handlertest"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/lockout"
+	"github.com/katana-stuidio/access-control/pkg/service/password_policy"
+	"github.com/katana-stuidio/access-control/pkg/service/password_reset"
+	service_role "github.com/katana-stuidio/access-control/pkg/service/role"
+	service_ten "github.com/katana-stuidio/access-control/pkg/service/tenant"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+	"github.com/katana-stuidio/access-control/pkg/service/user"
+)
+
+var publicRoutes = map[string]bool{
+	"POST /api/v1/user/getjwt":     true,
+	"POST /api/v1/user/refreshjwt": true,
+}
+
+func newTestRouter(conf *config.Config) *gin.Engine {
+	return handlertest.NewRouter(conf, func(router *gin.Engine, guard *middleware.Guard) {
+		RegisterUserAPIHandlers(router, guard, nil, nil, nil, nil, nil, nil, nil, nil, conf, nil, nil)
+	})
+}
+
+func TestProtectedRoutesRejectMissingToken(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf)
+
+	for _, route := range router.Routes() {
+		if publicRoutes[route.Method+" "+route.Path] {
+			continue
+		}
+
+		req := httptest.NewRequest(route.Method, route.Path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s sem token: esperado %d, mas obteve %d", route.Method, route.Path, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
+
+func TestProtectedRoutesRejectInvalidToken(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf)
+
+	otherConf := config.NewConfig()
+	otherConf.JWTSecretKey = "outra-chave"
+	forged := handlertest.SignToken(t, otherConf, model.RoleAdmin)
+
+	for _, route := range router.Routes() {
+		if publicRoutes[route.Method+" "+route.Path] {
+			continue
+		}
+
+		for _, header := range []string{"Bearer invalido", "Bearer " + forged, forged} {
+			req := httptest.NewRequest(route.Method, route.Path, nil)
+			req.Header.Set("Authorization", header)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s com token inválido: esperado %d, mas obteve %d", route.Method, route.Path, http.StatusUnauthorized, w.Code)
+			}
+		}
+	}
+}
+
+func TestRestrictedRoutesRejectRole(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf)
+	token := handlertest.SignToken(t, conf, model.RoleEstudante)
+
+	restricted := []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/user/"},
+		{http.MethodGet, "/api/v1/user/"},
+		{http.MethodPatch, "/api/v1/user/00000000-0000-0000-0000-000000000003"},
+		{http.MethodDelete, "/api/v1/user/00000000-0000-0000-0000-000000000003"},
+		{http.MethodGet, "/api/v1/user/00000000-0000-0000-0000-000000000003/roles"},
+		{http.MethodPut, "/api/v1/user/00000000-0000-0000-0000-000000000003/roles"},
+		{http.MethodDelete, "/api/v1/user/00000000-0000-0000-0000-000000000003/lockout"},
+	}
+
+	for _, route := range restricted {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s com role %s: esperado %d, mas obteve %d", route.method, route.path, model.RoleEstudante, http.StatusForbidden, w.Code)
+		}
+	}
+}
+
+func TestPublicRoutesDoNotRequireToken(t *testing.T) {
+	conf := config.NewConfig()
+	router := newTestRouter(conf)
+
+	registered := map[string]bool{}
+	for _, info := range router.Routes() {
+		registered[info.Method+" "+info.Path] = true
+	}
+
+	for route := range publicRoutes {
+		if !registered[route] {
+			t.Errorf("rota pública %s não registrada", route)
+		}
+	}
+
+	// getjwt chega ao handler, que rejeita o corpo vazio com 400
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user/getjwt", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("getjwt sem token: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// fakeUserService rejects every password
+type fakeUserService struct {
+	user.UserServiceInterface
+}
+
+var lockedUser = &model.User{ID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), Username: "maria"}
+
+func (f *fakeUserService) Authenticate(username, password string) (*model.User, error) {
+	return nil, errors.New("invalid credentials")
+}
+
+// ChangePassword checks the new password against the default policy
+func (f *fakeUserService) ChangePassword(ctx context.Context, userName, currentPassword, newPassword string) error {
+	if violations := model.DefaultPasswordPolicy(uuid.Nil).Check(lockedUser, newPassword); len(violations) > 0 {
+		return &password_policy.PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (f *fakeUserService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	if ID != lockedUser.ID {
+		return &model.User{}
+	}
+	return lockedUser
+}
+
+// fakeLockoutService locks a username after maxAttempts wrong passwords and throttles the IP of throttled
+type fakeLockoutService struct {
+	maxAttempts int
+	failures    map[string]int
+	throttled   string
+}
+
+func (f *fakeLockoutService) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	if f.failures[username] >= f.maxAttempts {
+		return 15 * time.Minute, lockout.ErrLocked
+	}
+	if ip == f.throttled {
+		return 4 * time.Second, lockout.ErrThrottled
+	}
+	return 0, nil
+}
+
+func (f *fakeLockoutService) Fail(ctx context.Context, username, ip string) (time.Duration, error) {
+	f.failures[username]++
+	if f.failures[username] >= f.maxAttempts {
+		return 15 * time.Minute, lockout.ErrLocked
+	}
+	return time.Second, nil
+}
+
+func (f *fakeLockoutService) Succeed(ctx context.Context, username string) {
+	delete(f.failures, username)
+}
+
+func (f *fakeLockoutService) Unlock(ctx context.Context, username string) bool {
+	locked := f.failures[username] >= f.maxAttempts
+	delete(f.failures, username)
+	return locked
+}
+
+func newLockoutRouter(conf *config.Config, lockouts *fakeLockoutService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetTrustedProxies(conf.TrustedProxies)
+	RegisterUserAPIHandlers(router, middleware.NewGuard(conf, nil, nil), &fakeUserService{}, nil, nil, nil, nil, nil, lockouts, nil, conf, nil, nil)
+	return router
+}
+
+func postLogin(router *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user/getjwt", strings.NewReader(`{"username":"maria","password":"errada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetJWTLocksAfterFailedLogins(t *testing.T) {
+	conf := config.NewConfig()
+	lockouts := &fakeLockoutService{maxAttempts: 3, failures: map[string]int{}}
+	router := newLockoutRouter(conf, lockouts)
+
+	for i := 1; i < lockouts.maxAttempts; i++ {
+		if w := postLogin(router, "10.0.0.1:1234"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("tentativa %d: esperado %d, mas obteve %d", i, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	w := postLogin(router, "10.0.0.1:1234")
+	if w.Code != http.StatusLocked || w.Header().Get("Retry-After") != "900" || !strings.Contains(w.Body.String(), `"retry_after":900`) {
+		t.Fatalf("última tentativa: esperado %d com Retry-After 900, mas obteve %d %q %s", http.StatusLocked, w.Code, w.Header().Get("Retry-After"), w.Body.String())
+	}
+
+	// O bloqueio é conferido antes da senha
+	if w := postLogin(router, "10.0.0.2:1234"); w.Code != http.StatusLocked {
+		t.Errorf("bloqueado: esperado %d, mas obteve %d", http.StatusLocked, w.Code)
+	}
+
+	// Desbloqueio pelo administrador
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/user/"+lockedUser.ID.String()+"/lockout", nil)
+	req.Header.Set("Authorization", "Bearer "+handlertest.SignToken(t, conf, model.RoleAdmin))
+	unlock := httptest.NewRecorder()
+	router.ServeHTTP(unlock, req)
+	if unlock.Code != http.StatusOK {
+		t.Fatalf("desbloqueio: esperado %d, mas obteve %d: %s", http.StatusOK, unlock.Code, unlock.Body.String())
+	}
+
+	if w := postLogin(router, "10.0.0.1:1234"); w.Code != http.StatusUnauthorized {
+		t.Errorf("após o desbloqueio: esperado %d, mas obteve %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestGetJWTThrottled(t *testing.T) {
+	conf := config.NewConfig()
+	router := newLockoutRouter(conf, &fakeLockoutService{maxAttempts: 3, failures: map[string]int{}, throttled: "10.0.0.9"})
+
+	w := postLogin(router, "10.0.0.9:1234")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "4" {
+		t.Errorf("esperado %d com Retry-After 4, mas obteve %d %q", http.StatusTooManyRequests, w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func postLoginForwarded(router *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user/getjwt", strings.NewReader(`{"username":"maria","password":"errada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestGetJWTThrottlesByTrustedClientIP(t *testing.T) {
+	conf := config.NewConfig()
+	lockouts := &fakeLockoutService{maxAttempts: 3, failures: map[string]int{}, throttled: "10.0.0.9"}
+
+	// Sem proxies confiáveis um X-Forwarded-For forjado não troca o IP limitado
+	router := newLockoutRouter(conf, lockouts)
+	if code := postLoginForwarded(router, "10.0.0.9:1234", "203.0.113.7"); code != http.StatusTooManyRequests {
+		t.Errorf("cabeçalho forjado: esperado %d, mas obteve %d", http.StatusTooManyRequests, code)
+	}
+
+	// Atrás do proxy configurado vale o IP que ele encaminha
+	conf.TrustedProxies = []string{"10.0.0.1"}
+	router = newLockoutRouter(conf, lockouts)
+	if code := postLoginForwarded(router, "10.0.0.1:1234", "10.0.0.9"); code != http.StatusTooManyRequests {
+		t.Errorf("proxy confiável: esperado %d, mas obteve %d", http.StatusTooManyRequests, code)
+	}
+	if code := postLoginForwarded(router, "10.0.0.1:1234", "203.0.113.7"); code != http.StatusUnauthorized {
+		t.Errorf("outro cliente: esperado %d, mas obteve %d", http.StatusUnauthorized, code)
+	}
+}
+
+func TestChangePasswordListsEveryViolation(t *testing.T) {
+	conf := config.NewConfig()
+	router := newLockoutRouter(conf, &fakeLockoutService{maxAttempts: 3, failures: map[string]int{}})
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/user/changepassword", strings.NewReader(`{"username":"maria","old_password":"Antiga#1","new_password":"fraca"}`))
+	req.Header.Set("Authorization", "Bearer "+handlertest.SignToken(t, conf, model.RoleEstudante))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	var response dto.PasswordRejectedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("resposta inválida: %s", w.Body.String())
+	}
+
+	rules := map[string]bool{}
+	for _, violation := range response.Violations {
+		rules[violation.Rule] = true
+	}
+	for _, rule := range []string{model.PasswordRuleMinLength, model.PasswordRuleUppercase, model.PasswordRuleNumber, model.PasswordRuleSymbol} {
+		if !rules[rule] {
+			t.Errorf("regra %s ausente em %s", rule, w.Body.String())
+		}
+	}
+}
+
+// expiredUserService accepts the password of maria, older than the maximum age of the policy
+type expiredUserService struct {
+	user.UserServiceInterface
+}
+
+func (f *expiredUserService) Authenticate(username, password string) (*model.User, error) {
+	return &model.User{ID: lockedUser.ID, TenantID: testTenant.ID, Username: username, Enable: true}, nil
+}
+
+func (f *expiredUserService) PasswordExpired(ctx context.Context, usr *model.User) (bool, error) {
+	return true, nil
+}
+
+var testTenant = &model.Tenant{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), IsActive: true}
+
+type fakeTenantService struct {
+	service_ten.TenantServiceInterface
+}
+
+func (f *fakeTenantService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Tenant {
+	return testTenant
+}
+
+type fakeResetService struct {
+	password_reset.PasswordResetServiceInterface
+	issuedTo uuid.UUID
+}
+
+func (f *fakeResetService) IssueChange(ctx context.Context, userID uuid.UUID) (string, error) {
+	f.issuedTo = userID
+	return "troca-123", nil
+}
+
+func TestGetJWTExpiredPasswordReturnsChangeToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.NewConfig()
+	resets := &fakeResetService{}
+
+	router := gin.New()
+	RegisterUserAPIHandlers(router, middleware.NewGuard(conf, nil, nil), &expiredUserService{}, &fakeTenantService{}, nil, nil, nil, nil, nil, resets, conf, nil, nil)
+
+	// Sem email o usuário não teria como trocar a senha expirada
+	w := postLogin(router, "10.0.0.1:1234")
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+
+	var response dto.PasswordExpiredResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("resposta inválida: %s", w.Body.String())
+	}
+	if !response.PasswordExpired || response.PasswordChangeToken != "troca-123" || response.ExpiresIn != int(password_reset.ChangeLifetime.Seconds()) {
+		t.Errorf("resposta inesperada: %+v", response)
+	}
+	if resets.issuedTo != lockedUser.ID {
+		t.Errorf("token emitido para %s, esperado %s", resets.issuedTo, lockedUser.ID)
+	}
+	if strings.Contains(w.Body.String(), "access_token") {
+		t.Error("senha expirada não pode abrir sessão")
+	}
+}
+
+// updateUserService records the updates of lockedUser
+type updateUserService struct {
+	user.UserServiceInterface
+	updated   *model.User
+	passwords map[string]string
+}
+
+func (f *updateUserService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	usr := *lockedUser
+	usr.Role = model.RoleEstudante
+	return &usr
+}
+
+func (f *updateUserService) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, usr *model.User) int64 {
+	f.updated = usr
+	return 1
+}
+
+func (f *updateUserService) ValidatePassword(ctx context.Context, usr *model.User, password string) error {
+	if violations := model.DefaultPasswordPolicy(uuid.Nil).Check(usr, password); len(violations) > 0 {
+		return &password_policy.PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (f *updateUserService) UpdatePassword(ctx context.Context, userName, newPassword string) int64 {
+	f.passwords[userName] = newPassword
+	return 1
+}
+
+type fakeTokenService struct {
+	token.TokenServiceInterface
+	revoked []string
+}
+
+func (f *fakeTokenService) DeleteAllUserTokens(ctx context.Context, userID string) error {
+	f.revoked = append(f.revoked, userID)
+	return nil
+}
+
+func TestUpdateUserSetsPasswordThroughPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.NewConfig()
+	service := &updateUserService{passwords: map[string]string{}}
+	sessions := &fakeTokenService{}
+
+	router := gin.New()
+	RegisterUserAPIHandlers(router, middleware.NewGuard(conf, nil, nil), service, nil, nil, nil, nil, nil, nil, nil, conf, sessions, nil)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/user/"+lockedUser.ID.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+handlertest.SignToken(t, conf, model.RoleAdmin))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Sem senha os dados são atualizados e a senha fica como está
+	w := patch(`{"username":"maria","name":"Maria","role":"Estudante","enable":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("sem senha: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if len(service.passwords) != 0 || len(sessions.revoked) != 0 {
+		t.Errorf("senha alterada sem ter sido enviada: %v %v", service.passwords, sessions.revoked)
+	}
+
+	// Senha fraca: a política é aplicada antes de qualquer alteração
+	service.updated = nil
+	if w := patch(`{"username":"maria","name":"Maria","role":"Estudante","enable":true,"password":"fraca"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("senha fraca: esperado %d, mas obteve %d", http.StatusBadRequest, w.Code)
+	}
+	if service.updated != nil {
+		t.Error("usuário atualizado com senha rejeitada")
+	}
+
+	w = patch(`{"username":"maria","name":"Maria","role":"Estudante","enable":true,"password":"Senha#Forte2024"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("senha válida: esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if service.passwords["maria"] != "Senha#Forte2024" {
+		t.Error("a senha deveria ser gravada por UpdatePassword, com hash e histórico")
+	}
+	if service.updated.Password != "" || strings.Contains(w.Body.String(), "Senha#Forte2024") {
+		t.Errorf("a senha não pode ir para Update nem voltar na resposta: %s", w.Body.String())
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != lockedUser.ID.String() {
+		t.Errorf("sessões não revogadas após a troca da senha: %v", sessions.revoked)
+	}
+}
+
+// adminTargetService holds an Admin of the caller tenant, which no change may reach
+type adminTargetService struct {
+	user.UserServiceInterface
+	target  *model.User
+	changed bool
+}
+
+func (f *adminTargetService) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	usr := *f.target
+	return &usr
+}
+
+func (f *adminTargetService) ValidatePassword(ctx context.Context, usr *model.User, password string) error {
+	return nil
+}
+
+func (f *adminTargetService) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, usr *model.User) int64 {
+	f.changed = true
+	return 1
+}
+
+func (f *adminTargetService) UpdatePassword(ctx context.Context, userName, newPassword string) int64 {
+	f.changed = true
+	return 1
+}
+
+func (f *adminTargetService) Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) int64 {
+	f.changed = true
+	return 1
+}
+
+// catalogRoleService accepts every role and records the roles set
+type catalogRoleService struct {
+	service_role.RoleServiceInterface
+	set bool
+}
+
+func (f *catalogRoleService) InvalidRoles(ctx context.Context, tenantID uuid.UUID, roles []string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (f *catalogRoleService) SetUserRoles(ctx context.Context, userID, tenantID uuid.UUID, roles []string) error {
+	f.set = true
+	return nil
+}
+
+func TestInstituicaoCannotChangeAdminOfItsTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.NewConfig()
+	tenantID := uuid.MustParse(handlertest.TenantID)
+
+	targets := map[string]*model.User{
+		"papel principal": {ID: lockedUser.ID, TenantID: tenantID, Username: "admin", Role: model.RoleAdmin, Enable: true},
+		"papel adicional": {ID: lockedUser.ID, TenantID: tenantID, Username: "admin", Role: model.RoleProfessor, Roles: []string{model.RoleAdmin}, Enable: true},
+	}
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPatch, "/api/v1/user/" + lockedUser.ID.String(), `{"username":"admin","name":"Admin","role":"Professor","enable":false,"password":"Senha#Forte2024"}`},
+		{http.MethodDelete, "/api/v1/user/" + lockedUser.ID.String(), ""},
+		{http.MethodPut, "/api/v1/user/" + lockedUser.ID.String() + "/roles", `{"roles":["Estudante"]}`},
+		{http.MethodDelete, "/api/v1/user/" + lockedUser.ID.String() + "/lockout", ""},
+	}
+
+	for name, target := range targets {
+		for _, r := range requests {
+			t.Run(name+" "+r.method+" "+r.path, func(t *testing.T) {
+				service := &adminTargetService{target: target}
+				roles := &catalogRoleService{}
+				lockouts := &fakeLockoutService{maxAttempts: 1, failures: map[string]int{"admin": 1}}
+
+				router := gin.New()
+				RegisterUserAPIHandlers(router, middleware.NewGuard(conf, nil, nil), service, &fakeTenantService{}, nil, roles, nil, nil, lockouts, nil, conf, &fakeTokenService{}, nil)
+
+				req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+handlertest.SignToken(t, conf, model.RoleInstituicao))
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if w.Code != ErroHttpMsgRoleOutOfScope.Code {
+					t.Errorf("esperado %d, mas obteve %d: %s", ErroHttpMsgRoleOutOfScope.Code, w.Code, w.Body.String())
+				}
+				if service.changed || roles.set || lockouts.failures["admin"] != 1 {
+					t.Error("Admin alterado por uma Instituicao")
+				}
+			})
+		}
+	}
+}
+
+func TestUpdateUserRoleRevokesSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.NewConfig()
+	service := &updateUserService{passwords: map[string]string{}}
+	sessions := &fakeTokenService{}
+
+	router := gin.New()
+	RegisterUserAPIHandlers(router, middleware.NewGuard(conf, nil, nil), service, nil, nil, &catalogRoleService{}, nil, nil, nil, nil, conf, sessions, nil)
+
+	// O refresh token copia o papel do token apresentado, a sessão antiga manteria o papel anterior
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/user/"+lockedUser.ID.String(), strings.NewReader(`{"username":"maria","name":"Maria","role":"Professor","enable":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+handlertest.SignToken(t, conf, model.RoleAdmin))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != lockedUser.ID.String() {
+		t.Errorf("sessões não revogadas após a troca do papel: %v", sessions.revoked)
+	}
+}
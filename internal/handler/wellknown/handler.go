@@ -0,0 +1,113 @@
+package wellknown
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// jwksCacheControl lets clients cache the key set for less than the time a rotated key
+// stays published before signing, so the cache never misses the key of a new token
+const jwksCacheControl = "public, max-age=30"
+
+// WellKnownHandler publishes the signing keys and the OpenID Connect discovery document
+type WellKnownHandler struct {
+	conf *config.Config
+}
+
+func NewWellKnownHandler(conf *config.Config) *WellKnownHandler {
+	return &WellKnownHandler{
+		conf: conf,
+	}
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys that validate the access tokens, selected by the kid header of the token
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} jwt.JWKS
+// @Failure 404 {object} handler.HttpMsg
+// @Router /.well-known/jwks.json [get]
+func (h *WellKnownHandler) JWKS(c *gin.Context) {
+	ring := jwt.CurrentKeyRing()
+	if ring == nil {
+		// HS256 tokens are validated with the shared secret, there is no public key to publish
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tokens are not signed with asymmetric keys"})
+		return
+	}
+
+	set, err := ring.JWKS(time.Now())
+	if err != nil {
+		logger.Error("Error encoding JWT signing keys", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not publish keys"})
+		return
+	}
+
+	c.Header("Cache-Control", jwksCacheControl)
+	c.JSON(http.StatusOK, set)
+}
+
+// @Summary OpenID Connect discovery
+// @Description Issuer, key set, endpoints and algorithms of the tokens
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} dto.OpenIDConfiguration
+// @Router /.well-known/openid-configuration [get]
+func (h *WellKnownHandler) OpenIDConfiguration(c *gin.Context) {
+	baseURL := publicBaseURL(h.conf)
+
+	// The issuer must match the iss claim of the tokens
+	issuer := h.conf.JWTIssuer
+	if issuer == "" {
+		issuer = baseURL
+	}
+
+	c.Header("Cache-Control", jwksCacheControl)
+	c.JSON(http.StatusOK, dto.OpenIDConfiguration{
+		Issuer:                           issuer,
+		JWKSURI:                          baseURL + "/.well-known/jwks.json",
+		AuthorizationEndpoint:            baseURL + "/oauth/authorize",
+		TokenEndpoint:                    baseURL + "/oauth/token",
+		TokenEndpointAuthMethods:         []string{"client_secret_basic", "client_secret_post", "none"},
+		IntrospectionEndpoint:            baseURL + "/oauth/introspect",
+		RevocationEndpoint:               baseURL + "/oauth/revoke",
+		UserInfoEndpoint:                 baseURL + "/userinfo",
+		RefreshEndpoint:                  baseURL + "/api/v1/user/refreshjwt",
+		GrantTypesSupported:              []string{"authorization_code", "refresh_token", "client_credentials"},
+		ScopesSupported:                  scopesSupported(h.conf),
+		ResponseTypesSupported:           []string{"code"},
+		CodeChallengeMethodsSupported:    []string{"S256"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{h.conf.JWTSigningAlg},
+		ClaimsSupported: []string{
+			"user_id", "username", "tenant_id", "tenant_name", "group_id", "group_name",
+			"role", "roles", "first_access", "token_id", "client_id", "scope", "permissions", "iss", "sub", "aud", "exp", "nbf", "iat", "jti",
+			"name", "preferred_username", "email", "nonce", "auth_time", "azp",
+		},
+	})
+}
+
+// publicBaseURL is the configured public URL of the service, or the issuer when only that
+// is set. The request Host and X-Forwarded-Proto are not used: the document is cacheable
+// and a forged header would publish the endpoints of another host.
+func publicBaseURL(conf *config.Config) string {
+	if conf.PublicURL != "" {
+		return strings.TrimSuffix(conf.PublicURL, "/")
+	}
+	return strings.TrimSuffix(conf.JWTIssuer, "/")
+}
+
+// scopesSupported lists the OpenID Connect scopes only when ID tokens can be issued
+func scopesSupported(conf *config.Config) []string {
+	if !jwt.IDTokensSupported(conf) {
+		return []string{}
+	}
+	return []string{model.ScopeOpenID, model.ScopeProfile, model.ScopeEmail}
+}
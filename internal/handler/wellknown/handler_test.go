@@ -0,0 +1,116 @@
+package wellknown
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/dto"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+)
+
+func newTestRouter(conf *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, middleware.NewGuard(conf, nil, nil), NewWellKnownHandler(conf))
+	return router
+}
+
+func TestJWKS(t *testing.T) {
+	conf := &config.Config{JWTSigningAlg: jwt.AlgRS256}
+	router := newTestRouter(conf)
+
+	rsaKey, _ := jwt.GenerateSigningKey(jwt.AlgRS256, time.Now())
+	ecKey, _ := jwt.GenerateSigningKey(jwt.AlgES256, time.Now())
+	edKey, _ := jwt.GenerateSigningKey(jwt.AlgEdDSA, time.Now())
+	retired, _ := jwt.GenerateSigningKey(jwt.AlgRS256, time.Now().Add(-time.Hour))
+	retired.NotAfter = time.Now().Add(-time.Minute)
+
+	jwt.SetKeyRing(jwt.NewKeyRing([]jwt.SigningKey{rsaKey, ecKey, edKey, retired}))
+	defer jwt.SetKeyRing(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	var set jwt.JWKS
+	json.Unmarshal(w.Body.Bytes(), &set)
+	if len(set.Keys) != 3 {
+		t.Fatalf("esperadas 3 chaves, mas obteve %d", len(set.Keys))
+	}
+
+	expected := map[string]string{rsaKey.ID: "RSA", ecKey.ID: "EC", edKey.ID: "OKP"}
+	for _, key := range set.Keys {
+		if expected[key.Kid] != key.Kty || key.Use != "sig" {
+			t.Errorf("chave inesperada %+v", key)
+		}
+	}
+
+	// O módulo publicado reconstrói a chave pública RSA
+	for _, key := range set.Keys {
+		if key.Kid != rsaKey.ID {
+			continue
+		}
+		n, _ := base64.RawURLEncoding.DecodeString(key.N)
+		if new(big.Int).SetBytes(n).Cmp(rsaKey.PublicKey.(*rsa.PublicKey).N) != 0 || key.E != "AQAB" {
+			t.Errorf("chave RSA publicada não corresponde: %+v", key)
+		}
+	}
+}
+
+func TestJWKSWithSharedSecret(t *testing.T) {
+	router := newTestRouter(&config.Config{JWTSigningAlg: jwt.AlgHS256})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func getOpenIDConfiguration(t *testing.T, conf *config.Config) dto.OpenIDConfiguration {
+	t.Helper()
+	router := newTestRouter(conf)
+
+	// Host e X-Forwarded-Proto forjados não alteram o documento, que pode ficar em cache
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+	req.Host = "evil.example"
+	req.Header.Set("X-Forwarded-Proto", "http")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("esperado %d, mas obteve %d", http.StatusOK, w.Code)
+	}
+
+	var document dto.OpenIDConfiguration
+	json.Unmarshal(w.Body.Bytes(), &document)
+	return document
+}
+
+func TestOpenIDConfiguration(t *testing.T) {
+	document := getOpenIDConfiguration(t, &config.Config{JWTSigningAlg: jwt.AlgES256, JWTIssuer: "https://auth.katana.com", PublicURL: "https://api.katana.com/"})
+	if document.Issuer != "https://auth.katana.com" || document.JWKSURI != "https://api.katana.com/.well-known/jwks.json" ||
+		document.TokenEndpoint != "https://api.katana.com/oauth/token" {
+		t.Errorf("documento inesperado %+v", document)
+	}
+	if len(document.IDTokenSigningAlgValuesSupported) != 1 || document.IDTokenSigningAlgValuesSupported[0] != jwt.AlgES256 {
+		t.Errorf("algoritmos inesperados %v", document.IDTokenSigningAlgValuesSupported)
+	}
+
+	// Sem URL pública os endpoints partem do issuer
+	document = getOpenIDConfiguration(t, &config.Config{JWTSigningAlg: jwt.AlgES256, JWTIssuer: "https://auth.katana.com"})
+	if document.JWKSURI != "https://auth.katana.com/.well-known/jwks.json" {
+		t.Errorf("documento inesperado %+v", document)
+	}
+}
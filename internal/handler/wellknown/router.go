@@ -0,0 +1,19 @@
+package wellknown
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/middleware"
+)
+
+// SetupRoutes publishes the keys and the discovery document used to validate tokens offline
+func SetupRoutes(router *gin.Engine, guard *middleware.Guard, handler *WellKnownHandler) {
+	wellKnownRoutes := router.Group("/.well-known")
+	{
+		guard.Register(wellKnownRoutes, []middleware.Route{
+			{Method: http.MethodGet, Path: "/jwks.json", Access: middleware.Public, Handler: handler.JWKS},
+			{Method: http.MethodGet, Path: "/openid-configuration", Access: middleware.Public, Handler: handler.OpenIDConfiguration},
+		})
+	}
+}
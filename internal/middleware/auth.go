@@ -5,13 +5,17 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/katana-stuidio/access-control/internal/config"
 	"github.com/katana-stuidio/access-control/internal/config/logger"
 	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
 )
 
-// AuthMiddleware validates JWT tokens and extracts user information
-func AuthMiddleware(conf *config.Config) gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens and extracts user information.
+// Tokens in the revocation denylist are rejected; a nil tokenService skips that check.
+func AuthMiddleware(conf *config.Config, tokenService token.TokenServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -42,18 +46,56 @@ func AuthMiddleware(conf *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// Check if the token was revoked (logout, admin revocation, disabled user)
+		if tokenService != nil {
+			revoked, err := tokenService.IsAccessTokenRevoked(c.Request.Context(), claims.TokenID)
+			if err != nil {
+				logger.Error("Token revocation check failed: ", err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not validate token"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
 		// Set user information in context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("tenant_id", claims.TenantID)
+		c.Set("group_id", claims.GroupID)
 		c.Set("role", claims.Role)
+		c.Set("roles", claims.UserRoles())
 		c.Set("token_id", claims.TokenID)
+		c.Set("client_id", claims.ClientID)
+		c.Set("scope", claims.Scope)
+		c.Set("permissions", claims.Permissions)
+		if claims.ExpiresAt != nil {
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
 }
 
-// RoleMiddleware checks if the user has the required role
+// UserMiddleware rejects the tokens issued to an OAuth client for itself (client credentials
+// grant): the routes of a user act on the user of the token. It must run after AuthMiddleware.
+func UserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_id") == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "A user token is required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RoleMiddleware checks if any of the user roles is one of the required roles
 func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("role")
@@ -73,7 +115,7 @@ func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 		// Check if user has any of the required roles
 		hasRole := false
 		for _, requiredRole := range requiredRoles {
-			if role == requiredRole {
+			if contains(userRoles(c, role), requiredRole) {
 				hasRole = true
 				break
 			}
@@ -89,20 +131,39 @@ func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	}
 }
 
-// TenantMiddleware ensures the user can only access their own tenant's data
+// TenantMiddleware builds the tenant scope of the request from the token claims.
+// Admin sees every tenant, group level roles see the tenants of their group and
+// everyone else only their own tenant. The scope is stored in the request context
+// so handlers can hand it to the services.
 func TenantMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tenantID, exists := c.Get("tenant_id")
-		if !exists {
+		tenantID, err := uuid.Parse(c.GetString("tenant_id"))
+		if err != nil || tenantID == uuid.Nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Tenant ID not found"})
 			c.Abort()
 			return
 		}
 
-		// For now, we'll just ensure the tenant ID is set
-		// In a more complex implementation, you might want to validate
-		// that the user is accessing data from their own tenant
-		c.Set("current_tenant_id", tenantID)
+		// Tokens issued before tenant groups existed have no group
+		groupID, _ := uuid.Parse(c.GetString("group_id"))
+
+		scope := model.TenantScope{
+			Level:    model.ScopeLevelForRoles(userRoles(c, c.GetString("role"))...),
+			TenantID: tenantID,
+			GroupID:  groupID,
+		}
+
+		c.Set("current_tenant_id", tenantID.String())
+		c.Set("tenant_scope", scope)
+		c.Request = c.Request.WithContext(model.ContextWithTenantScope(c.Request.Context(), scope))
 		c.Next()
 	}
 }
+
+// userRoles returns the roles set by AuthMiddleware, or only the main role when there are none
+func userRoles(c *gin.Context, role string) []string {
+	if roles := c.GetStringSlice("roles"); len(roles) > 0 {
+		return roles
+	}
+	return []string{role}
+}
@@ -0,0 +1,301 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/pkg/jwt"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+)
+
+func runTenantMiddleware(t *testing.T, role, tenantID, groupID string) (model.TenantScope, int) {
+	gin.SetMode(gin.TestMode)
+
+	var scope model.TenantScope
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Set("role", role)
+		c.Set("tenant_id", tenantID)
+		c.Set("group_id", groupID)
+	}, TenantMiddleware(), func(c *gin.Context) {
+		scope = model.TenantScopeFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	return scope, w.Code
+}
+
+func TestTenantMiddlewareScopeByRole(t *testing.T) {
+	tenantID := uuid.New()
+	groupID := uuid.New()
+
+	cases := []struct {
+		role  string
+		level model.ScopeLevel
+	}{
+		{model.RoleEstudante, model.ScopeTenant},
+		{model.RoleProfessor, model.ScopeTenant},
+		{model.RoleInstituicao, model.ScopeTenant},
+		{model.RoleGrupoEducacional, model.ScopeGroup},
+		{model.RoleAdmin, model.ScopeGlobal},
+	}
+
+	for _, tc := range cases {
+		scope, code := runTenantMiddleware(t, tc.role, tenantID.String(), groupID.String())
+		if code != http.StatusOK {
+			t.Fatalf("role %s: esperado %d, mas obteve %d", tc.role, http.StatusOK, code)
+		}
+
+		if scope.Level != tc.level || scope.TenantID != tenantID || scope.GroupID != groupID {
+			t.Errorf("role %s: escopo inesperado %+v", tc.role, scope)
+		}
+	}
+}
+
+func TestTenantScopeIsolation(t *testing.T) {
+	tenantID := uuid.New()
+	groupID := uuid.New()
+
+	tenantScope, _ := runTenantMiddleware(t, model.RoleProfessor, tenantID.String(), groupID.String())
+	if !tenantScope.AllowsTenant(tenantID, groupID) {
+		t.Error("escopo de tenant deveria permitir o próprio tenant")
+	}
+	if tenantScope.AllowsTenant(uuid.New(), groupID) {
+		t.Error("escopo de tenant não deveria permitir outro tenant do mesmo grupo")
+	}
+
+	all, _, filterGroup := tenantScope.Filter()
+	if all || filterGroup != uuid.Nil {
+		t.Error("filtro de escopo de tenant não deveria incluir o grupo")
+	}
+
+	groupScope, _ := runTenantMiddleware(t, model.RoleGrupoEducacional, tenantID.String(), groupID.String())
+	if !groupScope.AllowsTenant(uuid.New(), groupID) {
+		t.Error("escopo de grupo deveria permitir outro tenant do mesmo grupo")
+	}
+	if groupScope.AllowsTenant(uuid.New(), uuid.New()) {
+		t.Error("escopo de grupo não deveria permitir tenant de outro grupo")
+	}
+}
+
+func TestTenantMiddlewareRejectsMissingTenant(t *testing.T) {
+	_, code := runTenantMiddleware(t, model.RoleAdmin, "", "")
+	if code != http.StatusUnauthorized {
+		t.Errorf("esperado %d, mas obteve %d", http.StatusUnauthorized, code)
+	}
+
+	if scope := model.TenantScopeFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); scope.AllowsTenant(uuid.Nil, uuid.Nil) {
+		t.Error("contexto sem escopo não deveria permitir nenhum tenant")
+	}
+}
+
+// fakeTokenService implements token.TokenServiceInterface with only the denylist
+type fakeTokenService struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (f *fakeTokenService) SaveRefreshToken(ctx context.Context, tokenID, familyID, userID, username, tenantID, role string, issuedAt, exp time.Time) error {
+	return nil
+}
+
+func (f *fakeTokenService) GetRefreshToken(ctx context.Context, tokenID string) (*token.RefreshTokenData, error) {
+	return nil, errors.New("not found")
+}
+
+func (f *fakeTokenService) ConsumeRefreshToken(ctx context.Context, tokenID string) (*token.RefreshTokenData, error) {
+	return nil, token.ErrRefreshTokenNotFound
+}
+
+func (f *fakeTokenService) RevokeTokenFamily(ctx context.Context, familyID string) error { return nil }
+
+func (f *fakeTokenService) DeleteRefreshToken(ctx context.Context, tokenID string) error { return nil }
+
+func (f *fakeTokenService) DeleteAllUserTokens(ctx context.Context, userID string) error { return nil }
+
+func (f *fakeTokenService) DeleteAllTenantTokens(ctx context.Context, tenantID string) error {
+	return nil
+}
+
+func (f *fakeTokenService) IsTokenValid(ctx context.Context, tokenID string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeTokenService) RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	f.revoked[tokenID] = true
+	return nil
+}
+
+func (f *fakeTokenService) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return f.revoked[tokenID], f.err
+}
+
+func TestAuthMiddlewareRejectsRevokedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.NewConfig()
+	tokens := &fakeTokenService{revoked: map[string]bool{}}
+
+	router := gin.New()
+	router.GET("/", AuthMiddleware(conf, tokens), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	claims := &jwt.Claims{
+		UserID:   uuid.NewString(),
+		TenantID: uuid.NewString(),
+		Role:     model.RoleProfessor,
+		TokenID:  "session-1",
+		RegisteredClaims: jwtlib.RegisteredClaims{
+			ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(time.Minute)),
+			Issuer:    conf.JWTIssuer,
+			Audience:  jwtlib.ClaimStrings{conf.JWTAudience},
+		},
+	}
+	signed, err := jwtlib.NewWithClaims(jwtlib.SigningMethodHS256, claims).SignedString([]byte(conf.JWTSecretKey))
+	if err != nil {
+		t.Fatalf("Erro ao assinar token de teste: %v", err)
+	}
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("token válido: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+
+	tokens.RevokeAccessToken(context.Background(), "session-1", time.Time{})
+	if code := request(); code != http.StatusUnauthorized {
+		t.Errorf("token revogado: esperado %d, mas obteve %d", http.StatusUnauthorized, code)
+	}
+
+	tokens.err = errors.New("redis offline")
+	if code := request(); code != http.StatusServiceUnavailable {
+		t.Errorf("denylist indisponível: esperado %d, mas obteve %d", http.StatusServiceUnavailable, code)
+	}
+}
+
+func runRoleMiddleware(role string, roles []string, required ...string) int {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Set("role", role)
+		if roles != nil {
+			c.Set("roles", roles)
+		}
+	}, RoleMiddleware(required...), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Code
+}
+
+func TestRoleMiddlewareMatchesAnyRole(t *testing.T) {
+	roles := []string{model.RoleProfessor, model.RoleInstituicao}
+
+	if code := runRoleMiddleware(model.RoleProfessor, roles, model.RoleAdmin, model.RoleInstituicao); code != http.StatusOK {
+		t.Errorf("role secundário: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+	if code := runRoleMiddleware(model.RoleProfessor, roles, model.RoleAdmin); code != http.StatusForbidden {
+		t.Errorf("nenhum role exigido: esperado %d, mas obteve %d", http.StatusForbidden, code)
+	}
+
+	// Tokens emitidos antes da claim roles usam apenas o role principal
+	if code := runRoleMiddleware(model.RoleAdmin, nil, model.RoleAdmin); code != http.StatusOK {
+		t.Errorf("token sem roles: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+}
+
+func TestTenantMiddlewareWidestRoleScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var scope model.TenantScope
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Set("role", model.RoleProfessor)
+		c.Set("roles", []string{model.RoleProfessor, model.RoleGrupoEducacional})
+		c.Set("tenant_id", uuid.NewString())
+		c.Set("group_id", uuid.NewString())
+	}, TenantMiddleware(), func(c *gin.Context) {
+		scope = model.TenantScopeFromContext(c.Request.Context())
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if scope.Level != model.ScopeGroup {
+		t.Errorf("esperado o escopo do grupo, mas obteve %v", scope.Level)
+	}
+}
+
+func TestGuardRejectsClientTokensOnUserRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := config.NewConfig()
+	guard := NewGuard(conf, nil, nil)
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	guard.Register(router.Group(""), []Route{
+		{Method: http.MethodGet, Path: "/me", Access: Authenticated, Handler: ok},
+		{Method: http.MethodGet, Path: "/admin", Access: Restricted, Roles: []string{model.RoleAdmin}, Handler: ok},
+		{Method: http.MethodGet, Path: "/check", Access: Service, Scopes: []string{model.ScopeAuthz}, Handler: ok},
+	})
+
+	client := &model.OAuthClient{ClientID: "quiz-service", Scopes: []string{model.ScopeAuthz}}
+	tokens, err := jwt.GenerateClientToken(client, &model.Tenant{ID: uuid.New()}, client.Scopes, conf)
+	if err != nil {
+		t.Fatalf("Erro ao emitir token de cliente: %v", err)
+	}
+
+	request := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", tokens.AccessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// O token de client credentials só alcança as rotas Service
+	for _, path := range []string{"/me", "/admin"} {
+		if code := request(path); code != http.StatusForbidden {
+			t.Errorf("%s: esperado %d, mas obteve %d", path, http.StatusForbidden, code)
+		}
+	}
+	if code := request("/check"); code != http.StatusOK {
+		t.Errorf("/check: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+}
+
+func TestGuardRefusesRouteWithoutAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	guard := NewGuard(config.NewConfig(), nil, nil)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("rota sem Access registrada como pública")
+		}
+	}()
+
+	router := gin.New()
+	guard.Register(router.Group(""), []Route{
+		{Method: http.MethodGet, Path: "/esquecida", Handler: func(c *gin.Context) { c.Status(http.StatusOK) }},
+	})
+}
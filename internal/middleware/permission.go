@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+)
+
+// PermissionMiddleware checks the relation (ex: can_create_quiz) between the
+// authenticated user and its tenant. It must run after AuthMiddleware.
+func PermissionMiddleware(permissionService permission.PermissionServiceInterface, relation string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if permissionService == nil {
+			logger.Info("Permission service not configured, denying relation: " + relation)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission service unavailable"})
+			c.Abort()
+			return
+		}
+
+		userID := c.GetString("user_id")
+		tenantID := c.GetString("tenant_id")
+		if userID == "" || tenantID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User information not found"})
+			c.Abort()
+			return
+		}
+
+		allowed, err := permissionService.CheckPermission(c.Request.Context(), userID, tenantID, relation)
+		if err != nil {
+			logger.Error("Permission check failed for relation "+relation, err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Permission check failed"})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
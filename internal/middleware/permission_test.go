@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+)
+
+// fakePermissionService implements permission.PermissionServiceInterface in memory
+type fakePermissionService struct {
+	relations map[string]bool
+	err       error
+}
+
+func newFakePermissionService() *fakePermissionService {
+	return &fakePermissionService{relations: map[string]bool{}}
+}
+
+func (f *fakePermissionService) key(userID, tenantID, relation string) string {
+	return "user:" + userID + "#" + relation + "@tenant:" + tenantID
+}
+
+func (f *fakePermissionService) AddRelation(ctx context.Context, userID, tenantID, relation string) error {
+	f.relations[f.key(userID, tenantID, relation)] = true
+	return nil
+}
+
+func (f *fakePermissionService) RemoveRelation(ctx context.Context, userID, tenantID, relation string) error {
+	delete(f.relations, f.key(userID, tenantID, relation))
+	return nil
+}
+
+func (f *fakePermissionService) CheckPermission(ctx context.Context, userID, tenantID, relation string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	return f.relations[f.key(userID, tenantID, relation)], nil
+}
+
+func (f *fakePermissionService) Write(ctx context.Context, writes, deletes []permission.Tuple) error {
+	return f.err
+}
+
+func (f *fakePermissionService) Check(ctx context.Context, req permission.CheckRequest) (*permission.CheckResult, error) {
+	if req.Object.Type != permission.TypeTenant || req.User.Type != permission.TypeUser {
+		return &permission.CheckResult{}, f.err
+	}
+	allowed, err := f.CheckPermission(ctx, req.User.ID, req.Object.ID, req.Relation)
+	return &permission.CheckResult{Allowed: allowed, AuthorizationModelID: "fake-model"}, err
+}
+
+func (f *fakePermissionService) ListObjects(ctx context.Context, req permission.ListObjectsRequest) ([]permission.Object, error) {
+	return nil, f.err
+}
+
+func (f *fakePermissionService) ListUsers(ctx context.Context, req permission.ListUsersRequest) ([]permission.Object, error) {
+	return nil, f.err
+}
+
+func runPermissionMiddleware(service permission.PermissionServiceInterface, relation string) int {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Set("tenant_id", "t1")
+	}, PermissionMiddleware(service, relation), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Code
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	service := newFakePermissionService()
+
+	if code := runPermissionMiddleware(service, "can_create_quiz"); code != http.StatusForbidden {
+		t.Errorf("sem relação: esperado %d, mas obteve %d", http.StatusForbidden, code)
+	}
+
+	service.AddRelation(context.Background(), "u1", "t1", "can_create_quiz")
+	if code := runPermissionMiddleware(service, "can_create_quiz"); code != http.StatusOK {
+		t.Errorf("com relação: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+
+	if code := runPermissionMiddleware(service, "can_delete_quiz"); code != http.StatusForbidden {
+		t.Errorf("outra relação: esperado %d, mas obteve %d", http.StatusForbidden, code)
+	}
+
+	service.err = errors.New("openfga offline")
+	if code := runPermissionMiddleware(service, "can_create_quiz"); code != http.StatusServiceUnavailable {
+		t.Errorf("erro no serviço: esperado %d, mas obteve %d", http.StatusServiceUnavailable, code)
+	}
+
+	if code := runPermissionMiddleware(nil, "can_create_quiz"); code != http.StatusServiceUnavailable {
+		t.Errorf("serviço ausente: esperado %d, mas obteve %d", http.StatusServiceUnavailable, code)
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+	"github.com/katana-stuidio/access-control/pkg/service/token"
+)
+
+// Access defines who is allowed to call a route. The zero value is not a level,
+// so a route that leaves Access out is refused at startup instead of being public.
+type Access int
+
+const (
+	accessUnset Access = iota
+	// Public routes are reachable without a token (login, refresh)
+	Public
+	// Authenticated routes require a valid access token of a user
+	Authenticated
+	// Restricted routes require a valid access token of a user with one of the route roles
+	Restricted
+	// Service routes are for backend services: a client credentials token granted one
+	// of the route scopes, or a user token with one of the route roles
+	Service
+)
+
+// Route is one entry of a handler package policy table
+type Route struct {
+	Method string
+	Path   string
+	Access Access
+	Roles  []string
+	// Relation is checked against the permission service after the role check (ex: can_create_quiz)
+	Relation string
+	// Scopes are required in the access token (ex: quiz:create), see RequireScope
+	Scopes  []string
+	Handler gin.HandlerFunc
+}
+
+// Guard builds the middleware chain for each route of a policy table
+type Guard struct {
+	conf              *config.Config
+	permissionService permission.PermissionServiceInterface
+	tokenService      token.TokenServiceInterface
+}
+
+// NewGuard accepts a nil permission service, routes with a Relation are then denied.
+// A nil token service disables the access token denylist check.
+func NewGuard(conf *config.Config, permissionService permission.PermissionServiceInterface, tokenService token.TokenServiceInterface) *Guard {
+	return &Guard{
+		conf:              conf,
+		permissionService: permissionService,
+		tokenService:      tokenService,
+	}
+}
+
+// Chain returns the middlewares followed by the handler for the route policy.
+// It panics on a route without a valid Access.
+func (g *Guard) Chain(route Route) []gin.HandlerFunc {
+	if route.Access <= accessUnset || route.Access > Service {
+		panic(fmt.Sprintf("route %s %s has no valid Access", route.Method, route.Path))
+	}
+
+	chain := []gin.HandlerFunc{}
+
+	if route.Access != Public {
+		chain = append(chain, AuthMiddleware(g.conf, g.tokenService), TenantMiddleware())
+	}
+
+	// Client credentials tokens have no user, they only reach Service routes
+	if route.Access == Authenticated || route.Access == Restricted {
+		chain = append(chain, UserMiddleware())
+	}
+
+	if route.Access == Restricted {
+		chain = append(chain, RoleMiddleware(route.Roles...))
+	}
+
+	if route.Access == Service {
+		chain = append(chain, ServiceMiddleware(route.Scopes, route.Roles...))
+	} else if len(route.Scopes) > 0 && route.Access != Public {
+		chain = append(chain, RequireScope(route.Scopes...))
+	}
+
+	if route.Relation != "" && route.Access != Public {
+		chain = append(chain, PermissionMiddleware(g.permissionService, route.Relation))
+	}
+
+	return append(chain, route.Handler)
+}
+
+// Register adds every route of the policy table to the router group
+func (g *Guard) Register(group *gin.RouterGroup, routes []Route) {
+	for _, route := range routes {
+		group.Handle(route.Method, route.Path, g.Chain(route)...)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope checks that the access token grants every one of the scopes (ex: quiz:create).
+// It must run after AuthMiddleware. User tokens need the scopes in the permissions claim,
+// tokens issued to an OAuth client in the scope claim, and tokens a user delegated to a
+// client in both: the client cannot do more than the user nor more than it was granted.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userToken := c.GetString("user_id") != ""
+		clientToken := c.GetString("client_id") != ""
+		if !userToken && !clientToken {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User information not found"})
+			c.Abort()
+			return
+		}
+
+		permissions := c.GetStringSlice("permissions")
+		granted := strings.Fields(c.GetString("scope"))
+
+		for _, scope := range scopes {
+			if (userToken && !contains(permissions, scope)) || (clientToken && !contains(granted, scope)) {
+				// RFC 6750, section 3.1
+				c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient scope"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// ServiceMiddleware lets through client credentials tokens granted one of the scopes and
+// user tokens with one of the roles. It must run after AuthMiddleware.
+func ServiceMiddleware(scopes []string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ServiceClient(c) {
+			granted := strings.Fields(c.GetString("scope"))
+			for _, scope := range scopes {
+				if contains(granted, scope) {
+					c.Next()
+					return
+				}
+			}
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient scope"})
+			c.Abort()
+			return
+		}
+
+		if c.GetString("user_id") == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User information not found"})
+			c.Abort()
+			return
+		}
+
+		for _, role := range userRoles(c, c.GetString("role")) {
+			if contains(roles, role) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
+// ServiceClient reports whether the token was issued to an OAuth client for itself
+// (client credentials grant), with no user behind it
+func ServiceClient(c *gin.Context) bool {
+	return c.GetString("client_id") != "" && c.GetString("user_id") == ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func runRequireScope(claims map[string]any, scopes ...string) (int, string) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		for key, value := range claims {
+			c.Set(key, value)
+		}
+	}, RequireScope(scopes...), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Code, w.Header().Get("WWW-Authenticate")
+}
+
+func TestRequireScope(t *testing.T) {
+	user := map[string]any{"user_id": "u1", "permissions": []string{"quiz:create", "quiz:read"}}
+	if code, _ := runRequireScope(user, "quiz:create"); code != http.StatusOK {
+		t.Errorf("usuário com escopo: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+	code, challenge := runRequireScope(user, "quiz:create", "quiz:delete")
+	if code != http.StatusForbidden {
+		t.Errorf("usuário sem escopo: esperado %d, mas obteve %d", http.StatusForbidden, code)
+	}
+	if challenge != `Bearer error="insufficient_scope", scope="quiz:create quiz:delete"` {
+		t.Errorf("WWW-Authenticate inesperado %q", challenge)
+	}
+
+	client := map[string]any{"client_id": "reports", "scope": "quiz:read ranking:read"}
+	if code, _ := runRequireScope(client, "ranking:read"); code != http.StatusOK {
+		t.Errorf("cliente com escopo: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+	if code, _ := runRequireScope(client, "quiz:create"); code != http.StatusForbidden {
+		t.Errorf("cliente sem escopo: esperado %d, mas obteve %d", http.StatusForbidden, code)
+	}
+
+	// Token delegado: o cliente não faz mais do que o usuário nem mais do que lhe foi concedido
+	delegated := map[string]any{"user_id": "u1", "client_id": "portal", "permissions": []string{"quiz:create", "quiz:read"}, "scope": "openid quiz:read"}
+	if code, _ := runRequireScope(delegated, "quiz:read"); code != http.StatusOK {
+		t.Errorf("token delegado com escopo: esperado %d, mas obteve %d", http.StatusOK, code)
+	}
+	if code, _ := runRequireScope(delegated, "quiz:create"); code != http.StatusForbidden {
+		t.Errorf("token delegado sem escopo concedido: esperado %d, mas obteve %d", http.StatusForbidden, code)
+	}
+
+	if code, _ := runRequireScope(map[string]any{}, "quiz:read"); code != http.StatusUnauthorized {
+		t.Errorf("sem token: esperado %d, mas obteve %d", http.StatusUnauthorized, code)
+	}
+}
+
+func runServiceMiddleware(claims map[string]any) int {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		for key, value := range claims {
+			c.Set(key, value)
+		}
+	}, ServiceMiddleware([]string{"authz:check"}, "Admin"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Code
+}
+
+func TestServiceMiddleware(t *testing.T) {
+	cases := []struct {
+		name     string
+		claims   map[string]any
+		expected int
+	}{
+		{"serviço com escopo", map[string]any{"client_id": "quiz-service", "scope": "authz:check"}, http.StatusOK},
+		{"serviço sem escopo", map[string]any{"client_id": "reports", "scope": "ranking:read"}, http.StatusForbidden},
+		{"admin", map[string]any{"user_id": "u1", "role": "Admin"}, http.StatusOK},
+		{"estudante", map[string]any{"user_id": "u1", "role": "Estudante"}, http.StatusForbidden},
+		// Token delegado vale pelo usuário, o escopo do cliente não basta
+		{"token delegado", map[string]any{"user_id": "u1", "role": "Estudante", "client_id": "portal", "scope": "authz:check"}, http.StatusForbidden},
+		{"sem token", map[string]any{}, http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		if code := runServiceMiddleware(tc.claims); code != tc.expected {
+			t.Errorf("%s: esperado %d, mas obteve %d", tc.name, tc.expected, code)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+/* ============================================================
+   Histórico do modelo de autorização fixado (OpenFGA)
+   A linha mais recente é o modelo avaliado em todas as
+   verificações; as anteriores formam a trilha de auditoria.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_fga_model_pin (
+  id          bigserial PRIMARY KEY,
+  model_id    varchar(64)  NOT NULL,
+  pinned_by   varchar(64),
+  pinned_at   timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_fga_model_pin_pinned_at
+  ON public.tb_fga_model_pin(pinned_at DESC);
@@ -0,0 +1,31 @@
+/* ============================================================
+   Outbox de tuplas do OpenFGA
+   Gravada na mesma transação que cria/altera/exclui o usuário
+   e aplicada pelo permission.OutboxWorker, com novas tentativas
+   em caso de falha. dead_at marca as entradas que esgotaram as
+   tentativas (dead letter), mantidas com last_error para análise.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_fga_outbox (
+  id               bigserial PRIMARY KEY,
+  operation        varchar(10)  NOT NULL,             -- write | delete
+  user_id          uuid         NOT NULL,
+  tenant_id        uuid         NOT NULL,
+  relation         varchar(100) NOT NULL,
+  attempts         int          NOT NULL DEFAULT 0,
+  last_error       text,
+  next_attempt_at  timestamp    NOT NULL DEFAULT now(),
+  processed_at     timestamp,
+  dead_at          timestamp,
+  created_at       timestamp    NOT NULL DEFAULT now()
+);
+
+ALTER TABLE public.tb_fga_outbox
+  ADD COLUMN IF NOT EXISTS dead_at timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_fga_outbox_pending
+  ON public.tb_fga_outbox(next_attempt_at)
+  WHERE processed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_fga_outbox_tuple
+  ON public.tb_fga_outbox(user_id, tenant_id, relation)
+  WHERE processed_at IS NULL;
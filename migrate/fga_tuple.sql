@@ -0,0 +1,19 @@
+/* ============================================================
+   Tuplas do motor de autorização local (SRV_FGA_ENGINE=local)
+   Usada no lugar do OpenFGA em desenvolvimento e testes.
+   user_relation é vazio para usuários e preenchido para
+   usersets (ex: tenant:abc#admin).
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_fga_tuple (
+  object_type    varchar(100) NOT NULL,
+  object_id      varchar(255) NOT NULL,
+  relation       varchar(100) NOT NULL,
+  user_type      varchar(100) NOT NULL,
+  user_id        varchar(255) NOT NULL,
+  user_relation  varchar(100) NOT NULL DEFAULT '',
+  created_at     timestamp    NOT NULL DEFAULT now(),
+  PRIMARY KEY (object_type, object_id, relation, user_type, user_id, user_relation)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fga_tuple_user
+  ON public.tb_fga_tuple(user_type, user_id, user_relation);
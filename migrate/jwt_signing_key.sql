@@ -0,0 +1,19 @@
+/* ============================================================
+   Chaves de assinatura dos JWT (SRV_JWT_KEY_SOURCE=postgres)
+   Uma chave nova é gerada a cada SRV_JWT_KEY_ROTATION e passa
+   a assinar a partir de not_before; a anterior segue validando
+   tokens até not_after (SRV_JWT_KEY_OVERLAP).
+   private_key guarda o PEM cifrado com AES-256-GCM
+   (SRV_JWT_KEY_ENCRYPTION_KEY), nunca em texto puro.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_jwt_signing_key (
+  kid          varchar(64)  PRIMARY KEY,
+  algorithm    varchar(10)  NOT NULL,
+  private_key  text         NOT NULL,
+  not_before   timestamptz  NOT NULL DEFAULT now(),
+  not_after    timestamptz,
+  created_at   timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jwt_signing_key_not_after
+  ON public.tb_jwt_signing_key(not_after);
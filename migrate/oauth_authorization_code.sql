@@ -0,0 +1,16 @@
+/* ============================================================
+   Fluxo authorization code com PKCE (GET/POST /oauth/authorize)
+   redirect_uris: URIs de retorno aceitas, separadas por espaço,
+   comparadas exatamente. first_party dispensa o consentimento
+   do usuário; public identifica SPAs e apps móveis, que não
+   guardam segredo e se autenticam apenas com o PKCE.
+   Os códigos ficam no Redis, não há tabela para eles.
+   ============================================================ */
+ALTER TABLE public.tb_oauth_client
+  ADD COLUMN IF NOT EXISTS redirect_uris text    NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS first_party   boolean NOT NULL DEFAULT false,
+  ADD COLUMN IF NOT EXISTS public        boolean NOT NULL DEFAULT false;
+
+-- Clientes públicos não possuem segredo
+ALTER TABLE public.tb_oauth_client
+  ALTER COLUMN secret_hash DROP NOT NULL;
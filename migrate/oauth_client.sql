@@ -0,0 +1,22 @@
+/* ============================================================
+   Clientes OAuth 2.0 (POST /oauth/token)
+   O segredo é guardado apenas como hash bcrypt; scopes são os
+   escopos permitidos, separados por espaço. Os tokens emitidos
+   ao cliente pertencem ao tenant vinculado.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_oauth_client (
+  client_id    varchar(64)  PRIMARY KEY,
+  name         varchar(150) NOT NULL,
+  secret_hash  varchar      NOT NULL,
+  scopes       text         NOT NULL DEFAULT '',
+  tenant_id    uuid         NOT NULL,
+  CONSTRAINT   fk_oauth_client_tenant
+    FOREIGN KEY (tenant_id) REFERENCES public.tb_tenant(id)
+    ON DELETE CASCADE,
+  enabled      boolean      NOT NULL DEFAULT true,
+  created_at   timestamp    NOT NULL DEFAULT now(),
+  updated_at   timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_client_tenant
+  ON public.tb_oauth_client(tenant_id);
@@ -0,0 +1,41 @@
+/* ============================================================
+   Política de senhas por grupo de tenants
+   tb_tenant_group_password_policy: regras das senhas dos usuários
+   de cada grupo. Grupos sem linha usam a política padrão (8
+   caracteres com maiúscula, número e símbolo). history_size e
+   max_age_days iguais a 0 desligam as regras.
+   tb_user_password_history: hashes bcrypt das últimas senhas de
+   cada usuário, a atual incluída, para impedir a reutilização.
+   tb_user.password_changed_at: data da última troca de senha,
+   usada para expirar a senha após max_age_days.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_tenant_group_password_policy (
+  group_id               uuid PRIMARY KEY,
+  CONSTRAINT             fk_password_policy_group
+    FOREIGN KEY (group_id) REFERENCES public.tb_tenant_group(id)
+    ON DELETE CASCADE,
+  min_length             integer      NOT NULL DEFAULT 8,
+  require_uppercase      boolean      NOT NULL DEFAULT true,
+  require_lowercase      boolean      NOT NULL DEFAULT false,
+  require_number         boolean      NOT NULL DEFAULT true,
+  require_symbol         boolean      NOT NULL DEFAULT true,
+  disallow_personal_data boolean      NOT NULL DEFAULT false,
+  history_size           integer      NOT NULL DEFAULT 0,
+  max_age_days           integer      NOT NULL DEFAULT 0,
+  updated_at             timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.tb_user_password_history (
+  id               bigserial    PRIMARY KEY,
+  user_id          uuid         NOT NULL,
+  CONSTRAINT       fk_password_history_user
+    FOREIGN KEY (user_id) REFERENCES public.tb_user(id)
+    ON DELETE CASCADE,
+  hashed_password  varchar      NOT NULL,
+  created_at       timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user ON public.tb_user_password_history (user_id, id);
+
+ALTER TABLE public.tb_user
+  ADD COLUMN IF NOT EXISTS password_changed_at timestamp NOT NULL DEFAULT now();
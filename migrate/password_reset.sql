@@ -0,0 +1,19 @@
+/* ============================================================
+   Redefinição de senha (esqueci minha senha)
+   tb_password_reset: tokens enviados por email, gravados apenas
+   como SHA-256. used_at preenchido indica um token já usado ou
+   substituído por um pedido mais novo; expires_at limita a
+   validade a SRV_PASSWORD_RESET_TTL minutos.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_password_reset (
+  token_hash   char(64)     PRIMARY KEY,
+  user_id      uuid         NOT NULL,
+  CONSTRAINT   fk_password_reset_user
+    FOREIGN KEY (user_id) REFERENCES public.tb_user(id)
+    ON DELETE CASCADE,
+  expires_at   timestamp    NOT NULL,
+  used_at      timestamp,
+  created_at   timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_user ON public.tb_password_reset (user_id, created_at);
@@ -0,0 +1,58 @@
+/* ============================================================
+   Catálogo de roles e roles do usuário por tenant
+   tb_role: roles globais (group_id nulo) e roles de um grupo
+   educacional. Um grupo sobrescreve o role global de mesmo nome
+   (descrição, habilitado) ou cria roles próprios. Os roles de
+   sistema são usados pela API e não podem ser excluídos.
+   tb_user_role: roles de cada usuário em cada tenant; role_usr
+   de tb_user continua sendo o role principal do usuário.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_role (
+  id           uuid PRIMARY KEY             DEFAULT uuid_generate_v4(),
+  group_id     uuid,
+  CONSTRAINT   fk_role_tenant_group
+    FOREIGN KEY (group_id) REFERENCES public.tb_tenant_group(id)
+    ON DELETE CASCADE,
+  name         varchar(50)  NOT NULL,
+  description  varchar(255) NOT NULL DEFAULT '',
+  enabled      boolean      NOT NULL DEFAULT true,
+  system       boolean      NOT NULL DEFAULT false,
+  created_at   timestamp    NOT NULL DEFAULT now(),
+  updated_at   timestamp    NOT NULL DEFAULT now()
+);
+
+-- Um nome por catálogo: o global e o de cada grupo
+CREATE UNIQUE INDEX IF NOT EXISTS idx_role_group_name
+  ON public.tb_role (COALESCE(group_id, '00000000-0000-0000-0000-000000000000'::uuid), name);
+
+INSERT INTO public.tb_role (name, description, system) VALUES
+  ('Admin',            'Administrador de todos os tenants',        true),
+  ('GrupoEducacional', 'Gestor dos tenants do grupo educacional',  true),
+  ('Instituicao',      'Gestor da instituição',                    true),
+  ('Professor',        'Professor',                                true),
+  ('Estudante',        'Estudante',                                true)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.tb_user_role (
+  user_id     uuid        NOT NULL,
+  CONSTRAINT  fk_user_role_user
+    FOREIGN KEY (user_id) REFERENCES public.tb_user(id)
+    ON DELETE CASCADE,
+  tenant_id   uuid        NOT NULL,
+  CONSTRAINT  fk_user_role_tenant
+    FOREIGN KEY (tenant_id) REFERENCES public.tb_tenant(id)
+    ON DELETE CASCADE,
+  role        varchar(50) NOT NULL,
+  created_at  timestamp   NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, tenant_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_role_tenant
+  ON public.tb_user_role (tenant_id, role);
+
+-- O role principal dos usuários existentes passa a constar em tb_user_role
+INSERT INTO public.tb_user_role (user_id, tenant_id, role)
+SELECT u.id, u.id_tanant, u.role_usr
+FROM public.tb_user u
+WHERE EXISTS (SELECT 1 FROM public.tb_role r WHERE r.group_id IS NULL AND r.name = u.role_usr)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,24 @@
+/* ============================================================
+   Passkeys (WebAuthn)
+   tb_user_credential: credenciais de chave pública registradas
+   pelo usuário. credential_id é o ID da credencial em base64url,
+   public_key a chave no formato COSE e sign_count o contador de
+   assinaturas (0 para autenticadores sem contador).
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_user_credential (
+  id            uuid PRIMARY KEY             DEFAULT uuid_generate_v4(),
+  user_id       uuid         NOT NULL,
+  CONSTRAINT    fk_user_credential_user
+    FOREIGN KEY (user_id) REFERENCES public.tb_user(id)
+    ON DELETE CASCADE,
+  credential_id varchar(1400) NOT NULL UNIQUE,
+  public_key    bytea        NOT NULL,
+  sign_count    bigint       NOT NULL DEFAULT 0,
+  aaguid        uuid,
+  transports    text         NOT NULL DEFAULT '',
+  name          varchar(100) NOT NULL DEFAULT '',
+  created_at    timestamp    NOT NULL DEFAULT now(),
+  last_used_at  timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_credential_user_id ON public.tb_user_credential (user_id);
@@ -0,0 +1,42 @@
+/* ============================================================
+   Autenticação em dois fatores (TOTP, RFC 6238)
+   tb_user_mfa: segredo TOTP do usuário, cifrado com AES-GCM
+   (SRV_MFA_ENCRYPTION_KEY). confirmed_at nulo indica um cadastro
+   ainda não confirmado com um código; last_used_step impede que
+   o mesmo código seja aceito duas vezes.
+   tb_user_mfa_recovery_code: códigos de recuperação de uso único,
+   gravados apenas como SHA-256.
+   tb_tenant_mfa_policy: roles que precisam de MFA no login em
+   cada tenant, separados por espaço.
+   ============================================================ */
+CREATE TABLE IF NOT EXISTS public.tb_user_mfa (
+  user_id          uuid PRIMARY KEY,
+  CONSTRAINT       fk_user_mfa_user
+    FOREIGN KEY (user_id) REFERENCES public.tb_user(id)
+    ON DELETE CASCADE,
+  secret_encrypted text         NOT NULL,
+  confirmed_at     timestamp,
+  last_used_step   bigint       NOT NULL DEFAULT 0,
+  created_at       timestamp    NOT NULL DEFAULT now(),
+  updated_at       timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.tb_user_mfa_recovery_code (
+  user_id      uuid         NOT NULL,
+  CONSTRAINT   fk_user_mfa_recovery_code_user
+    FOREIGN KEY (user_id) REFERENCES public.tb_user(id)
+    ON DELETE CASCADE,
+  code_hash    char(64)     NOT NULL,
+  used_at      timestamp,
+  created_at   timestamp    NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS public.tb_tenant_mfa_policy (
+  tenant_id      uuid PRIMARY KEY,
+  CONSTRAINT     fk_tenant_mfa_policy_tenant
+    FOREIGN KEY (tenant_id) REFERENCES public.tb_tenant(id)
+    ON DELETE CASCADE,
+  required_roles text         NOT NULL DEFAULT '',
+  updated_at     timestamp    NOT NULL DEFAULT now()
+);
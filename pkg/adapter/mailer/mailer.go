@@ -0,0 +1,210 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+)
+
+var (
+	ErrInvalidMessage = errors.New("invalid email message")
+	// ErrNoDriver means SRV_MAIL_DRIVER was not set: the messages carry password reset tokens,
+	// so whether they are sent or only logged must be chosen explicitly
+	ErrNoDriver = errors.New("mail driver not set (SRV_MAIL_DRIVER=smtp or log)")
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type SenderInterface interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// New returns the sender of conf.MailDriver: smtp, or log to write the messages to
+// conf.MailDir (or only their headers to the log when empty) instead of sending them
+func New(conf *config.Config) (SenderInterface, error) {
+	switch conf.MailDriver {
+	case "":
+		return nil, ErrNoDriver
+	case "smtp":
+		return NewSMTPSender(conf.SMTPHost, conf.SMTPPort, conf.SMTPUser, conf.SMTPPass, conf.MailFrom), nil
+	case "log":
+		return NewLogSender(conf.MailDir, conf.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", conf.MailDriver)
+	}
+}
+
+// Timeout bounds the SMTP conversation when the context of Send has no deadline
+const Timeout = 30 * time.Second
+
+// SMTPSender sends the messages with an SMTP server, authenticating with PLAIN when user is set.
+// The connection is upgraded with STARTTLS when the server offers it.
+type SMTPSender struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPSender(host, port, user, pass, from string) *SMTPSender {
+	sender := &SMTPSender{
+		addr: net.JoinHostPort(host, port),
+		host: host,
+		from: from,
+	}
+	if user != "" {
+		sender.auth = smtp.PlainAuth("", user, pass, host)
+	}
+	return sender
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message *Message) error {
+	data, err := format(s.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := s.send(ctx, message.To, data); err != nil {
+		logger.Error("Error sending email", err)
+		return err
+	}
+	return nil
+}
+
+// send is smtp.SendMail on a connection bound to the deadline of ctx, so a server that
+// stops answering cannot hold the sender forever
+func (s *SMTPSender) send(ctx context.Context, to string, data []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(Timeout)
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(address(s.from)); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// LogSender is the stand-in of development and tests: each message is written to a .eml file
+// of dir or, without dir, to the log without its body, which may hold a password reset token
+type LogSender struct {
+	dir  string
+	from string
+}
+
+func NewLogSender(dir, from string) *LogSender {
+	return &LogSender{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (s *LogSender) Send(ctx context.Context, message *Message) error {
+	data, err := format(s.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if s.dir == "" {
+		headers, _, _ := strings.Cut(string(data), "\r\n\r\n")
+		logger.Info("Email not sent (SRV_MAIL_DRIVER=log, body omitted, set SRV_MAIL_DIR to keep it):\n" + headers)
+		return nil
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(message.To))
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o600); err != nil {
+		logger.Error("Error writing email file", err)
+		return err
+	}
+	return nil
+}
+
+// format builds the RFC 5322 message; line breaks in the headers are refused, as they would
+// let the recipient or the subject add headers
+func format(from string, message *Message, date time.Time) ([]byte, error) {
+	if _, err := mail.ParseAddress(message.To); err != nil {
+		return nil, fmt.Errorf("%w: recipient: %v", ErrInvalidMessage, err)
+	}
+	if strings.ContainsAny(message.Subject, "\r\n") || strings.ContainsAny(from, "\r\n") {
+		return nil, fmt.Errorf("%w: line break in a header", ErrInvalidMessage)
+	}
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "From: %s\r\n", from)
+	fmt.Fprintf(&data, "To: %s\r\n", message.To)
+	fmt.Fprintf(&data, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&data, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	data.WriteString("MIME-Version: 1.0\r\n")
+	data.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	data.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	data.WriteString("\r\n")
+	data.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return data.Bytes(), nil
+}
+
+// address is the bare address of a From such as "Escola <no-reply@escola.br>"
+func address(from string) string {
+	if parsed, err := mail.ParseAddress(from); err == nil {
+		return parsed.Address
+	}
+	return from
+}
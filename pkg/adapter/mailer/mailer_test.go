@@ -0,0 +1,118 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config"
+)
+
+func TestFormat(t *testing.T) {
+	message := &Message{To: "maria@escola.br", Subject: "Redefinição de senha", Body: "Olá\nClique no link"}
+
+	data, err := format("Escola <no-reply@escola.br>", message, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+
+	text := string(data)
+	for _, expected := range []string{
+		"From: Escola <no-reply@escola.br>\r\n",
+		"To: maria@escola.br\r\n",
+		"Subject: =?utf-8?q?Redefini=C3=A7=C3=A3o_de_senha?=\r\n",
+		"Date: Fri, 02 Jan 2026 03:04:05 +0000\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nOlá\r\nClique no link",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("mensagem sem %q:\n%s", expected, text)
+		}
+	}
+}
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	messages := []*Message{
+		{To: "maria@escola.br\r\nBcc: todos@escola.br", Subject: "Oi"},
+		{To: "maria@escola.br", Subject: "Oi\r\nBcc: todos@escola.br"},
+		{To: "não é email", Subject: "Oi"},
+	}
+
+	for _, message := range messages {
+		if _, err := format("no-reply@escola.br", message, time.Now()); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("%q / %q: esperado ErrInvalidMessage, mas obteve %v", message.To, message.Subject, err)
+		}
+	}
+}
+
+func TestLogSenderWritesFile(t *testing.T) {
+	dir := t.TempDir()
+	sender := NewLogSender(dir, "no-reply@escola.br")
+
+	if err := sender.Send(context.Background(), &Message{To: "maria@escola.br", Subject: "Oi", Body: "token=abc"}); err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("esperado 1 arquivo, mas obteve %d", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "token=abc") {
+		t.Errorf("corpo ausente do arquivo:\n%s", data)
+	}
+}
+
+func TestNewRequiresDriver(t *testing.T) {
+	conf := &config.Config{}
+	if _, err := New(conf); !errors.Is(err, ErrNoDriver) {
+		t.Errorf("sem driver: esperado ErrNoDriver, mas obteve %v", err)
+	}
+
+	conf.MailDriver = "log"
+	if _, err := New(conf); err != nil {
+		t.Errorf("driver log: erro inesperado %v", err)
+	}
+
+	conf.MailDriver = "sendmail"
+	if _, err := New(conf); err == nil {
+		t.Error("driver desconhecido deveria falhar")
+	}
+}
+
+func TestSMTPSenderStopsAtDeadline(t *testing.T) {
+	// Servidor que aceita a conexão e nunca responde
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("erro ao abrir a porta: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	sender := NewSMTPSender(host, port, "", "", "no-reply@escola.br")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := sender.Send(ctx, &Message{To: "maria@escola.br", Subject: "Oi", Body: "token=abc"}); err == nil {
+		t.Fatal("esperado erro do servidor mudo")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("envio preso por %s, o prazo era de 200ms", elapsed)
+	}
+}
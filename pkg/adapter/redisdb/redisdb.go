@@ -17,9 +17,16 @@ type RedisClientInterface interface {
 	GetClient() *redis.Client
 	ReadData(ctx context.Context, key string) (data []byte, err error)
 	SaveData(ctx context.Context, key string, data []byte, timer time.Duration) (ok bool)
+	SaveDataIfNotExists(ctx context.Context, key string, data []byte, timer time.Duration) (ok bool, err error)
 	SaveHSetData(ctx context.Context, key, field string, value interface{}) (ok bool)
 	ReadHSetData(ctx context.Context, key string) (data map[string]string, err error)
 	DeleteAllHSetData(ctx context.Context, key string) (ok bool)
+	Exists(ctx context.Context, key string) (exists bool, err error)
+	Increment(ctx context.Context, key string, timer time.Duration) (count int64, err error)
+	TimeToLive(ctx context.Context, key string) (ttl time.Duration, err error)
+	AddToSet(ctx context.Context, key, member string, timer time.Duration) (ok bool)
+	ReadSetMembers(ctx context.Context, key string) (members []string, err error)
+	RemoveFromSet(ctx context.Context, key, member string) (ok bool)
 	Publish(ctx context.Context, message []byte) error
 	Subscriber(ctx context.Context, callback func(msg *redis.Message))
 }
@@ -136,6 +143,26 @@ func (rs *redis_client) SaveData(ctx context.Context, key string, data []byte, t
 	return
 }
 
+// SaveDataIfNotExists salva a chave apenas se ela ainda não existir (SET NX).
+// ok é false quando a chave já existia.
+func (rs *redis_client) SaveDataIfNotExists(ctx context.Context, key string, data []byte, timer time.Duration) (ok bool, err error) {
+
+	rs.modifyLock.Lock()
+	defer rs.modifyLock.Unlock()
+
+	if timer <= 0 {
+		timer = time.Duration(15 * time.Minute)
+	}
+
+	ok, err = rs.rdb.SetNX(ctx, key, data, timer).Result()
+	if err != nil {
+		logger.Error("SaveDataIfNotExists, Erro ao tentar salvar uma informação", err)
+		return false, err
+	}
+
+	return
+}
+
 // SaveHSetData salva um hashset
 func (rs *redis_client) SaveHSetData(ctx context.Context, key, datakey string, value interface{}) (ok bool) {
 	rs.modifyLock.Lock()
@@ -175,6 +202,109 @@ func (rs *redis_client) DeleteAllHSetData(ctx context.Context, key string) (ok b
 	return true
 }
 
+// Exists verifica se a chave existe, sem tratar chave ausente como erro
+func (rs *redis_client) Exists(ctx context.Context, key string) (exists bool, err error) {
+	rs.modifyLock.Lock()
+	defer rs.modifyLock.Unlock()
+
+	count, err := rs.rdb.Exists(ctx, key).Result()
+	if err != nil {
+		logger.Error("Exists, Erro ao tentar ler uma informação", err)
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Increment soma 1 ao contador (INCR); o TTL é definido no primeiro incremento,
+// então o contador expira timer depois da primeira contagem
+func (rs *redis_client) Increment(ctx context.Context, key string, timer time.Duration) (count int64, err error) {
+	rs.modifyLock.Lock()
+	defer rs.modifyLock.Unlock()
+
+	if timer <= 0 {
+		timer = time.Duration(15 * time.Minute)
+	}
+
+	count, err = rs.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		logger.Error("Increment, Erro ao tentar salvar uma informação", err)
+		return 0, err
+	}
+
+	if count == 1 {
+		if err = rs.rdb.Expire(ctx, key, timer).Err(); err != nil {
+			logger.Error("Increment, Erro ao tentar definir o TTL", err)
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// TimeToLive retorna o tempo até a chave expirar; 0 quando ela não existe ou não expira
+func (rs *redis_client) TimeToLive(ctx context.Context, key string) (ttl time.Duration, err error) {
+	rs.modifyLock.Lock()
+	defer rs.modifyLock.Unlock()
+
+	ttl, err = rs.rdb.TTL(ctx, key).Result()
+	if err != nil {
+		logger.Error("TimeToLive, Erro ao tentar ler uma informação", err)
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// AddToSet adiciona um membro a um set e renova o TTL do set
+func (rs *redis_client) AddToSet(ctx context.Context, key, member string, timer time.Duration) (ok bool) {
+	rs.modifyLock.Lock()
+	defer rs.modifyLock.Unlock()
+
+	if timer <= 0 {
+		timer = time.Duration(15 * time.Minute)
+	}
+
+	pipe := rs.rdb.TxPipeline()
+	pipe.SAdd(ctx, key, member)
+	pipe.Expire(ctx, key, timer)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("AddToSet, Erro ao tentar salvar uma informação", err)
+		return
+	}
+	return true
+}
+
+// ReadSetMembers lê todos os membros de um set
+func (rs *redis_client) ReadSetMembers(ctx context.Context, key string) (members []string, err error) {
+	rs.modifyLock.Lock()
+	defer rs.modifyLock.Unlock()
+
+	members, err = rs.rdb.SMembers(ctx, key).Result()
+	if err != nil {
+		logger.Error("ReadSetMembers, Erro ao tentar Ler uma informação", err)
+		return nil, err
+	}
+
+	return
+}
+
+// RemoveFromSet remove um membro de um set
+func (rs *redis_client) RemoveFromSet(ctx context.Context, key, member string) (ok bool) {
+	rs.modifyLock.Lock()
+	defer rs.modifyLock.Unlock()
+
+	result := rs.rdb.SRem(ctx, key, member)
+	if result.Err() != nil {
+		logger.Error("RemoveFromSet, Erro ao tentar Deletar uma informação", result.Err())
+		return
+	}
+	return true
+}
+
 // Publish envia uma mensagem para um canal específico no Redis.
 //
 // Esta função recebe um contexto (ctx), um nome de canal (channel) e uma
@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// UserProfile holds the OpenID Connect claims of a user, shared by the ID token and /userinfo.
+// Which of them are set depends on the granted scopes, see NewUserProfile.
+type UserProfile struct {
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	TenantID          string `json:"tenant_id,omitempty"`
+	TenantName        string `json:"tenant_name,omitempty"`
+	GroupID           string `json:"group_id,omitempty"`
+	GroupName         string `json:"group_name,omitempty"`
+}
+
+// NewUserProfile releases the claims allowed by the space separated scopes:
+// openid the tenant and the group, profile the names and email the email
+func NewUserProfile(user *model.User, tenant *model.Tenant, tenantGroup *model.TenantGroup, scopes string) UserProfile {
+	profile := UserProfile{}
+	if !model.HasScope(scopes, model.ScopeOpenID) {
+		return profile
+	}
+
+	profile.TenantID = user.TenantID.String()
+	if tenant != nil {
+		profile.TenantName = tenant.Name
+	}
+	if tenantGroup != nil {
+		profile.GroupID = tenantGroup.ID.String()
+		profile.GroupName = tenantGroup.Name
+	}
+
+	if model.HasScope(scopes, model.ScopeProfile) {
+		profile.Name = user.Name
+		profile.PreferredUsername = user.Username
+	}
+	if model.HasScope(scopes, model.ScopeEmail) {
+		profile.Email = user.Email
+	}
+
+	return profile
+}
+
+// ErrIDTokenUnsupported is returned when the clients could not verify an ID token:
+// signed with the shared HS256 secret or issued by something other than an https URL
+var ErrIDTokenUnsupported = errors.New("ID tokens require an asymmetric signing key and an https issuer")
+
+// IDTokensSupported reports whether ID tokens can be issued: signed with a KeyRing key,
+// published in the JWKS, and with an https URL as issuer (OpenID Connect Core, section 2)
+func IDTokensSupported(conf *config.Config) bool {
+	if CurrentKeyRing() == nil {
+		return false
+	}
+	issuer, err := url.Parse(conf.JWTIssuer)
+	return err == nil && issuer.Scheme == "https" && issuer.Host != "" && issuer.RawQuery == "" && issuer.Fragment == ""
+}
+
+// IDTokenClaims are the claims of an OpenID Connect ID token. The audience is the client,
+// so ValidateToken, which requires the API audience, does not accept it as an access token.
+type IDTokenClaims struct {
+	Nonce           string           `json:"nonce,omitempty"`
+	AuthTime        *jwt.NumericDate `json:"auth_time,omitempty"`
+	AuthorizedParty string           `json:"azp,omitempty"`
+	UserProfile
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken issues the ID token of user to clientID. nonce is the value of the
+// authorization request and authTime when the user logged in.
+func GenerateIDToken(user *model.User, tenant *model.Tenant, tenantGroup *model.TenantGroup, clientID, scopes, nonce string, authTime time.Time, conf *config.Config) (string, error) {
+	if !model.HasScope(scopes, model.ScopeOpenID) {
+		return "", errors.New("the openid scope was not granted")
+	}
+	if !IDTokensSupported(conf) {
+		return "", ErrIDTokenUnsupported
+	}
+
+	tokenID, err := generateTokenID()
+	if err != nil {
+		log.Println("Error generating token ID:", err)
+		return "", err
+	}
+
+	now := time.Now()
+	expiration := now.Add(time.Duration(conf.JWTTokenExp) * time.Minute)
+	claims := &IDTokenClaims{
+		Nonce:            nonce,
+		AuthorizedParty:  clientID,
+		UserProfile:      NewUserProfile(user, tenant, tenantGroup, scopes),
+		RegisteredClaims: registeredClaims(conf, user.ID.String(), tokenID, jwt.ClaimStrings{clientID}, now, expiration),
+	}
+	if !authTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(authTime)
+	}
+
+	return createToken(claims, conf)
+}
+
+// UserInfo is the response of the OpenID Connect UserInfo endpoint
+type UserInfo struct {
+	Subject string `json:"sub"`
+	UserProfile
+}
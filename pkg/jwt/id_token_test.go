@@ -0,0 +1,109 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+func testUser() (*model.User, *model.Tenant, *model.TenantGroup) {
+	group := &model.TenantGroup{ID: uuid.New(), Name: "Rede"}
+	tenant := &model.Tenant{ID: uuid.New(), GroupID: group.ID, Name: "Escola"}
+	user := &model.User{ID: uuid.New(), TenantID: tenant.ID, Username: "maria", Name: "Maria Silva", Email: "maria@escola.com"}
+	return user, tenant, group
+}
+
+func TestNewUserProfileReleasesClaimsByScope(t *testing.T) {
+	user, tenant, group := testUser()
+
+	tests := []struct {
+		scopes   string
+		expected UserProfile
+	}{
+		{"users:read", UserProfile{}},
+		{"openid", UserProfile{TenantID: tenant.ID.String(), TenantName: "Escola", GroupID: group.ID.String(), GroupName: "Rede"}},
+		{"openid email", UserProfile{Email: "maria@escola.com", TenantID: tenant.ID.String(), TenantName: "Escola", GroupID: group.ID.String(), GroupName: "Rede"}},
+		{"openid profile email", UserProfile{Name: "Maria Silva", PreferredUsername: "maria", Email: "maria@escola.com",
+			TenantID: tenant.ID.String(), TenantName: "Escola", GroupID: group.ID.String(), GroupName: "Rede"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scopes, func(t *testing.T) {
+			if got := NewUserProfile(user, tenant, group, tt.scopes); got != tt.expected {
+				t.Errorf("esperado %+v, mas obteve %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenerateIDToken(t *testing.T) {
+	conf := claimsConfig()
+	conf.JWTTokenExp = 15
+	user, tenant, group := testUser()
+	authTime := time.Now().Add(-time.Minute)
+
+	// Com HS256 o cliente não teria como verificar a assinatura
+	if _, err := GenerateIDToken(user, tenant, group, "portal", "openid", "n-0S6", authTime, conf); !errors.Is(err, ErrIDTokenUnsupported) {
+		t.Errorf("esperado %v, mas obteve %v", ErrIDTokenUnsupported, err)
+	}
+
+	key := newTestKey(t, AlgES256, time.Now().Add(-time.Minute))
+	useKeyRing(t, key)
+
+	if _, err := GenerateIDToken(user, tenant, group, "portal", "profile", "n-0S6", authTime, conf); err == nil {
+		t.Error("ID token emitido sem o escopo openid")
+	}
+
+	idToken, err := GenerateIDToken(user, tenant, group, "portal", "openid email", "n-0S6", authTime, conf)
+	if err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(idToken, claims, func(*jwt.Token) (interface{}, error) {
+		return key.PublicKey, nil
+	}, jwt.WithAudience("portal"), jwt.WithIssuer(conf.JWTIssuer))
+	if err != nil {
+		t.Fatalf("ID token inválido: %v", err)
+	}
+
+	if claims.Subject != user.ID.String() || claims.Nonce != "n-0S6" || claims.AuthorizedParty != "portal" ||
+		claims.Email != "maria@escola.com" || claims.Name != "" || claims.GroupID != group.ID.String() {
+		t.Errorf("claims inesperadas %+v", claims)
+	}
+	if claims.AuthTime == nil || claims.AuthTime.Unix() != authTime.Unix() {
+		t.Errorf("auth_time inesperado %v", claims.AuthTime)
+	}
+
+	// O ID token não serve como access token da API
+	if _, err := ValidateToken(idToken, conf); err == nil {
+		t.Error("ID token aceito como access token")
+	}
+}
+
+func TestIDTokensSupported(t *testing.T) {
+	useKeyRing(t, newTestKey(t, AlgEdDSA, time.Now().Add(-time.Minute)))
+
+	for issuer, expected := range map[string]bool{
+		"https://auth.katana.com":      true,
+		"https://auth.katana.com/oidc": true,
+		"access-control":               false,
+		"http://auth.katana.com":       false,
+		"https://auth.katana.com?x=1":  false,
+		"":                             false,
+	} {
+		if got := IDTokensSupported(&config.Config{JWTIssuer: issuer}); got != expected {
+			t.Errorf("issuer %q: esperado %v, mas obteve %v", issuer, expected, got)
+		}
+	}
+
+	SetKeyRing(nil)
+	if IDTokensSupported(&config.Config{JWTIssuer: "https://auth.katana.com"}) {
+		t.Error("ID tokens habilitados com HS256")
+	}
+}
@@ -0,0 +1,73 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// JWK is the public part of a signing key in the JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the JSON Web Key Set published to the services that validate the tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that still validate tokens, including the rotated keys
+// not yet signing, so downstream caches know them before the first token shows up
+func (r *KeyRing) JWKS(now time.Time) (JWKS, error) {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range r.Keys(now) {
+		jwk, err := PublicJWK(key)
+		if err != nil {
+			return JWKS{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// PublicJWK encodes the public key of key
+func PublicJWK(key SigningKey) (JWK, error) {
+	jwk := JWK{Use: "sig", Kid: key.ID, Alg: key.Algorithm}
+
+	switch public := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64URL(public.N.Bytes())
+		jwk.E = base64URL(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = base64URL(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64URL(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64URL(public)
+	default:
+		return JWK{}, fmt.Errorf("key %s: unsupported public key type %T", key.ID, key.PublicKey)
+	}
+
+	return jwk, nil
+}
+
+func base64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
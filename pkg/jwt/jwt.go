@@ -23,44 +23,108 @@ type TokenDetails struct {
 }
 
 type Claims struct {
-	Username    string `json:"username"`
-	UserID      string `json:"user_id"`
-	TenantID    string `json:"tenant_id"`
-	TenantName  string `json:"tenant_name,omitempty"`
-	GroupID     string `json:"group_id,omitempty"`
-	GroupName   string `json:"group_name,omitempty"`
-	Role        string `json:"role"`
-	FirstAccess bool   `json:"first_access"`
-	Renew       bool   `json:"renew,omitempty"`
-	TokenID     string `json:"token_id,omitempty"`
+	Username   string `json:"username"`
+	UserID     string `json:"user_id"`
+	TenantID   string `json:"tenant_id"`
+	TenantName string `json:"tenant_name,omitempty"`
+	GroupID    string `json:"group_id,omitempty"`
+	GroupName  string `json:"group_name,omitempty"`
+	Role       string `json:"role"`
+	// Roles are every role of the user in the tenant, Role included
+	Roles       []string `json:"roles,omitempty"`
+	FirstAccess bool     `json:"first_access"`
+	Renew       bool     `json:"renew,omitempty"`
+	TokenID     string   `json:"token_id,omitempty"`
+	// ClientID and Scope (space separated) are set on tokens issued through /oauth/token
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// Permissions are the scopes of the user, computed from the role when the access token is issued
+	Permissions []string `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates both access and refresh tokens with Redis integration
-func GenerateToken(user *model.User, tenant *model.Tenant, tenantGroup *model.TenantGroup, conf *config.Config, tokenService token.TokenServiceInterface) (*TokenDetails, error) {
-	jwtKey := []byte(conf.JWTSecretKey)
+// UserRoles returns the roles of the token; tokens issued before the roles claim only have the role
+func (c *Claims) UserRoles() []string {
+	if len(c.Roles) > 0 {
+		return c.Roles
+	}
+	if c.Role != "" {
+		return []string{c.Role}
+	}
+	return []string{}
+}
+
+// ErrUnknownClient is returned for a client_id without configured audiences
+var ErrUnknownClient = errors.New("unknown client")
+
+// Audience returns the audiences of the tokens issued to clientID: the audience of this API
+// followed by the audiences configured for the client. An empty clientID gets only the API audience.
+func Audience(conf *config.Config, clientID string) (jwt.ClaimStrings, error) {
+	if _, ok := conf.JWTClientAudiences[clientID]; clientID != "" && !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownClient, clientID)
+	}
+	return clientAudience(conf, clientID), nil
+}
 
+// clientAudience is the audience of this API plus the audiences configured for clientID, if any
+func clientAudience(conf *config.Config, clientID string) jwt.ClaimStrings {
+	audience := jwt.ClaimStrings{}
+	if conf.JWTAudience != "" {
+		audience = append(audience, conf.JWTAudience)
+	}
+
+	for _, aud := range conf.JWTClientAudiences[clientID] {
+		if aud != conf.JWTAudience {
+			audience = append(audience, aud)
+		}
+	}
+
+	return audience
+}
+
+// GenerateToken generates both access and refresh tokens with Redis integration.
+// clientID selects the audiences of the tokens, see Audience, and resolver the
+// permissions claim (nil grants the scopes of the roles).
+func GenerateToken(ctx context.Context, user *model.User, tenant *model.Tenant, tenantGroup *model.TenantGroup, clientID string, conf *config.Config, tokenService token.TokenServiceInterface, resolver PermissionResolver) (*TokenDetails, error) {
+	audience, err := Audience(conf, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateUserTokens(ctx, user, tenant, tenantGroup, audience, "", nil, conf, tokenService, resolver)
+}
+
+// GenerateAuthorizationCodeToken issues the tokens of a user who authorized an OAuth client
+// (authorization_code grant): the user claims plus the client_id and the granted scopes,
+// which the rotated tokens keep.
+func GenerateAuthorizationCodeToken(ctx context.Context, user *model.User, tenant *model.Tenant, tenantGroup *model.TenantGroup, client *model.OAuthClient, scopes []string, conf *config.Config, tokenService token.TokenServiceInterface, resolver PermissionResolver) (*TokenDetails, error) {
+	return generateUserTokens(ctx, user, tenant, tenantGroup, clientAudience(conf, client.ClientID), client.ClientID, scopes, conf, tokenService, resolver)
+}
+
+func generateUserTokens(ctx context.Context, user *model.User, tenant *model.Tenant, tenantGroup *model.TenantGroup, audience jwt.ClaimStrings, clientID string, scopes []string, conf *config.Config, tokenService token.TokenServiceInterface, resolver PermissionResolver) (*TokenDetails, error) {
 	// Generate a unique token ID for Redis storage
 	tokenID, err := generateTokenID()
 	if err != nil {
 		log.Println("Error generating token ID:", err)
 		return nil, err
 	}
+	now := time.Now()
 
 	// Generate Access Token (short-lived)
-	accessExpiration := time.Now().Add(time.Duration(conf.JWTTokenExp) * time.Minute)
+	accessExpiration := now.Add(time.Duration(conf.JWTTokenExp) * time.Minute)
 	accessClaims := &Claims{
-		Username:   user.Username,
-		UserID:     user.ID.String(),
-		TenantID:   user.TenantID.String(),
-		TenantName: tenant.Name,
-		Role:       user.Role,
-		Renew:      false,
-		TokenID:    tokenID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(accessExpiration),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+		Username:         user.Username,
+		UserID:           user.ID.String(),
+		TenantID:         user.TenantID.String(),
+		TenantName:       tenant.Name,
+		Role:             user.Role,
+		Roles:            user.AllRoles(),
+		Renew:            false,
+		TokenID:          tokenID,
+		ClientID:         clientID,
+		Scope:            strings.Join(scopes, " "),
+		Permissions:      userPermissions(ctx, resolver, user.ID.String(), user.TenantID.String(), user.AllRoles()),
+		RegisteredClaims: registeredClaims(conf, user.ID.String(), tokenID, audience, now, accessExpiration),
 	}
 
 	// Add group information (now mandatory)
@@ -68,26 +132,26 @@ func GenerateToken(user *model.User, tenant *model.Tenant, tenantGroup *model.Te
 		accessClaims.GroupID = tenantGroup.ID.String()
 		accessClaims.GroupName = tenantGroup.Name
 	}
-	accessToken, err := createToken(accessClaims, jwtKey)
+	accessToken, err := createToken(accessClaims, conf)
 	if err != nil {
 		log.Println("Error generating access token:", err)
 		return nil, err
 	}
 
 	// Generate Refresh Token (long-lived)
-	refreshExpiration := time.Now().Add(time.Duration(conf.JWTRefreshExp) * time.Minute)
+	refreshExpiration := now.Add(time.Duration(conf.JWTRefreshExp) * time.Minute)
 	refreshClaims := &Claims{
-		Username:   user.Username,
-		UserID:     user.ID.String(),
-		TenantID:   user.TenantID.String(),
-		TenantName: tenant.Name,
-		Role:       user.Role,
-		Renew:      true,
-		TokenID:    tokenID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(refreshExpiration),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+		Username:         user.Username,
+		UserID:           user.ID.String(),
+		TenantID:         user.TenantID.String(),
+		TenantName:       tenant.Name,
+		Role:             user.Role,
+		Roles:            user.AllRoles(),
+		Renew:            true,
+		TokenID:          tokenID,
+		ClientID:         clientID,
+		Scope:            strings.Join(scopes, " "),
+		RegisteredClaims: registeredClaims(conf, user.ID.String(), tokenID, audience, now, refreshExpiration),
 	}
 
 	// Add group information (now mandatory)
@@ -95,22 +159,22 @@ func GenerateToken(user *model.User, tenant *model.Tenant, tenantGroup *model.Te
 		refreshClaims.GroupID = tenantGroup.ID.String()
 		refreshClaims.GroupName = tenantGroup.Name
 	}
-	refreshToken, err := createToken(refreshClaims, jwtKey)
+	refreshToken, err := createToken(refreshClaims, conf)
 	if err != nil {
 		log.Println("Error generating refresh token:", err)
 		return nil, err
 	}
 
-	// Save refresh token to Redis
-	ctx := context.Background()
+	// Save refresh token to Redis, the login starts a new token family
 	err = tokenService.SaveRefreshToken(
 		ctx,
 		tokenID,
+		tokenID,
 		user.ID.String(),
 		user.Username,
 		user.TenantID.String(),
 		user.Role,
-		time.Now(),
+		now,
 		refreshExpiration,
 	)
 	if err != nil {
@@ -125,14 +189,45 @@ func GenerateToken(user *model.User, tenant *model.Tenant, tenantGroup *model.Te
 	}, nil
 }
 
+// GenerateClientToken issues an access token to an OAuth client (client_credentials grant).
+// The token has no user and no role: sub and client_id are the client, the tenant is the
+// client tenant and scopes replace the role. No refresh token is issued, the client asks again.
+func GenerateClientToken(client *model.OAuthClient, tenant *model.Tenant, scopes []string, conf *config.Config) (*TokenDetails, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		log.Println("Error generating token ID:", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	expiration := now.Add(time.Duration(conf.JWTTokenExp) * time.Minute)
+	claims := &Claims{
+		TenantID:         tenant.ID.String(),
+		TenantName:       tenant.Name,
+		GroupID:          tenant.GroupID.String(),
+		TokenID:          tokenID,
+		ClientID:         client.ClientID,
+		Scope:            strings.Join(scopes, " "),
+		RegisteredClaims: registeredClaims(conf, client.ClientID, tokenID, clientAudience(conf, client.ClientID), now, expiration),
+	}
+
+	accessToken, err := createToken(claims, conf)
+	if err != nil {
+		log.Println("Error generating access token:", err)
+		return nil, err
+	}
+
+	return &TokenDetails{
+		AccessToken: fmt.Sprintf("Bearer %s", accessToken),
+		TokenID:     tokenID,
+	}, nil
+}
+
 // ValidateToken validates a token string and returns the claims
 func ValidateToken(tokenStr string, conf *config.Config) (*Claims, error) {
-	jwtKey := []byte(conf.JWTSecretKey)
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	token, err := parseToken(tokenStr, claims, conf)
 	if err != nil {
 		if errors.Is(err, jwt.ErrSignatureInvalid) {
 			log.Println("Invalid token signature")
@@ -148,20 +243,14 @@ func ValidateToken(tokenStr string, conf *config.Config) (*Claims, error) {
 	return claims, nil
 }
 
-// RefreshJWT creates a new access token using a valid refresh token with Redis validation
-func RefreshJWT(tknStr string, conf *config.Config, tokenService token.TokenServiceInterface) (token *TokenDetails, ok bool) {
-	jwtKey := []byte(conf.JWTSecretKey)
-
+// RefreshJWT rotates a valid refresh token: the presented token is consumed in Redis and a new
+// access and refresh token pair of the same family is returned. Presenting a refresh token that was
+// already used revokes the whole family.
+func RefreshJWT(ctx context.Context, tknStr string, conf *config.Config, tokenService token.TokenServiceInterface, resolver PermissionResolver) (token *TokenDetails, ok bool) {
 	claims := &Claims{}
-	tkn, err := jwt.ParseWithClaims(tknStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-
+	tkn, err := parseToken(tknStr, claims, conf)
 	if err != nil {
 		log.Println(err.Error())
-		if err == jwt.ErrSignatureInvalid {
-			return token, false
-		}
 		return token, false
 	}
 
@@ -175,30 +264,65 @@ func RefreshJWT(tknStr string, conf *config.Config, tokenService token.TokenServ
 		return token, false
 	}
 
-	// Validate refresh token against Redis
-	ctx := context.Background()
-	isValid, err := tokenService.IsTokenValid(ctx, claims.TokenID)
-	if err != nil || !isValid {
-		log.Println("Error: refresh token not found in Redis or invalid")
+	// Consume the refresh token in Redis, it cannot be used again
+	session, err := tokenService.ConsumeRefreshToken(ctx, claims.TokenID)
+	if err != nil {
+		log.Println("Error: refresh token rejected:", err)
 		return token, false
 	}
 
+	tokenID, err := generateTokenID()
+	if err != nil {
+		log.Println("Error generating token ID:", err)
+		return token, false
+	}
+
+	now := time.Now()
+	claims.TokenID = tokenID
+
+	// The rotated tokens keep the subject and the audiences of the client that logged in
+	accessExpiration := now.Add(time.Duration(conf.JWTTokenExp) * time.Minute)
+	refreshExpiration := now.Add(time.Duration(conf.JWTRefreshExp) * time.Minute)
+
 	// Generate new access token
-	claims.Renew = false
-	expirationTime := time.Now().Add(time.Duration(conf.JWTTokenExp) * time.Minute)
-	claims.ExpiresAt = jwt.NewNumericDate(expirationTime)
-	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	// Permissions are computed again, the role or the relations may have changed
+	accessClaims := *claims
+	accessClaims.Renew = false
+	accessClaims.Permissions = userPermissions(ctx, resolver, claims.UserID, claims.TenantID, claims.UserRoles())
+	accessClaims.RegisteredClaims = registeredClaims(conf, claims.UserID, tokenID, claims.Audience, now, accessExpiration)
 
-	newThk := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	thkString, err := newThk.SignedString(jwtKey)
+	accessToken, err := createToken(&accessClaims, conf)
 	if err != nil {
 		log.Println("Error creating new token")
 		return token, false
 	}
 
+	// Generate the rotated refresh token
+	refreshClaims := *claims
+	refreshClaims.RegisteredClaims = registeredClaims(conf, claims.UserID, tokenID, claims.Audience, now, refreshExpiration)
+
+	refreshToken, err := createToken(&refreshClaims, conf)
+	if err != nil {
+		log.Println("Error generating refresh token:", err)
+		return token, false
+	}
+
+	familyID := session.FamilyID
+	if familyID == "" {
+		// Tokens saved before families existed start their own family
+		familyID = tokenID
+	}
+
+	err = tokenService.SaveRefreshToken(ctx, tokenID, familyID, session.UserID, session.Username, session.TenantID, session.Role, now, refreshExpiration)
+	if err != nil {
+		log.Println("Error saving refresh token to Redis:", err)
+		return token, false
+	}
+
 	token = &TokenDetails{
-		AccessToken: fmt.Sprintf("Bearer %s", thkString),
-		TokenID:     claims.TokenID,
+		AccessToken:  fmt.Sprintf("Bearer %s", accessToken),
+		RefreshToken: fmt.Sprintf("Bearer %s", refreshToken),
+		TokenID:      tokenID,
 	}
 
 	return token, true
@@ -210,6 +334,13 @@ func RevokeToken(tokenID string, tokenService token.TokenServiceInterface) error
 	return tokenService.DeleteRefreshToken(ctx, tokenID)
 }
 
+// RevokeAccessToken adds the access token to the Redis denylist until it expires,
+// a zero expiresAt keeps it for the whole access token lifetime
+func RevokeAccessToken(tokenID string, expiresAt time.Time, tokenService token.TokenServiceInterface) error {
+	ctx := context.Background()
+	return tokenService.RevokeAccessToken(ctx, tokenID, expiresAt)
+}
+
 // RevokeAllUserTokens removes all refresh tokens for a user
 func RevokeAllUserTokens(userID string, tokenService token.TokenServiceInterface) error {
 	ctx := context.Background()
@@ -222,10 +353,77 @@ func RevokeAllTenantTokens(tenantID string, tokenService token.TokenServiceInter
 	return tokenService.DeleteAllTenantTokens(ctx, tenantID)
 }
 
-// Helper function to create a new token
-func createToken(claims *Claims, jwtKey []byte) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+// Helper function to create a new token, signed with the current key of the KeyRing
+// (kid header) or with HS256 and the shared secret when no KeyRing is set
+func createToken(claims jwt.Claims, conf *config.Config) (string, error) {
+	ring := CurrentKeyRing()
+	if ring == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(conf.JWTSecretKey))
+	}
+
+	key, err := ring.SigningKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// registeredClaims fills the standard claims: sub is the user ID and jti the token ID
+func registeredClaims(conf *config.Config, subject, tokenID string, audience jwt.ClaimStrings, issuedAt, expiresAt time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    conf.JWTIssuer,
+		Subject:   subject,
+		Audience:  audience,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		NotBefore: jwt.NewNumericDate(issuedAt),
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+		ID:        tokenID,
+	}
+}
+
+// validationOptions requires the configured issuer and audience and tolerates
+// a clock skew of up to conf.JWTLeeway seconds on exp, nbf and iat
+func validationOptions(conf *config.Config, algorithms []string) []jwt.ParserOption {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithLeeway(time.Duration(conf.JWTLeeway) * time.Second),
+		jwt.WithIssuedAt(),
+	}
+	if conf.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(conf.JWTIssuer))
+	}
+	if conf.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(conf.JWTAudience))
+	}
+	return options
+}
+
+// parseToken checks the signature of a token. Only the algorithm of the configured mode is
+// accepted, so a token signed with HS256 is rejected when the KeyRing is in use (and the
+// public key is never taken as an HMAC secret), and the key is selected by the kid header.
+func parseToken(tokenStr string, claims *Claims, conf *config.Config) (*jwt.Token, error) {
+	ring := CurrentKeyRing()
+	if ring == nil {
+		return jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+			return []byte(conf.JWTSecretKey), nil
+		}, validationOptions(conf, []string{AlgHS256})...)
+	}
+
+	return jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token without kid header")
+		}
+		return ring.VerificationKey(kid, t.Method.Alg(), time.Now())
+	}, validationOptions(conf, asymmetricAlgorithms)...)
 }
 
 // Helper function to generate a unique token ID
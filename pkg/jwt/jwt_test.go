@@ -0,0 +1,82 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/katana-stuidio/access-control/internal/config"
+)
+
+func claimsConfig() *config.Config {
+	return &config.Config{
+		JWTSecretKey: "segredo",
+		JWTIssuer:    "https://auth.katana.com",
+		JWTAudience:  "access-control",
+		JWTLeeway:    30,
+		JWTClientAudiences: map[string][]string{
+			"quiz-web": {"quiz-api", "access-control"},
+		},
+	}
+}
+
+func TestAudience(t *testing.T) {
+	conf := claimsConfig()
+
+	audience, err := Audience(conf, "quiz-web")
+	if err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	if len(audience) != 2 || audience[0] != "access-control" || audience[1] != "quiz-api" {
+		t.Errorf("audiências inesperadas %v", audience)
+	}
+
+	if audience, _ := Audience(conf, ""); len(audience) != 1 {
+		t.Errorf("audiências inesperadas %v", audience)
+	}
+
+	if _, err := Audience(conf, "desconhecido"); !errors.Is(err, ErrUnknownClient) {
+		t.Errorf("esperado %v, mas obteve %v", ErrUnknownClient, err)
+	}
+}
+
+func TestValidateTokenRegisteredClaims(t *testing.T) {
+	conf := claimsConfig()
+	now := time.Now()
+
+	sign := func(issuer string, audience jwt.ClaimStrings, issuedAt time.Time) string {
+		claims := &Claims{UserID: "user-1", TokenID: "token-1"}
+		claims.RegisteredClaims = registeredClaims(conf, "user-1", "token-1", audience, issuedAt, issuedAt.Add(time.Minute))
+		claims.Issuer = issuer
+		tokenStr, err := createToken(claims, conf)
+		if err != nil {
+			t.Fatalf("erro ao assinar token: %v", err)
+		}
+		return tokenStr
+	}
+
+	claims, err := ValidateToken(sign(conf.JWTIssuer, jwt.ClaimStrings{"access-control", "quiz-api"}, now), conf)
+	if err != nil {
+		t.Fatalf("token válido rejeitado: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.ID != "token-1" || claims.NotBefore == nil {
+		t.Errorf("claims registradas inesperadas %+v", claims.RegisteredClaims)
+	}
+
+	// Relógio do emissor 10s adiantado, dentro da tolerância
+	if _, err := ValidateToken(sign(conf.JWTIssuer, jwt.ClaimStrings{"access-control"}, now.Add(10*time.Second)), conf); err != nil {
+		t.Errorf("token dentro da tolerância rejeitado: %v", err)
+	}
+
+	cases := map[string]string{
+		"emissor":    sign("https://outro.com", jwt.ClaimStrings{"access-control"}, now),
+		"audiência":  sign(conf.JWTIssuer, jwt.ClaimStrings{"quiz-api"}, now),
+		"nbf futuro": sign(conf.JWTIssuer, jwt.ClaimStrings{"access-control"}, now.Add(2*time.Minute)),
+	}
+	for name, tokenStr := range cases {
+		if _, err := ValidateToken(tokenStr, conf); err == nil {
+			t.Errorf("token com %s inválido aceito", name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidEncryptedKey = errors.New("invalid encrypted signing key")
+	// ErrMissingKeyEncryptionKey is returned without SRV_JWT_KEY_ENCRYPTION_KEY: the keys in
+	// tb_jwt_signing_key are never stored in plain text
+	ErrMissingKeyEncryptionKey = errors.New("JWT key encryption key is not configured")
+)
+
+// KeyCipher encrypts the private keys kept by PostgresKeyStore. The kid is bound to the
+// encrypted key, so a key copied to another row does not decrypt. A KMS client can
+// implement it instead of AESKeyCipher.
+type KeyCipher interface {
+	Encrypt(kid string, privateKey []byte) (string, error)
+	Decrypt(kid, encrypted string) ([]byte, error)
+}
+
+// AESKeyCipher encrypts the private keys with AES-256-GCM
+type AESKeyCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAESKeyCipher builds the cipher from a 32 byte key
+func NewAESKeyCipher(key []byte) (*AESKeyCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("JWT key encryption key must have 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESKeyCipher{aead: aead}, nil
+}
+
+// NewAESKeyCipherFromKey decodes a base64 key, returning ErrMissingKeyEncryptionKey when it is empty
+func NewAESKeyCipherFromKey(encoded string) (*AESKeyCipher, error) {
+	if encoded == "" {
+		return nil, ErrMissingKeyEncryptionKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("JWT key encryption key is not valid base64: %w", err)
+	}
+	return NewAESKeyCipher(key)
+}
+
+// Encrypt returns base64(nonce | ciphertext) of privateKey bound to kid
+func (c *AESKeyCipher) Encrypt(kid string, privateKey []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, privateKey, []byte(kid))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt
+func (c *AESKeyCipher) Decrypt(kid, encrypted string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("key %s: %w", kid, ErrInvalidEncryptedKey)
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	privateKey, err := c.aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", kid, ErrInvalidEncryptedKey)
+	}
+	return privateKey, nil
+}
@@ -0,0 +1,262 @@
+package jwt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+)
+
+const (
+	// keyReloadInterval is how often WatchKeys reloads the key source
+	keyReloadInterval = 30 * time.Second
+	// keyActivationDelay keeps a rotated key published before it signs, so every
+	// instance has loaded it before the first token signed with it shows up
+	keyActivationDelay = 2 * keyReloadInterval
+)
+
+// KeySource loads the signing and validation keys
+type KeySource interface {
+	LoadKeys(ctx context.Context) ([]SigningKey, error)
+}
+
+// LoadKeyRing creates a ring with the keys of source
+func LoadKeyRing(ctx context.Context, source KeySource) (*KeyRing, error) {
+	keys, err := source.LoadKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyRing(keys), nil
+}
+
+// WatchKeys reloads the keys of source into ring periodically. A failed reload keeps the current keys.
+func WatchKeys(ctx context.Context, ring *KeyRing, source KeySource) {
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		keys, err := source.LoadKeys(ctx)
+		if err != nil {
+			logger.Error("Error reloading JWT signing keys", err)
+			continue
+		}
+		ring.SetKeys(keys)
+	}
+}
+
+// PEMKeySource reads the keys from the *.pem files of a directory; the file name is the kid.
+// Private keys sign and validate, public keys only validate tokens of a retired key.
+// The signing key is SigningKID or, when empty, the most recently modified private key,
+// so a key is rotated by adding its file and retired by removing the old one.
+// Every private key that may sign must be of Algorithm; public keys may keep an older
+// algorithm, so tokens signed before a change of algorithm still validate.
+type PEMKeySource struct {
+	Dir        string
+	SigningKID string
+	Algorithm  string
+}
+
+func NewPEMKeySource(dir, signingKID, algorithm string) *PEMKeySource {
+	return &PEMKeySource{
+		Dir:        dir,
+		SigningKID: signingKID,
+		Algorithm:  algorithm,
+	}
+}
+
+func (s *PEMKeySource) LoadKeys(ctx context.Context) ([]SigningKey, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]SigningKey, 0, len(paths))
+	foundSigningKID := false
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParsePEMKey(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, err
+		}
+		key.NotBefore = info.ModTime()
+
+		if s.SigningKID != "" {
+			if key.ID == s.SigningKID && key.PrivateKey != nil {
+				foundSigningKID = true
+			} else {
+				key.PrivateKey = nil
+			}
+		}
+
+		if key.PrivateKey != nil && key.Algorithm != s.Algorithm {
+			return nil, fmt.Errorf("key %s is a %s key, the signing algorithm is %s", key.ID, key.Algorithm, s.Algorithm)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM keys found in %q", s.Dir)
+	}
+	if s.SigningKID != "" && !foundSigningKID {
+		return nil, fmt.Errorf("signing key %q not found in %q", s.SigningKID, s.Dir)
+	}
+
+	return keys, nil
+}
+
+// KeyRotation schedules the generation of signing keys: a new key is created every Interval
+// and the previous key keeps validating tokens for Overlap after the new one starts signing.
+// Overlap should be at least the refresh token lifetime.
+type KeyRotation struct {
+	Algorithm string
+	Interval  time.Duration
+	Overlap   time.Duration
+}
+
+// PostgresKeyStore keeps the keys in tb_jwt_signing_key and rotates them on schedule,
+// shared by every instance of the service. The private keys are stored encrypted by cipher.
+type PostgresKeyStore struct {
+	dbp      pgsql.DatabaseInterface
+	rotation KeyRotation
+	cipher   KeyCipher
+}
+
+func NewPostgresKeyStore(database_pool pgsql.DatabaseInterface, rotation KeyRotation, cipher KeyCipher) *PostgresKeyStore {
+	return &PostgresKeyStore{
+		dbp:      database_pool,
+		rotation: rotation,
+		cipher:   cipher,
+	}
+}
+
+// LoadKeys rotates the keys when the rotation is due and returns the keys not yet retired
+func (s *PostgresKeyStore) LoadKeys(ctx context.Context) ([]SigningKey, error) {
+	if _, err := s.RotateIfDue(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.dbp.GetDB().QueryContext(ctx,
+		"SELECT kid, private_key, not_before, not_after FROM tb_jwt_signing_key WHERE not_after IS NULL OR not_after > now()")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []SigningKey{}
+	for rows.Next() {
+		var kid, encrypted string
+		var notBefore time.Time
+		var notAfter sql.NullTime
+		if err := rows.Scan(&kid, &encrypted, &notBefore, &notAfter); err != nil {
+			return nil, err
+		}
+
+		privateKey, err := s.cipher.Decrypt(kid, encrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParsePEMKey(kid, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		key.NotBefore = notBefore
+		key.NotAfter = notAfter.Time
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RotateIfDue creates a new signing key when the newest key is older than the rotation interval.
+// The table lock makes a single instance rotate when several check at the same time.
+func (s *PostgresKeyStore) RotateIfDue(ctx context.Context) (bool, error) {
+	tx, err := s.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE tb_jwt_signing_key IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return false, err
+	}
+
+	var newest sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		"SELECT max(not_before) FROM tb_jwt_signing_key WHERE not_after IS NULL").Scan(&newest)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if newest.Valid && now.Sub(newest.Time) < s.rotation.Interval {
+		return false, nil
+	}
+
+	// The first key signs right away, the next ones after every instance has loaded them
+	activation := now
+	if newest.Valid {
+		activation = now.Add(keyActivationDelay)
+	}
+
+	key, err := GenerateSigningKey(s.rotation.Algorithm, activation)
+	if err != nil {
+		return false, err
+	}
+	privateKey, err := MarshalPrivateKeyPEM(key.PrivateKey)
+	if err != nil {
+		return false, err
+	}
+	encrypted, err := s.cipher.Encrypt(key.ID, privateKey)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM tb_jwt_signing_key WHERE not_after <= now()")
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE tb_jwt_signing_key SET not_after = $1 WHERE not_after IS NULL", activation.Add(s.rotation.Overlap))
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO tb_jwt_signing_key (kid, algorithm, private_key, not_before) VALUES ($1, $2, $3, $4)",
+		key.ID, key.Algorithm, encrypted, key.NotBefore)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	logger.Info(fmt.Sprintf("JWT signing key rotated: %s (%s), active from %s", key.ID, key.Algorithm, activation.Format(time.RFC3339)))
+	return true, nil
+}
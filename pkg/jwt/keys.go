@@ -0,0 +1,268 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// asymmetricAlgorithms are the algorithms accepted when a KeyRing is in use
+var asymmetricAlgorithms = []string{AlgRS256, AlgES256, AlgEdDSA}
+
+var (
+	ErrNoSigningKey      = errors.New("no active signing key")
+	ErrUnknownKey        = errors.New("unknown or retired signing key")
+	ErrAlgorithmMismatch = errors.New("token algorithm does not match the signing key")
+)
+
+// SigningKey is an asymmetric key identified by the kid header of the tokens it signs.
+// A key signs from NotBefore on and is accepted for validation until NotAfter (zero means
+// no end); keys without PrivateKey only validate tokens.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	NotBefore  time.Time
+	NotAfter   time.Time
+}
+
+func (k SigningKey) validAt(now time.Time) bool {
+	return k.NotAfter.IsZero() || now.Before(k.NotAfter)
+}
+
+// KeyRing holds the keys used to sign and validate tokens. It is safe for concurrent use
+// and its keys are replaced by WatchKeys when the key source changes.
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+func NewKeyRing(keys []SigningKey) *KeyRing {
+	return &KeyRing{keys: keys}
+}
+
+// SetKeys replaces the keys of the ring
+func (r *KeyRing) SetKeys(keys []SigningKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = keys
+}
+
+// Keys returns the keys that still validate tokens, including the ones not yet signing
+func (r *KeyRing) Keys(now time.Time) []SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []SigningKey{}
+	for _, key := range r.keys {
+		if key.validAt(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SigningKey returns the most recently activated key with a private key
+func (r *KeyRing) SigningKey(now time.Time) (SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var current *SigningKey
+	for i, key := range r.keys {
+		if key.PrivateKey == nil || now.Before(key.NotBefore) || !key.validAt(now) {
+			continue
+		}
+		if current == nil || key.NotBefore.After(current.NotBefore) {
+			current = &r.keys[i]
+		}
+	}
+
+	if current == nil {
+		return SigningKey{}, ErrNoSigningKey
+	}
+	return *current, nil
+}
+
+// VerificationKey returns the public key of kid. The token algorithm must be the
+// algorithm of the key, so a token can never choose how its own signature is checked.
+func (r *KeyRing) VerificationKey(kid, algorithm string, now time.Time) (crypto.PublicKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.ID != kid || !key.validAt(now) {
+			continue
+		}
+		if key.Algorithm != algorithm {
+			return nil, ErrAlgorithmMismatch
+		}
+		return key.PublicKey, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+var (
+	keyRingMu sync.RWMutex
+	keyRing   *KeyRing
+)
+
+// SetKeyRing switches token signing and validation to the keys of ring.
+// With a nil ring tokens are signed with HS256 and Config.JWTSecretKey.
+func SetKeyRing(ring *KeyRing) {
+	keyRingMu.Lock()
+	defer keyRingMu.Unlock()
+	keyRing = ring
+}
+
+// CurrentKeyRing returns the ring set by SetKeyRing, nil when HS256 is in use
+func CurrentKeyRing() *KeyRing {
+	keyRingMu.RLock()
+	defer keyRingMu.RUnlock()
+	return keyRing
+}
+
+// GenerateSigningKey creates a key pair for algorithm with a random kid
+func GenerateSigningKey(algorithm string, notBefore time.Time) (SigningKey, error) {
+	var private crypto.Signer
+	var err error
+
+	switch algorithm {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	kid, err := generateTokenID()
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	return SigningKey{
+		ID:         kid,
+		Algorithm:  algorithm,
+		PrivateKey: private,
+		PublicKey:  private.Public(),
+		NotBefore:  notBefore,
+	}, nil
+}
+
+// ParsePEMKey reads a private key (PKCS#8, PKCS#1 or SEC 1) or a public key (PKIX).
+// The algorithm follows the key type: RSA is RS256, P-256 is ES256 and Ed25519 is EdDSA.
+func ParsePEMKey(kid string, data []byte) (SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, fmt.Errorf("key %s: no PEM block found", kid)
+	}
+
+	key := SigningKey{ID: kid}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+		key.PublicKey = public
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+		key.PrivateKey = private
+	case "EC PRIVATE KEY":
+		private, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+		key.PrivateKey = private
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("key %s: %w", kid, err)
+		}
+		private, ok := parsed.(crypto.Signer)
+		if !ok {
+			return SigningKey{}, fmt.Errorf("key %s: unsupported private key type %T", kid, parsed)
+		}
+		key.PrivateKey = private
+	default:
+		return SigningKey{}, fmt.Errorf("key %s: unsupported PEM block %q", kid, block.Type)
+	}
+
+	if key.PrivateKey != nil {
+		key.PublicKey = key.PrivateKey.Public()
+	}
+
+	algorithm, err := algorithmForKey(key.PublicKey)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("key %s: %w", kid, err)
+	}
+	key.Algorithm = algorithm
+
+	return key, nil
+}
+
+// MarshalPrivateKeyPEM encodes the private key as PKCS#8
+func MarshalPrivateKeyPEM(private crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func algorithmForKey(public crypto.PublicKey) (string, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return "", errors.New("RSA keys must have at least 2048 bits")
+		}
+		return AlgRS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", errors.New("only P-256 ECDSA keys are supported")
+		}
+		return AlgES256, nil
+	case ed25519.PublicKey:
+		return AlgEdDSA, nil
+	}
+	return "", fmt.Errorf("unsupported public key type %T", public)
+}
+
+// signingMethod maps the algorithm to the golang-jwt signing method
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	return method, nil
+}
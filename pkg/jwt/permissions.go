@@ -0,0 +1,37 @@
+package jwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+	"go.uber.org/zap"
+)
+
+// PermissionTimeout bounds the checks of the permissions claim, a slow OpenFGA
+// delays the login by at most this long and the token keeps the role permissions
+const PermissionTimeout = 2 * time.Second
+
+// PermissionResolver computes the permissions claim of a user when a token is issued or rotated
+type PermissionResolver interface {
+	Resolve(ctx context.Context, userID, tenantID string, roles []string) ([]string, error)
+}
+
+// userPermissions resolves the permissions of the user. A nil resolver grants the scopes of
+// the user roles, see permission.ScopesForRole. A failing resolver still returns the
+// permissions it could compute, the token is issued without the missing ones.
+func userPermissions(ctx context.Context, resolver PermissionResolver, userID, tenantID string, roles []string) []string {
+	if resolver == nil {
+		return permission.ScopesForRoles(roles)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, PermissionTimeout)
+	defer cancel()
+
+	permissions, err := resolver.Resolve(ctx, userID, tenantID, roles)
+	if err != nil {
+		logger.Error("Error resolving token permissions", err, zap.String("user_id", userID))
+	}
+	return permissions
+}
@@ -0,0 +1,59 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+type ctxKey struct{}
+
+// recordingResolver guarda o contexto recebido e devolve permissions e err
+type recordingResolver struct {
+	ctx         context.Context
+	permissions []string
+	err         error
+}
+
+func (r *recordingResolver) Resolve(ctx context.Context, userID, tenantID string, roles []string) ([]string, error) {
+	r.ctx = ctx
+	return r.permissions, r.err
+}
+
+func TestUserPermissionsUsesRequestContextWithTimeout(t *testing.T) {
+	resolver := &recordingResolver{permissions: []string{"quiz:create"}}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "requisição")
+
+	permissions := userPermissions(ctx, resolver, "u1", "t1", []string{model.RoleProfessor})
+	if len(permissions) != 1 || permissions[0] != "quiz:create" {
+		t.Errorf("permissões inesperadas %v", permissions)
+	}
+
+	// O resolver recebe o contexto da requisição, limitado por PermissionTimeout
+	if resolver.ctx.Value(ctxKey{}) != "requisição" {
+		t.Error("o resolver não recebeu o contexto da requisição")
+	}
+	deadline, ok := resolver.ctx.Deadline()
+	if !ok || time.Until(deadline) > PermissionTimeout {
+		t.Errorf("prazo inesperado %v (%v)", deadline, ok)
+	}
+	if resolver.ctx.Err() == nil {
+		t.Error("o contexto do resolver não foi cancelado ao retornar")
+	}
+}
+
+func TestUserPermissionsFallback(t *testing.T) {
+	// Sem resolver, os escopos dos roles
+	if permissions := userPermissions(context.Background(), nil, "u1", "t1", []string{model.RoleEstudante}); len(permissions) == 0 {
+		t.Error("esperados os escopos do role")
+	}
+
+	// Com erro, o que o resolver conseguiu calcular
+	resolver := &recordingResolver{permissions: []string{"quiz:respond"}, err: errors.New("openfga offline")}
+	if permissions := userPermissions(context.Background(), resolver, "u1", "t1", nil); len(permissions) != 1 {
+		t.Errorf("permissões inesperadas %v", permissions)
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAStatus is the second factor state of a user
+type MFAStatus struct {
+	// Enabled is set once the user confirmed the TOTP secret with a code
+	Enabled bool `json:"enabled"`
+	// Pending is an enrolled secret still waiting for confirmation
+	Pending bool `json:"pending"`
+	// RecoveryCodes is the number of recovery codes not used yet
+	RecoveryCodes int `json:"recovery_codes"`
+	// Required is set when the tenant policy requires MFA for one of the user roles
+	Required bool `json:"required"`
+}
+
+// ChallengeRequired reports whether the login must be completed with a second factor
+func (s *MFAStatus) ChallengeRequired() bool {
+	return s.Enabled || s.Required
+}
+
+// MFAPolicy lists the roles that must log in with a second factor in a tenant
+type MFAPolicy struct {
+	TenantID      uuid.UUID `json:"tenant_id"`
+	RequiredRoles []string  `json:"required_roles"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+}
+
+// Requires reports whether any of the roles is required to use MFA
+func (p *MFAPolicy) Requires(roles []string) bool {
+	for _, required := range p.RequiredRoles {
+		for _, role := range roles {
+			if role == required {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Rules of the password policy, reported in PasswordViolation.Rule
+const (
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleMaxLength    = "max_length"
+	PasswordRuleUppercase    = "uppercase"
+	PasswordRuleLowercase    = "lowercase"
+	PasswordRuleNumber       = "number"
+	PasswordRuleSymbol       = "symbol"
+	PasswordRulePersonalData = "personal_data"
+	PasswordRuleHistory      = "history"
+)
+
+// PasswordHistoryLimit is the largest history a policy can keep, and the number of hashes stored per user
+const PasswordHistoryLimit = 24
+
+// PasswordMaxBytes is the longest password bcrypt hashes, in bytes; a policy MinLength above it
+// would reject every password
+const PasswordMaxBytes = 72
+
+// personalDataMinLength ignores pieces of personal data too short to matter, as a 2 letter username
+const personalDataMinLength = 3
+
+// PasswordPolicy are the rules of the passwords of the users of a tenant group.
+// HistorySize forbids reusing the last N passwords and MaxAgeDays expires the password
+// at the login after that many days; 0 disables both
+type PasswordPolicy struct {
+	GroupID              uuid.UUID `json:"group_id"`
+	MinLength            int       `json:"min_length"`
+	RequireUppercase     bool      `json:"require_uppercase"`
+	RequireLowercase     bool      `json:"require_lowercase"`
+	RequireNumber        bool      `json:"require_number"`
+	RequireSymbol        bool      `json:"require_symbol"`
+	DisallowPersonalData bool      `json:"disallow_personal_data"`
+	HistorySize          int       `json:"history_size"`
+	MaxAgeDays           int       `json:"max_age_days"`
+	UpdatedAt            time.Time `json:"updated_at,omitempty"`
+}
+
+// PasswordViolation is a rule of the policy the password does not meet
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// DefaultPasswordPolicy is used by the tenant groups without a policy: at least 8 characters
+// with an uppercase letter, a number and a symbol
+func DefaultPasswordPolicy(groupID uuid.UUID) *PasswordPolicy {
+	return &PasswordPolicy{
+		GroupID:          groupID,
+		MinLength:        8,
+		RequireUppercase: true,
+		RequireNumber:    true,
+		RequireSymbol:    true,
+	}
+}
+
+// Check returns every rule the password breaks, except the history that needs the stored hashes
+func (p *PasswordPolicy) Check(user *User, password string) []PasswordViolation {
+	violations := []PasswordViolation{}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{PasswordRuleMinLength, fmt.Sprintf("password must be at least %d characters long", p.MinLength)})
+	}
+	// bcrypt counts bytes, an accented letter takes two
+	if len(password) > PasswordMaxBytes {
+		violations = append(violations, PasswordViolation{PasswordRuleMaxLength, fmt.Sprintf("password must be at most %d bytes long", PasswordMaxBytes)})
+	}
+
+	var upper, lower, number, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			number = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUppercase && !upper {
+		violations = append(violations, PasswordViolation{PasswordRuleUppercase, "password must contain at least one uppercase letter"})
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, PasswordViolation{PasswordRuleLowercase, "password must contain at least one lowercase letter"})
+	}
+	if p.RequireNumber && !number {
+		violations = append(violations, PasswordViolation{PasswordRuleNumber, "password must contain at least one number"})
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, PasswordViolation{PasswordRuleSymbol, "password must contain at least one symbol"})
+	}
+
+	if p.DisallowPersonalData && user != nil && containsPersonalData(user, password) {
+		violations = append(violations, PasswordViolation{PasswordRulePersonalData, "password must not contain the username, email or CPF"})
+	}
+
+	return violations
+}
+
+// HistoryViolation is reported when the password is one of the last HistorySize passwords
+func (p *PasswordPolicy) HistoryViolation() PasswordViolation {
+	return PasswordViolation{PasswordRuleHistory, fmt.Sprintf("password must differ from the last %d passwords", p.HistorySize)}
+}
+
+// Expired reports whether a password changed at changedAt is older than MaxAgeDays
+func (p *PasswordPolicy) Expired(changedAt time.Time) bool {
+	if p.MaxAgeDays <= 0 || changedAt.IsZero() {
+		return false
+	}
+	return time.Since(changedAt) > time.Duration(p.MaxAgeDays)*24*time.Hour
+}
+
+// containsPersonalData looks for the username, the local part of the email and the CPF digits
+// in the password. The username is the CPF of most users, so its digits are also compared
+// with the digits of the password, ignoring the punctuation of a formatted CPF
+func containsPersonalData(user *User, password string) bool {
+	lowered := strings.ToLower(password)
+
+	pieces := []string{user.Username}
+	if at := strings.LastIndex(user.Email, "@"); at > 0 {
+		pieces = append(pieces, user.Email[:at])
+	}
+	for _, piece := range pieces {
+		piece = strings.ToLower(strings.TrimSpace(piece))
+		if utf8.RuneCountInString(piece) >= personalDataMinLength && strings.Contains(lowered, piece) {
+			return true
+		}
+	}
+
+	if cpf := onlyDigits(user.Username); len(cpf) == 11 {
+		return strings.Contains(onlyDigits(password), cpf)
+	}
+	return false
+}
+
+func onlyDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
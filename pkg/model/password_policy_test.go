@@ -0,0 +1,105 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func rulesOf(violations []PasswordViolation) map[string]bool {
+	rules := map[string]bool{}
+	for _, violation := range violations {
+		rules[violation.Rule] = true
+	}
+	return rules
+}
+
+func TestPasswordPolicyCheckListsEveryRule(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 10, RequireUppercase: true, RequireLowercase: true, RequireNumber: true, RequireSymbol: true}
+
+	rules := rulesOf(policy.Check(nil, "abc"))
+	for _, rule := range []string{PasswordRuleMinLength, PasswordRuleUppercase, PasswordRuleNumber, PasswordRuleSymbol} {
+		if !rules[rule] {
+			t.Errorf("regra %s ausente", rule)
+		}
+	}
+	if rules[PasswordRuleLowercase] {
+		t.Error("a senha tem letra minúscula")
+	}
+
+	if violations := policy.Check(nil, "Senha#Forte2024"); len(violations) != 0 {
+		t.Errorf("senha válida rejeitada: %v", violations)
+	}
+}
+
+func TestPasswordPolicyCountsCharacters(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 8}
+
+	// 8 caracteres acentuados ocupam mais de 8 bytes
+	if violations := policy.Check(nil, "áéíóúãõç"); len(violations) != 0 {
+		t.Errorf("esperado nenhum erro, mas obteve %v", violations)
+	}
+	if violations := policy.Check(nil, "áéíóúãõ"); !rulesOf(violations)[PasswordRuleMinLength] {
+		t.Error("7 caracteres deveriam violar o tamanho mínimo")
+	}
+}
+
+func TestPasswordPolicyLimitsBytes(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 8}
+
+	if violations := policy.Check(nil, strings.Repeat("a", PasswordMaxBytes)); len(violations) != 0 {
+		t.Errorf("esperado nenhum erro, mas obteve %v", violations)
+	}
+	if violations := policy.Check(nil, strings.Repeat("a", PasswordMaxBytes+1)); !rulesOf(violations)[PasswordRuleMaxLength] {
+		t.Error("73 bytes deveriam violar o tamanho máximo")
+	}
+
+	// 40 caracteres acentuados são 80 bytes, mais do que o bcrypt aceita
+	if violations := policy.Check(nil, strings.Repeat("á", 40)); !rulesOf(violations)[PasswordRuleMaxLength] {
+		t.Error("o tamanho máximo é contado em bytes")
+	}
+}
+
+func TestPasswordPolicyDisallowsPersonalData(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 8, DisallowPersonalData: true}
+	user := &User{Username: "52998224725", Email: "Maria.Silva@escola.br"}
+
+	cases := map[string]bool{
+		"xx52998224725yy":   true,
+		"529.982.247-25!":   true,
+		"#maria.silva#2024": true,
+		"MARIA.SILVA":       true,
+		"escola.br-2024":    false,
+		"Senha#Forte2024":   false,
+	}
+
+	for password, rejected := range cases {
+		if got := rulesOf(policy.Check(user, password))[PasswordRulePersonalData]; got != rejected {
+			t.Errorf("senha %q: esperado %v, mas obteve %v", password, rejected, got)
+		}
+	}
+
+	policy.DisallowPersonalData = false
+	if violations := policy.Check(user, "xx52998224725yy"); len(violations) != 0 {
+		t.Errorf("regra desligada: esperado nenhum erro, mas obteve %v", violations)
+	}
+}
+
+func TestPasswordPolicyExpired(t *testing.T) {
+	policy := DefaultPasswordPolicy(uuid.Nil)
+	old := time.Now().Add(-100 * 24 * time.Hour)
+
+	if policy.Expired(old) {
+		t.Error("sem idade máxima a senha não expira")
+	}
+
+	policy.MaxAgeDays = 90
+	if !policy.Expired(old) {
+		t.Error("senha de 100 dias deveria expirar com idade máxima de 90")
+	}
+	if policy.Expired(time.Now().Add(-89 * 24 * time.Hour)) {
+		t.Error("senha de 89 dias não deveria expirar")
+	}
+}
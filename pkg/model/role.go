@@ -0,0 +1,63 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SystemRoles are the roles the API itself checks, seeded as global roles of tb_role
+var SystemRoles = []string{RoleAdmin, RoleGrupoEducacional, RoleInstituicao, RoleProfessor, RoleEstudante}
+
+// roleNamePattern keeps role names safe for the space separated lists of the services
+var roleNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{1,49}$`)
+
+// Role is an entry of the role catalog. Global roles have no GroupID; a tenant group
+// overrides the global role of the same name (description, enabled) with its own entry
+// or adds roles only its tenants can grant. System roles cannot be deleted.
+type Role struct {
+	ID          uuid.UUID `json:"id"`
+	GroupID     uuid.UUID `json:"group_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Enable      bool      `json:"enable"`
+	System      bool      `json:"system"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+func NewRole(role_request *Role) (*Role, error) {
+	role := &Role{
+		ID:          uuid.New(),
+		GroupID:     role_request.GroupID,
+		Name:        role_request.Name,
+		Description: role_request.Description,
+		Enable:      role_request.Enable,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	return role, nil
+}
+
+// Global reports whether the role belongs to the global catalog
+func (r *Role) Global() bool {
+	return r.GroupID == uuid.Nil
+}
+
+// ValidRoleName checks the format of a role name (letters, digits and _)
+func ValidRoleName(name string) bool {
+	return roleNamePattern.MatchString(name)
+}
+
+// ScopeLevelForRoles returns the widest scope level granted by any of the roles
+func ScopeLevelForRoles(roles ...string) ScopeLevel {
+	level := ScopeTenant
+	for _, role := range roles {
+		if l := ScopeLevelForRole(role); l > level {
+			level = l
+		}
+	}
+	return level
+}
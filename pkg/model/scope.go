@@ -0,0 +1,87 @@
+package model
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type ScopeLevel int
+
+const (
+	// ScopeTenant restricts access to the caller's own tenant
+	ScopeTenant ScopeLevel = iota
+	// ScopeGroup extends access to every tenant of the caller's tenant group
+	ScopeGroup
+	// ScopeGlobal gives access to every tenant (Admin)
+	ScopeGlobal
+)
+
+// TenantScope describes which tenants a request is allowed to read or change
+type TenantScope struct {
+	Level    ScopeLevel `json:"level"`
+	TenantID uuid.UUID  `json:"tenant_id"`
+	GroupID  uuid.UUID  `json:"group_id"`
+}
+
+type tenantScopeKey struct{}
+
+// ScopeLevelForRole returns the scope level granted to a role
+func ScopeLevelForRole(role string) ScopeLevel {
+	switch role {
+	case RoleAdmin:
+		return ScopeGlobal
+	case RoleGrupoEducacional:
+		return ScopeGroup
+	default:
+		return ScopeTenant
+	}
+}
+
+// GlobalScope is used by internal flows (login, token refresh) that are not bound to a caller
+func GlobalScope() TenantScope {
+	return TenantScope{Level: ScopeGlobal}
+}
+
+// Filter returns the values used by the services in the SQL tenant filter
+// "($1 OR tenant_id = $2 OR group_id = $3)". The group is only returned
+// for group level scopes so a tenant scope never matches its siblings.
+func (s TenantScope) Filter() (all bool, tenantID uuid.UUID, groupID uuid.UUID) {
+	if s.Level == ScopeGroup {
+		groupID = s.GroupID
+	}
+
+	return s.Level == ScopeGlobal, s.TenantID, groupID
+}
+
+// AllowsTenant checks a tenant against the scope
+func (s TenantScope) AllowsTenant(tenantID, groupID uuid.UUID) bool {
+	switch s.Level {
+	case ScopeGlobal:
+		return true
+	case ScopeGroup:
+		return s.GroupID != uuid.Nil && s.GroupID == groupID
+	default:
+		return s.TenantID != uuid.Nil && s.TenantID == tenantID
+	}
+}
+
+// AllowsGroup checks a tenant group against the scope
+func (s TenantScope) AllowsGroup(groupID uuid.UUID) bool {
+	return s.Level == ScopeGlobal || (s.GroupID != uuid.Nil && s.GroupID == groupID)
+}
+
+func ContextWithTenantScope(ctx context.Context, scope TenantScope) context.Context {
+	return context.WithValue(ctx, tenantScopeKey{}, scope)
+}
+
+// TenantScopeFromContext returns the scope set by TenantMiddleware. Without
+// one the zero scope is returned, which matches no tenant at all.
+func TenantScopeFromContext(ctx context.Context) TenantScope {
+	scope, ok := ctx.Value(tenantScopeKey{}).(TenantScope)
+	if !ok {
+		return TenantScope{}
+	}
+
+	return scope
+}
@@ -10,6 +10,18 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Roles accepted for model.User.Role
+const (
+	RoleProfessor   = "Professor"
+	RoleEstudante   = "Estudante"
+	RoleInstituicao = "Instituicao"
+	RoleAdmin       = "Admin"
+	// RoleGrupoEducacional manages every tenant of its tenant group
+	RoleGrupoEducacional = "GrupoEducacional"
+)
+
+// User.Role is the main role of the user and Roles every role of the user
+// in its tenant (tb_user_role), Role included
 type User struct {
 	ID             uuid.UUID `json:"id"`
 	TenantID       uuid.UUID `json:"tenant_id"`
@@ -22,10 +34,24 @@ type User struct {
 	Enable         bool      `json:"enable"`
 	ChangePassword bool      `json:"change_password"`
 	Role           string    `json:"role"`
+	Roles          []string  `json:"roles,omitempty"`
 	CreatedAt      time.Time `json:"created_at,omitempty"`
 	UpdatedAt      time.Time `json:"updated_at,omitempty"`
 }
 
+// AllRoles returns the main role followed by the other roles of the user, without repetitions
+func (u *User) AllRoles() []string {
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, role := range append([]string{u.Role}, u.Roles...) {
+		if role != "" && !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
 func (u *User) passwordToHash() {
 	if u.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), 10)
@@ -91,6 +117,7 @@ func NewUser(user_request *User) (*User, error) {
 		Enable:         true,
 		ChangePassword: true,
 		Role:           user_request.Role,
+		Roles:          user_request.Roles,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserCredential is a passkey (WebAuthn public key credential) of a user
+type UserCredential struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	// CredentialID is the base64url ID chosen by the authenticator
+	CredentialID string `json:"credential_id"`
+	// PublicKey is the COSE_Key of the credential
+	PublicKey  []byte     `json:"-"`
+	SignCount  uint32     `json:"sign_count"`
+	AAGUID     uuid.UUID  `json:"aaguid"`
+	Transports []string   `json:"transports"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func NewUserCredential(credential_request *UserCredential) (*UserCredential, error) {
+	credential := &UserCredential{
+		ID:           uuid.New(),
+		UserID:       credential_request.UserID,
+		CredentialID: credential_request.CredentialID,
+		PublicKey:    credential_request.PublicKey,
+		SignCount:    credential_request.SignCount,
+		AAGUID:       credential_request.AAGUID,
+		Transports:   credential_request.Transports,
+		Name:         credential_request.Name,
+		CreatedAt:    time.Now(),
+	}
+
+	return credential, nil
+}
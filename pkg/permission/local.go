@@ -0,0 +1,296 @@
+package permission
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// maxCheckDepth limita a resolução de relações aninhadas, evitando laços no modelo
+const maxCheckDepth = 25
+
+var (
+	ErrConditionNotSupported = errors.New("o motor local não avalia condições de tuplas")
+	ErrMaxDepthExceeded      = errors.New("profundidade máxima de resolução de relações atingida")
+)
+
+// TupleStore guarda as tuplas avaliadas pelo LocalPermissionService
+type TupleStore interface {
+	// ReadTuples retorna as tuplas diretas de object#relation
+	ReadTuples(ctx context.Context, object Object, relation string) ([]Tuple, error)
+	// ListObjectIDs retorna os IDs dos objetos do tipo que aparecem em alguma tupla
+	ListObjectIDs(ctx context.Context, objectType string) ([]string, error)
+	// ListUsers retorna os usuários (ou usersets, se userRelation não for vazio) do tipo que aparecem em alguma tupla
+	ListUsers(ctx context.Context, userType, userRelation string) ([]Object, error)
+	WriteTuples(ctx context.Context, writes, deletes []Tuple) error
+}
+
+// LocalPermissionService avalia o modelo de autorização no próprio processo, sem um
+// servidor OpenFGA. Suporta relações diretas, computedUserset, tupleToUserset, union,
+// intersection e difference; condições não são suportadas.
+type LocalPermissionService struct {
+	store   TupleStore
+	types   map[string]map[string]openfga.Userset
+	modelID string
+}
+
+// NewLocalPermissionService interpreta o modelo no formato JSON de GetDefaultEducationalModel.
+// O ID do modelo é derivado do conteúdo, para identificar a versão avaliada.
+func NewLocalPermissionService(store TupleStore, modelJSON string) (*LocalPermissionService, error) {
+	var model openfga.WriteAuthorizationModelRequest
+	if err := json.Unmarshal([]byte(modelJSON), &model); err != nil {
+		return nil, fmt.Errorf("erro ao fazer parse do JSON: %v", err)
+	}
+
+	types := make(map[string]map[string]openfga.Userset, len(model.TypeDefinitions))
+	for _, typeDefinition := range model.TypeDefinitions {
+		types[typeDefinition.Type] = typeDefinition.GetRelations()
+	}
+
+	sum := sha256.Sum256([]byte(modelJSON))
+
+	return &LocalPermissionService{
+		store:   store,
+		types:   types,
+		modelID: "local-" + hex.EncodeToString(sum[:6]),
+	}, nil
+}
+
+// AddRelation adiciona uma relação entre user e tenant
+func (ls *LocalPermissionService) AddRelation(ctx context.Context, userID, tenantID, relation string) error {
+	return ls.Write(ctx, []Tuple{NewTuple(UserObject(userID), relation, TenantObject(tenantID))}, nil)
+}
+
+// RemoveRelation remove uma relação entre user e tenant
+func (ls *LocalPermissionService) RemoveRelation(ctx context.Context, userID, tenantID, relation string) error {
+	return ls.Write(ctx, nil, []Tuple{NewTuple(UserObject(userID), relation, TenantObject(tenantID))})
+}
+
+// CheckPermission verifica se o user tem permissão (relation) sobre o tenant
+func (ls *LocalPermissionService) CheckPermission(ctx context.Context, userID, tenantID, relation string) (bool, error) {
+	result, err := ls.Check(ctx, CheckRequest{User: UserObject(userID), Relation: relation, Object: TenantObject(tenantID)})
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Write grava e remove tuplas; tuplas com condição são rejeitadas
+func (ls *LocalPermissionService) Write(ctx context.Context, writes, deletes []Tuple) error {
+	if err := rejectConditions(writes); err != nil {
+		return err
+	}
+	return ls.store.WriteTuples(ctx, writes, deletes)
+}
+
+// Check verifica se o user (ou userset) tem a relação sobre o objeto
+func (ls *LocalPermissionService) Check(ctx context.Context, req CheckRequest) (*CheckResult, error) {
+	if err := rejectConditions(req.ContextualTuples); err != nil {
+		return nil, err
+	}
+
+	allowed, err := ls.check(ctx, req.User, req.Relation, req.Object, req.ContextualTuples, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckResult{Allowed: allowed, AuthorizationModelID: ls.modelID}, nil
+}
+
+// ListObjects verifica cada objeto do tipo presente nas tuplas
+func (ls *LocalPermissionService) ListObjects(ctx context.Context, req ListObjectsRequest) ([]Object, error) {
+	if err := rejectConditions(req.ContextualTuples); err != nil {
+		return nil, err
+	}
+
+	ids, err := ls.store.ListObjectIDs(ctx, req.ObjectType)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range req.ContextualTuples {
+		if t.Object.Type == req.ObjectType {
+			ids = append(ids, t.Object.ID)
+		}
+	}
+
+	objects := []Object{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		object := NewObject(req.ObjectType, id)
+		allowed, err := ls.check(ctx, req.User, req.Relation, object, req.ContextualTuples, 0)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
+
+// ListUsers verifica cada usuário (ou userset) do tipo presente nas tuplas
+func (ls *LocalPermissionService) ListUsers(ctx context.Context, req ListUsersRequest) ([]Object, error) {
+	if err := rejectConditions(req.ContextualTuples); err != nil {
+		return nil, err
+	}
+
+	candidates, err := ls.store.ListUsers(ctx, req.UserType, req.UserRelation)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range req.ContextualTuples {
+		if t.User.Type == req.UserType && t.User.Relation == req.UserRelation {
+			candidates = append(candidates, t.User)
+		}
+	}
+
+	users := []Object{}
+	seen := map[Object]bool{}
+	for _, user := range candidates {
+		if seen[user] {
+			continue
+		}
+		seen[user] = true
+
+		allowed, err := ls.check(ctx, user, req.Relation, req.Object, req.ContextualTuples, 0)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+// check resolve se user tem relation sobre object seguindo a regra da relação no modelo.
+// Relações não definidas no tipo resultam em false.
+func (ls *LocalPermissionService) check(ctx context.Context, user Object, relation string, object Object, contextual []Tuple, depth int) (bool, error) {
+	if depth > maxCheckDepth {
+		return false, ErrMaxDepthExceeded
+	}
+
+	// O userset tenant:abc#admin tem, por definição, a relação admin sobre tenant:abc
+	if user.Relation == relation && user.Type == object.Type && user.ID == object.ID {
+		return true, nil
+	}
+
+	rewrite, ok := ls.types[object.Type][relation]
+	if !ok {
+		return false, nil
+	}
+
+	return ls.evaluate(ctx, user, relation, object, rewrite, contextual, depth)
+}
+
+func (ls *LocalPermissionService) evaluate(ctx context.Context, user Object, relation string, object Object, rewrite openfga.Userset, contextual []Tuple, depth int) (bool, error) {
+	switch {
+	case rewrite.ComputedUserset != nil:
+		return ls.check(ctx, user, rewrite.ComputedUserset.GetRelation(), object, contextual, depth+1)
+
+	case rewrite.TupleToUserset != nil:
+		tuples, err := ls.readTuples(ctx, object, rewrite.TupleToUserset.Tupleset.GetRelation(), contextual)
+		if err != nil {
+			return false, err
+		}
+		for _, t := range tuples {
+			parent := NewObject(t.User.Type, t.User.ID)
+			allowed, err := ls.check(ctx, user, rewrite.TupleToUserset.ComputedUserset.GetRelation(), parent, contextual, depth+1)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+		return false, nil
+
+	case rewrite.Union != nil:
+		for _, child := range rewrite.Union.Child {
+			allowed, err := ls.evaluate(ctx, user, relation, object, child, contextual, depth)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+		return false, nil
+
+	case rewrite.Intersection != nil:
+		for _, child := range rewrite.Intersection.Child {
+			allowed, err := ls.evaluate(ctx, user, relation, object, child, contextual, depth)
+			if err != nil || !allowed {
+				return false, err
+			}
+		}
+		return len(rewrite.Intersection.Child) > 0, nil
+
+	case rewrite.Difference != nil:
+		allowed, err := ls.evaluate(ctx, user, relation, object, rewrite.Difference.Base, contextual, depth)
+		if err != nil || !allowed {
+			return false, err
+		}
+		excluded, err := ls.evaluate(ctx, user, relation, object, rewrite.Difference.Subtract, contextual, depth)
+		return !excluded, err
+
+	default:
+		// "this" ou relação vazia ({}), como as relações de tenant no modelo padrão
+		return ls.direct(ctx, user, relation, object, contextual, depth)
+	}
+}
+
+// direct avalia as tuplas gravadas diretamente em object#relation
+func (ls *LocalPermissionService) direct(ctx context.Context, user Object, relation string, object Object, contextual []Tuple, depth int) (bool, error) {
+	tuples, err := ls.readTuples(ctx, object, relation, contextual)
+	if err != nil {
+		return false, err
+	}
+
+	for _, t := range tuples {
+		if t.User == user {
+			return true, nil
+		}
+		if t.User.ID == "*" && t.User.Relation == "" && t.User.Type == user.Type && user.Relation == "" {
+			return true, nil
+		}
+		if t.User.Relation != "" {
+			allowed, err := ls.check(ctx, user, t.User.Relation, NewObject(t.User.Type, t.User.ID), contextual, depth+1)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (ls *LocalPermissionService) readTuples(ctx context.Context, object Object, relation string, contextual []Tuple) ([]Tuple, error) {
+	tuples, err := ls.store.ReadTuples(ctx, object, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range contextual {
+		if t.Relation == relation && t.Object == object {
+			tuples = append(tuples, t)
+		}
+	}
+
+	return tuples, nil
+}
+
+func rejectConditions(tuples []Tuple) error {
+	for _, t := range tuples {
+		if t.Condition != nil {
+			return ErrConditionNotSupported
+		}
+	}
+	return nil
+}
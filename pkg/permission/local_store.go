@@ -0,0 +1,123 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+)
+
+// PostgresTupleStore guarda as tuplas do motor local em tb_fga_tuple
+type PostgresTupleStore struct {
+	dbp pgsql.DatabaseInterface
+}
+
+func NewPostgresTupleStore(database_pool pgsql.DatabaseInterface) *PostgresTupleStore {
+	return &PostgresTupleStore{
+		dbp: database_pool,
+	}
+}
+
+func (s *PostgresTupleStore) ReadTuples(ctx context.Context, object Object, relation string) ([]Tuple, error) {
+	rows, err := s.dbp.GetDB().QueryContext(ctx, `
+        SELECT user_type, user_id, user_relation
+        FROM tb_fga_tuple
+        WHERE object_type = $1 AND object_id = $2 AND relation = $3`, object.Type, object.ID, relation)
+	if err != nil {
+		logger.Error("Error reading FGA tuples", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tuples := []Tuple{}
+	for rows.Next() {
+		user := Object{}
+		if err := rows.Scan(&user.Type, &user.ID, &user.Relation); err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, NewTuple(user, relation, object))
+	}
+
+	return tuples, rows.Err()
+}
+
+func (s *PostgresTupleStore) ListObjectIDs(ctx context.Context, objectType string) ([]string, error) {
+	rows, err := s.dbp.GetDB().QueryContext(ctx,
+		"SELECT DISTINCT object_id FROM tb_fga_tuple WHERE object_type = $1 ORDER BY object_id", objectType)
+	if err != nil {
+		logger.Error("Error listing FGA objects", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (s *PostgresTupleStore) ListUsers(ctx context.Context, userType, userRelation string) ([]Object, error) {
+	rows, err := s.dbp.GetDB().QueryContext(ctx, `
+        SELECT DISTINCT user_id
+        FROM tb_fga_tuple
+        WHERE user_type = $1 AND user_relation = $2
+        ORDER BY user_id`, userType, userRelation)
+	if err != nil {
+		logger.Error("Error listing FGA users", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []Object{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, NewObject(userType, id).Userset(userRelation))
+	}
+
+	return users, rows.Err()
+}
+
+// WriteTuples aplica gravações e remoções em uma transação. Gravar uma tupla existente
+// ou remover uma inexistente não é erro, como em AddRelation/RemoveRelation do OpenFGA.
+func (s *PostgresTupleStore) WriteTuples(ctx context.Context, writes, deletes []Tuple) error {
+	tx, err := s.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range deletes {
+		_, err := tx.ExecContext(ctx, `
+            DELETE FROM tb_fga_tuple
+            WHERE object_type = $1 AND object_id = $2 AND relation = $3
+              AND user_type = $4 AND user_id = $5 AND user_relation = $6`,
+			t.Object.Type, t.Object.ID, t.Relation, t.User.Type, t.User.ID, t.User.Relation)
+		if err != nil {
+			logger.Error("Error deleting FGA tuple", err)
+			return err
+		}
+	}
+
+	for _, t := range writes {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO tb_fga_tuple (object_type, object_id, relation, user_type, user_id, user_relation)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT DO NOTHING`,
+			t.Object.Type, t.Object.ID, t.Relation, t.User.Type, t.User.ID, t.User.Relation)
+		if err != nil {
+			logger.Error("Error writing FGA tuple", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,231 @@
+package permission
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+// memoryTupleStore implementa TupleStore em memória
+type memoryTupleStore struct {
+	tuples map[Tuple]bool
+}
+
+func newMemoryTupleStore() *memoryTupleStore {
+	return &memoryTupleStore{tuples: map[Tuple]bool{}}
+}
+
+func (m *memoryTupleStore) ReadTuples(ctx context.Context, object Object, relation string) ([]Tuple, error) {
+	var tuples []Tuple
+	for t := range m.tuples {
+		if t.Object == object && t.Relation == relation {
+			tuples = append(tuples, t)
+		}
+	}
+	return tuples, nil
+}
+
+func (m *memoryTupleStore) ListObjectIDs(ctx context.Context, objectType string) ([]string, error) {
+	var ids []string
+	for t := range m.tuples {
+		if t.Object.Type == objectType {
+			ids = append(ids, t.Object.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (m *memoryTupleStore) ListUsers(ctx context.Context, userType, userRelation string) ([]Object, error) {
+	var users []Object
+	for t := range m.tuples {
+		if t.User.Type == userType && t.User.Relation == userRelation {
+			users = append(users, t.User)
+		}
+	}
+	return users, nil
+}
+
+func (m *memoryTupleStore) WriteTuples(ctx context.Context, writes, deletes []Tuple) error {
+	for _, t := range deletes {
+		delete(m.tuples, t)
+	}
+	for _, t := range writes {
+		m.tuples[t] = true
+	}
+	return nil
+}
+
+const schoolModelDSL = `model
+  schema 1.1
+
+type user
+
+type tenant_group
+  relations
+    define admin: [user]
+
+type tenant
+  relations
+    define group: [tenant_group]
+    define admin: [user] or admin from group
+    define professor: [user, tenant#admin]
+    define estudante: [user]
+    define suspended: [user]
+
+type quiz
+  relations
+    define tenant: [tenant]
+    define owner: [user]
+    define public: [user:*]
+    define can_read: (owner or professor from tenant or estudante from tenant or public) but not suspended from tenant
+    define can_grade: owner and professor from tenant
+`
+
+func newSchoolService(t *testing.T) *LocalPermissionService {
+	t.Helper()
+
+	modelJSON, err := ModelJSONFromDSL(schoolModelDSL)
+	if err != nil {
+		t.Fatalf("erro ao converter DSL: %v", err)
+	}
+
+	service, err := NewLocalPermissionService(newMemoryTupleStore(), modelJSON)
+	if err != nil {
+		t.Fatalf("erro ao criar motor local: %v", err)
+	}
+
+	err = service.Write(context.Background(), []Tuple{
+		NewTuple(NewObject(TypeTenantGroup, "g1"), "group", TenantObject("t1")),
+		NewTuple(UserObject("diretor"), "admin", NewObject(TypeTenantGroup, "g1")),
+		NewTuple(TenantObject("t1").Userset("admin"), "professor", TenantObject("t1")),
+		NewTuple(UserObject("prof"), "professor", TenantObject("t1")),
+		NewTuple(UserObject("aluno"), "estudante", TenantObject("t1")),
+		NewTuple(UserObject("suspenso"), "estudante", TenantObject("t1")),
+		NewTuple(UserObject("suspenso"), "suspended", TenantObject("t1")),
+		NewTuple(TenantObject("t1"), "tenant", NewObject(TypeQuiz, "q1")),
+		NewTuple(UserObject("prof"), "owner", NewObject(TypeQuiz, "q1")),
+		NewTuple(TenantObject("t2"), "tenant", NewObject(TypeQuiz, "q2")),
+		NewTuple(NewObject(TypeUser, "*"), "public", NewObject(TypeQuiz, "q3")),
+	}, nil)
+	if err != nil {
+		t.Fatalf("erro ao gravar tuplas: %v", err)
+	}
+
+	return service
+}
+
+func TestLocalCheck(t *testing.T) {
+	service := newSchoolService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		user     Object
+		relation string
+		object   Object
+		expected bool
+	}{
+		{"relação direta", UserObject("aluno"), "estudante", TenantObject("t1"), true},
+		{"tupleToUserset", UserObject("aluno"), "can_read", NewObject(TypeQuiz, "q1"), true},
+		{"outro tenant", UserObject("aluno"), "can_read", NewObject(TypeQuiz, "q2"), false},
+		{"admin do grupo", UserObject("diretor"), "admin", TenantObject("t1"), true},
+		{"userset tenant#admin", UserObject("diretor"), "can_read", NewObject(TypeQuiz, "q1"), true},
+		{"userset como usuário", TenantObject("t1").Userset("admin"), "professor", TenantObject("t1"), true},
+		{"difference", UserObject("suspenso"), "can_read", NewObject(TypeQuiz, "q1"), false},
+		{"intersection", UserObject("prof"), "can_grade", NewObject(TypeQuiz, "q1"), true},
+		{"intersection parcial", UserObject("diretor"), "can_grade", NewObject(TypeQuiz, "q1"), false},
+		{"wildcard", UserObject("qualquer"), "can_read", NewObject(TypeQuiz, "q3"), true},
+		{"relação inexistente", UserObject("aluno"), "can_fly", NewObject(TypeQuiz, "q1"), false},
+	}
+
+	for _, tt := range tests {
+		result, err := service.Check(ctx, CheckRequest{User: tt.user, Relation: tt.relation, Object: tt.object})
+		if err != nil {
+			t.Errorf("%s: erro inesperado %v", tt.name, err)
+			continue
+		}
+		if result.Allowed != tt.expected {
+			t.Errorf("%s: esperado %v, mas obteve %v", tt.name, tt.expected, result.Allowed)
+		}
+		if result.AuthorizationModelID != service.modelID {
+			t.Errorf("%s: modelo %q não informado", tt.name, result.AuthorizationModelID)
+		}
+	}
+}
+
+func TestLocalContextualTuplesAndConditions(t *testing.T) {
+	service := newSchoolService(t)
+	ctx := context.Background()
+
+	req := CheckRequest{
+		User:             UserObject("visitante"),
+		Relation:         "can_read",
+		Object:           NewObject(TypeQuiz, "q2"),
+		ContextualTuples: []Tuple{NewTuple(UserObject("visitante"), "estudante", TenantObject("t2"))},
+	}
+	result, err := service.Check(ctx, req)
+	if err != nil || !result.Allowed {
+		t.Errorf("tupla contextual não considerada: %v %v", result, err)
+	}
+
+	if result, _ := service.Check(ctx, CheckRequest{User: req.User, Relation: req.Relation, Object: req.Object}); result.Allowed {
+		t.Error("tupla contextual não deveria ser persistida")
+	}
+
+	conditional := NewTuple(UserObject("aluno"), "estudante", TenantObject("t2"))
+	conditional.Condition = &Condition{Name: "in_period"}
+	if err := service.Write(ctx, []Tuple{conditional}, nil); err != ErrConditionNotSupported {
+		t.Errorf("esperado ErrConditionNotSupported, mas obteve %v", err)
+	}
+}
+
+func TestLocalListObjectsAndUsers(t *testing.T) {
+	service := newSchoolService(t)
+	ctx := context.Background()
+
+	objects, err := service.ListObjects(ctx, ListObjectsRequest{User: UserObject("aluno"), Relation: "can_read", ObjectType: TypeQuiz})
+	if err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	if len(objects) != 2 || objects[0].String() != "quiz:q1" || objects[1].String() != "quiz:q3" {
+		t.Errorf("esperado [quiz:q1 quiz:q3], mas obteve %v", objects)
+	}
+
+	users, err := service.ListUsers(ctx, ListUsersRequest{Object: TenantObject("t1"), Relation: "professor", UserType: TypeUser})
+	if err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	var names []string
+	for _, user := range users {
+		names = append(names, user.String())
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "user:diretor" || names[1] != "user:prof" {
+		t.Errorf("esperado [user:diretor user:prof], mas obteve %v", names)
+	}
+}
+
+func TestLocalDefaultEducationalModel(t *testing.T) {
+	service, err := NewLocalPermissionService(newMemoryTupleStore(), GetDefaultEducationalModel())
+	if err != nil {
+		t.Fatalf("erro ao interpretar modelo padrão: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := service.AddRelation(ctx, "u1", "t1", "professor"); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+
+	allowed, err := service.CheckPermission(ctx, "u1", "t1", "professor")
+	if err != nil || !allowed {
+		t.Errorf("esperado professor em tenant:t1: %v %v", allowed, err)
+	}
+
+	if err := service.RemoveRelation(ctx, "u1", "t1", "professor"); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	if allowed, _ := service.CheckPermission(ctx, "u1", "t1", "professor"); allowed {
+		t.Error("relação removida ainda concedida")
+	}
+}
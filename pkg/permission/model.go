@@ -0,0 +1,185 @@
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	openfga "github.com/openfga/go-sdk"
+	"github.com/openfga/language/pkg/go/transformer"
+)
+
+// ModelManagerInterface é o contrato de gestão de versões do modelo de autorização
+type ModelManagerInterface interface {
+	RegisterModelFromJSON(ctx context.Context, modelJSON string) (string, error)
+	ListModels(ctx context.Context, pageSize int32, continuationToken string) ([]openfga.AuthorizationModel, string, error)
+	ReadModel(ctx context.Context, modelID string) (*openfga.AuthorizationModel, error)
+	ActiveModelID(ctx context.Context) (string, error)
+	SetModelID(modelID string)
+}
+
+// ListModels lista as versões do modelo, da mais recente para a mais antiga
+func (ps *PermissionService) ListModels(ctx context.Context, pageSize int32, continuationToken string) ([]openfga.AuthorizationModel, string, error) {
+	req := ps.FGAClient.OpenFgaApi.ReadAuthorizationModels(ctx, ps.StoreID).PageSize(pageSize)
+	if continuationToken != "" {
+		req = req.ContinuationToken(continuationToken)
+	}
+
+	resp, _, err := req.Execute()
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao listar modelos de autorização: %v", err)
+	}
+
+	return resp.GetAuthorizationModels(), resp.GetContinuationToken(), nil
+}
+
+// ReadModel lê uma versão do modelo
+func (ps *PermissionService) ReadModel(ctx context.Context, modelID string) (*openfga.AuthorizationModel, error) {
+	resp, _, err := ps.FGAClient.OpenFgaApi.ReadAuthorizationModel(ctx, ps.StoreID, modelID).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler modelo %s: %v", modelID, err)
+	}
+
+	model := resp.GetAuthorizationModel()
+	return &model, nil
+}
+
+// ModelJSONFromDSL converte um modelo escrito na DSL do OpenFGA para o JSON aceito por RegisterModelFromJSON
+func ModelJSONFromDSL(dsl string) (string, error) {
+	modelJSON, err := transformer.TransformDSLToJSON(dsl)
+	if err != nil {
+		return "", fmt.Errorf("erro ao interpretar DSL: %v", err)
+	}
+
+	return modelJSON, nil
+}
+
+// Tipos de alteração reportados por DiffModels
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ModelChange é a alteração de um tipo, relação ou condição entre duas versões.
+// Relation fica vazio quando a alteração é do tipo inteiro.
+type ModelChange struct {
+	Type     string `json:"type"`
+	Relation string `json:"relation,omitempty"`
+	Change   string `json:"change"`
+}
+
+// ModelDiff é a diferença entre duas versões do modelo
+type ModelDiff struct {
+	From       string        `json:"from"`
+	To         string        `json:"to"`
+	Types      []ModelChange `json:"types"`
+	Conditions []ModelChange `json:"conditions"`
+}
+
+// DiffModels compara duas versões do modelo. Uma relação é considerada alterada quando
+// muda sua regra (union, computedUserset...) ou os tipos que podem ser relacionados diretamente.
+func DiffModels(from, to openfga.AuthorizationModel) ModelDiff {
+	diff := ModelDiff{
+		From:       from.GetId(),
+		To:         to.GetId(),
+		Types:      []ModelChange{},
+		Conditions: []ModelChange{},
+	}
+
+	fromTypes := typeRelations(from)
+	toTypes := typeRelations(to)
+
+	for _, typeName := range sortedKeys(fromTypes, toTypes) {
+		fromRelations, inFrom := fromTypes[typeName]
+		toRelations, inTo := toTypes[typeName]
+
+		switch {
+		case !inTo:
+			diff.Types = append(diff.Types, ModelChange{Type: typeName, Change: ChangeRemoved})
+		case !inFrom:
+			diff.Types = append(diff.Types, ModelChange{Type: typeName, Change: ChangeAdded})
+		default:
+			for _, relation := range sortedKeys(fromRelations, toRelations) {
+				if change := compareDefinitions(fromRelations, toRelations, relation); change != "" {
+					diff.Types = append(diff.Types, ModelChange{Type: typeName, Relation: relation, Change: change})
+				}
+			}
+		}
+	}
+
+	fromConditions := conditionDefinitions(from)
+	toConditions := conditionDefinitions(to)
+	for _, name := range sortedKeys(fromConditions, toConditions) {
+		if change := compareDefinitions(fromConditions, toConditions, name); change != "" {
+			diff.Conditions = append(diff.Conditions, ModelChange{Type: name, Change: change})
+		}
+	}
+
+	return diff
+}
+
+// typeRelations indexa, por tipo e relação, a definição serializada da relação
+func typeRelations(model openfga.AuthorizationModel) map[string]map[string]string {
+	types := make(map[string]map[string]string, len(model.TypeDefinitions))
+
+	for _, typeDefinition := range model.TypeDefinitions {
+		relations := map[string]string{}
+
+		var metadata map[string]openfga.RelationMetadata
+		if typeDefinition.Metadata != nil && typeDefinition.Metadata.Relations != nil {
+			metadata = *typeDefinition.Metadata.Relations
+		}
+
+		for name, userset := range typeDefinition.GetRelations() {
+			definition, _ := json.Marshal(struct {
+				Rewrite  openfga.Userset          `json:"rewrite"`
+				Metadata openfga.RelationMetadata `json:"metadata"`
+			}{userset, metadata[name]})
+			relations[name] = string(definition)
+		}
+
+		types[typeDefinition.Type] = relations
+	}
+
+	return types
+}
+
+func conditionDefinitions(model openfga.AuthorizationModel) map[string]string {
+	conditions := map[string]string{}
+	for name, condition := range model.GetConditions() {
+		definition, _ := json.Marshal(condition)
+		conditions[name] = string(definition)
+	}
+	return conditions
+}
+
+func compareDefinitions(from, to map[string]string, key string) string {
+	fromDefinition, inFrom := from[key]
+	toDefinition, inTo := to[key]
+
+	switch {
+	case !inTo:
+		return ChangeRemoved
+	case !inFrom:
+		return ChangeAdded
+	case fromDefinition != toDefinition:
+		return ChangeChanged
+	}
+	return ""
+}
+
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
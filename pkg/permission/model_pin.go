@@ -0,0 +1,115 @@
+package permission
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+)
+
+const modelPinInterval = 30 * time.Second
+
+// ModelPin é um registro do histórico de modelos fixados
+type ModelPin struct {
+	ModelID  string    `json:"model_id"`
+	PinnedBy string    `json:"pinned_by,omitempty"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// ModelPinStoreInterface guarda qual versão do modelo está ativa
+type ModelPinStoreInterface interface {
+	Pin(ctx context.Context, modelID, pinnedBy string) error
+	Active(ctx context.Context) (string, error)
+	History(ctx context.Context, limit int) ([]ModelPin, error)
+}
+
+// ModelPinStore persiste em tb_fga_model_pin o modelo fixado, para que todas as
+// instâncias avaliem a mesma versão e cada troca fique registrada
+type ModelPinStore struct {
+	dbp pgsql.DatabaseInterface
+}
+
+func NewModelPinStore(database_pool pgsql.DatabaseInterface) *ModelPinStore {
+	return &ModelPinStore{
+		dbp: database_pool,
+	}
+}
+
+// Pin registra modelID como o modelo ativo
+func (s *ModelPinStore) Pin(ctx context.Context, modelID, pinnedBy string) error {
+	_, err := s.dbp.GetDB().ExecContext(ctx,
+		"INSERT INTO tb_fga_model_pin (model_id, pinned_by) VALUES ($1, NULLIF($2, ''))", modelID, pinnedBy)
+	if err != nil {
+		logger.Error("Error pinning authorization model", err)
+	}
+	return err
+}
+
+// Active retorna o modelo fixado mais recente, ou "" se nenhum foi fixado
+func (s *ModelPinStore) Active(ctx context.Context) (string, error) {
+	var modelID string
+	err := s.dbp.GetDB().QueryRowContext(ctx,
+		"SELECT model_id FROM tb_fga_model_pin ORDER BY pinned_at DESC, id DESC LIMIT 1").Scan(&modelID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return modelID, err
+}
+
+// History retorna os últimos modelos fixados, do mais recente para o mais antigo
+func (s *ModelPinStore) History(ctx context.Context, limit int) ([]ModelPin, error) {
+	rows, err := s.dbp.GetDB().QueryContext(ctx,
+		"SELECT model_id, COALESCE(pinned_by, ''), pinned_at FROM tb_fga_model_pin ORDER BY pinned_at DESC, id DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []ModelPin{}
+	for rows.Next() {
+		pin := ModelPin{}
+		if err := rows.Scan(&pin.ModelID, &pin.PinnedBy, &pin.PinnedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, pin)
+	}
+
+	return history, rows.Err()
+}
+
+// Apply aplica no serviço o modelo fixado; sem modelo fixado, fallbackModelID é usado.
+// Se a leitura falhar o serviço mantém o modelo aplicado antes, nunca volta ao mais recente.
+func (s *ModelPinStore) Apply(ctx context.Context, manager ModelManagerInterface, fallbackModelID string) error {
+	modelID, err := s.Active(ctx)
+	if err != nil {
+		return err
+	}
+
+	if modelID == "" {
+		modelID = fallbackModelID
+	}
+	manager.SetModelID(modelID)
+	return nil
+}
+
+// Watch relê periodicamente o modelo fixado, para acompanhar trocas feitas por outras
+// instâncias. O primeiro Apply é feito antes, na inicialização, para que nenhuma
+// verificação rode sem o modelo fixado.
+func (s *ModelPinStore) Watch(ctx context.Context, manager ModelManagerInterface, fallbackModelID string) {
+	ticker := time.NewTicker(modelPinInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Apply(ctx, manager, fallbackModelID); err != nil {
+			logger.Error("Error reading pinned authorization model, keeping the current one", err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	pgsql_mocks "github.com/katana-stuidio/access-control/pkg/adapter/pgsql/mocks"
+)
+
+const activePinQuery = "SELECT model_id FROM tb_fga_model_pin"
+
+// fakePinnedManager guarda o último modelo aplicado
+type fakePinnedManager struct {
+	ModelManagerInterface
+	modelID string
+}
+
+func (f *fakePinnedManager) SetModelID(modelID string) {
+	f.modelID = modelID
+}
+
+func newTestPinStore(t *testing.T) (*ModelPinStore, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar o banco de teste: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	pool := pgsql_mocks.NewMockDatabaseInterface(gomock.NewController(t))
+	pool.EXPECT().GetDB().Return(db).AnyTimes()
+	return NewModelPinStore(pool), mock
+}
+
+func TestModelPinApply(t *testing.T) {
+	store, mock := newTestPinStore(t)
+	manager := &fakePinnedManager{}
+
+	mock.ExpectQuery(activePinQuery).WillReturnRows(sqlmock.NewRows([]string{"model_id"}).AddRow("01PINNED"))
+	if err := store.Apply(context.Background(), manager, "01FALLBACK"); err != nil || manager.modelID != "01PINNED" {
+		t.Errorf("esperado 01PINNED, mas obteve %q (%v)", manager.modelID, err)
+	}
+
+	// Falha na leitura mantém o modelo já aplicado, não volta ao mais recente da store
+	mock.ExpectQuery(activePinQuery).WillReturnError(errors.New("conexão perdida"))
+	if err := store.Apply(context.Background(), manager, "01FALLBACK"); err == nil {
+		t.Error("esperado erro do banco")
+	}
+	if manager.modelID != "01PINNED" {
+		t.Errorf("esperado 01PINNED, mas obteve %q", manager.modelID)
+	}
+
+	// Sem modelo fixado vale SRV_FGA_MODEL_ID
+	mock.ExpectQuery(activePinQuery).WillReturnRows(sqlmock.NewRows([]string{"model_id"}))
+	if err := store.Apply(context.Background(), manager, "01FALLBACK"); err != nil || manager.modelID != "01FALLBACK" {
+		t.Errorf("esperado 01FALLBACK, mas obteve %q (%v)", manager.modelID, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,103 @@
+package permission
+
+import (
+	"encoding/json"
+	"testing"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+const baseModelDSL = `model
+  schema 1.1
+
+type user
+
+type tenant
+  relations
+    define admin: [user]
+    define professor: [user]
+
+type quiz
+  relations
+    define tenant: [tenant]
+    define can_read: professor from tenant
+`
+
+const nextModelDSL = `model
+  schema 1.1
+
+type user
+
+type tenant
+  relations
+    define admin: [user]
+    define professor: [user, tenant#admin]
+    define estudante: [user]
+
+type class
+  relations
+    define tenant: [tenant]
+`
+
+func modelFromDSL(t *testing.T, id, dsl string) openfga.AuthorizationModel {
+	t.Helper()
+
+	modelJSON, err := ModelJSONFromDSL(dsl)
+	if err != nil {
+		t.Fatalf("erro ao converter DSL: %v", err)
+	}
+
+	var model openfga.AuthorizationModel
+	if err := json.Unmarshal([]byte(modelJSON), &model); err != nil {
+		t.Fatalf("JSON gerado inválido: %v", err)
+	}
+	model.Id = id
+	return model
+}
+
+func TestModelJSONFromDSL(t *testing.T) {
+	model := modelFromDSL(t, "m1", baseModelDSL)
+
+	if model.GetSchemaVersion() != "1.1" {
+		t.Errorf("schema_version esperado 1.1, mas obteve %q", model.GetSchemaVersion())
+	}
+	if len(model.TypeDefinitions) != 3 {
+		t.Fatalf("esperado 3 tipos, mas obteve %d", len(model.TypeDefinitions))
+	}
+
+	canRead := model.TypeDefinitions[2].GetRelations()["can_read"]
+	if canRead.TupleToUserset == nil || canRead.TupleToUserset.Tupleset.GetRelation() != "tenant" {
+		t.Errorf("tupleToUserset não convertido: %+v", canRead)
+	}
+
+	if _, err := ModelJSONFromDSL("model\n  schema 1.1\ntype"); err == nil {
+		t.Error("esperado erro para DSL inválida")
+	}
+}
+
+func TestDiffModels(t *testing.T) {
+	diff := DiffModels(modelFromDSL(t, "m1", baseModelDSL), modelFromDSL(t, "m2", nextModelDSL))
+
+	if diff.From != "m1" || diff.To != "m2" {
+		t.Errorf("versões inesperadas %s -> %s", diff.From, diff.To)
+	}
+
+	expected := []ModelChange{
+		{Type: "class", Change: ChangeAdded},
+		{Type: "quiz", Change: ChangeRemoved},
+		{Type: "tenant", Relation: "estudante", Change: ChangeAdded},
+		{Type: "tenant", Relation: "professor", Change: ChangeChanged},
+	}
+	if len(diff.Types) != len(expected) {
+		t.Fatalf("esperado %+v, mas obteve %+v", expected, diff.Types)
+	}
+	for i := range expected {
+		if diff.Types[i] != expected[i] {
+			t.Errorf("alteração %d: esperado %+v, mas obteve %+v", i, expected[i], diff.Types[i])
+		}
+	}
+
+	if same := DiffModels(modelFromDSL(t, "m1", baseModelDSL), modelFromDSL(t, "m1", baseModelDSL)); len(same.Types) != 0 {
+		t.Errorf("modelos iguais não deveriam ter diferenças: %+v", same.Types)
+	}
+}
@@ -0,0 +1,154 @@
+package permission
+
+import (
+	"fmt"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// Tipos de objeto usados pela aplicação no modelo de autorização
+const (
+	TypeUser        = "user"
+	TypeTenant      = "tenant"
+	TypeTenantGroup = "tenant_group"
+	TypeQuiz        = "quiz"
+	TypeClass       = "class"
+)
+
+// Object referencia um objeto do OpenFGA (tipo:id) ou um userset (tipo:id#relação),
+// como tenant:abc#admin. ID "*" representa todos os objetos do tipo (user:*).
+type Object struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Relation string `json:"relation,omitempty"`
+}
+
+// NewObject cria a referência tipo:id
+func NewObject(objectType, id string) Object {
+	return Object{Type: objectType, ID: id}
+}
+
+// UserObject cria a referência user:<id>
+func UserObject(userID string) Object {
+	return NewObject(TypeUser, userID)
+}
+
+// TenantObject cria a referência tenant:<id>
+func TenantObject(tenantID string) Object {
+	return NewObject(TypeTenant, tenantID)
+}
+
+// Userset retorna o userset formado pelos usuários que têm a relação sobre o objeto
+func (o Object) Userset(relation string) Object {
+	o.Relation = relation
+	return o
+}
+
+// String formata a referência no formato do OpenFGA
+func (o Object) String() string {
+	if o.Relation != "" {
+		return fmt.Sprintf("%s:%s#%s", o.Type, o.ID, o.Relation)
+	}
+	return fmt.Sprintf("%s:%s", o.Type, o.ID)
+}
+
+// ParseObject interpreta tipo:id ou tipo:id#relação
+func ParseObject(s string) (Object, error) {
+	objectType, rest, ok := strings.Cut(s, ":")
+	if !ok || objectType == "" {
+		return Object{}, fmt.Errorf("referência de objeto inválida: %q", s)
+	}
+
+	id, relation, hasRelation := strings.Cut(rest, "#")
+	if id == "" || (hasRelation && relation == "") {
+		return Object{}, fmt.Errorf("referência de objeto inválida: %q", s)
+	}
+
+	return Object{Type: objectType, ID: id, Relation: relation}, nil
+}
+
+// Condition é a condição (nome e parâmetros) associada a uma tupla
+type Condition struct {
+	Name    string                 `json:"name"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// Tuple é a tupla user relation object, com condição opcional
+type Tuple struct {
+	User      Object     `json:"user"`
+	Relation  string     `json:"relation"`
+	Object    Object     `json:"object"`
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// NewTuple cria uma tupla sem condição
+func NewTuple(user Object, relation string, object Object) Tuple {
+	return Tuple{User: user, Relation: relation, Object: object}
+}
+
+func (t Tuple) key() openfga.TupleKey {
+	key := openfga.TupleKey{
+		User:     t.User.String(),
+		Relation: t.Relation,
+		Object:   t.Object.String(),
+	}
+	if t.Condition != nil {
+		key.Condition = &openfga.RelationshipCondition{Name: t.Condition.Name}
+		if t.Condition.Context != nil {
+			key.Condition.Context = &t.Condition.Context
+		}
+	}
+	return key
+}
+
+func (t Tuple) keyWithoutCondition() openfga.TupleKeyWithoutCondition {
+	return openfga.TupleKeyWithoutCondition{
+		User:     t.User.String(),
+		Relation: t.Relation,
+		Object:   t.Object.String(),
+	}
+}
+
+func contextualTupleKeys(tuples []Tuple) *openfga.ContextualTupleKeys {
+	if len(tuples) == 0 {
+		return nil
+	}
+
+	keys := make([]openfga.TupleKey, len(tuples))
+	for i, t := range tuples {
+		keys[i] = t.key()
+	}
+	return &openfga.ContextualTupleKeys{TupleKeys: keys}
+}
+
+// CheckRequest pergunta se User tem Relation sobre Object. ContextualTuples são
+// consideradas apenas nesta verificação e Context alimenta as condições do modelo.
+type CheckRequest struct {
+	User             Object
+	Relation         string
+	Object           Object
+	ContextualTuples []Tuple
+	Context          map[string]interface{}
+}
+
+// ListObjectsRequest pergunta quais objetos do tipo ObjectType User tem Relation
+// (ex: quais quizzes o estudante pode ler)
+type ListObjectsRequest struct {
+	User             Object
+	Relation         string
+	ObjectType       string
+	ContextualTuples []Tuple
+	Context          map[string]interface{}
+}
+
+// ListUsersRequest pergunta quais usuários do tipo UserType têm Relation sobre Object.
+// UserRelation filtra usersets (ex: UserType tenant e UserRelation admin).
+type ListUsersRequest struct {
+	Object           Object
+	Relation         string
+	UserType         string
+	UserRelation     string
+	ContextualTuples []Tuple
+	Context          map[string]interface{}
+}
@@ -0,0 +1,52 @@
+package permission
+
+import "testing"
+
+func TestParseObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Object
+		wantErr  bool
+	}{
+		{input: "quiz:123", expected: Object{Type: "quiz", ID: "123"}},
+		{input: "tenant:abc#admin", expected: Object{Type: "tenant", ID: "abc", Relation: "admin"}},
+		{input: "user:*", expected: Object{Type: "user", ID: "*"}},
+		{input: "quiz", wantErr: true},
+		{input: ":123", wantErr: true},
+		{input: "quiz:", wantErr: true},
+		{input: "tenant:abc#", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		object, err := ParseObject(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: esperado erro, mas obteve %+v", tt.input, object)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: erro inesperado %v", tt.input, err)
+			continue
+		}
+		if object != tt.expected {
+			t.Errorf("%q: esperado %+v, mas obteve %+v", tt.input, tt.expected, object)
+		}
+		if object.String() != tt.input {
+			t.Errorf("%q: String() retornou %q", tt.input, object.String())
+		}
+	}
+}
+
+func TestTupleKeyCondition(t *testing.T) {
+	tuple := NewTuple(TenantObject("abc").Userset("admin"), "owner", NewObject(TypeQuiz, "q1"))
+	tuple.Condition = &Condition{Name: "in_period", Context: map[string]interface{}{"start": "2025-01-01"}}
+
+	key := tuple.key()
+	if key.User != "tenant:abc#admin" || key.Object != "quiz:q1" {
+		t.Errorf("tupla inesperada %+v", key)
+	}
+	if key.Condition == nil || key.Condition.Name != "in_period" || (*key.Condition.Context)["start"] != "2025-01-01" {
+		t.Errorf("condição não repassada: %+v", key.Condition)
+	}
+}
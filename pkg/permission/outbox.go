@@ -0,0 +1,229 @@
+package permission
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+)
+
+const (
+	OutboxWrite  = "write"
+	OutboxDelete = "delete"
+
+	outboxBatchSize  = 50
+	outboxInterval   = 5 * time.Second
+	outboxMaxBackoff = 15 * time.Minute
+	// outboxMaxAttempts move a entrada para a dead letter (dead_at), fora da fila
+	outboxMaxAttempts = 20
+)
+
+// RoleBinding é o par role/tenant de um usuário, que vira a tupla user:<id> <relação> tenant:<id>
+type RoleBinding struct {
+	TenantID uuid.UUID
+	Role     string
+}
+
+// EnqueueRoleChange grava na outbox (tb_fga_outbox), dentro da transação que altera o
+// usuário, a remoção da tupla antiga e a escrita da nova. Before é nil na criação
+// e after é nil na exclusão. Nada é gravado se o role e o tenant não mudaram.
+func EnqueueRoleChange(ctx context.Context, tx *sql.Tx, userID uuid.UUID, before, after *RoleBinding) error {
+	if before != nil && after != nil && *before == *after {
+		return nil
+	}
+
+	query := "INSERT INTO tb_fga_outbox (operation, user_id, tenant_id, relation) VALUES ($1, $2, $3, $4)"
+
+	if before != nil {
+		if relation := RelationForRole(before.Role); relation != "" {
+			if _, err := tx.ExecContext(ctx, query, OutboxDelete, userID, before.TenantID, relation); err != nil {
+				logger.Error("Error enqueuing FGA tuple delete", err)
+				return err
+			}
+		}
+	}
+
+	if after != nil {
+		if relation := RelationForRole(after.Role); relation != "" {
+			if _, err := tx.ExecContext(ctx, query, OutboxWrite, userID, after.TenantID, relation); err != nil {
+				logger.Error("Error enqueuing FGA tuple write", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// EnqueueRolesChange grava na outbox as tuplas dos roles removidos e adicionados ao
+// usuário no tenant, quando o conjunto de roles (tb_user_role) é substituído
+func EnqueueRolesChange(ctx context.Context, tx *sql.Tx, userID, tenantID uuid.UUID, before, after []string) error {
+	kept := map[string]bool{}
+	for _, role := range after {
+		kept[role] = true
+	}
+	removed := map[string]bool{}
+	for _, role := range before {
+		removed[role] = true
+	}
+
+	for _, role := range before {
+		if !kept[role] {
+			if err := EnqueueRoleChange(ctx, tx, userID, &RoleBinding{TenantID: tenantID, Role: role}, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, role := range after {
+		if !removed[role] {
+			if err := EnqueueRoleChange(ctx, tx, userID, nil, &RoleBinding{TenantID: tenantID, Role: role}); err != nil {
+				return err
+			}
+			removed[role] = true
+		}
+	}
+
+	return nil
+}
+
+// OutboxWorker aplica no PermissionService as tuplas pendentes da outbox,
+// reagendando com backoff exponencial as que falharem. Após outboxMaxAttempts
+// falhas a entrada vai para a dead letter (dead_at) e deixa de bloquear as seguintes.
+type OutboxWorker struct {
+	dbp        pgsql.DatabaseInterface
+	permission PermissionServiceInterface
+}
+
+func NewOutboxWorker(database_pool pgsql.DatabaseInterface, permissionService PermissionServiceInterface) *OutboxWorker {
+	return &OutboxWorker{
+		dbp:        database_pool,
+		permission: permissionService,
+	}
+}
+
+// Run processa a outbox periodicamente até o contexto ser cancelado
+func (w *OutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.ProcessPending(ctx); err != nil {
+			logger.Error("Error processing FGA outbox", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type outboxEntry struct {
+	id        int64
+	operation string
+	userID    uuid.UUID
+	tenantID  uuid.UUID
+	relation  string
+	attempts  int
+}
+
+// ProcessPending aplica um lote da outbox e retorna quantas entradas foram concluídas.
+// As linhas são travadas com SKIP LOCKED para permitir várias instâncias da API, e uma
+// entrada só é processada quando não há entrada anterior pendente para a mesma tupla,
+// preservando a ordem escrita/remoção.
+func (w *OutboxWorker) ProcessPending(ctx context.Context) (int, error) {
+	tx, err := w.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT o.id, o.operation, o.user_id, o.tenant_id, o.relation, o.attempts
+        FROM tb_fga_outbox o
+        WHERE o.processed_at IS NULL AND o.dead_at IS NULL
+          AND o.next_attempt_at <= now()
+          AND NOT EXISTS (
+            SELECT 1 FROM tb_fga_outbox p
+            WHERE p.processed_at IS NULL AND p.dead_at IS NULL AND p.id < o.id
+              AND p.user_id = o.user_id AND p.tenant_id = o.tenant_id AND p.relation = o.relation)
+        ORDER BY o.id
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED`, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries []outboxEntry
+	for rows.Next() {
+		e := outboxEntry{}
+		if err := rows.Scan(&e.id, &e.operation, &e.userID, &e.tenantID, &e.relation, &e.attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+
+	done := 0
+	for _, e := range entries {
+		applyErr := w.apply(ctx, e)
+		switch {
+		case applyErr != nil && e.attempts+1 >= outboxMaxAttempts:
+			logger.Error(fmt.Sprintf("FGA outbox entry %d moved to the dead letter after %d attempts", e.id, e.attempts+1), applyErr)
+
+			_, err = tx.ExecContext(ctx,
+				"UPDATE tb_fga_outbox SET attempts = attempts + 1, last_error = $1, dead_at = now() WHERE id = $2",
+				applyErr.Error(), e.id)
+		case applyErr != nil:
+			logger.Error(fmt.Sprintf("Error applying FGA outbox entry %d (attempt %d)", e.id, e.attempts+1), applyErr)
+
+			_, err = tx.ExecContext(ctx,
+				"UPDATE tb_fga_outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = now() + make_interval(secs => $2) WHERE id = $3",
+				applyErr.Error(), outboxBackoff(e.attempts+1).Seconds(), e.id)
+		default:
+			done++
+			_, err = tx.ExecContext(ctx, "UPDATE tb_fga_outbox SET processed_at = now(), last_error = NULL WHERE id = $1", e.id)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return done, nil
+}
+
+func (w *OutboxWorker) apply(ctx context.Context, e outboxEntry) error {
+	switch e.operation {
+	case OutboxWrite:
+		return w.permission.AddRelation(ctx, e.userID.String(), e.tenantID.String(), e.relation)
+	case OutboxDelete:
+		return w.permission.RemoveRelation(ctx, e.userID.String(), e.tenantID.String(), e.relation)
+	default:
+		return fmt.Errorf("operação de outbox desconhecida: %s", e.operation)
+	}
+}
+
+// outboxBackoff dobra a espera a cada tentativa (2s, 4s, 8s...) até outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return outboxMaxBackoff
+	}
+
+	backoff := time.Duration(1<<attempts) * time.Second
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+
+	return backoff
+}
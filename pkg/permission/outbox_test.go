@@ -0,0 +1,200 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	pgsql_mocks "github.com/katana-stuidio/access-control/pkg/adapter/pgsql/mocks"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+var (
+	outboxUserID   = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	outboxTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	outboxOtherID  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+const outboxInsert = "INSERT INTO tb_fga_outbox (operation, user_id, tenant_id, relation) VALUES ($1, $2, $3, $4)"
+
+// fakeRelationService grava as tuplas aplicadas e falha com err, quando preenchido
+type fakeRelationService struct {
+	PermissionServiceInterface
+	err     error
+	applied []string
+}
+
+func (f *fakeRelationService) AddRelation(ctx context.Context, userID, tenantID, relation string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.applied = append(f.applied, "write "+relation)
+	return nil
+}
+
+func (f *fakeRelationService) RemoveRelation(ctx context.Context, userID, tenantID, relation string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.applied = append(f.applied, "delete "+relation)
+	return nil
+}
+
+func newOutboxDB(t *testing.T) (*pgsql_mocks.MockDatabaseInterface, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar o banco de teste: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	pool := pgsql_mocks.NewMockDatabaseInterface(gomock.NewController(t))
+	pool.EXPECT().GetDB().Return(db).AnyTimes()
+	return pool, mock
+}
+
+func TestEnqueueRoleChange(t *testing.T) {
+	pool, mock := newOutboxDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(outboxInsert)).WithArgs(OutboxDelete, outboxUserID, outboxTenantID, "estudante").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(outboxInsert)).WithArgs(OutboxWrite, outboxUserID, outboxOtherID, "professor").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	tx, _ := pool.GetDB().Begin()
+	ctx := context.Background()
+
+	// Sem mudança nada é gravado
+	same := &RoleBinding{TenantID: outboxTenantID, Role: model.RoleEstudante}
+	if err := EnqueueRoleChange(ctx, tx, outboxUserID, same, &RoleBinding{TenantID: outboxTenantID, Role: model.RoleEstudante}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	// Roles sem relação no modelo não geram tuplas
+	if err := EnqueueRoleChange(ctx, tx, outboxUserID, nil, &RoleBinding{TenantID: outboxTenantID, Role: "Coordenador"}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	// Troca de role e de tenant: remove a tupla antiga e escreve a nova
+	if err := EnqueueRoleChange(ctx, tx, outboxUserID, same, &RoleBinding{TenantID: outboxOtherID, Role: model.RoleProfessor}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	tx.Commit()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEnqueueRoleChangeReturnsInsertError(t *testing.T) {
+	pool, mock := newOutboxDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(outboxInsert)).WillReturnError(errors.New("conexão perdida"))
+
+	tx, _ := pool.GetDB().Begin()
+	if err := EnqueueRoleChange(context.Background(), tx, outboxUserID, nil, &RoleBinding{TenantID: outboxTenantID, Role: model.RoleAdmin}); err == nil {
+		t.Error("esperado erro da outbox, a transação do usuário precisa falhar")
+	}
+}
+
+func expectPending(mock sqlmock.Sqlmock, attempts int) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT o.id, o.operation").WithArgs(outboxBatchSize).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "operation", "user_id", "tenant_id", "relation", "attempts"}).
+			AddRow(7, OutboxWrite, outboxUserID, outboxTenantID, "admin", attempts))
+}
+
+func TestProcessPendingAppliesEntries(t *testing.T) {
+	pool, mock := newOutboxDB(t)
+	service := &fakeRelationService{}
+
+	expectPending(mock, 0)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tb_fga_outbox SET processed_at = now(), last_error = NULL WHERE id = $1")).
+		WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	done, err := NewOutboxWorker(pool, service).ProcessPending(context.Background())
+	if err != nil || done != 1 {
+		t.Fatalf("esperada 1 entrada concluída, mas obteve %d (%v)", done, err)
+	}
+	if len(service.applied) != 1 || service.applied[0] != "write admin" {
+		t.Errorf("tuplas aplicadas: %v", service.applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProcessPendingRetriesWithBackoff(t *testing.T) {
+	pool, mock := newOutboxDB(t)
+	service := &fakeRelationService{err: errors.New("openfga indisponível")}
+
+	// Terceira falha: a próxima tentativa espera 8 segundos
+	expectPending(mock, 2)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tb_fga_outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = now() + make_interval(secs => $2) WHERE id = $3")).
+		WithArgs("openfga indisponível", float64(8), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	done, err := NewOutboxWorker(pool, service).ProcessPending(context.Background())
+	if err != nil || done != 0 {
+		t.Fatalf("esperada nenhuma entrada concluída, mas obteve %d (%v)", done, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProcessPendingMovesToDeadLetter(t *testing.T) {
+	pool, mock := newOutboxDB(t)
+	service := &fakeRelationService{err: errors.New("relação inválida")}
+
+	expectPending(mock, outboxMaxAttempts-1)
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tb_fga_outbox SET attempts = attempts + 1, last_error = $1, dead_at = now() WHERE id = $2")).
+		WithArgs("relação inválida", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if _, err := NewOutboxWorker(pool, service).ProcessPending(context.Background()); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProcessPendingRollsBackOnUpdateError(t *testing.T) {
+	pool, mock := newOutboxDB(t)
+
+	expectPending(mock, 0)
+	mock.ExpectExec("UPDATE tb_fga_outbox SET processed_at").WillReturnError(errors.New("conexão perdida"))
+	mock.ExpectRollback()
+
+	if _, err := NewOutboxWorker(pool, &fakeRelationService{}).ProcessPending(context.Background()); err == nil {
+		t.Error("esperado erro ao marcar a entrada")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  2 * time.Second,
+		2:  4 * time.Second,
+		5:  32 * time.Second,
+		9:  512 * time.Second,
+		10: outboxMaxBackoff,
+		11: outboxMaxBackoff,
+		60: outboxMaxBackoff,
+	}
+
+	for attempts, expected := range cases {
+		if got := outboxBackoff(attempts); got != expected {
+			t.Errorf("tentativa %d: esperado %s, mas obteve %s", attempts, expected, got)
+		}
+	}
+}
@@ -5,14 +5,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 
 	openfga "github.com/openfga/go-sdk"
 )
 
+// PermissionServiceInterface é o contrato usado pelo restante da aplicação,
+// permitindo trocar o OpenFGA por uma implementação fake nos testes
+type PermissionServiceInterface interface {
+	AddRelation(ctx context.Context, userID, tenantID, relation string) error
+	RemoveRelation(ctx context.Context, userID, tenantID, relation string) error
+	CheckPermission(ctx context.Context, userID, tenantID, relation string) (bool, error)
+	Write(ctx context.Context, writes, deletes []Tuple) error
+	Check(ctx context.Context, req CheckRequest) (*CheckResult, error)
+	ListObjects(ctx context.Context, req ListObjectsRequest) ([]Object, error)
+	ListUsers(ctx context.Context, req ListUsersRequest) ([]Object, error)
+}
+
+// CheckResult é a resposta de Check, com o modelo de autorização usado na avaliação
+type CheckResult struct {
+	Allowed              bool   `json:"allowed"`
+	AuthorizationModelID string `json:"authorization_model_id"`
+}
+
 // PermissionService lida com autorização via OpenFGA
 type PermissionService struct {
 	FGAClient *openfga.APIClient
 	StoreID   string
+
+	// modelID fixa o modelo de autorização; vazio usa o modelo mais recente da store
+	mu      sync.RWMutex
+	modelID string
 }
 
 // NewPermissionService instancia um novo serviço de permissão
@@ -37,52 +60,228 @@ func NewPermissionService(ctx context.Context, apiURL, storeID, apiToken string)
 
 // AddRelation adiciona uma relação entre user e tenant (ex: estudante, professor, etc)
 func (ps *PermissionService) AddRelation(ctx context.Context, userID, tenantID, relation string) error {
-	req := openfga.WriteRequest{
-		Writes: &openfga.WriteRequestWrites{
-			TupleKeys: []openfga.TupleKey{
-				{
-					User:     fmt.Sprintf("user:%s", userID),
-					Relation: relation, // ex: "estudante"
-					Object:   fmt.Sprintf("tenant:%s", tenantID),
-				},
-			},
-		},
+	tuple := NewTuple(UserObject(userID), relation, TenantObject(tenantID)) // ex: "estudante"
+	err := ps.Write(ctx, []Tuple{tuple}, nil)
+	if err != nil {
+		// O OpenFGA rejeita tuplas duplicadas, o que não é erro para quem reenvia a escrita
+		if exists, readErr := ps.tupleExists(ctx, tuple); readErr == nil && exists {
+			return nil
+		}
 	}
-	_, _, err := ps.FGAClient.OpenFgaApi.Write(ctx, ps.StoreID).Body(req).Execute()
 	return err
 }
 
 // RemoveRelation remove uma relação entre user e tenant
 func (ps *PermissionService) RemoveRelation(ctx context.Context, userID, tenantID, relation string) error {
-	req := openfga.WriteRequest{
-		Deletes: &openfga.WriteRequestDeletes{
-			TupleKeys: []openfga.TupleKeyWithoutCondition{
-				{
-					User:     fmt.Sprintf("user:%s", userID),
-					Relation: relation,
-					Object:   fmt.Sprintf("tenant:%s", tenantID),
-				},
-			},
-		},
+	tuple := NewTuple(UserObject(userID), relation, TenantObject(tenantID))
+	err := ps.Write(ctx, nil, []Tuple{tuple})
+	if err != nil {
+		// Remover uma tupla que já não existe também é considerado sucesso
+		if exists, readErr := ps.tupleExists(ctx, tuple); readErr == nil && !exists {
+			return nil
+		}
+	}
+	return err
+}
+
+// Write grava e remove tuplas de qualquer tipo em uma única transação do OpenFGA
+func (ps *PermissionService) Write(ctx context.Context, writes, deletes []Tuple) error {
+	req := openfga.WriteRequest{}
+	if len(writes) > 0 {
+		keys := make([]openfga.TupleKey, len(writes))
+		for i, t := range writes {
+			keys[i] = t.key()
+		}
+		req.Writes = &openfga.WriteRequestWrites{TupleKeys: keys}
+	}
+	if len(deletes) > 0 {
+		keys := make([]openfga.TupleKeyWithoutCondition, len(deletes))
+		for i, t := range deletes {
+			keys[i] = t.keyWithoutCondition()
+		}
+		req.Deletes = &openfga.WriteRequestDeletes{TupleKeys: keys}
 	}
+	if req.Writes == nil && req.Deletes == nil {
+		return nil
+	}
+
 	_, _, err := ps.FGAClient.OpenFgaApi.Write(ctx, ps.StoreID).Body(req).Execute()
 	return err
 }
 
+// tupleExists lê diretamente a tupla (sem avaliar relações computadas)
+func (ps *PermissionService) tupleExists(ctx context.Context, tuple Tuple) (bool, error) {
+	user := tuple.User.String()
+	object := tuple.Object.String()
+	req := openfga.ReadRequest{
+		TupleKey: &openfga.ReadRequestTupleKey{
+			User:     &user,
+			Relation: &tuple.Relation,
+			Object:   &object,
+		},
+	}
+	resp, _, err := ps.FGAClient.OpenFgaApi.Read(ctx, ps.StoreID).Body(req).Execute()
+	if err != nil {
+		return false, err
+	}
+	return len(resp.GetTuples()) > 0, nil
+}
+
 // CheckPermission verifica se o user tem permissão (relation) sobre o tenant
 func (ps *PermissionService) CheckPermission(ctx context.Context, userID, tenantID, relation string) (bool, error) {
-	req := openfga.CheckRequest{
+	result, err := ps.Check(ctx, CheckRequest{
+		User:     UserObject(userID),
+		Relation: relation, // ex: "can_responder_questionarios"
+		Object:   TenantObject(tenantID),
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Check verifica se o user (ou userset) tem a relação sobre qualquer objeto (ex: quiz:123)
+func (ps *PermissionService) Check(ctx context.Context, req CheckRequest) (*CheckResult, error) {
+	modelID, err := ps.authorizationModelID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body := openfga.CheckRequest{
 		TupleKey: openfga.CheckRequestTupleKey{
-			User:     fmt.Sprintf("user:%s", userID),
-			Relation: relation, // ex: "can_responder_questionarios"
-			Object:   fmt.Sprintf("tenant:%s", tenantID),
+			User:     req.User.String(),
+			Relation: req.Relation,
+			Object:   req.Object.String(),
 		},
+		ContextualTuples:     contextualTupleKeys(req.ContextualTuples),
+		AuthorizationModelId: &modelID,
 	}
-	resp, _, err := ps.FGAClient.OpenFgaApi.Check(ctx, ps.StoreID).Body(req).Execute()
+	if req.Context != nil {
+		body.Context = &req.Context
+	}
+
+	resp, _, err := ps.FGAClient.OpenFgaApi.Check(ctx, ps.StoreID).Body(body).Execute()
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	return &CheckResult{
+		Allowed:              resp.GetAllowed(),
+		AuthorizationModelID: modelID,
+	}, nil
+}
+
+// ListObjects retorna os objetos do tipo pedido sobre os quais o user tem a relação
+func (ps *PermissionService) ListObjects(ctx context.Context, req ListObjectsRequest) ([]Object, error) {
+	modelID, err := ps.authorizationModelID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body := openfga.ListObjectsRequest{
+		AuthorizationModelId: &modelID,
+		Type:                 req.ObjectType,
+		Relation:             req.Relation,
+		User:                 req.User.String(),
+		ContextualTuples:     contextualTupleKeys(req.ContextualTuples),
+	}
+	if req.Context != nil {
+		body.Context = &req.Context
+	}
+
+	resp, _, err := ps.FGAClient.OpenFgaApi.ListObjects(ctx, ps.StoreID).Body(body).Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, 0, len(resp.GetObjects()))
+	for _, s := range resp.GetObjects() {
+		object, err := ParseObject(s)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// ListUsers retorna os usuários (ou usersets) que têm a relação sobre o objeto
+func (ps *PermissionService) ListUsers(ctx context.Context, req ListUsersRequest) ([]Object, error) {
+	modelID, err := ps.authorizationModelID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := openfga.UserTypeFilter{Type: req.UserType}
+	if req.UserRelation != "" {
+		filter.Relation = &req.UserRelation
+	}
+
+	body := openfga.ListUsersRequest{
+		AuthorizationModelId: &modelID,
+		Object:               openfga.FgaObject{Type: req.Object.Type, Id: req.Object.ID},
+		Relation:             req.Relation,
+		UserFilters:          []openfga.UserTypeFilter{filter},
+	}
+	if keys := contextualTupleKeys(req.ContextualTuples); keys != nil {
+		body.ContextualTuples = &keys.TupleKeys
+	}
+	if req.Context != nil {
+		body.Context = &req.Context
 	}
-	return resp.GetAllowed(), nil
+
+	resp, _, err := ps.FGAClient.OpenFgaApi.ListUsers(ctx, ps.StoreID).Body(body).Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]Object, 0, len(resp.GetUsers()))
+	for _, u := range resp.GetUsers() {
+		switch {
+		case u.Object != nil:
+			users = append(users, NewObject(u.Object.Type, u.Object.Id))
+		case u.Userset != nil:
+			users = append(users, NewObject(u.Userset.Type, u.Userset.Id).Userset(u.Userset.Relation))
+		case u.Wildcard != nil:
+			users = append(users, NewObject(u.Wildcard.Type, "*"))
+		}
+	}
+	return users, nil
+}
+
+// SetModelID fixa o modelo usado nas verificações; vazio volta a usar o mais recente
+func (ps *PermissionService) SetModelID(modelID string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.modelID = modelID
+}
+
+// ActiveModelID retorna o modelo avaliado nas verificações
+func (ps *PermissionService) ActiveModelID(ctx context.Context) (string, error) {
+	return ps.authorizationModelID(ctx)
+}
+
+// authorizationModelID retorna o modelo fixado ou o mais recente da store, para que toda
+// verificação informe explicitamente o modelo avaliado
+func (ps *PermissionService) authorizationModelID(ctx context.Context) (string, error) {
+	ps.mu.RLock()
+	modelID := ps.modelID
+	ps.mu.RUnlock()
+
+	if modelID != "" {
+		return modelID, nil
+	}
+
+	resp, _, err := ps.FGAClient.OpenFgaApi.ReadAuthorizationModels(ctx, ps.StoreID).PageSize(1).Execute()
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler modelo de autorização: %v", err)
+	}
+
+	models := resp.GetAuthorizationModels()
+	if len(models) == 0 {
+		return "", fmt.Errorf("nenhum modelo de autorização registrado na store %s", ps.StoreID)
+	}
+
+	return models[0].GetId(), nil
 }
 
 // RegisterModelFromJSON registra um modelo de autorização a partir de JSON
@@ -101,20 +300,20 @@ func (ps *PermissionService) RegisterModelFromJSON(ctx context.Context, modelJSO
 	return resp.GetAuthorizationModelId(), nil
 }
 
-// UpdateModelFromJSON atualiza um modelo de autorização existente a partir de JSON
-func (ps *PermissionService) UpdateModelFromJSON(ctx context.Context, modelID, modelJSON string) error {
-	var modelRequest openfga.WriteAuthorizationModelRequest
-
-	if err := json.Unmarshal([]byte(modelJSON), &modelRequest); err != nil {
-		return fmt.Errorf("erro ao fazer parse do JSON: %v", err)
+// UpdateModelFromJSON cria uma nova versão a partir do modelo modelID e retorna o ID dela.
+// Modelos do OpenFGA são imutáveis, então a atualização é uma nova versão, que só passa
+// a ser avaliada quando for fixada (ModelPinStore.Pin).
+func (ps *PermissionService) UpdateModelFromJSON(ctx context.Context, modelID, modelJSON string) (string, error) {
+	if _, err := ps.ReadModel(ctx, modelID); err != nil {
+		return "", fmt.Errorf("erro ao atualizar modelo: %v", err)
 	}
 
-	_, _, err := ps.FGAClient.OpenFgaApi.WriteAuthorizationModel(ctx, ps.StoreID).Body(modelRequest).Execute()
+	newModelID, err := ps.RegisterModelFromJSON(ctx, modelJSON)
 	if err != nil {
-		return fmt.Errorf("erro ao atualizar modelo: %v", err)
+		return "", fmt.Errorf("erro ao atualizar modelo: %v", err)
 	}
 
-	return nil
+	return newModelID, nil
 }
 
 // GetDefaultEducationalModel retorna o modelo padrão para o sistema educacional
@@ -123,11 +322,90 @@ func GetDefaultEducationalModel() string {
 		"schema_version": "1.1",
 		"type_definitions": [
 			{
-				"type": "user",
+				"type": "user"
+			},
+			{
+				"type": "quiz",
+				"relations": {
+					"owner": {
+						"union": {
+							"child": [
+								{
+									"computedUserset": {
+										"relation": "professor"
+									}
+								},
+								{
+									"computedUserset": {
+										"relation": "admin"
+									}
+								}
+							]
+						}
+					},
+					"can_read": {
+						"union": {
+							"child": [
+								{
+									"computedUserset": {
+										"relation": "owner"
+									}
+								},
+								{
+									"computedUserset": {
+										"relation": "estudante"
+									}
+								},
+								{
+									"computedUserset": {
+										"relation": "admin"
+									}
+								},
+								{
+									"computedUserset": {
+										"relation": "instituicao"
+									}
+								},
+								{
+									"computedUserset": {
+										"relation": "secretaria"
+									}
+								},
+								{
+									"computedUserset": {
+										"relation": "grupo_educacional"
+									}
+								},
+								{
+									"computedUserset": {
+										"relation": "coordenador"
+									}
+								}
+							]
+						}
+					},
+					"can_respond": {
+						"union": {
+							"child": [
+								{
+									"computedUserset": {
+										"relation": "estudante"
+									}
+								}
+							]
+						}
+					}
+				}
+			},
+			{
+				"type": "tenant",
 				"relations": {
 					"can_create_quiz": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "professor"
@@ -144,6 +422,9 @@ func GetDefaultEducationalModel() string {
 					"can_read_quiz": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "professor"
@@ -185,6 +466,9 @@ func GetDefaultEducationalModel() string {
 					"can_update_quiz": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "professor"
@@ -201,6 +485,9 @@ func GetDefaultEducationalModel() string {
 					"can_delete_quiz": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "professor"
@@ -217,6 +504,9 @@ func GetDefaultEducationalModel() string {
 					"can_respond_quiz": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "estudante"
@@ -228,6 +518,9 @@ func GetDefaultEducationalModel() string {
 					"can_view_rankings": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "estudante"
@@ -264,6 +557,9 @@ func GetDefaultEducationalModel() string {
 					"can_view_teachers": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "instituicao"
@@ -295,6 +591,9 @@ func GetDefaultEducationalModel() string {
 					"can_view_students": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "instituicao"
@@ -321,6 +620,9 @@ func GetDefaultEducationalModel() string {
 					"can_edit_profile": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "estudante"
@@ -342,6 +644,9 @@ func GetDefaultEducationalModel() string {
 					"can_view_quizzes_by_class": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "professor"
@@ -363,6 +668,9 @@ func GetDefaultEducationalModel() string {
 					"can_view_quizzes_by_student": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "professor"
@@ -384,6 +692,9 @@ func GetDefaultEducationalModel() string {
 					"can_view_graphs": {
 						"union": {
 							"child": [
+								{
+									"this": {}
+								},
 								{
 									"computedUserset": {
 										"relation": "instituicao"
@@ -408,29 +719,10 @@ func GetDefaultEducationalModel() string {
 						}
 					},
 					"can_access_full_system": {
-						"computedUserset": {
-							"relation": "admin"
-						}
-					},
-					"professor": {},
-					"estudante": {},
-					"instituicao": {},
-					"admin": {},
-					"secretaria": {},
-					"grupo_educacional": {},
-					"coordenador": {}
-				}
-			},
-			{
-				"type": "quiz",
-				"relations": {
-					"owner": {
 						"union": {
 							"child": [
 								{
-									"computedUserset": {
-										"relation": "professor"
-									}
+									"this": {}
 								},
 								{
 									"computedUserset": {
@@ -440,63 +732,6 @@ func GetDefaultEducationalModel() string {
 							]
 						}
 					},
-					"can_read": {
-						"union": {
-							"child": [
-								{
-									"computedUserset": {
-										"relation": "owner"
-									}
-								},
-								{
-									"computedUserset": {
-										"relation": "estudante"
-									}
-								},
-								{
-									"computedUserset": {
-										"relation": "admin"
-									}
-								},
-								{
-									"computedUserset": {
-										"relation": "instituicao"
-									}
-								},
-								{
-									"computedUserset": {
-										"relation": "secretaria"
-									}
-								},
-								{
-									"computedUserset": {
-										"relation": "grupo_educacional"
-									}
-								},
-								{
-									"computedUserset": {
-										"relation": "coordenador"
-									}
-								}
-							]
-						}
-					},
-					"can_respond": {
-						"union": {
-							"child": [
-								{
-									"computedUserset": {
-										"relation": "estudante"
-									}
-								}
-							]
-						}
-					}
-				}
-			},
-			{
-				"type": "tenant",
-				"relations": {
 					"professor": {},
 					"estudante": {},
 					"instituicao": {},
@@ -0,0 +1,20 @@
+package permission
+
+import (
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// roleRelations liga o role do usuário (tb_user.role_usr) à relação do tipo tenant
+// definida em GetDefaultEducationalModel
+var roleRelations = map[string]string{
+	model.RoleProfessor:        "professor",
+	model.RoleEstudante:        "estudante",
+	model.RoleInstituicao:      "instituicao",
+	model.RoleAdmin:            "admin",
+	model.RoleGrupoEducacional: "grupo_educacional",
+}
+
+// RelationForRole retorna a relação do OpenFGA para o role, ou "" se o role não tiver relação
+func RelationForRole(role string) string {
+	return roleRelations[role]
+}
@@ -0,0 +1,117 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// scopeGrant liga um escopo do access token à relação can_* do tipo tenant no modelo
+// GetDefaultEducationalModel e aos roles que recebem essa relação
+type scopeGrant struct {
+	Scope    string
+	Relation string
+	Roles    []string
+}
+
+// scopeGrants repete, para os roles de roleRelations, as relações de papel que cada can_*
+// une no tenant (secretaria e coordenador não têm role na aplicação), para que os serviços
+// verifiquem "quiz:create" em vez de repetir o mapeamento. TestScopeGrantsMatchModel
+// confere as duas listas.
+var scopeGrants = []scopeGrant{
+	{"quiz:create", "can_create_quiz", []string{model.RoleProfessor, model.RoleAdmin}},
+	{"quiz:read", "can_read_quiz", []string{model.RoleProfessor, model.RoleEstudante, model.RoleAdmin, model.RoleInstituicao, model.RoleGrupoEducacional}},
+	{"quiz:update", "can_update_quiz", []string{model.RoleProfessor, model.RoleAdmin}},
+	{"quiz:delete", "can_delete_quiz", []string{model.RoleProfessor, model.RoleAdmin}},
+	{"quiz:respond", "can_respond_quiz", []string{model.RoleEstudante}},
+	{"quiz:read_by_class", "can_view_quizzes_by_class", []string{model.RoleProfessor, model.RoleAdmin}},
+	{"quiz:read_by_student", "can_view_quizzes_by_student", []string{model.RoleProfessor, model.RoleAdmin}},
+	{"ranking:read", "can_view_rankings", []string{model.RoleEstudante, model.RoleInstituicao, model.RoleAdmin, model.RoleGrupoEducacional}},
+	{"teacher:read", "can_view_teachers", []string{model.RoleInstituicao, model.RoleAdmin, model.RoleGrupoEducacional}},
+	{"student:read", "can_view_students", []string{model.RoleInstituicao, model.RoleAdmin, model.RoleGrupoEducacional}},
+	{"profile:update", "can_edit_profile", []string{model.RoleEstudante, model.RoleProfessor, model.RoleAdmin}},
+	{"graph:read", "can_view_graphs", []string{model.RoleInstituicao, model.RoleAdmin, model.RoleGrupoEducacional}},
+	{"system:full", "can_access_full_system", []string{model.RoleAdmin}},
+}
+
+// ScopesForRole retorna os escopos concedidos ao role pelo modelo padrão
+func ScopesForRole(role string) []string {
+	return ScopesForRoles([]string{role})
+}
+
+// ScopesForRoles retorna, sem repetições, os escopos concedidos a qualquer um dos roles
+func ScopesForRoles(roles []string) []string {
+	scopes := []string{}
+	for _, grant := range scopeGrants {
+		for _, role := range roles {
+			if hasRole(grant.Roles, role) {
+				scopes = append(scopes, grant.Scope)
+				break
+			}
+		}
+	}
+	return scopes
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RelationForScope retorna a relação do OpenFGA equivalente ao escopo, ou "" se não houver
+func RelationForScope(scope string) string {
+	for _, grant := range scopeGrants {
+		if grant.Scope == scope {
+			return grant.Relation
+		}
+	}
+	return ""
+}
+
+// ScopeResolver calcula os escopos do usuário na emissão do token: os dos roles e,
+// com um serviço de permissão, também os das relações can_* concedidas no tenant do
+// usuário (por exemplo um estudante que recebeu tenant:<id>#can_create_quiz diretamente)
+type ScopeResolver struct {
+	service PermissionServiceInterface
+}
+
+// NewScopeResolver aceita service nil, usando então apenas os escopos dos roles
+func NewScopeResolver(service PermissionServiceInterface) *ScopeResolver {
+	return &ScopeResolver{
+		service: service,
+	}
+}
+
+// Resolve retorna os escopos do usuário. Em caso de erro no OpenFGA os escopos dos roles
+// são retornados junto com o erro, para que a emissão do token não dependa do OpenFGA.
+func (r *ScopeResolver) Resolve(ctx context.Context, userID, tenantID string, roles []string) ([]string, error) {
+	scopes := ScopesForRoles(roles)
+	if r.service == nil || userID == "" || tenantID == "" {
+		return scopes, nil
+	}
+
+	granted := map[string]bool{}
+	for _, scope := range scopes {
+		granted[scope] = true
+	}
+
+	for _, grant := range scopeGrants {
+		if granted[grant.Scope] {
+			continue
+		}
+
+		allowed, err := r.service.CheckPermission(ctx, userID, tenantID, grant.Relation)
+		if err != nil {
+			return scopes, err
+		}
+		if allowed {
+			scopes = append(scopes, grant.Scope)
+		}
+	}
+
+	return scopes, nil
+}
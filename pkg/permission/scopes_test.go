@@ -0,0 +1,125 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+// relationChecker responde CheckPermission a partir de um mapa de relações;
+// os demais métodos da interface não são usados pelo ScopeResolver
+type relationChecker struct {
+	PermissionServiceInterface
+	relations map[string]bool
+	err       error
+}
+
+func (r *relationChecker) CheckPermission(ctx context.Context, userID, tenantID, relation string) (bool, error) {
+	return r.relations[relation], r.err
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func TestScopesForRole(t *testing.T) {
+	if scopes := ScopesForRole(model.RoleProfessor); !hasScope(scopes, "quiz:create") || hasScope(scopes, "quiz:respond") {
+		t.Errorf("escopos inesperados para professor: %v", scopes)
+	}
+	if scopes := ScopesForRole(model.RoleEstudante); hasScope(scopes, "quiz:create") || !hasScope(scopes, "quiz:respond") {
+		t.Errorf("escopos inesperados para estudante: %v", scopes)
+	}
+	if scopes := ScopesForRole("Desconhecido"); len(scopes) != 0 {
+		t.Errorf("esperado nenhum escopo, mas obteve %v", scopes)
+	}
+	// Um usuário com vários roles recebe a união dos escopos, sem repetições
+	scopes := ScopesForRoles([]string{model.RoleEstudante, model.RoleProfessor})
+	if !hasScope(scopes, "quiz:create") || !hasScope(scopes, "quiz:respond") {
+		t.Errorf("escopos inesperados para estudante e professor: %v", scopes)
+	}
+	seen := map[string]bool{}
+	for _, scope := range scopes {
+		if seen[scope] {
+			t.Errorf("escopo repetido %s", scope)
+		}
+		seen[scope] = true
+	}
+
+	if relation := RelationForScope("quiz:create"); relation != "can_create_quiz" {
+		t.Errorf("esperado can_create_quiz, mas obteve %q", relation)
+	}
+}
+
+func TestScopeResolver(t *testing.T) {
+	ctx := context.Background()
+
+	scopes, err := NewScopeResolver(nil).Resolve(ctx, "u1", "t1", []string{model.RoleEstudante})
+	if err != nil || hasScope(scopes, "quiz:create") {
+		t.Errorf("sem OpenFGA: escopos inesperados %v (%v)", scopes, err)
+	}
+
+	// Relação concedida diretamente no OpenFGA soma-se aos escopos do role
+	checker := &relationChecker{relations: map[string]bool{"can_create_quiz": true}}
+	scopes, err = NewScopeResolver(checker).Resolve(ctx, "u1", "t1", []string{model.RoleEstudante})
+	if err != nil || !hasScope(scopes, "quiz:create") || !hasScope(scopes, "quiz:respond") {
+		t.Errorf("com OpenFGA: escopos inesperados %v (%v)", scopes, err)
+	}
+
+	checker.err = errors.New("openfga offline")
+	scopes, err = NewScopeResolver(checker).Resolve(ctx, "u1", "t1", []string{model.RoleEstudante})
+	if err == nil {
+		t.Error("esperado erro do OpenFGA")
+	}
+	if !hasScope(scopes, "quiz:respond") || hasScope(scopes, "quiz:create") {
+		t.Errorf("erro no OpenFGA: esperados apenas os escopos do role, mas obteve %v", scopes)
+	}
+}
+
+func TestScopeGrantsMatchModel(t *testing.T) {
+	ctx := context.Background()
+
+	for role, relation := range roleRelations {
+		service, err := NewLocalPermissionService(newMemoryTupleStore(), GetDefaultEducationalModel())
+		if err != nil {
+			t.Fatalf("erro ao interpretar modelo padrão: %v", err)
+		}
+		if err := service.AddRelation(ctx, "u1", "t1", relation); err != nil {
+			t.Fatalf("erro inesperado %v", err)
+		}
+
+		// O ScopeResolver consulta tenant:<id>#can_*, que o modelo deve definir
+		for _, grant := range scopeGrants {
+			allowed, err := service.CheckPermission(ctx, "u1", "t1", grant.Relation)
+			if err != nil {
+				t.Fatalf("%s: erro inesperado %v", grant.Relation, err)
+			}
+			if expected := hasRole(grant.Roles, role); allowed != expected {
+				t.Errorf("%s com %s: o modelo concede %v, scopeGrants %v", role, grant.Relation, allowed, expected)
+			}
+		}
+	}
+}
+
+func TestScopeResolverWithDefaultModel(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewLocalPermissionService(newMemoryTupleStore(), GetDefaultEducationalModel())
+	if err != nil {
+		t.Fatalf("erro ao interpretar modelo padrão: %v", err)
+	}
+
+	// Relação can_* concedida diretamente no tenant, além do role
+	service.AddRelation(ctx, "u1", "t1", "estudante")
+	service.AddRelation(ctx, "u1", "t1", "can_create_quiz")
+
+	scopes, err := NewScopeResolver(service).Resolve(ctx, "u1", "t1", []string{model.RoleEstudante})
+	if err != nil || !hasScope(scopes, "quiz:create") || !hasScope(scopes, "quiz:respond") || hasScope(scopes, "quiz:delete") {
+		t.Errorf("escopos inesperados %v (%v)", scopes, err)
+	}
+}
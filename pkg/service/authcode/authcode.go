@@ -0,0 +1,196 @@
+package authcode
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/redisdb"
+	"go.uber.org/zap"
+)
+
+const (
+	// CodeLifetime is how long an authorization code can be exchanged (RFC 6749 recommends at most 10 minutes)
+	CodeLifetime = time.Minute
+	// usedCodeLifetime keeps the replay marker for a while after the code expired
+	usedCodeLifetime = 10 * time.Minute
+)
+
+var (
+	ErrCodeNotFound = errors.New("authorization code not found or expired")
+	ErrCodeReused   = errors.New("authorization code already used")
+)
+
+// AuthorizationCode is the grant stored for a code issued by /oauth/authorize
+type AuthorizationCode struct {
+	ClientID            string   `json:"client_id"`
+	RedirectURI         string   `json:"redirect_uri"`
+	UserID              string   `json:"user_id"`
+	TenantID            string   `json:"tenant_id"`
+	Scopes              []string `json:"scopes"`
+	CodeChallenge       string   `json:"code_challenge"`
+	CodeChallengeMethod string   `json:"code_challenge_method"`
+	// Nonce and AuthTime go into the ID token when the openid scope is granted
+	Nonce     string    `json:"nonce,omitempty"`
+	AuthTime  time.Time `json:"auth_time"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// FamilyID is the token family issued in exchange for the code, revoked if the code is replayed
+	FamilyID string `json:"family_id,omitempty"`
+	// RedirectURIExplicit is set when the authorization request sent redirect_uri, which the
+	// token request must then repeat (RFC 6749, section 4.1.3)
+	RedirectURIExplicit bool `json:"redirect_uri_explicit,omitempty"`
+}
+
+type AuthCodeServiceInterface interface {
+	Issue(ctx context.Context, grant *AuthorizationCode) (code string, err error)
+	Consume(ctx context.Context, code string) (*AuthorizationCode, error)
+	RecordFamily(ctx context.Context, code, familyID string) error
+}
+
+// AuthCodeService keeps the codes in Redis under their SHA-256, so the codes themselves are never stored
+type AuthCodeService struct {
+	redis redisdb.RedisClientInterface
+}
+
+func NewAuthCodeService(redis redisdb.RedisClientInterface) *AuthCodeService {
+	return &AuthCodeService{
+		redis: redis,
+	}
+}
+
+// Issue stores the grant and returns a new random code valid for CodeLifetime
+func (s *AuthCodeService) Issue(ctx context.Context, grant *AuthorizationCode) (string, error) {
+	codeBytes := make([]byte, 32)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return "", err
+	}
+	code := base64.RawURLEncoding.EncodeToString(codeBytes)
+
+	grant.ExpiresAt = time.Now().Add(CodeLifetime)
+	data, err := json.Marshal(grant)
+	if err != nil {
+		logger.Error("Error marshaling authorization code", err)
+		return "", fmt.Errorf("failed to marshal authorization code: %w", err)
+	}
+
+	if !s.redis.SaveData(ctx, codeKey(code), data, CodeLifetime) {
+		return "", fmt.Errorf("failed to save authorization code to Redis")
+	}
+
+	return code, nil
+}
+
+// Consume returns the grant of a code and invalidates it. A code presented a second time
+// returns ErrCodeReused together with the grant, so the caller can revoke what it issued.
+func (s *AuthCodeService) Consume(ctx context.Context, code string) (*AuthorizationCode, error) {
+	data, err := s.redis.ReadData(ctx, codeKey(code))
+	if err != nil {
+		used, usedErr := s.redis.ReadData(ctx, usedCodeKey(code))
+		if usedErr != nil || len(used) == 0 {
+			return nil, ErrCodeNotFound
+		}
+		return s.reused(used)
+	}
+
+	var grant AuthorizationCode
+	if err := json.Unmarshal(data, &grant); err != nil {
+		logger.Error("Error unmarshaling authorization code", err)
+		return nil, fmt.Errorf("failed to unmarshal authorization code: %w", err)
+	}
+	if time.Now().After(grant.ExpiresAt) {
+		return nil, ErrCodeNotFound
+	}
+
+	// SET NX settles concurrent exchanges of the same code: only one of them wins.
+	// The marker is kept longer than the code, so a late replay is still recognized.
+	ok, err := s.redis.SaveDataIfNotExists(ctx, usedCodeKey(code), data, usedCodeLifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark authorization code as used: %w", err)
+	}
+	if !ok {
+		used, _ := s.redis.ReadData(ctx, usedCodeKey(code))
+		return s.reused(used)
+	}
+
+	s.redis.DeleteAllHSetData(ctx, codeKey(code))
+	return &grant, nil
+}
+
+// RecordFamily saves in the used marker the token family issued for code
+func (s *AuthCodeService) RecordFamily(ctx context.Context, code, familyID string) error {
+	data, err := s.redis.ReadData(ctx, usedCodeKey(code))
+	if err != nil {
+		return fmt.Errorf("authorization code marker not found: %w", err)
+	}
+
+	var grant AuthorizationCode
+	if err := json.Unmarshal(data, &grant); err != nil {
+		return fmt.Errorf("failed to unmarshal authorization code: %w", err)
+	}
+	grant.FamilyID = familyID
+
+	data, err = json.Marshal(&grant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal authorization code: %w", err)
+	}
+	if !s.redis.SaveData(ctx, usedCodeKey(code), data, usedCodeLifetime) {
+		return fmt.Errorf("failed to save authorization code marker to Redis")
+	}
+
+	return nil
+}
+
+func (s *AuthCodeService) reused(used []byte) (*AuthorizationCode, error) {
+	var grant AuthorizationCode
+	if len(used) > 0 {
+		json.Unmarshal(used, &grant)
+	}
+
+	logger.Error("Security event: authorization code reuse detected", ErrCodeReused,
+		zap.String("event", "authorization_code_reuse"),
+		zap.String("client_id", grant.ClientID),
+		zap.String("user_id", grant.UserID),
+		zap.String("family_id", grant.FamilyID),
+	)
+
+	return &grant, ErrCodeReused
+}
+
+func codeKey(code string) string {
+	return "oauth_code:" + hashCode(code)
+}
+
+func usedCodeKey(code string) string {
+	return "oauth_code_used:" + hashCode(code)
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyPKCE checks the code_verifier against the code_challenge of the authorization request (RFC 7636)
+func VerifyPKCE(verifier, challenge, method string) bool {
+	// 43 to 128 characters from the unreserved set (RFC 7636, section 4.1)
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, r := range verifier {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_' || r == '~') {
+			return false
+		}
+	}
+
+	if method != "S256" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+}
@@ -0,0 +1,150 @@
+package authcode
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis implements redisdb.RedisClientInterface in memory
+type fakeRedis struct {
+	data map[string][]byte
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}}
+}
+
+func (f *fakeRedis) GetClient() *redis.Client { return nil }
+
+func (f *fakeRedis) ReadData(ctx context.Context, key string) ([]byte, error) {
+	data, ok := f.data[key]
+	if !ok {
+		return nil, errors.New("redis: nil")
+	}
+	return data, nil
+}
+
+func (f *fakeRedis) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) bool {
+	f.data[key] = data
+	return true
+}
+
+func (f *fakeRedis) SaveDataIfNotExists(ctx context.Context, key string, data []byte, timer time.Duration) (bool, error) {
+	if _, ok := f.data[key]; ok {
+		return false, nil
+	}
+	f.data[key] = data
+	return true, nil
+}
+
+func (f *fakeRedis) SaveHSetData(ctx context.Context, key, field string, value interface{}) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadHSetData(ctx context.Context, key string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) DeleteAllHSetData(ctx context.Context, key string) bool {
+	delete(f.data, key)
+	return true
+}
+
+func (f *fakeRedis) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := f.data[key]
+	return ok, nil
+}
+
+func (f *fakeRedis) Increment(ctx context.Context, key string, timer time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRedis) TimeToLive(ctx context.Context, key string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (f *fakeRedis) AddToSet(ctx context.Context, key, member string, timer time.Duration) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadSetMembers(ctx context.Context, key string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) RemoveFromSet(ctx context.Context, key, member string) bool { return true }
+
+func (f *fakeRedis) Publish(ctx context.Context, message []byte) error { return nil }
+
+func (f *fakeRedis) Subscriber(ctx context.Context, callback func(msg *redis.Message)) {}
+
+func TestConsumeIsSingleUse(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewAuthCodeService(rdb)
+	ctx := context.Background()
+
+	code, err := s.Issue(ctx, &AuthorizationCode{ClientID: "spa", UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+
+	// O código não é gravado em claro
+	for key := range rdb.data {
+		if strings.Contains(key, code) {
+			t.Errorf("código gravado em claro na chave %s", key)
+		}
+	}
+
+	grant, err := s.Consume(ctx, code)
+	if err != nil || grant.ClientID != "spa" {
+		t.Fatalf("esperado o código do cliente spa, mas obteve %+v (%v)", grant, err)
+	}
+	if err := s.RecordFamily(ctx, code, "family-1"); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+
+	grant, err = s.Consume(ctx, code)
+	if !errors.Is(err, ErrCodeReused) {
+		t.Fatalf("esperado %v, mas obteve %v", ErrCodeReused, err)
+	}
+	if grant.FamilyID != "family-1" {
+		t.Errorf("esperada a família family-1, mas obteve %q", grant.FamilyID)
+	}
+
+	if _, err := s.Consume(ctx, "desconhecido"); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("esperado %v, mas obteve %v", ErrCodeNotFound, err)
+	}
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := strings.Repeat("a1-._~", 8)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		verifier string
+		method   string
+		expected bool
+	}{
+		{"S256 válido", verifier, "S256", true},
+		{"verificador diferente", strings.Repeat("b", 48), "S256", false},
+		{"verificador curto", "abc", "S256", false},
+		{"caractere inválido", verifier + "!", "S256", false},
+		{"método plain", verifier, "plain", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, challenge, tt.method); got != tt.expected {
+				t.Errorf("esperado %v, mas obteve %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package lockout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/redisdb"
+)
+
+var (
+	// ErrLocked is returned while the user is locked after too many wrong passwords
+	ErrLocked = errors.New("account temporarily locked")
+	// ErrThrottled is returned while the progressive delay of the user or the limit of the IP is running
+	ErrThrottled = errors.New("too many login attempts")
+)
+
+// Policy limits the wrong passwords. A zero MaxAttempts or IPMaxAttempts disables that limit.
+type Policy struct {
+	// MaxAttempts consecutive failures of a username lock it for Lockout
+	MaxAttempts int
+	Lockout     time.Duration
+	// MaxDelay caps the progressive delay, which doubles from one second at each failure
+	MaxDelay time.Duration
+	// IPMaxAttempts failures of an IP within Window block its logins until the window ends
+	IPMaxAttempts int
+	Window        time.Duration
+}
+
+type LockoutServiceInterface interface {
+	Check(ctx context.Context, username, ip string) (retryAfter time.Duration, err error)
+	Fail(ctx context.Context, username, ip string) (retryAfter time.Duration, err error)
+	Succeed(ctx context.Context, username string)
+	Unlock(ctx context.Context, username string) (locked bool)
+}
+
+// LockoutService counts the failed logins in Redis, so the limits hold across the API instances.
+// Usernames are keyed by their SHA-256, as they come from unauthenticated requests.
+type LockoutService struct {
+	redis  redisdb.RedisClientInterface
+	policy Policy
+}
+
+func NewLockoutService(redis redisdb.RedisClientInterface, policy Policy) *LockoutService {
+	return &LockoutService{
+		redis:  redis,
+		policy: policy,
+	}
+}
+
+// Check tells whether a login of username from ip may be attempted now. It returns ErrLocked or
+// ErrThrottled with the time to wait; Redis errors do not block the login.
+func (s *LockoutService) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	if locked, _ := s.redis.Exists(ctx, lockKey(username)); locked {
+		return s.ttl(ctx, lockKey(username)), ErrLocked
+	}
+
+	if s.policy.IPMaxAttempts > 0 && s.count(ctx, ipKey(ip)) >= int64(s.policy.IPMaxAttempts) {
+		return s.ttl(ctx, ipKey(ip)), ErrThrottled
+	}
+
+	if delayed, _ := s.redis.Exists(ctx, delayKey(username)); delayed {
+		return s.ttl(ctx, delayKey(username)), ErrThrottled
+	}
+
+	return 0, nil
+}
+
+// Fail counts a wrong password of username from ip. It returns ErrLocked when the failure locked
+// the user, otherwise the delay before the next attempt.
+func (s *LockoutService) Fail(ctx context.Context, username, ip string) (time.Duration, error) {
+	if s.policy.IPMaxAttempts > 0 {
+		s.redis.Increment(ctx, ipKey(ip), s.policy.Window)
+	}
+
+	failures, err := s.redis.Increment(ctx, failureKey(username), s.policy.Window)
+	if err != nil {
+		return 0, nil
+	}
+
+	if s.policy.MaxAttempts > 0 && failures >= int64(s.policy.MaxAttempts) {
+		s.redis.SaveData(ctx, lockKey(username), []byte(time.Now().UTC().Format(time.RFC3339)), s.policy.Lockout)
+		s.redis.DeleteAllHSetData(ctx, failureKey(username))
+		s.redis.DeleteAllHSetData(ctx, delayKey(username))
+		logger.Info("Login locked after " + strconv.FormatInt(failures, 10) + " wrong passwords")
+		return s.policy.Lockout, ErrLocked
+	}
+
+	delay := s.delay(failures)
+	if delay > 0 {
+		s.redis.SaveData(ctx, delayKey(username), []byte("1"), delay)
+	}
+	return delay, nil
+}
+
+// Succeed clears the failures of username after a correct password. The counter of the IP
+// is kept, or one valid account would reset it between guesses at other accounts.
+func (s *LockoutService) Succeed(ctx context.Context, username string) {
+	s.redis.DeleteAllHSetData(ctx, failureKey(username))
+	s.redis.DeleteAllHSetData(ctx, delayKey(username))
+}
+
+// Unlock removes the lock and the failures of username and tells whether it was locked
+func (s *LockoutService) Unlock(ctx context.Context, username string) bool {
+	locked, _ := s.redis.Exists(ctx, lockKey(username))
+	s.redis.DeleteAllHSetData(ctx, lockKey(username))
+	s.Succeed(ctx, username)
+	return locked
+}
+
+// delay doubles from one second at each failure, up to MaxDelay
+func (s *LockoutService) delay(failures int64) time.Duration {
+	if failures <= 0 || s.policy.MaxDelay <= 0 {
+		return 0
+	}
+
+	delay := time.Second
+	for i := int64(1); i < failures && delay < s.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.policy.MaxDelay)
+}
+
+func (s *LockoutService) count(ctx context.Context, key string) int64 {
+	if exists, _ := s.redis.Exists(ctx, key); !exists {
+		return 0
+	}
+
+	data, err := s.redis.ReadData(ctx, key)
+	if err != nil {
+		return 0
+	}
+
+	count, _ := strconv.ParseInt(string(data), 10, 64)
+	return count
+}
+
+// ttl is at least one second, the precision of Retry-After
+func (s *LockoutService) ttl(ctx context.Context, key string) time.Duration {
+	ttl, _ := s.redis.TimeToLive(ctx, key)
+	return max(ttl, time.Second)
+}
+
+func lockKey(username string) string {
+	return "login_lock:" + hashUsername(username)
+}
+
+func failureKey(username string) string {
+	return "login_failures:" + hashUsername(username)
+}
+
+func delayKey(username string) string {
+	return "login_delay:" + hashUsername(username)
+}
+
+func ipKey(ip string) string {
+	return "login_failures_ip:" + ip
+}
+
+func hashUsername(username string) string {
+	sum := sha256.Sum256([]byte(username))
+	return hex.EncodeToString(sum[:])
+}
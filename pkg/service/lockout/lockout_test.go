@@ -0,0 +1,224 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis implements redisdb.RedisClientInterface in memory, keeping the TTLs without expiring the keys
+type fakeRedis struct {
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (f *fakeRedis) GetClient() *redis.Client { return nil }
+
+func (f *fakeRedis) ReadData(ctx context.Context, key string) ([]byte, error) {
+	data, ok := f.data[key]
+	if !ok {
+		return nil, errors.New("redis: nil")
+	}
+	return data, nil
+}
+
+func (f *fakeRedis) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) bool {
+	f.data[key] = data
+	f.ttls[key] = timer
+	return true
+}
+
+func (f *fakeRedis) SaveDataIfNotExists(ctx context.Context, key string, data []byte, timer time.Duration) (bool, error) {
+	if _, ok := f.data[key]; ok {
+		return false, nil
+	}
+	return f.SaveData(ctx, key, data, timer), nil
+}
+
+func (f *fakeRedis) SaveHSetData(ctx context.Context, key, field string, value interface{}) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadHSetData(ctx context.Context, key string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) DeleteAllHSetData(ctx context.Context, key string) bool {
+	delete(f.data, key)
+	delete(f.ttls, key)
+	return true
+}
+
+func (f *fakeRedis) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := f.data[key]
+	return ok, nil
+}
+
+func (f *fakeRedis) Increment(ctx context.Context, key string, timer time.Duration) (int64, error) {
+	count, _ := strconv.ParseInt(string(f.data[key]), 10, 64)
+	count++
+	f.data[key] = []byte(strconv.FormatInt(count, 10))
+	if count == 1 {
+		f.ttls[key] = timer
+	}
+	return count, nil
+}
+
+func (f *fakeRedis) TimeToLive(ctx context.Context, key string) (time.Duration, error) {
+	return f.ttls[key], nil
+}
+
+func (f *fakeRedis) AddToSet(ctx context.Context, key, member string, timer time.Duration) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadSetMembers(ctx context.Context, key string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) RemoveFromSet(ctx context.Context, key, member string) bool { return true }
+
+func (f *fakeRedis) Publish(ctx context.Context, message []byte) error { return nil }
+
+func (f *fakeRedis) Subscriber(ctx context.Context, callback func(msg *redis.Message)) {}
+
+var testPolicy = Policy{
+	MaxAttempts:   5,
+	Lockout:       15 * time.Minute,
+	MaxDelay:      4 * time.Second,
+	IPMaxAttempts: 10,
+	Window:        15 * time.Minute,
+}
+
+func TestProgressiveDelay(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewLockoutService(rdb, testPolicy)
+	ctx := context.Background()
+
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		delay, err := s.Fail(ctx, "maria", "10.0.0.1")
+		if err != nil || delay != expected {
+			t.Fatalf("erro %d: esperado atraso de %s, mas obteve %s (%v)", i+1, expected, delay, err)
+		}
+	}
+
+	retryAfter, err := s.Check(ctx, "maria", "10.0.0.1")
+	if !errors.Is(err, ErrThrottled) || retryAfter != 4*time.Second {
+		t.Errorf("durante o atraso: esperado ErrThrottled por 4s, mas obteve %v por %s", err, retryAfter)
+	}
+
+	// O atraso é do usuário, não de quem tenta outro usuário do mesmo IP
+	if _, err := s.Check(ctx, "joao", "10.0.0.1"); err != nil {
+		t.Errorf("outro usuário: esperado nil, mas obteve %v", err)
+	}
+
+	// Passado o atraso (a chave expira no Redis) o login é permitido
+	rdb.DeleteAllHSetData(ctx, delayKey("maria"))
+	if _, err := s.Check(ctx, "maria", "10.0.0.1"); err != nil {
+		t.Errorf("após o atraso: esperado nil, mas obteve %v", err)
+	}
+}
+
+func TestLockoutAfterMaxAttempts(t *testing.T) {
+	s := NewLockoutService(newFakeRedis(), testPolicy)
+	ctx := context.Background()
+
+	for i := 1; i < testPolicy.MaxAttempts; i++ {
+		if _, err := s.Fail(ctx, "maria", "10.0.0.1"); err != nil {
+			t.Fatalf("erro %d: esperado nil, mas obteve %v", i, err)
+		}
+	}
+
+	retryAfter, err := s.Fail(ctx, "maria", "10.0.0.1")
+	if !errors.Is(err, ErrLocked) || retryAfter != testPolicy.Lockout {
+		t.Fatalf("esperado ErrLocked por %s, mas obteve %v por %s", testPolicy.Lockout, err, retryAfter)
+	}
+
+	if retryAfter, err := s.Check(ctx, "maria", "10.0.0.2"); !errors.Is(err, ErrLocked) || retryAfter != testPolicy.Lockout {
+		t.Errorf("bloqueio vale de qualquer IP: esperado ErrLocked por %s, mas obteve %v por %s", testPolicy.Lockout, err, retryAfter)
+	}
+
+	if !s.Unlock(ctx, "maria") {
+		t.Error("Unlock deveria informar que o usuário estava bloqueado")
+	}
+	if _, err := s.Check(ctx, "maria", "10.0.0.2"); err != nil {
+		t.Errorf("após o desbloqueio: esperado nil, mas obteve %v", err)
+	}
+	if s.Unlock(ctx, "maria") {
+		t.Error("Unlock de usuário não bloqueado deveria retornar false")
+	}
+}
+
+func TestSucceedResetsUserFailures(t *testing.T) {
+	s := NewLockoutService(newFakeRedis(), testPolicy)
+	ctx := context.Background()
+
+	for i := 1; i < testPolicy.MaxAttempts; i++ {
+		s.Fail(ctx, "maria", "10.0.0.1")
+	}
+	s.Succeed(ctx, "maria")
+
+	// A contagem recomeça: um novo erro não bloqueia
+	if delay, err := s.Fail(ctx, "maria", "10.0.0.1"); err != nil || delay != time.Second {
+		t.Errorf("esperado atraso de 1s, mas obteve %s (%v)", delay, err)
+	}
+}
+
+func TestIPLimit(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewLockoutService(rdb, testPolicy)
+	ctx := context.Background()
+
+	// Senhas erradas espalhadas por vários usuários, nenhum chega ao bloqueio
+	for i := 0; i < testPolicy.IPMaxAttempts; i++ {
+		s.Fail(ctx, "user-"+strconv.Itoa(i), "10.0.0.1")
+	}
+
+	retryAfter, err := s.Check(ctx, "outro", "10.0.0.1")
+	if !errors.Is(err, ErrThrottled) || retryAfter != testPolicy.Window {
+		t.Errorf("esperado ErrThrottled por %s, mas obteve %v por %s", testPolicy.Window, err, retryAfter)
+	}
+
+	if _, err := s.Check(ctx, "outro", "10.0.0.2"); err != nil {
+		t.Errorf("outro IP: esperado nil, mas obteve %v", err)
+	}
+}
+
+func TestDisabledLimits(t *testing.T) {
+	s := NewLockoutService(newFakeRedis(), Policy{Window: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		if delay, err := s.Fail(ctx, "maria", "10.0.0.1"); err != nil || delay != 0 {
+			t.Fatalf("limites desativados: esperado nil sem atraso, mas obteve %v (%s)", err, delay)
+		}
+	}
+	if _, err := s.Check(ctx, "maria", "10.0.0.1"); err != nil {
+		t.Errorf("limites desativados: esperado nil, mas obteve %v", err)
+	}
+}
+
+func TestUsernameStoredHashed(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewLockoutService(rdb, testPolicy)
+
+	s.Fail(context.Background(), "maria@escola.br", "10.0.0.1")
+
+	for key := range rdb.data {
+		if strings.Contains(key, "maria") {
+			t.Errorf("usuário gravado em claro na chave %s", key)
+		}
+	}
+	if _, ok := rdb.data[failureKey("maria@escola.br")]; !ok {
+		t.Error("contador do usuário ausente")
+	}
+}
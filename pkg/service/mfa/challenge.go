@@ -0,0 +1,170 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/redisdb"
+)
+
+const (
+	// ChallengeLifetime is how long the second step of the login can be completed
+	ChallengeLifetime = 5 * time.Minute
+	// MaxAttempts codes of a user may be checked in FailureWindow, across every challenge
+	// and the OAuth login, before its second factor is locked until the window ends
+	MaxAttempts   = 5
+	FailureWindow = 15 * time.Minute
+)
+
+var (
+	ErrChallengeNotFound = errors.New("MFA challenge not found or expired")
+	// ErrLocked is returned while the user has no code attempt left
+	ErrLocked = errors.New("MFA temporarily locked after too many wrong codes")
+)
+
+// Challenge is the login waiting for the second factor, returned by getjwt as an opaque mfa_token
+type Challenge struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id"`
+	// EnrollmentRequired is set when the tenant policy requires MFA and the user has no secret yet:
+	// the challenge then allows enrolling one before the login completes
+	EnrollmentRequired bool      `json:"enrollment_required"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+type ChallengeServiceInterface interface {
+	Issue(ctx context.Context, challenge *Challenge) (token string, err error)
+	Get(ctx context.Context, token string) (*Challenge, error)
+	Consume(ctx context.Context, token string) (*Challenge, error)
+	Attempt(ctx context.Context, userID string) (remaining int, retryAfter time.Duration, err error)
+	Succeed(ctx context.Context, userID string)
+}
+
+// ChallengeService keeps the challenges in Redis under the SHA-256 of the token
+type ChallengeService struct {
+	redis redisdb.RedisClientInterface
+}
+
+func NewChallengeService(redis redisdb.RedisClientInterface) *ChallengeService {
+	return &ChallengeService{
+		redis: redis,
+	}
+}
+
+// Issue stores the challenge and returns a new random token valid for ChallengeLifetime
+func (s *ChallengeService) Issue(ctx context.Context, challenge *Challenge) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	challenge.ExpiresAt = time.Now().Add(ChallengeLifetime)
+	if err := s.save(ctx, token, challenge); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Get returns the challenge of token without consuming it
+func (s *ChallengeService) Get(ctx context.Context, token string) (*Challenge, error) {
+	data, err := s.redis.ReadData(ctx, challengeKey(token))
+	if err != nil || len(data) == 0 {
+		return nil, ErrChallengeNotFound
+	}
+
+	var challenge Challenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		logger.Error("Error unmarshaling MFA challenge", err)
+		return nil, fmt.Errorf("failed to unmarshal MFA challenge: %w", err)
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrChallengeNotFound
+	}
+
+	return &challenge, nil
+}
+
+// Attempt counts a code of the user before it is checked, with an atomic INCR on a key of the user:
+// concurrent requests and new challenges share the same MaxAttempts. It returns how many codes are
+// left after this one, or ErrLocked with the time to wait. Redis errors refuse the attempt.
+func (s *ChallengeService) Attempt(ctx context.Context, userID string) (int, time.Duration, error) {
+	attempts, err := s.redis.Increment(ctx, attemptsKey(userID), FailureWindow)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count MFA attempt: %w", err)
+	}
+
+	if attempts > MaxAttempts {
+		if attempts == MaxAttempts+1 {
+			logger.Info("MFA locked after " + strconv.Itoa(MaxAttempts) + " wrong codes")
+		}
+		ttl, _ := s.redis.TimeToLive(ctx, attemptsKey(userID))
+		return 0, max(ttl, time.Second), ErrLocked
+	}
+
+	return MaxAttempts - int(attempts), 0, nil
+}
+
+// Succeed clears the attempts of the user after a correct code
+func (s *ChallengeService) Succeed(ctx context.Context, userID string) {
+	s.redis.DeleteAllHSetData(ctx, attemptsKey(userID))
+}
+
+// Consume returns the challenge and invalidates it; of concurrent calls with the same token only one succeeds
+func (s *ChallengeService) Consume(ctx context.Context, token string) (*Challenge, error) {
+	challenge, err := s.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.redis.SaveDataIfNotExists(ctx, usedChallengeKey(token), []byte("1"), ChallengeLifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark MFA challenge as used: %w", err)
+	}
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+
+	s.redis.DeleteAllHSetData(ctx, challengeKey(token))
+	return challenge, nil
+}
+
+func (s *ChallengeService) save(ctx context.Context, token string, challenge *Challenge) error {
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		logger.Error("Error marshaling MFA challenge", err)
+		return fmt.Errorf("failed to marshal MFA challenge: %w", err)
+	}
+
+	if !s.redis.SaveData(ctx, challengeKey(token), data, time.Until(challenge.ExpiresAt)) {
+		return fmt.Errorf("failed to save MFA challenge to Redis")
+	}
+	return nil
+}
+
+func challengeKey(token string) string {
+	return "mfa_challenge:" + hashToken(token)
+}
+
+func attemptsKey(userID string) string {
+	return "mfa_attempts:" + userID
+}
+
+func usedChallengeKey(token string) string {
+	return "mfa_challenge_used:" + hashToken(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
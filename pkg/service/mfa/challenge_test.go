@@ -0,0 +1,170 @@
+package mfa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis implements redisdb.RedisClientInterface in memory
+type fakeRedis struct {
+	data map[string][]byte
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}}
+}
+
+func (f *fakeRedis) GetClient() *redis.Client { return nil }
+
+func (f *fakeRedis) ReadData(ctx context.Context, key string) ([]byte, error) {
+	data, ok := f.data[key]
+	if !ok {
+		return nil, errors.New("redis: nil")
+	}
+	return data, nil
+}
+
+func (f *fakeRedis) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) bool {
+	f.data[key] = data
+	return true
+}
+
+func (f *fakeRedis) SaveDataIfNotExists(ctx context.Context, key string, data []byte, timer time.Duration) (bool, error) {
+	if _, ok := f.data[key]; ok {
+		return false, nil
+	}
+	f.data[key] = data
+	return true, nil
+}
+
+func (f *fakeRedis) SaveHSetData(ctx context.Context, key, field string, value interface{}) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadHSetData(ctx context.Context, key string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) DeleteAllHSetData(ctx context.Context, key string) bool {
+	delete(f.data, key)
+	return true
+}
+
+func (f *fakeRedis) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := f.data[key]
+	return ok, nil
+}
+
+func (f *fakeRedis) Increment(ctx context.Context, key string, timer time.Duration) (int64, error) {
+	count, _ := strconv.ParseInt(string(f.data[key]), 10, 64)
+	count++
+	f.data[key] = []byte(strconv.FormatInt(count, 10))
+	return count, nil
+}
+
+func (f *fakeRedis) TimeToLive(ctx context.Context, key string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (f *fakeRedis) AddToSet(ctx context.Context, key, member string, timer time.Duration) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadSetMembers(ctx context.Context, key string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) RemoveFromSet(ctx context.Context, key, member string) bool { return true }
+
+func (f *fakeRedis) Publish(ctx context.Context, message []byte) error { return nil }
+
+func (f *fakeRedis) Subscriber(ctx context.Context, callback func(msg *redis.Message)) {}
+
+func TestChallengeIsSingleUse(t *testing.T) {
+	s := NewChallengeService(newFakeRedis())
+	ctx := context.Background()
+
+	token, err := s.Issue(ctx, &Challenge{UserID: "user-1", ClientID: "portal"})
+	if err != nil {
+		t.Fatalf("erro ao emitir desafio: %v", err)
+	}
+
+	challenge, err := s.Consume(ctx, token)
+	if err != nil || challenge.UserID != "user-1" || challenge.ClientID != "portal" {
+		t.Fatalf("esperado o desafio emitido, mas obteve %+v (%v)", challenge, err)
+	}
+
+	if _, err := s.Consume(ctx, token); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("segundo uso: esperado ErrChallengeNotFound, mas obteve %v", err)
+	}
+}
+
+func TestChallengeStoredHashed(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewChallengeService(rdb)
+
+	token, _ := s.Issue(context.Background(), &Challenge{UserID: "user-1"})
+
+	sum := sha256.Sum256([]byte(token))
+	if _, ok := rdb.data["mfa_challenge:"+hex.EncodeToString(sum[:])]; !ok {
+		t.Error("desafio deveria ser gravado sob o SHA-256 do token")
+	}
+	for key := range rdb.data {
+		if strings.Contains(key, token) {
+			t.Errorf("token gravado em claro na chave %s", key)
+		}
+	}
+}
+
+func TestAttemptsAreCountedPerUser(t *testing.T) {
+	s := NewChallengeService(newFakeRedis())
+	ctx := context.Background()
+
+	// Cada novo getjwt emite outro desafio, mas as tentativas continuam contando
+	for i := 1; i <= MaxAttempts; i++ {
+		s.Issue(ctx, &Challenge{UserID: "user-1"})
+		remaining, _, err := s.Attempt(ctx, "user-1")
+		if err != nil || remaining != MaxAttempts-i {
+			t.Fatalf("tentativa %d: esperado %d restantes, mas obteve %d (%v)", i, MaxAttempts-i, remaining, err)
+		}
+	}
+
+	_, retryAfter, err := s.Attempt(ctx, "user-1")
+	if !errors.Is(err, ErrLocked) || retryAfter <= 0 {
+		t.Errorf("esperado ErrLocked com tempo de espera, mas obteve %v (%s)", err, retryAfter)
+	}
+	if _, _, err := s.Attempt(ctx, "user-2"); err != nil {
+		t.Errorf("outro usuário não deveria ser bloqueado: %v", err)
+	}
+
+	s.Succeed(ctx, "user-1")
+	if remaining, _, err := s.Attempt(ctx, "user-1"); err != nil || remaining != MaxAttempts-1 {
+		t.Errorf("após o acerto: esperado %d restantes, mas obteve %d (%v)", MaxAttempts-1, remaining, err)
+	}
+}
+
+func TestChallengeExpired(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewChallengeService(rdb)
+	ctx := context.Background()
+
+	token, _ := s.Issue(ctx, &Challenge{UserID: "user-1"})
+
+	// O fake não expira as chaves; a validade gravada no desafio também é conferida
+	expired := &Challenge{UserID: "user-1", ExpiresAt: time.Now().Add(-time.Second)}
+	data, _ := json.Marshal(expired)
+	rdb.data[challengeKey(token)] = data
+
+	if _, err := s.Get(ctx, token); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("esperado ErrChallengeNotFound, mas obteve %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidCiphertext = errors.New("invalid encrypted secret")
+	// ErrMissingKey is returned without SRV_MFA_ENCRYPTION_KEY: a key derived from other
+	// settings could be computed by anyone who knows them
+	ErrMissingKey = errors.New("MFA encryption key is not configured")
+)
+
+// SecretCipher encrypts the TOTP secrets with AES-256-GCM. The user ID is the additional data,
+// so a secret copied to the row of another user does not decrypt.
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher builds the cipher from a 32 byte key
+func NewSecretCipher(key []byte) (*SecretCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("MFA encryption key must have 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretCipher{aead: aead}, nil
+}
+
+// NewSecretCipherFromKey decodes a base64 key, returning ErrMissingKey when it is empty
+func NewSecretCipherFromKey(encoded string) (*SecretCipher, error) {
+	if encoded == "" {
+		return nil, ErrMissingKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("MFA encryption key is not valid base64: %w", err)
+	}
+	return NewSecretCipher(key)
+}
+
+// Encrypt returns base64(nonce | ciphertext) of secret bound to userID
+func (s *SecretCipher) Encrypt(userID, secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), []byte(userID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt
+func (s *SecretCipher) Decrypt(userID, encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, []byte(userID))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(secret), nil
+}
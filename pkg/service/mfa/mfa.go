@@ -0,0 +1,346 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+var (
+	// ErrMFANotEnabled is returned when the user has no confirmed second factor
+	ErrMFANotEnabled = errors.New("MFA is not enabled")
+	// ErrMFANotEnrolled is returned when confirming without a pending secret
+	ErrMFANotEnrolled = errors.New("no pending MFA enrollment")
+	// ErrMFAAlreadyEnabled is returned when enrolling a user that already confirmed a secret
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled")
+	// ErrInvalidCode is returned for wrong, expired or already used codes
+	ErrInvalidCode = errors.New("invalid MFA code")
+)
+
+type MFAServiceInterface interface {
+	Status(ctx context.Context, user *model.User) (*model.MFAStatus, error)
+	Enroll(ctx context.Context, userID uuid.UUID) (secret string, err error)
+	Confirm(ctx context.Context, userID uuid.UUID, code string) (recoveryCodes []string, err error)
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+	Disable(ctx context.Context, userID uuid.UUID) (int64, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error)
+	GetPolicy(ctx context.Context, tenantID uuid.UUID) (*model.MFAPolicy, error)
+	SetPolicy(ctx context.Context, policy *model.MFAPolicy) error
+}
+
+type MFA_service struct {
+	dbp    pgsql.DatabaseInterface
+	cipher *SecretCipher
+}
+
+func NewMFAService(database_pool pgsql.DatabaseInterface, cipher *SecretCipher) *MFA_service {
+	return &MFA_service{
+		dbp:    database_pool,
+		cipher: cipher,
+	}
+}
+
+// Status returns the second factor state of the user and whether the policy of its tenant requires it
+func (ms *MFA_service) Status(ctx context.Context, user *model.User) (*model.MFAStatus, error) {
+	status := &model.MFAStatus{}
+
+	var confirmed bool
+	err := ms.dbp.GetDB().QueryRowContext(ctx, `
+        SELECT m.confirmed_at IS NOT NULL,
+               (SELECT count(*) FROM tb_user_mfa_recovery_code r WHERE r.user_id = m.user_id AND r.used_at IS NULL)
+        FROM tb_user_mfa m
+        WHERE m.user_id = $1`, user.ID).Scan(&confirmed, &status.RecoveryCodes)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		logger.Error("Error reading MFA status", err)
+		return nil, err
+	default:
+		status.Enabled = confirmed
+		status.Pending = !confirmed
+	}
+
+	policy, err := ms.GetPolicy(ctx, user.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	status.Required = policy.Requires(user.AllRoles())
+
+	return status, nil
+}
+
+// Enroll saves a new pending secret for the user, replacing a pending one.
+// The secret only protects the login after Confirm.
+func (ms *MFA_service) Enroll(ctx context.Context, userID uuid.UUID) (string, error) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, err := ms.cipher.Encrypt(userID.String(), secret)
+	if err != nil {
+		logger.Error("Error encrypting MFA secret", err)
+		return "", err
+	}
+
+	result, err := ms.dbp.GetDB().ExecContext(ctx, `
+        INSERT INTO tb_user_mfa (user_id, secret_encrypted)
+        VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE
+        SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, updated_at = now()
+        WHERE tb_user_mfa.confirmed_at IS NULL`, userID, encrypted)
+	if err != nil {
+		logger.Error("Error saving MFA secret", err)
+		return "", err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return "", ErrMFAAlreadyEnabled
+	}
+
+	return secret, nil
+}
+
+// Confirm enables the pending secret when code matches it and returns new recovery codes
+func (ms *MFA_service) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	secret, _, err := ms.secret(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := ValidateCode(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	tx, err := ms.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting transaction", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+        UPDATE tb_user_mfa SET confirmed_at = now(), last_used_step = $2, updated_at = now()
+        WHERE user_id = $1 AND confirmed_at IS NULL`, userID, step)
+	if err != nil {
+		logger.Error("Error confirming MFA", err)
+		return nil, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrMFANotEnrolled
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Error committing transaction", err)
+		return nil, err
+	}
+
+	logger.Info("MFA enabled for user " + userID.String())
+	return codes, nil
+}
+
+// Verify accepts a TOTP code, each time step once, or an unused recovery code, which is spent
+func (ms *MFA_service) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	secret, lastStep, err := ms.secret(ctx, userID, true)
+	if err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return ms.useRecoveryCode(ctx, userID, code)
+	}
+
+	step, ok := ValidateCode(secret, code, time.Now())
+	if !ok || step <= lastStep {
+		return ErrInvalidCode
+	}
+
+	// The condition settles concurrent logins with the same code: only one moves the step
+	result, err := ms.dbp.GetDB().ExecContext(ctx,
+		"UPDATE tb_user_mfa SET last_used_step = $2, updated_at = now() WHERE user_id = $1 AND last_used_step < $2", userID, step)
+	if err != nil {
+		logger.Error("Error saving MFA time step", err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+// Disable removes the secret and the recovery codes of the user
+func (ms *MFA_service) Disable(ctx context.Context, userID uuid.UUID) (int64, error) {
+	tx, err := ms.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting transaction", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tb_user_mfa_recovery_code WHERE user_id = $1", userID); err != nil {
+		logger.Error("Error deleting recovery codes", err)
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM tb_user_mfa WHERE user_id = $1", userID)
+	if err != nil {
+		logger.Error("Error deleting MFA secret", err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Error committing transaction", err)
+		return 0, err
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user
+func (ms *MFA_service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	tx, err := ms.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting transaction", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var enabled bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT confirmed_at IS NOT NULL FROM tb_user_mfa WHERE user_id = $1 FOR UPDATE", userID).Scan(&enabled)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !enabled) {
+		return nil, ErrMFANotEnabled
+	}
+	if err != nil {
+		logger.Error("Error reading MFA secret", err)
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Error committing transaction", err)
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// GetPolicy returns the MFA policy of the tenant, empty when none was saved
+func (ms *MFA_service) GetPolicy(ctx context.Context, tenantID uuid.UUID) (*model.MFAPolicy, error) {
+	policy := &model.MFAPolicy{TenantID: tenantID, RequiredRoles: []string{}}
+
+	var roles string
+	err := ms.dbp.GetDB().QueryRowContext(ctx,
+		"SELECT required_roles, updated_at FROM tb_tenant_mfa_policy WHERE tenant_id = $1", tenantID).Scan(&roles, &policy.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return policy, nil
+	}
+	if err != nil {
+		logger.Error("Error reading MFA policy", err)
+		return nil, err
+	}
+
+	policy.RequiredRoles = strings.Fields(roles)
+	return policy, nil
+}
+
+// SetPolicy saves the roles that must use MFA in the tenant
+func (ms *MFA_service) SetPolicy(ctx context.Context, policy *model.MFAPolicy) error {
+	_, err := ms.dbp.GetDB().ExecContext(ctx, `
+        INSERT INTO tb_tenant_mfa_policy (tenant_id, required_roles, updated_at)
+        VALUES ($1, $2, now())
+        ON CONFLICT (tenant_id) DO UPDATE
+        SET required_roles = EXCLUDED.required_roles, updated_at = now()`,
+		policy.TenantID, strings.Join(policy.RequiredRoles, " "))
+	if err != nil {
+		logger.Error("Error saving MFA policy", err)
+		return err
+	}
+
+	return nil
+}
+
+// secret decrypts the confirmed (or the pending) secret of the user
+func (ms *MFA_service) secret(ctx context.Context, userID uuid.UUID, confirmed bool) (string, int64, error) {
+	var encrypted string
+	var lastStep int64
+	err := ms.dbp.GetDB().QueryRowContext(ctx, `
+        SELECT secret_encrypted, last_used_step FROM tb_user_mfa
+        WHERE user_id = $1 AND (confirmed_at IS NOT NULL) = $2`, userID, confirmed).Scan(&encrypted, &lastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		if confirmed {
+			return "", 0, ErrMFANotEnabled
+		}
+		return "", 0, ErrMFANotEnrolled
+	}
+	if err != nil {
+		logger.Error("Error reading MFA secret", err)
+		return "", 0, err
+	}
+
+	secret, err := ms.cipher.Decrypt(userID.String(), encrypted)
+	if err != nil {
+		logger.Error("Error decrypting MFA secret of user "+userID.String(), err)
+		return "", 0, err
+	}
+
+	return secret, lastStep, nil
+}
+
+func (ms *MFA_service) useRecoveryCode(ctx context.Context, userID uuid.UUID, code string) error {
+	result, err := ms.dbp.GetDB().ExecContext(ctx, `
+        UPDATE tb_user_mfa_recovery_code SET used_at = now()
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, hashRecoveryCode(code))
+	if err != nil {
+		logger.Error("Error using recovery code", err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrInvalidCode
+	}
+
+	logger.Info("Recovery code used by user " + userID.String())
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]string, error) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tb_user_mfa_recovery_code WHERE user_id = $1", userID); err != nil {
+		logger.Error("Error deleting recovery codes", err)
+		return nil, err
+	}
+
+	for _, code := range codes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO tb_user_mfa_recovery_code (user_id, code_hash) VALUES ($1, $2)", userID, hashRecoveryCode(code)); err != nil {
+			logger.Error("Error saving recovery code", err)
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
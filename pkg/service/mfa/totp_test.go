@@ -0,0 +1,135 @@
+package mfa
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vetores de teste do RFC 6238 (apêndice B) para SHA-1 com 8 dígitos
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tc := range cases {
+		if got := hotp(key, step(time.Unix(tc.unix, 0)), 8); got != tc.code {
+			t.Errorf("T=%d: esperado %s, mas obteve %s", tc.unix, tc.code, got)
+		}
+	}
+}
+
+func TestValidateCodeAcceptsSkew(t *testing.T) {
+	secret := secretEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+
+	code, err := GenerateCode(secret, now)
+	if err != nil {
+		t.Fatalf("erro ao gerar código: %v", err)
+	}
+	// Os 6 últimos dígitos do vetor de 8 dígitos
+	if code != "050471" {
+		t.Fatalf("esperado 050471, mas obteve %s", code)
+	}
+
+	for _, offset := range []time.Duration{-Period, 0, Period} {
+		matched, ok := ValidateCode(secret, code, now.Add(offset))
+		if !ok {
+			t.Errorf("código rejeitado com desvio de %v", offset)
+		}
+		if matched != step(now) {
+			t.Errorf("desvio de %v: esperado passo %d, mas obteve %d", offset, step(now), matched)
+		}
+	}
+
+	if _, ok := ValidateCode(secret, code, now.Add(3*Period)); ok {
+		t.Error("código expirado não deveria ser aceito")
+	}
+	if _, ok := ValidateCode(secret, "12345", now); ok {
+		t.Error("código com tamanho errado não deveria ser aceito")
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("erro ao gerar segredo: %v", err)
+	}
+	if key, err := decodeSecret(secret); err != nil || len(key) != secretSize {
+		t.Fatalf("segredo inválido %q: %v", secret, err)
+	}
+
+	uri := ProvisioningURI("access-control", "maria", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/access-control:maria?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("URI inesperada: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("erro ao gerar códigos: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("esperado %d códigos, mas obteve %d", RecoveryCodeCount, len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("formato inesperado: %s", code)
+		}
+		if seen[code] {
+			t.Errorf("código repetido: %s", code)
+		}
+		seen[code] = true
+	}
+
+	// O usuário pode digitar sem hífen e em maiúsculas
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))
+	if hashRecoveryCode(typed) != hashRecoveryCode(codes[0]) {
+		t.Error("código digitado sem hífen deveria ser equivalente")
+	}
+}
+
+func TestSecretCipher(t *testing.T) {
+	cipher, err := NewSecretCipherFromKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("erro ao criar cifra: %v", err)
+	}
+
+	encrypted, err := cipher.Encrypt("user-1", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("erro ao cifrar: %v", err)
+	}
+	if strings.Contains(encrypted, "JBSWY3DPEHPK3PXP") {
+		t.Fatal("segredo gravado em claro")
+	}
+
+	if secret, err := cipher.Decrypt("user-1", encrypted); err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("esperado o segredo original, mas obteve %q (%v)", secret, err)
+	}
+
+	// O segredo copiado para outro usuário não decifra
+	if _, err := cipher.Decrypt("user-2", encrypted); err == nil {
+		t.Error("segredo de outro usuário não deveria decifrar")
+	}
+
+	if _, err := NewSecretCipherFromKey("curta"); err == nil {
+		t.Error("chave inválida deveria falhar")
+	}
+	if _, err := NewSecretCipherFromKey(""); !errors.Is(err, ErrMissingKey) {
+		t.Errorf("sem chave: esperado ErrMissingKey, mas obteve %v", err)
+	}
+}
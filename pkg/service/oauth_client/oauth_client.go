@@ -0,0 +1,154 @@
+package oauth_client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidClient is returned for unknown or disabled clients and wrong secrets alike
+var ErrInvalidClient = errors.New("invalid client credentials")
+
+// dummySecretHash is compared when the client does not exist, so the response time
+// does not reveal which client IDs are registered
+var dummySecretHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-client-secret"), bcrypt.DefaultCost)
+
+type OAuthClientServiceInterface interface {
+	GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error)
+	GetByClientID(ctx context.Context, clientID string) (*model.OAuthClient, error)
+	Create(ctx context.Context, client *model.OAuthClient) (*model.OAuthClient, error)
+	Delete(ctx context.Context, clientID string) int64
+	Authenticate(ctx context.Context, clientID, secret string) (*model.OAuthClient, error)
+}
+
+type OAuthClient_service struct {
+	dbp pgsql.DatabaseInterface
+}
+
+func NewOAuthClientService(database_pool pgsql.DatabaseInterface) *OAuthClient_service {
+	return &OAuthClient_service{
+		dbp: database_pool,
+	}
+}
+
+func (s *OAuthClient_service) GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error) {
+	var total int64
+	err := s.dbp.GetDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM tb_oauth_client").Scan(&total)
+	if err != nil {
+		logger.Error("Error getting total count", err)
+		return nil, err
+	}
+
+	paginate := model.NewPaginate(limit, page, total)
+
+	offset := (paginate.Page - 1) * paginate.Limit
+	rows, err := s.dbp.GetDB().QueryContext(ctx,
+		"SELECT client_id, name, secret_hash, scopes, tenant_id, redirect_uris, first_party, public, enabled, created_at, updated_at FROM tb_oauth_client ORDER BY created_at LIMIT $1 OFFSET $2",
+		paginate.Limit, offset)
+	if err != nil {
+		logger.Error("Error querying oauth clients", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	client_list := &model.OAuthClientList{List: []model.OAuthClient{}}
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			logger.Error("Error scanning oauth client", err)
+			return nil, err
+		}
+		client_list.List = append(client_list.List, *client)
+	}
+
+	paginate.Paginate(client_list)
+	return paginate, nil
+}
+
+func (s *OAuthClient_service) GetByClientID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	row := s.dbp.GetDB().QueryRowContext(ctx,
+		"SELECT client_id, name, secret_hash, scopes, tenant_id, redirect_uris, first_party, public, enabled, created_at, updated_at FROM tb_oauth_client WHERE client_id = $1", clientID)
+
+	client, err := scanClient(row)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Error("Error getting oauth client", err)
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (s *OAuthClient_service) Create(ctx context.Context, client *model.OAuthClient) (*model.OAuthClient, error) {
+	query := "INSERT INTO tb_oauth_client (client_id, name, secret_hash, scopes, tenant_id, redirect_uris, first_party, public, enabled) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+
+	_, err := s.dbp.GetDB().ExecContext(ctx, query,
+		client.ClientID, client.Name, sql.NullString{String: client.SecretHash, Valid: client.SecretHash != ""},
+		strings.Join(client.Scopes, " "), client.TenantID, strings.Join(client.RedirectURIs, " "),
+		client.FirstParty, client.Public, client.Enable)
+	if err != nil {
+		logger.Error("Error executing SQL query insert oauth client", err)
+		return client, err
+	}
+
+	return client, nil
+}
+
+func (s *OAuthClient_service) Delete(ctx context.Context, clientID string) int64 {
+	result, err := s.dbp.GetDB().ExecContext(ctx, "DELETE FROM tb_oauth_client WHERE client_id = $1", clientID)
+	if err != nil {
+		logger.Error("Error deleting oauth client", err)
+		return 0
+	}
+
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error getting rows affected", err)
+		return 0
+	}
+
+	return rowsAff
+}
+
+// Authenticate checks the client secret; disabled clients cannot authenticate
+func (s *OAuthClient_service) Authenticate(ctx context.Context, clientID, secret string) (*model.OAuthClient, error) {
+	client, err := s.GetByClientID(ctx, clientID)
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummySecretHash, []byte(secret))
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if !client.CheckSecret(secret) || !client.Enable {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanClient(row rowScanner) (*model.OAuthClient, error) {
+	client := &model.OAuthClient{}
+	var secretHash sql.NullString
+	var scopes, redirectURIs string
+	if err := row.Scan(&client.ClientID, &client.Name, &secretHash, &scopes, &client.TenantID, &redirectURIs,
+		&client.FirstParty, &client.Public, &client.Enable, &client.CreatedAt, &client.UpdatedAt); err != nil {
+		return nil, err
+	}
+	client.SecretHash = secretHash.String
+	client.Scopes = strings.Fields(scopes)
+	client.RedirectURIs = strings.Fields(redirectURIs)
+	return client, nil
+}
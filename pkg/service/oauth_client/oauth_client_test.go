@@ -0,0 +1,178 @@
+package oauth_client
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	pgsql_mocks "github.com/katana-stuidio/access-control/pkg/adapter/pgsql/mocks"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const selectClient = "SELECT client_id, name, secret_hash, scopes, tenant_id, redirect_uris, first_party, public, enabled, created_at, updated_at FROM tb_oauth_client WHERE client_id = $1"
+
+var testTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+
+func newTestService(t *testing.T) (*OAuthClient_service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar o banco de teste: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	pool := pgsql_mocks.NewMockDatabaseInterface(gomock.NewController(t))
+	pool.EXPECT().GetDB().Return(db).AnyTimes()
+	return NewOAuthClientService(pool), mock
+}
+
+var clientColumns = []string{"client_id", "name", "secret_hash", "scopes", "tenant_id", "redirect_uris", "first_party", "public", "enabled", "created_at", "updated_at"}
+
+func noClient() *sqlmock.Rows {
+	return sqlmock.NewRows(clientColumns)
+}
+
+func clientRows(secretHash interface{}, enabled bool) *sqlmock.Rows {
+	return sqlmock.NewRows(clientColumns).
+		AddRow("reports", "Relatórios", secretHash, "quiz:read ranking:read", testTenantID, "https://reports.katana.com/cb https://reports.katana.com/cb2", false, secretHash == nil, enabled, time.Now(), time.Now())
+}
+
+// secretHash confere que o banco recebe o hash bcrypt do segredo, e não o segredo
+type secretHash struct{ secret string }
+
+func (h secretHash) Match(v driver.Value) bool {
+	value, ok := v.(string)
+	return ok && value != h.secret && bcrypt.CompareHashAndPassword([]byte(value), []byte(h.secret)) == nil
+}
+
+func TestCreateStoresSecretHash(t *testing.T) {
+	service, mock := newTestService(t)
+
+	client, secret, err := model.NewOAuthClient(&model.OAuthClient{Name: "Relatórios", Scopes: []string{"quiz:read"}, TenantID: testTenantID})
+	if err != nil || secret == "" {
+		t.Fatalf("Erro ao criar o cliente: %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tb_oauth_client")).
+		WithArgs(client.ClientID, "Relatórios", secretHash{secret}, "quiz:read", testTenantID, "", false, false, true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.Create(context.Background(), client); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreatePublicClientWithoutSecret(t *testing.T) {
+	service, mock := newTestService(t)
+
+	client, secret, _ := model.NewOAuthClient(&model.OAuthClient{Name: "Portal", TenantID: testTenantID, Public: true})
+	if secret != "" {
+		t.Fatal("cliente público recebeu segredo")
+	}
+
+	// Sem segredo a coluna fica NULL
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tb_oauth_client")).
+		WithArgs(client.ClientID, "Portal", nil, "", testTenantID, "", false, true, true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.Create(context.Background(), client); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetByClientID(t *testing.T) {
+	service, mock := newTestService(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectClient)).WithArgs("reports").WillReturnRows(clientRows("hash", true))
+	client, err := service.GetByClientID(context.Background(), "reports")
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if client.SecretHash != "hash" || len(client.Scopes) != 2 || len(client.RedirectURIs) != 2 || client.TenantID != testTenantID {
+		t.Errorf("cliente inesperado %+v", client)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectClient)).WithArgs("desconhecido").WillReturnRows(noClient())
+	if _, err := service.GetByClientID(context.Background(), "desconhecido"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("esperado sql.ErrNoRows, mas obteve %v", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("s3gr3d0"), bcrypt.MinCost)
+
+	tests := []struct {
+		name     string
+		rows     *sqlmock.Rows
+		dbErr    error
+		secret   string
+		expected error
+	}{
+		{"segredo correto", clientRows(string(hash), true), nil, "s3gr3d0", nil},
+		{"segredo errado", clientRows(string(hash), true), nil, "errado", ErrInvalidClient},
+		{"cliente desativado", clientRows(string(hash), false), nil, "s3gr3d0", ErrInvalidClient},
+		{"cliente público", clientRows(nil, true), nil, "", ErrInvalidClient},
+		{"cliente desconhecido", noClient(), nil, "s3gr3d0", ErrInvalidClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mock := newTestService(t)
+			mock.ExpectQuery(regexp.QuoteMeta(selectClient)).WithArgs("reports").WillReturnRows(tt.rows)
+
+			client, err := service.Authenticate(context.Background(), "reports", tt.secret)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("esperado %v, mas obteve %v", tt.expected, err)
+			}
+			if err == nil && client.ClientID != "reports" {
+				t.Errorf("cliente inesperado %+v", client)
+			}
+		})
+	}
+}
+
+func TestAuthenticateReturnsDatabaseError(t *testing.T) {
+	service, mock := newTestService(t)
+
+	// Falha do banco não se confunde com credencial inválida
+	mock.ExpectQuery(regexp.QuoteMeta(selectClient)).WillReturnError(errors.New("conexão perdida"))
+	if _, err := service.Authenticate(context.Background(), "reports", "s3gr3d0"); err == nil || errors.Is(err, ErrInvalidClient) {
+		t.Errorf("esperado o erro do banco, mas obteve %v", err)
+	}
+}
+
+func TestUnknownClientCostsLikeKnownClient(t *testing.T) {
+	// O hash comparado para clientes desconhecidos tem o custo dos segredos gravados,
+	// assim o tempo de resposta não revela quais client_id existem
+	cost, err := bcrypt.Cost(dummySecretHash)
+	if err != nil || cost != bcrypt.DefaultCost {
+		t.Fatalf("esperado custo %d, mas obteve %d (%v)", bcrypt.DefaultCost, cost, err)
+	}
+
+	service, mock := newTestService(t)
+	mock.ExpectQuery(regexp.QuoteMeta(selectClient)).WillReturnRows(noClient())
+
+	// Abaixo de um bcrypt.CompareHashAndPassword o hash não foi comparado
+	start := time.Now()
+	bcrypt.CompareHashAndPassword(dummySecretHash, []byte("s3gr3d0"))
+	compare := time.Since(start)
+
+	start = time.Now()
+	service.Authenticate(context.Background(), "desconhecido", "s3gr3d0")
+	if elapsed := time.Since(start); elapsed < compare/2 {
+		t.Errorf("cliente desconhecido respondeu em %v, uma comparação leva %v", elapsed, compare)
+	}
+}
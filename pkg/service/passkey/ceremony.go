@@ -0,0 +1,107 @@
+package passkey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/redisdb"
+	"github.com/katana-stuidio/access-control/pkg/webauthn"
+)
+
+// Ceremony types
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+var ErrCeremonyNotFound = errors.New("WebAuthn ceremony not found or expired")
+
+// Ceremony is a registration or login started with a challenge and waiting for the authenticator
+type Ceremony struct {
+	Type string `json:"type"`
+	// UserID is the user registering a passkey, or the user named at the start of a login;
+	// empty for a login with a discoverable credential
+	UserID    string    `json:"user_id,omitempty"`
+	ClientID  string    `json:"client_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type CeremonyServiceInterface interface {
+	Begin(ctx context.Context, challenge string, ceremony *Ceremony) error
+	Consume(ctx context.Context, challenge string) (*Ceremony, error)
+}
+
+// CeremonyService keeps the ceremonies in Redis under the SHA-256 of the challenge
+type CeremonyService struct {
+	redis redisdb.RedisClientInterface
+}
+
+func NewCeremonyService(redis redisdb.RedisClientInterface) *CeremonyService {
+	return &CeremonyService{
+		redis: redis,
+	}
+}
+
+// Begin stores the ceremony of challenge for webauthn.Timeout
+func (s *CeremonyService) Begin(ctx context.Context, challenge string, ceremony *Ceremony) error {
+	ceremony.ExpiresAt = time.Now().Add(webauthn.Timeout)
+
+	data, err := json.Marshal(ceremony)
+	if err != nil {
+		logger.Error("Error marshaling WebAuthn ceremony", err)
+		return fmt.Errorf("failed to marshal WebAuthn ceremony: %w", err)
+	}
+
+	if !s.redis.SaveData(ctx, ceremonyKey(challenge), data, webauthn.Timeout) {
+		return fmt.Errorf("failed to save WebAuthn ceremony to Redis")
+	}
+	return nil
+}
+
+// Consume returns the ceremony of challenge and invalidates it; of concurrent calls with the same
+// challenge only one succeeds, so a signed response cannot be replayed
+func (s *CeremonyService) Consume(ctx context.Context, challenge string) (*Ceremony, error) {
+	data, err := s.redis.ReadData(ctx, ceremonyKey(challenge))
+	if err != nil || len(data) == 0 {
+		return nil, ErrCeremonyNotFound
+	}
+
+	var ceremony Ceremony
+	if err := json.Unmarshal(data, &ceremony); err != nil {
+		logger.Error("Error unmarshaling WebAuthn ceremony", err)
+		return nil, fmt.Errorf("failed to unmarshal WebAuthn ceremony: %w", err)
+	}
+	if time.Now().After(ceremony.ExpiresAt) {
+		return nil, ErrCeremonyNotFound
+	}
+
+	ok, err := s.redis.SaveDataIfNotExists(ctx, usedCeremonyKey(challenge), []byte("1"), webauthn.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark WebAuthn ceremony as used: %w", err)
+	}
+	if !ok {
+		return nil, ErrCeremonyNotFound
+	}
+
+	s.redis.DeleteAllHSetData(ctx, ceremonyKey(challenge))
+	return &ceremony, nil
+}
+
+func ceremonyKey(challenge string) string {
+	return "webauthn_ceremony:" + hashChallenge(challenge)
+}
+
+func usedCeremonyKey(challenge string) string {
+	return "webauthn_ceremony_used:" + hashChallenge(challenge)
+}
+
+func hashChallenge(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return hex.EncodeToString(sum[:])
+}
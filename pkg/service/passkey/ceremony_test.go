@@ -0,0 +1,136 @@
+package passkey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis implements redisdb.RedisClientInterface in memory
+type fakeRedis struct {
+	data map[string][]byte
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}}
+}
+
+func (f *fakeRedis) GetClient() *redis.Client { return nil }
+
+func (f *fakeRedis) ReadData(ctx context.Context, key string) ([]byte, error) {
+	data, ok := f.data[key]
+	if !ok {
+		return nil, errors.New("redis: nil")
+	}
+	return data, nil
+}
+
+func (f *fakeRedis) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) bool {
+	f.data[key] = data
+	return true
+}
+
+func (f *fakeRedis) SaveDataIfNotExists(ctx context.Context, key string, data []byte, timer time.Duration) (bool, error) {
+	if _, ok := f.data[key]; ok {
+		return false, nil
+	}
+	f.data[key] = data
+	return true, nil
+}
+
+func (f *fakeRedis) SaveHSetData(ctx context.Context, key, field string, value interface{}) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadHSetData(ctx context.Context, key string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) DeleteAllHSetData(ctx context.Context, key string) bool {
+	delete(f.data, key)
+	return true
+}
+
+func (f *fakeRedis) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := f.data[key]
+	return ok, nil
+}
+
+func (f *fakeRedis) Increment(ctx context.Context, key string, timer time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRedis) TimeToLive(ctx context.Context, key string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (f *fakeRedis) AddToSet(ctx context.Context, key, member string, timer time.Duration) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadSetMembers(ctx context.Context, key string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) RemoveFromSet(ctx context.Context, key, member string) bool { return true }
+
+func (f *fakeRedis) Publish(ctx context.Context, message []byte) error { return nil }
+
+func (f *fakeRedis) Subscriber(ctx context.Context, callback func(msg *redis.Message)) {}
+
+func TestCeremonyIsSingleUse(t *testing.T) {
+	s := NewCeremonyService(newFakeRedis())
+	ctx := context.Background()
+
+	if err := s.Begin(ctx, "challenge-1", &Ceremony{Type: CeremonyLogin, ClientID: "portal"}); err != nil {
+		t.Fatalf("erro ao iniciar cerimônia: %v", err)
+	}
+
+	ceremony, err := s.Consume(ctx, "challenge-1")
+	if err != nil || ceremony.Type != CeremonyLogin || ceremony.ClientID != "portal" {
+		t.Fatalf("esperada a cerimônia iniciada, mas obteve %+v (%v)", ceremony, err)
+	}
+
+	if _, err := s.Consume(ctx, "challenge-1"); !errors.Is(err, ErrCeremonyNotFound) {
+		t.Errorf("segundo uso: esperado ErrCeremonyNotFound, mas obteve %v", err)
+	}
+}
+
+func TestCeremonyStoredHashed(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewCeremonyService(rdb)
+
+	s.Begin(context.Background(), "challenge-1", &Ceremony{Type: CeremonyRegistration, UserID: "user-1"})
+
+	sum := sha256.Sum256([]byte("challenge-1"))
+	if _, ok := rdb.data["webauthn_ceremony:"+hex.EncodeToString(sum[:])]; !ok {
+		t.Error("cerimônia deveria ser gravada sob o SHA-256 do desafio")
+	}
+	for key := range rdb.data {
+		if strings.Contains(key, "challenge-1") {
+			t.Errorf("desafio gravado em claro na chave %s", key)
+		}
+	}
+}
+
+func TestCeremonyExpired(t *testing.T) {
+	rdb := newFakeRedis()
+	s := NewCeremonyService(rdb)
+	ctx := context.Background()
+
+	// O fake não expira as chaves; a validade gravada na cerimônia também é conferida
+	expired := &Ceremony{Type: CeremonyLogin, ExpiresAt: time.Now().Add(-time.Second)}
+	data, _ := json.Marshal(expired)
+	rdb.data[ceremonyKey("challenge-1")] = data
+
+	if _, err := s.Consume(ctx, "challenge-1"); !errors.Is(err, ErrCeremonyNotFound) {
+		t.Errorf("esperado ErrCeremonyNotFound, mas obteve %v", err)
+	}
+}
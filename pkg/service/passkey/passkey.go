@@ -0,0 +1,158 @@
+package passkey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/webauthn"
+)
+
+var (
+	// ErrCredentialNotFound is returned when no passkey has the credential ID
+	ErrCredentialNotFound = errors.New("credential not found")
+	// ErrCredentialExists is returned when the credential ID is already registered
+	ErrCredentialExists = errors.New("credential already registered")
+)
+
+type PasskeyServiceInterface interface {
+	GetByUser(ctx context.Context, userID uuid.UUID) ([]model.UserCredential, error)
+	GetByCredentialID(ctx context.Context, credentialID string) (*model.UserCredential, error)
+	Create(ctx context.Context, credential *model.UserCredential) (*model.UserCredential, error)
+	UpdateSignCount(ctx context.Context, ID uuid.UUID, signCount uint32) error
+	Delete(ctx context.Context, userID, ID uuid.UUID) int64
+}
+
+type Passkey_service struct {
+	dbp pgsql.DatabaseInterface
+}
+
+func NewPasskeyService(database_pool pgsql.DatabaseInterface) *Passkey_service {
+	return &Passkey_service{
+		dbp: database_pool,
+	}
+}
+
+const credentialColumns = "id, user_id, credential_id, public_key, sign_count, aaguid, transports, name, created_at, last_used_at"
+
+// GetByUser returns the passkeys of the user, the most recent first
+func (ps *Passkey_service) GetByUser(ctx context.Context, userID uuid.UUID) ([]model.UserCredential, error) {
+	rows, err := ps.dbp.GetDB().QueryContext(ctx,
+		"SELECT "+credentialColumns+" FROM tb_user_credential WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		logger.Error("Error querying user credentials", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := []model.UserCredential{}
+	for rows.Next() {
+		credential, err := scanCredential(rows)
+		if err != nil {
+			logger.Error("Error scanning user credential", err)
+			return nil, err
+		}
+		credentials = append(credentials, *credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+// GetByCredentialID returns the passkey with the base64url credential ID of the authenticator
+func (ps *Passkey_service) GetByCredentialID(ctx context.Context, credentialID string) (*model.UserCredential, error) {
+	row := ps.dbp.GetDB().QueryRowContext(ctx,
+		"SELECT "+credentialColumns+" FROM tb_user_credential WHERE credential_id = $1", credentialID)
+
+	credential, err := scanCredential(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		logger.Error("Error reading user credential", err)
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+func (ps *Passkey_service) Create(ctx context.Context, credential *model.UserCredential) (*model.UserCredential, error) {
+	result, err := ps.dbp.GetDB().ExecContext(ctx, `
+        INSERT INTO tb_user_credential (id, user_id, credential_id, public_key, sign_count, aaguid, transports, name)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (credential_id) DO NOTHING`,
+		credential.ID, credential.UserID, credential.CredentialID, credential.PublicKey, int64(credential.SignCount),
+		credential.AAGUID, strings.Join(credential.Transports, " "), credential.Name)
+	if err != nil {
+		logger.Error("Error executing SQL query insert user credential", err)
+		return credential, err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return credential, ErrCredentialExists
+	}
+
+	logger.Info("Passkey registered for user " + credential.UserID.String())
+	return credential, nil
+}
+
+// UpdateSignCount saves the counter of a login, or returns webauthn.ErrSignCount when another login
+// already stored an equal or higher counter. The condition settles concurrent logins with the
+// same assertion; authenticators without a counter always send 0.
+func (ps *Passkey_service) UpdateSignCount(ctx context.Context, ID uuid.UUID, signCount uint32) error {
+	result, err := ps.dbp.GetDB().ExecContext(ctx, `
+        UPDATE tb_user_credential SET sign_count = $2, last_used_at = now()
+        WHERE id = $1 AND (sign_count < $2 OR $2 = 0)`, ID, int64(signCount))
+	if err != nil {
+		logger.Error("Error updating credential counter", err)
+		return err
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return webauthn.ErrSignCount
+	}
+	return nil
+}
+
+// Delete removes a passkey of the user
+func (ps *Passkey_service) Delete(ctx context.Context, userID, ID uuid.UUID) int64 {
+	result, err := ps.dbp.GetDB().ExecContext(ctx, "DELETE FROM tb_user_credential WHERE id = $1 AND user_id = $2", ID, userID)
+	if err != nil {
+		logger.Error("Error deleting user credential", err)
+		return 0
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCredential(row rowScanner) (*model.UserCredential, error) {
+	var credential model.UserCredential
+	var signCount int64
+	var aaguid uuid.NullUUID
+	var transports string
+	var lastUsedAt sql.NullTime
+
+	err := row.Scan(&credential.ID, &credential.UserID, &credential.CredentialID, &credential.PublicKey, &signCount,
+		&aaguid, &transports, &credential.Name, &credential.CreatedAt, &lastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	credential.SignCount = uint32(signCount)
+	credential.AAGUID = aaguid.UUID
+	credential.Transports = strings.Fields(transports)
+	if lastUsedAt.Valid {
+		credential.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &credential, nil
+}
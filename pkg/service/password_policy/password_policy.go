@@ -0,0 +1,212 @@
+package password_policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PolicyError lists every rule of the policy the password breaks
+type PolicyError struct {
+	Violations []model.PasswordViolation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
+
+type PasswordPolicyServiceInterface interface {
+	GetPolicy(ctx context.Context, groupID uuid.UUID) (*model.PasswordPolicy, error)
+	SetPolicy(ctx context.Context, policy *model.PasswordPolicy) error
+}
+
+type PasswordPolicy_service struct {
+	dbp pgsql.DatabaseInterface
+}
+
+func NewPasswordPolicyService(database_pool pgsql.DatabaseInterface) *PasswordPolicy_service {
+	return &PasswordPolicy_service{
+		dbp: database_pool,
+	}
+}
+
+const policyColumns = `p.min_length, p.require_uppercase, p.require_lowercase, p.require_number, p.require_symbol,
+        p.disallow_personal_data, p.history_size, p.max_age_days, p.updated_at`
+
+// GetPolicy returns the password policy of the tenant group, the default one when none was saved
+func (ps *PasswordPolicy_service) GetPolicy(ctx context.Context, groupID uuid.UUID) (*model.PasswordPolicy, error) {
+	return GroupPolicy(ctx, ps.dbp.GetDB(), groupID)
+}
+
+// SetPolicy saves the password policy of the tenant group
+func (ps *PasswordPolicy_service) SetPolicy(ctx context.Context, policy *model.PasswordPolicy) error {
+	err := ps.dbp.GetDB().QueryRowContext(ctx, `
+        INSERT INTO tb_tenant_group_password_policy (group_id, min_length, require_uppercase, require_lowercase, require_number,
+            require_symbol, disallow_personal_data, history_size, max_age_days, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now())
+        ON CONFLICT (group_id) DO UPDATE
+        SET min_length = EXCLUDED.min_length, require_uppercase = EXCLUDED.require_uppercase,
+            require_lowercase = EXCLUDED.require_lowercase, require_number = EXCLUDED.require_number,
+            require_symbol = EXCLUDED.require_symbol, disallow_personal_data = EXCLUDED.disallow_personal_data,
+            history_size = EXCLUDED.history_size, max_age_days = EXCLUDED.max_age_days, updated_at = now()
+        RETURNING updated_at`,
+		policy.GroupID, policy.MinLength, policy.RequireUppercase, policy.RequireLowercase, policy.RequireNumber,
+		policy.RequireSymbol, policy.DisallowPersonalData, policy.HistorySize, policy.MaxAgeDays).Scan(&policy.UpdatedAt)
+	if err != nil {
+		logger.Error("Error saving password policy", err)
+		return err
+	}
+
+	return nil
+}
+
+// Queryer is satisfied by *sql.DB and *sql.Tx, so the user service can check a password inside its transactions
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// TenantPolicy returns the password policy of the tenant group of the tenant
+func TenantPolicy(ctx context.Context, q Queryer, tenantID uuid.UUID) (*model.PasswordPolicy, error) {
+	var groupID uuid.UUID
+	err := q.QueryRowContext(ctx, "SELECT group_id FROM tb_tenant WHERE id = $1", tenantID).Scan(&groupID)
+	if err != nil {
+		logger.Error("Error reading tenant group of the password policy", err)
+		return nil, err
+	}
+
+	return GroupPolicy(ctx, q, groupID)
+}
+
+// GroupPolicy returns the password policy of the tenant group, the default one when none was saved
+func GroupPolicy(ctx context.Context, q Queryer, groupID uuid.UUID) (*model.PasswordPolicy, error) {
+	policy := model.DefaultPasswordPolicy(groupID)
+
+	row := q.QueryRowContext(ctx, "SELECT "+policyColumns+" FROM tb_tenant_group_password_policy p WHERE p.group_id = $1", groupID)
+	if err := scanPolicy(row, policy); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Error reading password policy", err)
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Validate checks the password against the policy of the tenant of the user, returning a *PolicyError
+// with every rule broken. The history is only checked for users already saved
+func Validate(ctx context.Context, q Queryer, user *model.User, password string) error {
+	policy, err := TenantPolicy(ctx, q, user.TenantID)
+	if err != nil {
+		return err
+	}
+
+	violations := policy.Check(user, password)
+
+	if policy.HistorySize > 0 && user.ID != uuid.Nil {
+		reused, err := inHistory(ctx, q, user.ID, policy.HistorySize, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, policy.HistoryViolation())
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Expired reports whether the password of the user is older than the maximum age of its policy
+func Expired(ctx context.Context, q Queryer, user *model.User) (bool, error) {
+	policy, err := TenantPolicy(ctx, q, user.TenantID)
+	if err != nil {
+		return false, err
+	}
+	if policy.MaxAgeDays <= 0 {
+		return false, nil
+	}
+
+	var changedAt time.Time
+	err = q.QueryRowContext(ctx, "SELECT password_changed_at FROM tb_user WHERE id = $1", user.ID).Scan(&changedAt)
+	if err != nil {
+		logger.Error("Error reading password age", err)
+		return false, err
+	}
+
+	return policy.Expired(changedAt), nil
+}
+
+// RecordHistory stores the hash of a new password, keeping the last model.PasswordHistoryLimit hashes of the user
+func RecordHistory(ctx context.Context, tx *sql.Tx, userID uuid.UUID, hashedPassword string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO tb_user_password_history (user_id, hashed_password) VALUES ($1, $2)", userID, hashedPassword)
+	if err != nil {
+		logger.Error("Error saving password history", err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        DELETE FROM tb_user_password_history
+        WHERE user_id = $1
+          AND id NOT IN (SELECT id FROM tb_user_password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2)`,
+		userID, model.PasswordHistoryLimit)
+	if err != nil {
+		logger.Error("Error pruning password history", err)
+		return err
+	}
+
+	return nil
+}
+
+// inHistory compares the password with the last size hashes of the user. The current hash of tb_user
+// is also compared, as users created before the history have no rows in it
+func inHistory(ctx context.Context, q Queryer, userID uuid.UUID, size int, password string) (bool, error) {
+	rows, err := q.QueryContext(ctx, `
+        SELECT h.hashed_password FROM (
+            SELECT hashed_password FROM tb_user_password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+        ) h
+        UNION
+        SELECT hashed_password FROM tb_user WHERE id = $1 AND hashed_password IS NOT NULL`, userID, size)
+	if err != nil {
+		logger.Error("Error reading password history", err)
+		return false, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			logger.Error("Error scanning password history", err)
+			return false, err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func scanPolicy(row *sql.Row, policy *model.PasswordPolicy) error {
+	return row.Scan(&policy.MinLength, &policy.RequireUppercase, &policy.RequireLowercase, &policy.RequireNumber,
+		&policy.RequireSymbol, &policy.DisallowPersonalData, &policy.HistorySize, &policy.MaxAgeDays, &policy.UpdatedAt)
+}
@@ -0,0 +1,161 @@
+package password_policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	testUserID   = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	testTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	testGroupID  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+const historyQuery = "SELECT h.hashed_password FROM"
+
+func newTestDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar o banco de teste: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func hash(t *testing.T, password string) string {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Erro ao gerar o hash: %v", err)
+	}
+	return string(hashed)
+}
+
+func TestInHistory(t *testing.T) {
+	db, mock := newTestDB(t)
+	hashes := sqlmock.NewRows([]string{"hashed_password"}).
+		AddRow(hash(t, "Antiga#2023")).
+		AddRow(hash(t, "Atual#2024"))
+
+	mock.ExpectQuery(historyQuery).WithArgs(testUserID, 5).WillReturnRows(hashes)
+	reused, err := inHistory(context.Background(), db, testUserID, 5, "Antiga#2023")
+	if err != nil || !reused {
+		t.Errorf("senha do histórico: esperado true, mas obteve %v (%v)", reused, err)
+	}
+
+	mock.ExpectQuery(historyQuery).WithArgs(testUserID, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"hashed_password"}).AddRow(hash(t, "Atual#2024")))
+	reused, err = inHistory(context.Background(), db, testUserID, 5, "Nova#2025")
+	if err != nil || reused {
+		t.Errorf("senha nova: esperado false, mas obteve %v (%v)", reused, err)
+	}
+
+	mock.ExpectQuery(historyQuery).WillReturnError(errors.New("conexão perdida"))
+	if _, err := inHistory(context.Background(), db, testUserID, 5, "Nova#2025"); err == nil {
+		t.Error("esperado erro do banco")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInHistoryIncludesCurrentPassword(t *testing.T) {
+	db, mock := newTestDB(t)
+
+	// Usuários criados antes do histórico só têm o hash de tb_user
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT hashed_password FROM tb_user WHERE id = $1 AND hashed_password IS NOT NULL")).
+		WithArgs(testUserID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"hashed_password"}).AddRow(hash(t, "Atual#2024")))
+
+	if reused, err := inHistory(context.Background(), db, testUserID, 1, "Atual#2024"); err != nil || !reused {
+		t.Errorf("senha atual: esperado true, mas obteve %v (%v)", reused, err)
+	}
+}
+
+func TestRecordHistoryPrunesBeyondLimit(t *testing.T) {
+	db, mock := newTestDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tb_user_password_history (user_id, hashed_password) VALUES ($1, $2)")).
+		WithArgs(testUserID, "hash").WillReturnResult(sqlmock.NewResult(25, 1))
+	// Mantém só os PasswordHistoryLimit hashes mais recentes do usuário
+	mock.ExpectExec(`DELETE FROM tb_user_password_history\s+WHERE user_id = \$1\s+AND id NOT IN \(SELECT id FROM tb_user_password_history WHERE user_id = \$1 ORDER BY id DESC LIMIT \$2\)`).
+		WithArgs(testUserID, model.PasswordHistoryLimit).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	if err := RecordHistory(context.Background(), tx, testUserID, "hash"); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	tx.Commit()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecordHistoryReturnsInsertError(t *testing.T) {
+	db, mock := newTestDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO tb_user_password_history").WillReturnError(errors.New("conexão perdida"))
+
+	tx, _ := db.Begin()
+	if err := RecordHistory(context.Background(), tx, testUserID, "hash"); err == nil {
+		t.Error("esperado erro, a troca de senha precisa falhar junto")
+	}
+}
+
+func expectPolicy(mock sqlmock.Sqlmock, historySize int) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT group_id FROM tb_tenant WHERE id = $1")).WithArgs(testTenantID).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow(testGroupID))
+
+	rows := sqlmock.NewRows([]string{"min_length", "require_uppercase", "require_lowercase", "require_number", "require_symbol",
+		"disallow_personal_data", "history_size", "max_age_days", "updated_at"})
+	if historySize > 0 {
+		rows.AddRow(8, false, false, false, false, false, historySize, 0, time.Now())
+	}
+	mock.ExpectQuery("FROM tb_tenant_group_password_policy p WHERE p.group_id = \\$1").WithArgs(testGroupID).WillReturnRows(rows)
+}
+
+func TestValidateRejectsReusedPassword(t *testing.T) {
+	db, mock := newTestDB(t)
+	usr := &model.User{ID: testUserID, TenantID: testTenantID, Username: "maria"}
+
+	expectPolicy(mock, 3)
+	mock.ExpectQuery(historyQuery).WithArgs(testUserID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"hashed_password"}).AddRow(hash(t, "Antiga#2023")))
+
+	var policyErr *PolicyError
+	err := Validate(context.Background(), db, usr, "Antiga#2023")
+	if !errors.As(err, &policyErr) || len(policyErr.Violations) != 1 || policyErr.Violations[0].Rule != model.PasswordRuleHistory {
+		t.Errorf("esperada a violação %s, mas obteve %v", model.PasswordRuleHistory, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTenantPolicyDefaultsWithoutSavedPolicy(t *testing.T) {
+	db, mock := newTestDB(t)
+
+	expectPolicy(mock, 0)
+
+	policy, err := TenantPolicy(context.Background(), db, testTenantID)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if *policy != *model.DefaultPasswordPolicy(testGroupID) {
+		t.Errorf("esperada a política padrão do grupo, mas obteve %+v", policy)
+	}
+}
@@ -0,0 +1,151 @@
+package password_reset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+)
+
+// RequestInterval is the minimum time between two reset emails to the same user
+const RequestInterval = time.Minute
+
+// ChangeLifetime is the validity of the token returned by getjwt for an expired password
+const ChangeLifetime = 10 * time.Minute
+
+var (
+	// ErrInvalidToken is returned for an unknown, expired, used or superseded token
+	ErrInvalidToken = errors.New("invalid or expired password reset token")
+	// ErrTooSoon is returned when a token was issued to the user less than RequestInterval ago
+	ErrTooSoon = errors.New("password reset requested too soon")
+)
+
+type PasswordResetServiceInterface interface {
+	Issue(ctx context.Context, userID uuid.UUID) (token string, err error)
+	IssueChange(ctx context.Context, userID uuid.UUID) (token string, err error)
+	Verify(ctx context.Context, token string) (userID uuid.UUID, err error)
+	Consume(ctx context.Context, token string) (userID uuid.UUID, err error)
+}
+
+// PasswordReset_service keeps the reset tokens in tb_password_reset under their SHA-256
+type PasswordReset_service struct {
+	dbp      pgsql.DatabaseInterface
+	lifetime time.Duration
+}
+
+func NewPasswordResetService(database_pool pgsql.DatabaseInterface, lifetime time.Duration) *PasswordReset_service {
+	return &PasswordReset_service{
+		dbp:      database_pool,
+		lifetime: lifetime,
+	}
+}
+
+// Issue returns a new token of the user valid for the lifetime of the service. The tokens
+// issued before are invalidated, so only the link of the last email works.
+func (ps *PasswordReset_service) Issue(ctx context.Context, userID uuid.UUID) (string, error) {
+	return ps.issue(ctx, userID, ps.lifetime, true)
+}
+
+// IssueChange returns a token valid for ChangeLifetime to a user who logged in with an expired
+// password, so users without email can set a new one. The caller checked the password, so
+// there is no RequestInterval; the tokens issued before are invalidated as in Issue.
+func (ps *PasswordReset_service) IssueChange(ctx context.Context, userID uuid.UUID) (string, error) {
+	return ps.issue(ctx, userID, ChangeLifetime, false)
+}
+
+func (ps *PasswordReset_service) issue(ctx context.Context, userID uuid.UUID, lifetime time.Duration, throttle bool) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	tx, err := ps.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting transaction", err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if throttle {
+		var recent bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM tb_password_reset WHERE user_id = $1 AND created_at > now() - make_interval(secs => $2))",
+			userID, RequestInterval.Seconds()).Scan(&recent)
+		if err != nil {
+			logger.Error("Error checking password reset requests", err)
+			return "", err
+		}
+		if recent {
+			return "", ErrTooSoon
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE tb_password_reset SET used_at = now() WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		logger.Error("Error invalidating password reset tokens", err)
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO tb_password_reset (token_hash, user_id, expires_at) VALUES ($1, $2, now() + make_interval(secs => $3))",
+		hashToken(token), userID, lifetime.Seconds()); err != nil {
+		logger.Error("Error executing SQL query insert password reset", err)
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Error committing transaction", err)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Consume invalidates the token and returns its user; of concurrent calls with the same token only one succeeds
+func (ps *PasswordReset_service) Consume(ctx context.Context, token string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := ps.dbp.GetDB().QueryRowContext(ctx, `
+        UPDATE tb_password_reset SET used_at = now()
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+        RETURNING user_id`, hashToken(token)).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	if err != nil {
+		logger.Error("Error consuming password reset token", err)
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// Verify returns the user of a valid token without using it, to check the new password before the token is spent
+func (ps *PasswordReset_service) Verify(ctx context.Context, token string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := ps.dbp.GetDB().QueryRowContext(ctx, `
+        SELECT user_id FROM tb_password_reset
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()`, hashToken(token)).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	if err != nil {
+		logger.Error("Error reading password reset token", err)
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+package password_reset
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	pgsql_mocks "github.com/katana-stuidio/access-control/pkg/adapter/pgsql/mocks"
+)
+
+var testUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+const (
+	recentQuery = "SELECT EXISTS(SELECT 1 FROM tb_password_reset WHERE user_id = $1 AND created_at > now() - make_interval(secs => $2))"
+	invalidate  = "UPDATE tb_password_reset SET used_at = now() WHERE user_id = $1 AND used_at IS NULL"
+	insertToken = "INSERT INTO tb_password_reset (token_hash, user_id, expires_at) VALUES ($1, $2, now() + make_interval(secs => $3))"
+	consume     = "UPDATE tb_password_reset SET used_at = now()"
+)
+
+func newTestService(t *testing.T) (*PasswordReset_service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar o banco de teste: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	pool := pgsql_mocks.NewMockDatabaseInterface(gomock.NewController(t))
+	pool.EXPECT().GetDB().Return(db).AnyTimes()
+	return NewPasswordResetService(pool, 30*time.Minute), mock
+}
+
+// tokenHash confere que o banco recebe o SHA-256 do token emitido, e não o token
+type tokenHash struct{ hash *string }
+
+func (h tokenHash) Match(v driver.Value) bool {
+	value, ok := v.(string)
+	*h.hash = value
+	return ok && len(value) == 64
+}
+
+func TestIssueInvalidatesOlderTokens(t *testing.T) {
+	service, mock := newTestService(t)
+
+	var stored string
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(recentQuery)).WithArgs(testUserID, RequestInterval.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(invalidate)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(insertToken)).WithArgs(tokenHash{&stored}, testUserID, float64(1800)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	token, err := service.Issue(context.Background(), testUserID)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if token == "" || stored != hashToken(token) {
+		t.Errorf("esperado o hash %s do token, mas o banco recebeu %s", hashToken(token), stored)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIssueTooSoon(t *testing.T) {
+	service, mock := newTestService(t)
+
+	// Um token emitido há menos de RequestInterval impede outro email
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(recentQuery)).WithArgs(testUserID, RequestInterval.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	if _, err := service.Issue(context.Background(), testUserID); !errors.Is(err, ErrTooSoon) {
+		t.Errorf("esperado ErrTooSoon, mas obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIssueRollsBackOnInsertError(t *testing.T) {
+	service, mock := newTestService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(recentQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(invalidate)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(insertToken)).WillReturnError(errors.New("conexão perdida"))
+	mock.ExpectRollback()
+
+	// Sem o novo token os antigos continuam válidos
+	if _, err := service.Issue(context.Background(), testUserID); err == nil {
+		t.Error("esperado erro ao gravar o token")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConsumeIsSingleUse(t *testing.T) {
+	service, mock := newTestService(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(consume)).WithArgs(hashToken("token")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+	// A segunda chamada não encontra o token, já marcado como usado pelo UPDATE
+	mock.ExpectQuery(regexp.QuoteMeta(consume)).WithArgs(hashToken("token")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	userID, err := service.Consume(context.Background(), "token")
+	if err != nil || userID != testUserID {
+		t.Fatalf("esperado o usuário %s, mas obteve %s (%v)", testUserID, userID, err)
+	}
+	if _, err := service.Consume(context.Background(), "token"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token reutilizado: esperado ErrInvalidToken, mas obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConsumeChecksUsedAndExpired(t *testing.T) {
+	service, mock := newTestService(t)
+
+	// As condições do UPDATE fazem o uso único, inclusive entre chamadas concorrentes
+	mock.ExpectQuery(`UPDATE tb_password_reset SET used_at = now\(\)\s+WHERE token_hash = \$1 AND used_at IS NULL AND expires_at > now\(\)\s+RETURNING user_id`).
+		WithArgs(hashToken("token")).WillReturnError(errors.New("conexão perdida"))
+
+	if _, err := service.Consume(context.Background(), "token"); err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("esperado o erro do banco, mas obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIssueChangeSkipsRequestInterval(t *testing.T) {
+	service, mock := newTestService(t)
+
+	// A senha já foi conferida pelo getjwt: sem intervalo entre pedidos e com validade curta
+	var stored string
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(invalidate)).WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(insertToken)).WithArgs(tokenHash{&stored}, testUserID, ChangeLifetime.Seconds()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if _, err := service.IssueChange(context.Background(), testUserID); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,401 @@
+package role
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/katana-stuidio/access-control/internal/config/logger"
+	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
+	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+)
+
+var (
+	// ErrRoleExists is returned when the catalog already has a role with the name
+	ErrRoleExists = errors.New("role already exists")
+	// ErrSystemRole is returned when deleting a global system role
+	ErrSystemRole = errors.New("system roles cannot be deleted")
+	// ErrRoleInUse is returned when deleting a role still granted to users
+	ErrRoleInUse = errors.New("role is granted to users")
+)
+
+type RoleServiceInterface interface {
+	GetAll(ctx context.Context, groupID uuid.UUID) ([]model.Role, error)
+	GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Role
+	Create(ctx context.Context, role *model.Role) (*model.Role, error)
+	Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, role *model.Role) int64
+	Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) (int64, error)
+	InvalidRoles(ctx context.Context, tenantID uuid.UUID, roles []string) ([]string, error)
+	GetUserRoles(ctx context.Context, userID, tenantID uuid.UUID) ([]string, error)
+	SetUserRoles(ctx context.Context, userID, tenantID uuid.UUID, roles []string) error
+}
+
+type Role_service struct {
+	dbp pgsql.DatabaseInterface
+}
+
+func NewRoleService(database_pool pgsql.DatabaseInterface) *Role_service {
+	return &Role_service{
+		dbp: database_pool,
+	}
+}
+
+// effectiveRolesQuery selects the catalog of a tenant group: the roles of the group
+// and the global roles the group did not override
+const effectiveRolesQuery = `
+        SELECT DISTINCT ON (name) id, group_id, name, description, enabled, system, created_at, updated_at
+        FROM tb_role
+        WHERE group_id IS NULL OR group_id = $1
+        ORDER BY name, group_id NULLS LAST`
+
+// GetAll returns the role catalog of the tenant group, uuid.Nil returns the global catalog
+func (rs *Role_service) GetAll(ctx context.Context, groupID uuid.UUID) ([]model.Role, error) {
+	rows, err := rs.dbp.GetDB().QueryContext(ctx, effectiveRolesQuery, nullUUID(groupID))
+	if err != nil {
+		logger.Error("Error querying roles", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []model.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			logger.Error("Error scanning role", err)
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetByID returns an empty role when the ID does not exist or belongs to a group outside the scope.
+// Global roles are visible to every caller.
+func (rs *Role_service) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Role {
+	row := rs.dbp.GetDB().QueryRowContext(ctx, `
+        SELECT id, group_id, name, description, enabled, system, created_at, updated_at
+        FROM tb_role
+        WHERE id = $1 AND (group_id IS NULL OR $2 OR group_id = $3)`,
+		ID, scope.Level == model.ScopeGlobal, scope.GroupID)
+
+	role, err := scanRole(row)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Error("Error getting role", err)
+		}
+		return &model.Role{}
+	}
+
+	return role
+}
+
+func (rs *Role_service) Create(ctx context.Context, role *model.Role) (*model.Role, error) {
+	var exists bool
+	err := rs.dbp.GetDB().QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tb_role WHERE name = $1 AND group_id IS NOT DISTINCT FROM $2)",
+		role.Name, nullUUID(role.GroupID)).Scan(&exists)
+	if err != nil {
+		logger.Error("Error checking existing role", err)
+		return role, err
+	}
+	if exists {
+		return role, ErrRoleExists
+	}
+
+	query := "INSERT INTO tb_role (id, group_id, name, description, enabled) VALUES ($1, $2, $3, $4, $5)"
+
+	_, err = rs.dbp.GetDB().ExecContext(ctx, query, role.ID, nullUUID(role.GroupID), role.Name, role.Description, role.Enable)
+	if err != nil {
+		logger.Error("Error executing SQL query insert role", err)
+		return role, err
+	}
+
+	logger.Info("Role created: " + role.Name)
+	return role, nil
+}
+
+// Update changes the description and the enabled flag; the name identifies the role in
+// tb_user_role and in the overrides, so it never changes. Global roles are only updated
+// by a global scope and group roles by the scope of the group.
+func (rs *Role_service) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, role *model.Role) int64 {
+	all, _, groupID := scope.Filter()
+
+	query := `
+        UPDATE tb_role SET description = $1, enabled = $2, updated_at = now()
+        WHERE id = $3 AND ($4 OR (group_id IS NOT NULL AND group_id = $5))`
+
+	result, err := rs.dbp.GetDB().ExecContext(ctx, query, role.Description, role.Enable, ID, all, groupID)
+	if err != nil {
+		logger.Error("Error updating role", err)
+		return 0
+	}
+
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error getting rows affected", err)
+		return 0
+	}
+
+	return rowsAff
+}
+
+// Delete removes a role of the catalog. Deleting the override of a global role restores the
+// global role; any other role can only be deleted when no user of the catalog has it.
+func (rs *Role_service) Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) (int64, error) {
+	tx, err := rs.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting transaction", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	all, _, groupID := scope.Filter()
+
+	var name string
+	var roleGroup uuid.NullUUID
+	var system bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT name, group_id, system FROM tb_role
+        WHERE id = $1 AND ($2 OR (group_id IS NOT NULL AND group_id = $3))
+        FOR UPDATE`, ID, all, groupID).Scan(&name, &roleGroup, &system)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		logger.Error("Error reading role before delete", err)
+		return 0, err
+	}
+
+	if system && !roleGroup.Valid {
+		return 0, ErrSystemRole
+	}
+
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM tb_user_role ur
+            JOIN tb_tenant t ON t.id = ur.tenant_id
+            WHERE ur.role = $1 AND ($2::uuid IS NULL OR t.group_id = $2)
+        ) AND NOT ($2::uuid IS NOT NULL AND EXISTS (SELECT 1 FROM tb_role WHERE name = $1 AND group_id IS NULL))`,
+		name, roleGroup).Scan(&inUse)
+	if err != nil {
+		logger.Error("Error checking role usage", err)
+		return 0, err
+	}
+	if inUse {
+		return 0, ErrRoleInUse
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM tb_role WHERE id = $1", ID)
+	if err != nil {
+		logger.Error("Error deleting role", err)
+		return 0, err
+	}
+
+	rowsAff, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error getting rows affected", err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Error committing transaction", err)
+		return 0, err
+	}
+
+	logger.Info("Delete Transaction committed")
+	return rowsAff, nil
+}
+
+// InvalidRoles returns the roles that are not enabled in the catalog of the tenant group
+func (rs *Role_service) InvalidRoles(ctx context.Context, tenantID uuid.UUID, roles []string) ([]string, error) {
+	var groupID uuid.NullUUID
+	err := rs.dbp.GetDB().QueryRowContext(ctx, "SELECT group_id FROM tb_tenant WHERE id = $1", tenantID).Scan(&groupID)
+	if err != nil {
+		logger.Error("Error getting tenant group of roles", err)
+		return nil, err
+	}
+
+	catalog, err := rs.GetAll(ctx, groupID.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := map[string]bool{}
+	for _, role := range catalog {
+		enabled[role.Name] = role.Enable
+	}
+
+	invalid := []string{}
+	for _, role := range roles {
+		if !enabled[role] {
+			invalid = append(invalid, role)
+		}
+	}
+
+	return invalid, nil
+}
+
+func (rs *Role_service) GetUserRoles(ctx context.Context, userID, tenantID uuid.UUID) ([]string, error) {
+	rows, err := rs.dbp.GetDB().QueryContext(ctx,
+		"SELECT role FROM tb_user_role WHERE user_id = $1 AND tenant_id = $2 ORDER BY role", userID, tenantID)
+	if err != nil {
+		logger.Error("Error querying user roles", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRoleNames(rows)
+}
+
+// SetUserRoles replaces the roles of the user in the tenant. When the main role of the user
+// (role_usr) is not among them, the first role becomes the main role.
+func (rs *Role_service) SetUserRoles(ctx context.Context, userID, tenantID uuid.UUID, roles []string) error {
+	tx, err := rs.dbp.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var homeTenant uuid.UUID
+	var mainRole string
+	err = tx.QueryRowContext(ctx, "SELECT id_tanant, role_usr FROM tb_user WHERE id = $1 FOR UPDATE", userID).Scan(&homeTenant, &mainRole)
+	if err != nil {
+		logger.Error("Error reading user before roles update", err)
+		return err
+	}
+
+	if err := ReplaceUserRoles(ctx, tx, userID, tenantID, roles); err != nil {
+		return err
+	}
+
+	if homeTenant == tenantID && len(roles) > 0 && !contains(roles, mainRole) {
+		if _, err := tx.ExecContext(ctx, "UPDATE tb_user SET role_usr = $1 WHERE id = $2", roles[0], userID); err != nil {
+			logger.Error("Error updating main role", err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Error committing transaction", err)
+		return err
+	}
+
+	logger.Info("User roles Transaction committed")
+	return nil
+}
+
+// TenantRoles reads the roles of the user in the tenant inside tx, locking them
+func TenantRoles(ctx context.Context, tx *sql.Tx, userID, tenantID uuid.UUID) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT role FROM tb_user_role WHERE user_id = $1 AND tenant_id = $2 ORDER BY role FOR UPDATE", userID, tenantID)
+	if err != nil {
+		logger.Error("Error querying user roles", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRoleNames(rows)
+}
+
+// ReplaceUserRoles replaces the roles of the user in the tenant inside tx and enqueues
+// the OpenFGA tuples of the roles removed and added. Nil roles remove every role.
+func ReplaceUserRoles(ctx context.Context, tx *sql.Tx, userID, tenantID uuid.UUID, roles []string) error {
+	before, err := TenantRoles(ctx, tx, userID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tb_user_role WHERE user_id = $1 AND tenant_id = $2", userID, tenantID); err != nil {
+		logger.Error("Error deleting user roles", err)
+		return err
+	}
+
+	for _, role := range roles {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO tb_user_role (user_id, tenant_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", userID, tenantID, role)
+		if err != nil {
+			logger.Error("Error inserting user role", err)
+			return err
+		}
+	}
+
+	return permission.EnqueueRolesChange(ctx, tx, userID, tenantID, before, roles)
+}
+
+// RemoveAllUserRoles removes the roles of the user in every tenant inside tx, before the user is deleted
+func RemoveAllUserRoles(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT tenant_id FROM tb_user_role WHERE user_id = $1", userID)
+	if err != nil {
+		logger.Error("Error querying user role tenants", err)
+		return err
+	}
+
+	tenants := []uuid.UUID{}
+	for rows.Next() {
+		var tenantID uuid.UUID
+		if err := rows.Scan(&tenantID); err != nil {
+			rows.Close()
+			return err
+		}
+		tenants = append(tenants, tenantID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, tenantID := range tenants {
+		if err := ReplaceUserRoles(ctx, tx, userID, tenantID, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRole(row rowScanner) (*model.Role, error) {
+	role := &model.Role{}
+	var groupID uuid.NullUUID
+	err := row.Scan(&role.ID, &groupID, &role.Name, &role.Description, &role.Enable, &role.System, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	role.GroupID = groupID.UUID
+	return role, nil
+}
+
+func scanRoleNames(rows *sql.Rows) ([]string, error) {
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			logger.Error("Error scanning user role", err)
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// nullUUID stores uuid.Nil as NULL, the group of the global roles
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
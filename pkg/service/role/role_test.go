@@ -0,0 +1,162 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	pgsql_mocks "github.com/katana-stuidio/access-control/pkg/adapter/pgsql/mocks"
+	"github.com/katana-stuidio/access-control/pkg/model"
+)
+
+var (
+	testGroupID  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+	testTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	testRoleID   = uuid.MustParse("00000000-0000-0000-0000-000000000004")
+)
+
+const (
+	selectForDelete = "SELECT name, group_id, system FROM tb_role"
+	roleInUse       = "SELECT EXISTS ("
+	deleteRole      = "DELETE FROM tb_role WHERE id = $1"
+)
+
+var roleColumns = []string{"id", "group_id", "name", "description", "enabled", "system", "created_at", "updated_at"}
+
+func newTestService(t *testing.T) (*Role_service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Erro ao criar o banco de teste: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	pool := pgsql_mocks.NewMockDatabaseInterface(gomock.NewController(t))
+	pool.EXPECT().GetDB().Return(db).AnyTimes()
+	return NewRoleService(pool), mock
+}
+
+func TestEffectiveRolesQueryPrefersGroupOverride(t *testing.T) {
+	// DISTINCT ON mantém a primeira linha de cada nome: a do grupo vem antes da global
+	for _, clause := range []string{
+		"SELECT DISTINCT ON (name)",
+		"WHERE group_id IS NULL OR group_id = $1",
+		"ORDER BY name, group_id NULLS LAST",
+	} {
+		if !regexp.MustCompile(regexp.QuoteMeta(clause)).MatchString(effectiveRolesQuery) {
+			t.Errorf("a consulta do catálogo não contém %q", clause)
+		}
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	service, mock := newTestService(t)
+	now := time.Now()
+
+	// O banco devolve uma linha por nome; a sobrescrita do grupo substitui a global
+	mock.ExpectQuery(regexp.QuoteMeta(effectiveRolesQuery)).WithArgs(testGroupID).
+		WillReturnRows(sqlmock.NewRows(roleColumns).
+			AddRow(uuid.New(), nil, model.RoleAdmin, "Administrador", true, true, now, now).
+			AddRow(uuid.New(), testGroupID, model.RoleEstudante, "Desativado na rede", false, false, now, now))
+
+	roles, err := service.GetAll(context.Background(), testGroupID)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if len(roles) != 2 || roles[0].GroupID != uuid.Nil || roles[1].GroupID != testGroupID || roles[1].Enable {
+		t.Errorf("catálogo inesperado %+v", roles)
+	}
+
+	// uuid.Nil consulta só o catálogo global (group_id NULL)
+	mock.ExpectQuery(regexp.QuoteMeta(effectiveRolesQuery)).WithArgs(nil).WillReturnRows(sqlmock.NewRows(roleColumns))
+	if _, err := service.GetAll(context.Background(), uuid.Nil); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInvalidRolesUsesGroupOverride(t *testing.T) {
+	service, mock := newTestService(t)
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT group_id FROM tb_tenant WHERE id = $1")).WithArgs(testTenantID).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow(testGroupID))
+	mock.ExpectQuery(regexp.QuoteMeta(effectiveRolesQuery)).WithArgs(testGroupID).
+		WillReturnRows(sqlmock.NewRows(roleColumns).
+			AddRow(uuid.New(), nil, model.RoleProfessor, "", true, true, now, now).
+			AddRow(uuid.New(), testGroupID, model.RoleEstudante, "", false, false, now, now))
+
+	invalid, err := service.InvalidRoles(context.Background(), testTenantID, []string{model.RoleProfessor, model.RoleEstudante, "Inexistente"})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if len(invalid) != 2 || invalid[0] != model.RoleEstudante || invalid[1] != "Inexistente" {
+		t.Errorf("esperado [%s Inexistente], mas obteve %v", model.RoleEstudante, invalid)
+	}
+}
+
+func expectRoleForDelete(mock sqlmock.Sqlmock, name string, group interface{}, system bool) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(selectForDelete)).WithArgs(testRoleID, false, testGroupID).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "group_id", "system"}).AddRow(name, group, system))
+}
+
+func TestDeleteRoleInUse(t *testing.T) {
+	service, mock := newTestService(t)
+	scope := model.TenantScope{Level: model.ScopeGroup, GroupID: testGroupID}
+
+	// Papel do grupo ainda concedido a usuários dos tenants do grupo
+	expectRoleForDelete(mock, "Monitor", testGroupID, false)
+	mock.ExpectQuery(regexp.QuoteMeta(roleInUse)).WithArgs("Monitor", testGroupID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	if _, err := service.Delete(context.Background(), scope, testRoleID); !errors.Is(err, ErrRoleInUse) {
+		t.Errorf("esperado ErrRoleInUse, mas obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteRoleNotInUse(t *testing.T) {
+	service, mock := newTestService(t)
+	scope := model.TenantScope{Level: model.ScopeGroup, GroupID: testGroupID}
+
+	// Remover a sobrescrita de um papel global restaura o global, mesmo com usuários
+	expectRoleForDelete(mock, model.RoleEstudante, testGroupID, false)
+	mock.ExpectQuery(`AND NOT \(\$2::uuid IS NOT NULL AND EXISTS \(SELECT 1 FROM tb_role WHERE name = \$1 AND group_id IS NULL\)\)`).
+		WithArgs(model.RoleEstudante, testGroupID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(deleteRole)).WithArgs(testRoleID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if rows, err := service.Delete(context.Background(), scope, testRoleID); err != nil || rows != 1 {
+		t.Errorf("esperado 1 papel removido, mas obteve %d (%v)", rows, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteSystemRole(t *testing.T) {
+	service, mock := newTestService(t)
+	scope := model.TenantScope{Level: model.ScopeGroup, GroupID: testGroupID}
+
+	expectRoleForDelete(mock, model.RoleAdmin, nil, true)
+	mock.ExpectRollback()
+
+	if _, err := service.Delete(context.Background(), scope, testRoleID); !errors.Is(err, ErrSystemRole) {
+		t.Errorf("esperado ErrSystemRole, mas obteve %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
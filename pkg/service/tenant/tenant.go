@@ -10,12 +10,12 @@ import (
 )
 
 type TenantServiceInterface interface {
-	GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error)
-	GetByID(ctx context.Context, ID uuid.UUID) *model.Tenant
+	GetAll(ctx context.Context, scope model.TenantScope, limit, page int64) (*model.Paginate, error)
+	GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Tenant
 	GetByCNPJ(ctx context.Context, CNPJ string) (*model.Tenant, error)
 	Create(ctx context.Context, tenant *model.Tenant) (*model.Tenant, error)
-	Update(ctx context.Context, ID uuid.UUID, tenant *model.Tenant) int64
-	Delete(ctx context.Context, ID uuid.UUID) int64
+	Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, tenant *model.Tenant) int64
+	Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) int64
 	GetExistCNPJ(ctx context.Context, cnpj string) (bool, error)
 }
 
@@ -29,10 +29,12 @@ func NewTenantService(database_pool pgsql.DatabaseInterface) *Tenant_service {
 	}
 }
 
-func (ts *Tenant_service) GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error) {
+func (ts *Tenant_service) GetAll(ctx context.Context, scope model.TenantScope, limit, page int64) (*model.Paginate, error) {
+	all, tenantID, groupID := scope.Filter()
+
 	// Get total count
 	var total int64
-	err := ts.dbp.GetDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM tb_tenant").Scan(&total)
+	err := ts.dbp.GetDB().QueryRowContext(ctx, "SELECT COUNT(*) FROM tb_tenant WHERE ($1 OR id = $2 OR group_id = $3)", all, tenantID, groupID).Scan(&total)
 	if err != nil {
 		logger.Error("Error getting total count", err)
 		return nil, err
@@ -44,8 +46,8 @@ func (ts *Tenant_service) GetAll(ctx context.Context, limit, page int64) (*model
 	// Get paginated data
 	offset := (paginate.Page - 1) * paginate.Limit
 	rows, err := ts.dbp.GetDB().QueryContext(ctx,
-		"SELECT id, group_id, cnpj, name, schema_name, is_active, created_at, updated_at FROM tb_tenant LIMIT $1 OFFSET $2",
-		paginate.Limit, offset)
+		"SELECT id, group_id, cnpj, name, schema_name, is_active, created_at, updated_at FROM tb_tenant WHERE ($1 OR id = $2 OR group_id = $3) LIMIT $4 OFFSET $5",
+		all, tenantID, groupID, paginate.Limit, offset)
 	if err != nil {
 		logger.Error("Error querying tenants", err)
 		return nil, err
@@ -66,17 +68,21 @@ func (ts *Tenant_service) GetAll(ctx context.Context, limit, page int64) (*model
 	return paginate, nil
 }
 
-func (ts *Tenant_service) GetByID(ctx context.Context, ID uuid.UUID) *model.Tenant {
-	stmt, err := ts.dbp.GetDB().PrepareContext(ctx, "SELECT id, group_id, cnpj, name, schema_name, is_active, created_at, updated_at FROM tb_tenant WHERE id = $1")
+// GetByID returns an empty tenant when the ID does not exist or is outside the scope
+func (ts *Tenant_service) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.Tenant {
+	t := model.Tenant{}
+
+	stmt, err := ts.dbp.GetDB().PrepareContext(ctx, "SELECT id, group_id, cnpj, name, schema_name, is_active, created_at, updated_at FROM tb_tenant WHERE id = $1 AND ($2 OR id = $3 OR group_id = $4)")
 	if err != nil {
 		logger.Error(err.Error(), err)
+		return &t
 	}
 
 	defer stmt.Close()
 
-	t := model.Tenant{}
+	all, tenantID, groupID := scope.Filter()
 
-	if err := stmt.QueryRowContext(ctx, ID).Scan(&t.ID, &t.GroupID, &t.CNPJ, &t.Name, &t.SchemaName, &t.IsActive, &t.CreatedAt, &t.UpdatedAt); err != nil {
+	if err := stmt.QueryRowContext(ctx, ID, all, tenantID, groupID).Scan(&t.ID, &t.GroupID, &t.CNPJ, &t.Name, &t.SchemaName, &t.IsActive, &t.CreatedAt, &t.UpdatedAt); err != nil {
 		logger.Error(err.Error(), err)
 	}
 
@@ -110,15 +116,19 @@ func (ts *Tenant_service) Create(ctx context.Context, tenant *model.Tenant) (*mo
 	return tenant, nil
 }
 
-func (ts *Tenant_service) Update(ctx context.Context, ID uuid.UUID, tenant *model.Tenant) int64 {
+// Update only touches tenants inside the scope. Only a global scope may move a tenant to another group.
+func (ts *Tenant_service) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, tenant *model.Tenant) int64 {
 	tx, err := ts.dbp.GetDB().BeginTx(ctx, nil)
 	if err != nil {
 		logger.Error("Error starting transaction", err)
+		return 0
 	}
 
-	query := "UPDATE tb_tenant SET group_id = $1, cnpj = $2, name = $3, schema_name = $4, is_active = $5 WHERE id = $6"
+	query := "UPDATE tb_tenant SET group_id = $1, cnpj = $2, name = $3, schema_name = $4, is_active = $5 WHERE id = $6 AND ($7 OR id = $8 OR group_id = $9) AND ($7 OR group_id = $1)"
+
+	all, tenantID, groupID := scope.Filter()
 
-	result, err := tx.ExecContext(ctx, query, tenant.GroupID, tenant.CNPJ, tenant.Name, tenant.SchemaName, tenant.IsActive, ID)
+	result, err := tx.ExecContext(ctx, query, tenant.GroupID, tenant.CNPJ, tenant.Name, tenant.SchemaName, tenant.IsActive, ID, all, tenantID, groupID)
 	if err != nil {
 		logger.Error("Error updating tenant", err)
 		return 0
@@ -142,15 +152,18 @@ func (ts *Tenant_service) Update(ctx context.Context, ID uuid.UUID, tenant *mode
 	return rowsAff
 }
 
-func (ts *Tenant_service) Delete(ctx context.Context, ID uuid.UUID) int64 {
+func (ts *Tenant_service) Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) int64 {
 	tx, err := ts.dbp.GetDB().BeginTx(ctx, nil)
 	if err != nil {
 		logger.Error("Error starting transaction", err)
+		return 0
 	}
 
-	query := "DELETE FROM tb_tenant WHERE id = $1"
+	query := "DELETE FROM tb_tenant WHERE id = $1 AND ($2 OR id = $3 OR group_id = $4)"
+
+	all, tenantID, groupID := scope.Filter()
 
-	result, err := tx.ExecContext(ctx, query, ID)
+	result, err := tx.ExecContext(ctx, query, ID, all, tenantID, groupID)
 	if err != nil {
 		logger.Error("Error deleting tenant", err)
 		return 0
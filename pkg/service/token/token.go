@@ -3,24 +3,38 @@ package token
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/katana-stuidio/access-control/internal/config"
 	"github.com/katana-stuidio/access-control/internal/config/logger"
 	"github.com/katana-stuidio/access-control/pkg/adapter/redisdb"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found or expired")
+	ErrRefreshTokenReused   = errors.New("refresh token already used")
 )
 
 type TokenServiceInterface interface {
-	SaveRefreshToken(ctx context.Context, tokenID, userID, username, tenantID, role string, issuedAt, exp time.Time) error
+	SaveRefreshToken(ctx context.Context, tokenID, familyID, userID, username, tenantID, role string, issuedAt, exp time.Time) error
 	GetRefreshToken(ctx context.Context, tokenID string) (*RefreshTokenData, error)
+	ConsumeRefreshToken(ctx context.Context, tokenID string) (*RefreshTokenData, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
 	DeleteRefreshToken(ctx context.Context, tokenID string) error
 	DeleteAllUserTokens(ctx context.Context, userID string) error
 	DeleteAllTenantTokens(ctx context.Context, tenantID string) error
 	IsTokenValid(ctx context.Context, tokenID string) (bool, error)
+	RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error)
 }
 
+// RefreshTokenData is the session stored for a refresh token. Every token issued by
+// rotating a refresh token shares the FamilyID of the token created at login.
 type RefreshTokenData struct {
+	FamilyID  string    `json:"family_id"`
 	UserID    string    `json:"user_id"`
 	Username  string    `json:"username"`
 	TenantID  string    `json:"tenant_id"`
@@ -42,8 +56,9 @@ func NewTokenService(redis redisdb.RedisClientInterface, conf *config.Config) *T
 }
 
 // SaveRefreshToken saves a refresh token in Redis with TTL
-func (ts *TokenService) SaveRefreshToken(ctx context.Context, tokenID, userID, username, tenantID, role string, issuedAt, exp time.Time) error {
+func (ts *TokenService) SaveRefreshToken(ctx context.Context, tokenID, familyID, userID, username, tenantID, role string, issuedAt, exp time.Time) error {
 	tokenData := &RefreshTokenData{
+		FamilyID:  familyID,
 		UserID:    userID,
 		Username:  username,
 		TenantID:  tenantID,
@@ -70,6 +85,15 @@ func (ts *TokenService) SaveRefreshToken(ctx context.Context, tokenID, userID, u
 		return fmt.Errorf("failed to save refresh token to Redis")
 	}
 
+	// Index the token by user and tenant so all sessions can be revoked at once.
+	// The index lives as long as the newest token it contains.
+	if !ts.redis.AddToSet(ctx, userTokensKey(userID), tokenID, ttl) ||
+		!ts.redis.AddToSet(ctx, tenantTokensKey(tenantID), tokenID, ttl) ||
+		!ts.redis.AddToSet(ctx, familyTokensKey(familyID), tokenID, ttl) {
+		ts.redis.DeleteAllHSetData(ctx, key)
+		return fmt.Errorf("failed to index refresh token in Redis")
+	}
+
 	logger.Info(fmt.Sprintf("Refresh token saved successfully: %s", tokenID))
 	return nil
 }
@@ -98,9 +122,91 @@ func (ts *TokenService) GetRefreshToken(ctx context.Context, tokenID string) (*R
 	return &tokenData, nil
 }
 
+// ConsumeRefreshToken returns the session of a refresh token and invalidates the token,
+// so each refresh token can be exchanged only once. Presenting a token that was already
+// consumed means it leaked: the whole family is revoked and ErrRefreshTokenReused is returned.
+func (ts *TokenService) ConsumeRefreshToken(ctx context.Context, tokenID string) (*RefreshTokenData, error) {
+	tokenData, err := ts.GetRefreshToken(ctx, tokenID)
+	if err != nil {
+		used, usedErr := ts.redis.ReadData(ctx, usedRefreshKey(tokenID))
+		if usedErr != nil || len(used) == 0 {
+			return nil, fmt.Errorf("%w: %v", ErrRefreshTokenNotFound, err)
+		}
+
+		var usedData RefreshTokenData
+		if err := json.Unmarshal(used, &usedData); err != nil {
+			logger.Error("Error unmarshaling token data", err)
+			return nil, fmt.Errorf("failed to unmarshal token data: %w", err)
+		}
+		return nil, ts.handleReuse(ctx, tokenID, &usedData)
+	}
+
+	data, err := json.Marshal(tokenData)
+	if err != nil {
+		logger.Error("Error marshaling token data", err)
+		return nil, fmt.Errorf("failed to marshal token data: %w", err)
+	}
+
+	// The marker outlives the token so a replay is recognized until the token would have expired.
+	// SET NX also settles concurrent refreshes with the same token: only one of them wins.
+	ok, err := ts.redis.SaveDataIfNotExists(ctx, usedRefreshKey(tokenID), data, time.Until(tokenData.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark refresh token as used: %w", err)
+	}
+	if !ok {
+		return nil, ts.handleReuse(ctx, tokenID, tokenData)
+	}
+
+	if !ts.redis.DeleteAllHSetData(ctx, fmt.Sprintf("refresh:%s", tokenID)) {
+		return nil, fmt.Errorf("failed to delete refresh token from Redis")
+	}
+
+	return tokenData, nil
+}
+
+// handleReuse revokes the family of a replayed refresh token and reports the security event
+func (ts *TokenService) handleReuse(ctx context.Context, tokenID string, tokenData *RefreshTokenData) error {
+	logger.Error("Security event: refresh token reuse detected, revoking token family", ErrRefreshTokenReused,
+		zap.String("event", "refresh_token_reuse"),
+		zap.String("token_id", tokenID),
+		zap.String("family_id", tokenData.FamilyID),
+		zap.String("user_id", tokenData.UserID),
+		zap.String("tenant_id", tokenData.TenantID),
+	)
+
+	if err := ts.RevokeTokenFamily(ctx, tokenData.FamilyID); err != nil {
+		logger.Error("Error revoking refresh token family", err, zap.String("family_id", tokenData.FamilyID))
+		return fmt.Errorf("%w: %v", ErrRefreshTokenReused, err)
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// RevokeTokenFamily removes every refresh token issued from the same login and revokes their access tokens
+func (ts *TokenService) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	count, err := ts.deleteIndexedTokens(ctx, familyTokensKey(familyID))
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("%d refresh tokens revoked for family: %s", count, familyID))
+	return nil
+}
+
 // DeleteRefreshToken removes a refresh token from Redis
 func (ts *TokenService) DeleteRefreshToken(ctx context.Context, tokenID string) error {
 	key := fmt.Sprintf("refresh:%s", tokenID)
+
+	// Keep the user and tenant indexes in sync when the token is still readable
+	if data, err := ts.redis.ReadData(ctx, key); err == nil {
+		var tokenData RefreshTokenData
+		if json.Unmarshal(data, &tokenData) == nil {
+			ts.redis.RemoveFromSet(ctx, userTokensKey(tokenData.UserID), tokenID)
+			ts.redis.RemoveFromSet(ctx, tenantTokensKey(tokenData.TenantID), tokenID)
+			ts.redis.RemoveFromSet(ctx, familyTokensKey(tokenData.FamilyID), tokenID)
+		}
+	}
+
 	success := ts.redis.DeleteAllHSetData(ctx, key)
 	if !success {
 		return fmt.Errorf("failed to delete refresh token from Redis")
@@ -112,24 +218,97 @@ func (ts *TokenService) DeleteRefreshToken(ctx context.Context, tokenID string)
 
 // DeleteAllUserTokens removes all refresh tokens for a specific user
 func (ts *TokenService) DeleteAllUserTokens(ctx context.Context, userID string) error {
-	// This would require a more complex implementation with Redis SCAN
-	// For now, we'll implement a simple version that deletes by pattern
-	// In production, you might want to maintain a separate index for user tokens
-
-	// Note: This is a simplified implementation
-	// In a real scenario, you'd need to scan all keys and filter by user_id
-	// For now, we'll return success as the main deletion happens during logout
-	logger.Info(fmt.Sprintf("User tokens deletion requested for user: %s", userID))
+	count, err := ts.deleteIndexedTokens(ctx, userTokensKey(userID))
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("%d refresh tokens revoked for user: %s", count, userID))
 	return nil
 }
 
 // DeleteAllTenantTokens removes all refresh tokens for a specific tenant
 func (ts *TokenService) DeleteAllTenantTokens(ctx context.Context, tenantID string) error {
-	// Similar to DeleteAllUserTokens, this would require SCAN implementation
-	logger.Info(fmt.Sprintf("Tenant tokens deletion requested for tenant: %s", tenantID))
+	count, err := ts.deleteIndexedTokens(ctx, tenantTokensKey(tenantID))
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("%d refresh tokens revoked for tenant: %s", count, tenantID))
+	return nil
+}
+
+// deleteIndexedTokens deletes every refresh token listed in the index set, then the set itself.
+// Tokens of the set may already be gone (logout, expiration), which is not an error.
+func (ts *TokenService) deleteIndexedTokens(ctx context.Context, indexKey string) (int, error) {
+	tokenIDs, err := ts.redis.ReadSetMembers(ctx, indexKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read token index: %w", err)
+	}
+
+	for _, tokenID := range tokenIDs {
+		if !ts.redis.DeleteAllHSetData(ctx, fmt.Sprintf("refresh:%s", tokenID)) {
+			return 0, fmt.Errorf("failed to delete refresh token from Redis")
+		}
+		// Access tokens share the session token ID and stop working right away
+		if err := ts.RevokeAccessToken(ctx, tokenID, time.Time{}); err != nil {
+			return 0, err
+		}
+	}
+
+	if !ts.redis.DeleteAllHSetData(ctx, indexKey) {
+		return 0, fmt.Errorf("failed to delete token index from Redis")
+	}
+
+	return len(tokenIDs), nil
+}
+
+// RevokeAccessToken adds the token ID to the access token denylist until expiresAt.
+// A zero expiresAt (token not at hand) keeps it for the whole access token lifetime.
+func (ts *TokenService) RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(time.Duration(ts.conf.JWTTokenExp) * time.Minute)
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Already expired, ValidateToken rejects it anyway
+		return nil
+	}
+
+	if !ts.redis.SaveData(ctx, revokedTokenKey(tokenID), []byte("1"), ttl) {
+		return fmt.Errorf("failed to revoke access token in Redis")
+	}
+
+	logger.Info(fmt.Sprintf("Access token revoked: %s", tokenID))
 	return nil
 }
 
+// IsAccessTokenRevoked checks the access token denylist
+func (ts *TokenService) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return ts.redis.Exists(ctx, revokedTokenKey(tokenID))
+}
+
+func revokedTokenKey(tokenID string) string {
+	return fmt.Sprintf("revoked:%s", tokenID)
+}
+
+func userTokensKey(userID string) string {
+	return fmt.Sprintf("user_tokens:%s", userID)
+}
+
+func tenantTokensKey(tenantID string) string {
+	return fmt.Sprintf("tenant_tokens:%s", tenantID)
+}
+
+func familyTokensKey(familyID string) string {
+	return fmt.Sprintf("family_tokens:%s", familyID)
+}
+
+func usedRefreshKey(tokenID string) string {
+	return fmt.Sprintf("refresh_used:%s", tokenID)
+}
+
 // IsTokenValid checks if a refresh token exists and is valid
 func (ts *TokenService) IsTokenValid(ctx context.Context, tokenID string) (bool, error) {
 	_, err := ts.GetRefreshToken(ctx, tokenID)
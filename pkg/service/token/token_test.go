@@ -0,0 +1,247 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/katana-stuidio/access-control/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis implements redisdb.RedisClientInterface in memory
+type fakeRedis struct {
+	data map[string][]byte
+	sets map[string]map[string]bool
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{data: map[string][]byte{}, sets: map[string]map[string]bool{}}
+}
+
+func (f *fakeRedis) GetClient() *redis.Client { return nil }
+
+func (f *fakeRedis) ReadData(ctx context.Context, key string) ([]byte, error) {
+	data, ok := f.data[key]
+	if !ok {
+		return nil, errors.New("redis: nil")
+	}
+	return data, nil
+}
+
+func (f *fakeRedis) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) bool {
+	f.data[key] = data
+	return true
+}
+
+func (f *fakeRedis) SaveDataIfNotExists(ctx context.Context, key string, data []byte, timer time.Duration) (bool, error) {
+	if _, ok := f.data[key]; ok {
+		return false, nil
+	}
+	f.data[key] = data
+	return true, nil
+}
+
+func (f *fakeRedis) SaveHSetData(ctx context.Context, key, field string, value interface{}) bool {
+	return true
+}
+
+func (f *fakeRedis) ReadHSetData(ctx context.Context, key string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRedis) DeleteAllHSetData(ctx context.Context, key string) bool {
+	delete(f.data, key)
+	delete(f.sets, key)
+	return true
+}
+
+func (f *fakeRedis) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := f.data[key]
+	return ok, nil
+}
+
+func (f *fakeRedis) Increment(ctx context.Context, key string, timer time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRedis) TimeToLive(ctx context.Context, key string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (f *fakeRedis) AddToSet(ctx context.Context, key, member string, timer time.Duration) bool {
+	if f.sets[key] == nil {
+		f.sets[key] = map[string]bool{}
+	}
+	f.sets[key][member] = true
+	return true
+}
+
+func (f *fakeRedis) ReadSetMembers(ctx context.Context, key string) ([]string, error) {
+	var members []string
+	for member := range f.sets[key] {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (f *fakeRedis) RemoveFromSet(ctx context.Context, key, member string) bool {
+	delete(f.sets[key], member)
+	return true
+}
+
+func (f *fakeRedis) Publish(ctx context.Context, message []byte) error { return nil }
+
+func (f *fakeRedis) Subscriber(ctx context.Context, callback func(msg *redis.Message)) {}
+
+func saveTestToken(t *testing.T, ts *TokenService, tokenID, userID, tenantID string) {
+	t.Helper()
+	saveFamilyToken(t, ts, tokenID, tokenID, userID, tenantID)
+}
+
+func saveFamilyToken(t *testing.T, ts *TokenService, tokenID, familyID, userID, tenantID string) {
+	t.Helper()
+	err := ts.SaveRefreshToken(context.Background(), tokenID, familyID, userID, "tester", tenantID, "Professor", time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("erro ao salvar token %s: %v", tokenID, err)
+	}
+}
+
+func isValid(ts *TokenService, tokenID string) bool {
+	valid, _ := ts.IsTokenValid(context.Background(), tokenID)
+	return valid
+}
+
+func TestDeleteAllUserTokens(t *testing.T) {
+	ts := NewTokenService(newFakeRedis(), &config.Config{JWTTokenExp: 15})
+	ctx := context.Background()
+
+	saveTestToken(t, ts, "a1", "user-a", "tenant-1")
+	saveTestToken(t, ts, "a2", "user-a", "tenant-1")
+	saveTestToken(t, ts, "b1", "user-b", "tenant-1")
+
+	if err := ts.DeleteAllUserTokens(ctx, "user-a"); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+
+	if isValid(ts, "a1") || isValid(ts, "a2") {
+		t.Error("tokens do usuário não foram revogados")
+	}
+	if revoked, _ := ts.IsAccessTokenRevoked(ctx, "a1"); !revoked {
+		t.Error("access token do usuário continua válido")
+	}
+	if !isValid(ts, "b1") {
+		t.Error("token de outro usuário foi revogado")
+	}
+}
+
+func TestDeleteAllTenantTokens(t *testing.T) {
+	ts := NewTokenService(newFakeRedis(), &config.Config{JWTTokenExp: 15})
+	ctx := context.Background()
+
+	saveTestToken(t, ts, "a1", "user-a", "tenant-1")
+	saveTestToken(t, ts, "b1", "user-b", "tenant-1")
+	saveTestToken(t, ts, "c1", "user-c", "tenant-2")
+
+	if err := ts.DeleteAllTenantTokens(ctx, "tenant-1"); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+
+	if isValid(ts, "a1") || isValid(ts, "b1") {
+		t.Error("tokens do tenant não foram revogados")
+	}
+	if !isValid(ts, "c1") {
+		t.Error("token de outro tenant foi revogado")
+	}
+}
+
+func TestDeleteRefreshTokenUpdatesIndexes(t *testing.T) {
+	redis := newFakeRedis()
+	ts := NewTokenService(redis, &config.Config{})
+
+	saveTestToken(t, ts, "a1", "user-a", "tenant-1")
+
+	if err := ts.DeleteRefreshToken(context.Background(), "a1"); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+
+	if len(redis.sets[userTokensKey("user-a")]) != 0 || len(redis.sets[tenantTokensKey("tenant-1")]) != 0 {
+		t.Errorf("índices não atualizados: %v", redis.sets)
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	ts := NewTokenService(newFakeRedis(), &config.Config{JWTTokenExp: 15})
+	ctx := context.Background()
+
+	if revoked, _ := ts.IsAccessTokenRevoked(ctx, "a1"); revoked {
+		t.Fatal("token não revogado aparece na denylist")
+	}
+
+	if err := ts.RevokeAccessToken(ctx, "a1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	if revoked, _ := ts.IsAccessTokenRevoked(ctx, "a1"); !revoked {
+		t.Error("token revogado não aparece na denylist")
+	}
+
+	// Um token já expirado não precisa entrar na denylist
+	if err := ts.RevokeAccessToken(ctx, "a2", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	if revoked, _ := ts.IsAccessTokenRevoked(ctx, "a2"); revoked {
+		t.Error("token expirado não deveria ocupar a denylist")
+	}
+}
+
+func TestConsumeRefreshToken(t *testing.T) {
+	ts := NewTokenService(newFakeRedis(), &config.Config{JWTTokenExp: 15})
+	ctx := context.Background()
+
+	saveTestToken(t, ts, "a1", "user-a", "tenant-1")
+
+	tokenData, err := ts.ConsumeRefreshToken(ctx, "a1")
+	if err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	if tokenData.FamilyID != "a1" || tokenData.UserID != "user-a" {
+		t.Errorf("sessão inesperada %+v", tokenData)
+	}
+	if isValid(ts, "a1") {
+		t.Error("refresh token consumido continua válido")
+	}
+
+	if _, err := ts.ConsumeRefreshToken(ctx, "desconhecido"); !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Errorf("esperado %v, mas obteve %v", ErrRefreshTokenNotFound, err)
+	}
+}
+
+func TestConsumeRefreshTokenReuseRevokesFamily(t *testing.T) {
+	ts := NewTokenService(newFakeRedis(), &config.Config{JWTTokenExp: 15})
+	ctx := context.Background()
+
+	// a1 foi trocado por a2 no refresh; b1 é outra sessão do mesmo usuário
+	saveFamilyToken(t, ts, "a1", "a1", "user-a", "tenant-1")
+	if _, err := ts.ConsumeRefreshToken(ctx, "a1"); err != nil {
+		t.Fatalf("erro inesperado %v", err)
+	}
+	saveFamilyToken(t, ts, "a2", "a1", "user-a", "tenant-1")
+	saveFamilyToken(t, ts, "b1", "b1", "user-a", "tenant-1")
+
+	if _, err := ts.ConsumeRefreshToken(ctx, "a1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("esperado %v, mas obteve %v", ErrRefreshTokenReused, err)
+	}
+
+	if isValid(ts, "a2") {
+		t.Error("refresh token da família continua válido após reuso")
+	}
+	for _, tokenID := range []string{"a1", "a2"} {
+		if revoked, _ := ts.IsAccessTokenRevoked(ctx, tokenID); !revoked {
+			t.Errorf("access token %s da família continua válido", tokenID)
+		}
+	}
+	if !isValid(ts, "b1") {
+		t.Error("token de outra família foi revogado")
+	}
+}
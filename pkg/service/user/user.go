@@ -2,30 +2,40 @@ package user
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"regexp"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/katana-stuidio/access-control/internal/config/logger"
 	"github.com/katana-stuidio/access-control/pkg/adapter/pgsql"
 	"github.com/katana-stuidio/access-control/pkg/model"
+	"github.com/katana-stuidio/access-control/pkg/permission"
+	"github.com/katana-stuidio/access-control/pkg/service/password_policy"
+	"github.com/katana-stuidio/access-control/pkg/service/role"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// userRolesColumn lists the roles of the user in its tenant, space separated
+const userRolesColumn = `COALESCE((SELECT string_agg(ur.role, ' ' ORDER BY ur.role) FROM tb_user_role ur
+            WHERE ur.user_id = u.id AND ur.tenant_id = u.id_tanant), '')`
+
 type UserServiceInterface interface {
-	GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error)
-	GetByID(ctx context.Context, ID uuid.UUID) *model.User
+	GetAll(ctx context.Context, scope model.TenantScope, limit, page int64) (*model.Paginate, error)
+	GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User
 	GetByUserName(ctx context.Context, userName string) (usr *model.User, err error)
 	Create(ctx context.Context, User *model.User) (*model.User, error)
-	Update(ctx context.Context, ID uuid.UUID, User *model.User) int64
-	Delete(ctx context.Context, ID uuid.UUID) int64
+	Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, User *model.User) int64
+	Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) int64
 	GetExistUserName(ctx context.Context, userName string) (bool, error)
 	Authenticate(username, password string) (*model.User, error)
-	GetByCNPJ(ctx context.Context, CNPJ string) (tenant_id string, err error)
+	GetByCNPJ(ctx context.Context, scope model.TenantScope, CNPJ string) (tenant_id string, err error)
 	ChangePassword(ctx context.Context, userName, currentPassword, newPassword string) error
 	UpdatePassword(ctx context.Context, userName, newPassword string) int64
 	EmailExists(ctx context.Context, email string) (bool, error)
+	ValidatePassword(ctx context.Context, User *model.User, password string) error
+	PasswordExpired(ctx context.Context, User *model.User) (bool, error)
 }
 
 type User_service struct {
@@ -38,11 +48,17 @@ func NewUserService(database_pool pgsql.DatabaseInterface) *User_service {
 	}
 }
 
-func (us *User_service) GetAll(ctx context.Context, limit, page int64) (*model.Paginate, error) {
+func (us *User_service) GetAll(ctx context.Context, scope model.TenantScope, limit, page int64) (*model.Paginate, error) {
+	all, tenantID, groupID := scope.Filter()
+
 	// Count total records
 	var total int64
-	countQuery := `SELECT COUNT(*) FROM user`
-	err := us.dbp.GetDB().QueryRowContext(ctx, countQuery).Scan(&total)
+	countQuery := `
+        SELECT COUNT(*)
+        FROM tb_user u
+        JOIN tb_tenant t ON t.id = u.id_tanant
+        WHERE ($1 OR u.id_tanant = $2 OR t.group_id = $3)`
+	err := us.dbp.GetDB().QueryRowContext(ctx, countQuery, all, tenantID, groupID).Scan(&total)
 	if err != nil {
 		logger.Error(err.Error(), err)
 		return nil, err
@@ -53,13 +69,15 @@ func (us *User_service) GetAll(ctx context.Context, limit, page int64) (*model.P
 
 	// Get paginated results
 	query := `
-        SELECT id, cnpj, username, name_full, email, enabled, role_usr, created_at, updated_at 
-        FROM user 
-        ORDER BY created_at DESC 
-        LIMIT $1 OFFSET $2`
+        SELECT u.id, u.id_tanant, t.cnpj, u.username, u.name_full, u.email, u.enabled, u.role_usr, u.created_at, u.updated_at
+        FROM tb_user u
+        JOIN tb_tenant t ON t.id = u.id_tanant
+        WHERE ($1 OR u.id_tanant = $2 OR t.group_id = $3)
+        ORDER BY u.created_at DESC
+        LIMIT $4 OFFSET $5`
 
 	offset := (paginate.Page - 1) * paginate.Limit
-	rows, err := us.dbp.GetDB().QueryContext(ctx, query, paginate.Limit, offset)
+	rows, err := us.dbp.GetDB().QueryContext(ctx, query, all, tenantID, groupID, paginate.Limit, offset)
 	if err != nil {
 		logger.Error(err.Error(), err)
 		return nil, err
@@ -71,6 +89,7 @@ func (us *User_service) GetAll(ctx context.Context, limit, page int64) (*model.P
 		user := &model.User{}
 		if err := rows.Scan(
 			&user.ID,
+			&user.TenantID,
 			&user.CNPJ,
 			&user.Username,
 			&user.Name,
@@ -90,19 +109,29 @@ func (us *User_service) GetAll(ctx context.Context, limit, page int64) (*model.P
 	return paginate, nil
 }
 
-func (us *User_service) GetByID(ctx context.Context, ID uuid.UUID) *model.User {
-	stmt, err := us.dbp.GetDB().PrepareContext(ctx, "SELECT id, id_tanant, username, name_full, email, enabled, role_usr, created_at, updated_at FROM tb_user WHERE id = $1")
+// GetByID returns an empty user when the ID does not exist or is outside the scope
+func (us *User_service) GetByID(ctx context.Context, scope model.TenantScope, ID uuid.UUID) *model.User {
+	u := model.User{}
+
+	stmt, err := us.dbp.GetDB().PrepareContext(ctx, `
+        SELECT u.id, u.id_tanant, u.username, u.name_full, u.email, u.enabled, u.role_usr, `+userRolesColumn+`, u.created_at, u.updated_at
+        FROM tb_user u
+        JOIN tb_tenant t ON t.id = u.id_tanant
+        WHERE u.id = $1 AND ($2 OR u.id_tanant = $3 OR t.group_id = $4)`)
 	if err != nil {
 		logger.Error(err.Error(), err)
+		return &u
 	}
 
 	defer stmt.Close()
 
-	u := model.User{}
+	all, tenantID, groupID := scope.Filter()
 
-	if err := stmt.QueryRowContext(ctx, ID).Scan(&u.ID, &u.TenantID, &u.Username, &u.Name, &u.Email, &u.Enable, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	var roles string
+	if err := stmt.QueryRowContext(ctx, ID, all, tenantID, groupID).Scan(&u.ID, &u.TenantID, &u.Username, &u.Name, &u.Email, &u.Enable, &u.Role, &roles, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		logger.Error(err.Error(), err)
 	}
+	u.Roles = strings.Fields(roles)
 
 	return &u
 }
@@ -131,9 +160,24 @@ func (us *User_service) Create(ctx context.Context, User *model.User) (*model.Us
 	_, err = tx.ExecContext(ctx, query, User.ID, User.TenantID, User.Username, User.Name, User.HashedPassword, User.Email, User.Enable, User.Role)
 	if err != nil {
 		logger.Error("Error executing SQL query insert user", err)
+		tx.Rollback()
 		return User, err
 	}
 
+	err = role.ReplaceUserRoles(ctx, tx, User.ID, User.TenantID, User.AllRoles())
+	if err != nil {
+		tx.Rollback()
+		return User, err
+	}
+
+	if User.HashedPassword != "" {
+		err = password_policy.RecordHistory(ctx, tx, User.ID, User.HashedPassword)
+		if err != nil {
+			tx.Rollback()
+			return User, err
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		tx.Rollback()
@@ -146,65 +190,130 @@ func (us *User_service) Create(ctx context.Context, User *model.User) (*model.Us
 	return User, nil
 }
 
-func (us *User_service) Update(ctx context.Context, ID uuid.UUID, User *model.User) int64 {
+// Update only touches users inside the scope and only moves them to tenants inside the scope.
+// The password is not changed here: UpdatePassword hashes it and records the history.
+func (us *User_service) Update(ctx context.Context, scope model.TenantScope, ID uuid.UUID, User *model.User) int64 {
 	tx, err := us.dbp.GetDB().BeginTx(ctx, nil)
 	if err != nil {
 		logger.Error("Error starting transaction", err)
+		return 0
 	}
 
-	query := "UPDATE tb_user SET id_tanant = $1, username = $2, name_full = $3, password = $4, email = $5, enabled = $6, role_usr = $7 WHERE id = $8"
+	query := `
+        UPDATE tb_user SET id_tanant = $1, username = $2, name_full = $3, email = $4, enabled = $5, role_usr = $6
+        WHERE id = $7
+          AND EXISTS (SELECT 1 FROM tb_tenant t WHERE t.id = tb_user.id_tanant AND ($8 OR t.id = $9 OR t.group_id = $10))
+          AND EXISTS (SELECT 1 FROM tb_tenant t WHERE t.id = $1 AND ($8 OR t.id = $9 OR t.group_id = $10))`
+
+	all, tenantID, groupID := scope.Filter()
 
-	result, err := tx.ExecContext(ctx, query, User.TenantID, User.Username, User.Name, User.Password, User.Email, User.Enable, User.Role, ID)
+	// Role and tenant before the update, used to sync the OpenFGA tuple
+	before := permission.RoleBinding{}
+	err = tx.QueryRowContext(ctx, "SELECT id_tanant, role_usr FROM tb_user WHERE id = $1 FOR UPDATE", ID).Scan(&before.TenantID, &before.Role)
 	if err != nil {
-		logger.Error("Error updating user", err)
+		logger.Error("Error reading user before update", err)
+		tx.Rollback()
 		return 0
 	}
 
-	err = tx.Commit()
+	result, err := tx.ExecContext(ctx, query, User.TenantID, User.Username, User.Name, User.Email, User.Enable, User.Role, ID, all, tenantID, groupID)
 	if err != nil {
-		logger.Error("Error committing transaction", err)
+		logger.Error("Error updating user", err)
 		tx.Rollback()
 		return 0
-	} else {
-		logger.Info("Update Transaction committed")
 	}
 
 	rowsAff, err := result.RowsAffected()
 	if err != nil {
 		logger.Error("Error getting rows affected", err)
+		tx.Rollback()
+		return 0
+	}
+
+	if rowsAff > 0 {
+		err = replaceMainRole(ctx, tx, ID, before, permission.RoleBinding{TenantID: User.TenantID, Role: User.Role})
+		if err != nil {
+			tx.Rollback()
+			return 0
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logger.Error("Error committing transaction", err)
+		tx.Rollback()
 		return 0
+	} else {
+		logger.Info("Update Transaction committed")
 	}
 
 	return rowsAff
 }
 
-func (us *User_service) Delete(ctx context.Context, ID uuid.UUID) int64 {
+func (us *User_service) Delete(ctx context.Context, scope model.TenantScope, ID uuid.UUID) int64 {
 	tx, err := us.dbp.GetDB().BeginTx(ctx, nil)
 	if err != nil {
 		logger.Error("Error starting transaction", err)
+		return 0
 	}
 
-	query := "DELETE FROM tb_user WHERE id = $1"
+	query := `
+        DELETE FROM tb_user
+        WHERE id = $1
+          AND EXISTS (SELECT 1 FROM tb_tenant t WHERE t.id = tb_user.id_tanant AND ($2 OR t.id = $3 OR t.group_id = $4))`
+
+	all, tenantID, groupID := scope.Filter()
 
-	result, err := tx.ExecContext(ctx, query, ID)
+	// Roles of the deleted user in every tenant, used to remove the OpenFGA tuples
+	before := permission.RoleBinding{}
+	err = tx.QueryRowContext(ctx, "SELECT id_tanant, role_usr FROM tb_user WHERE id = $1 FOR UPDATE", ID).Scan(&before.TenantID, &before.Role)
 	if err != nil {
-		logger.Error("Error deleting user", err)
+		logger.Error("Error reading user before delete", err)
+		tx.Rollback()
 		return 0
 	}
 
-	err = tx.Commit()
+	roles, err := role.TenantRoles(ctx, tx, ID, before.TenantID)
 	if err != nil {
-		logger.Error("Error committing transaction", err)
 		tx.Rollback()
 		return 0
-	} else {
-		logger.Info("Delete Transaction committed")
+	}
+
+	result, err := tx.ExecContext(ctx, query, ID, all, tenantID, groupID)
+	if err != nil {
+		logger.Error("Error deleting user", err)
+		tx.Rollback()
+		return 0
 	}
 
 	rowsAff, err := result.RowsAffected()
 	if err != nil {
 		logger.Error("Error getting rows affected", err)
+		tx.Rollback()
+		return 0
+	}
+
+	if rowsAff > 0 {
+		// Users created before tb_user_role only have the tuple of role_usr
+		if !contains(roles, before.Role) {
+			err = permission.EnqueueRoleChange(ctx, tx, ID, &before, nil)
+		}
+		if err == nil {
+			err = role.RemoveAllUserRoles(ctx, tx, ID)
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		logger.Error("Error committing transaction", err)
+		tx.Rollback()
 		return 0
+	} else {
+		logger.Info("Delete Transaction committed")
 	}
 
 	return rowsAff
@@ -224,7 +333,7 @@ func (us *User_service) GetExistUserName(ctx context.Context, userName string) (
 }
 
 func (us *User_service) GetByUserName(ctx context.Context, email string) (*model.User, error) {
-	stmt, err := us.dbp.GetDB().PrepareContext(ctx, "SELECT id, id_tanant, username, name_full, email, enabled, hashed_password, role_usr, created_at, updated_at FROM tb_user WHERE username = $1")
+	stmt, err := us.dbp.GetDB().PrepareContext(ctx, "SELECT u.id, u.id_tanant, u.username, u.name_full, u.email, u.enabled, u.hashed_password, u.role_usr, "+userRolesColumn+", u.created_at, u.updated_at FROM tb_user u WHERE u.username = $1")
 	u := model.User{}
 	if err != nil {
 		logger.Error(err.Error(), err)
@@ -233,10 +342,12 @@ func (us *User_service) GetByUserName(ctx context.Context, email string) (*model
 
 	defer stmt.Close()
 
-	if err := stmt.QueryRowContext(ctx, email).Scan(&u.ID, &u.TenantID, &u.Username, &u.Name, &u.Email, &u.Enable, &u.HashedPassword, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	var roles string
+	if err := stmt.QueryRowContext(ctx, email).Scan(&u.ID, &u.TenantID, &u.Username, &u.Name, &u.Email, &u.Enable, &u.HashedPassword, &u.Role, &roles, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		logger.Error(err.Error(), err)
 		return &u, err
 	}
+	u.Roles = strings.Fields(roles)
 
 	return &u, nil
 }
@@ -244,7 +355,7 @@ func (us *User_service) GetByUserName(ctx context.Context, email string) (*model
 func (us *User_service) Authenticate(username, password string) (*model.User, error) {
 	ctx := context.Background() // Ou use um contexto relevante
 
-	stmt, err := us.dbp.GetDB().PrepareContext(ctx, "SELECT id, id_tanant, username, name_full, email, enabled, hashed_password, role_usr, created_at, updated_at FROM tb_user WHERE username = $1")
+	stmt, err := us.dbp.GetDB().PrepareContext(ctx, "SELECT u.id, u.id_tanant, u.username, u.name_full, u.email, u.enabled, u.hashed_password, u.role_usr, "+userRolesColumn+", u.created_at, u.updated_at FROM tb_user u WHERE u.username = $1")
 	if err != nil {
 		logger.Error(err.Error(), err)
 		return nil, err
@@ -252,14 +363,15 @@ func (us *User_service) Authenticate(username, password string) (*model.User, er
 	defer stmt.Close()
 
 	u := &model.User{}
-	var hashedPassword string
+	var hashedPassword, roles string
 
-	if err := stmt.QueryRowContext(ctx, username).Scan(&u.ID, &u.TenantID, &u.Username, &u.Name, &u.Email, &u.Enable, &hashedPassword, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+	if err := stmt.QueryRowContext(ctx, username).Scan(&u.ID, &u.TenantID, &u.Username, &u.Name, &u.Email, &u.Enable, &hashedPassword, &u.Role, &roles, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		logger.Error(err.Error(), err)
 		return nil, errors.New("invalid username or password")
 	}
 
 	u.HashedPassword = hashedPassword
+	u.Roles = strings.Fields(roles)
 
 	if !u.CheckPassword(password) {
 		return nil, errors.New("invalid username or password")
@@ -268,9 +380,11 @@ func (us *User_service) Authenticate(username, password string) (*model.User, er
 	return u, nil
 }
 
-func (us *User_service) GetByCNPJ(ctx context.Context, CNPJ string) (tenant_id string, err error) {
-	query := "SELECT id FROM tb_tenant WHERE cnpj = $1"
-	err = us.dbp.GetDB().QueryRowContext(ctx, query, CNPJ).Scan(&tenant_id)
+func (us *User_service) GetByCNPJ(ctx context.Context, scope model.TenantScope, CNPJ string) (tenant_id string, err error) {
+	all, tenantID, groupID := scope.Filter()
+
+	query := "SELECT id FROM tb_tenant WHERE cnpj = $1 AND ($2 OR id = $3 OR group_id = $4)"
+	err = us.dbp.GetDB().QueryRowContext(ctx, query, CNPJ, all, tenantID, groupID).Scan(&tenant_id)
 	if err != nil {
 		logger.Error("Error getting tenant id by cnpj", err)
 		return "", err
@@ -278,13 +392,21 @@ func (us *User_service) GetByCNPJ(ctx context.Context, CNPJ string) (tenant_id s
 	return tenant_id, nil
 }
 
+// UpdatePassword stores the bcrypt hash of the plain text newPassword, without checking the current one
+// nor the password policy, and adds it to the password history
 func (us *User_service) UpdatePassword(ctx context.Context, userName, newPassword string) int64 {
-	_, err := us.GetByUserName(ctx, userName)
+	user, err := us.GetByUserName(ctx, userName)
 	if err != nil {
 		logger.Error("User not found for password update: "+userName, err)
 		return 0
 	}
 
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Error("Error generating hashed password for user: "+userName, err)
+		return 0
+	}
+
 	tx, err := us.dbp.GetDB().BeginTx(ctx, nil)
 	if err != nil {
 		logger.Error("Error starting transaction for user: "+userName, err)
@@ -292,10 +414,10 @@ func (us *User_service) UpdatePassword(ctx context.Context, userName, newPasswor
 	}
 	defer tx.Rollback() // Rollback if not committed
 
-	query := "UPDATE tb_user SET hashed_password = $1 WHERE username = $2"
+	query := "UPDATE tb_user SET hashed_password = $1, password_changed_at = now(), updated_at = now() WHERE username = $2"
 	logger.Info("Executing query: " + query)
 
-	result, err := tx.ExecContext(ctx, query, newPassword, userName)
+	result, err := tx.ExecContext(ctx, query, string(hashedPassword), userName)
 	if err != nil {
 		logger.Error("Error updating password for user: "+userName, err)
 		return 0
@@ -312,6 +434,10 @@ func (us *User_service) UpdatePassword(ctx context.Context, userName, newPasswor
 		return 0
 	}
 
+	if err := password_policy.RecordHistory(ctx, tx, user.ID, string(hashedPassword)); err != nil {
+		return 0
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		logger.Error("Error committing transaction for user: "+userName, err)
@@ -354,49 +480,13 @@ func (us *User_service) ChangePassword(ctx context.Context, userName, currentPas
 
 	logger.Info("Current password verified, validating new password requirements")
 
-	if len(newPassword) < 8 {
-		logger.Info("New password length is too short for user: " + userName)
-		return fmt.Errorf("new password length is too short")
-	}
-
-	const (
-		uppercasePattern = "^(.*[A-Z]).*$"
-		numberPattern    = "^(.*[0-9]).*$"
-		symbolPattern    = "^(.*[!@#$%^&*()\\-_+=]).*$"
-	)
-
-	matchCapital, errCapital := regexp.MatchString(uppercasePattern, newPassword)
-	if errCapital != nil || !matchCapital {
-		logger.Info("New password must contain at least one uppercase letter for user: " + userName)
-		return fmt.Errorf("new password must contain at least one uppercase letter")
-	}
-
-	matchNumber, errNumber := regexp.MatchString(numberPattern, newPassword)
-	if errNumber != nil || !matchNumber {
-		logger.Info("New password must contain at least one number for user: " + userName)
-		return fmt.Errorf("new password must contain at least one number")
-	}
-
-	matchSymbol, errSymbol := regexp.MatchString(symbolPattern, newPassword)
-	if errSymbol != nil || !matchSymbol {
-		logger.Info("New password must contain at least one symbol for user: " + userName)
-		return fmt.Errorf("new password must contain at least one symbol")
-	}
-
-	logger.Info("New password requirements met, generating hash")
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	if err != nil {
-		logger.Error("Error generating hashed password for user: "+userName, err)
-		return fmt.Errorf("error generating password hash: %v", err)
+	if err := us.ValidatePassword(ctx, user, newPassword); err != nil {
+		logger.Info(err.Error() + " for user: " + userName)
+		return err
 	}
 
-	pw := string(hashedPassword)
-	user.Password = pw
-	user.ChangePassword = false
-
 	logger.Info("Updating password in database for user: " + userName)
-	result := us.UpdatePassword(ctx, userName, pw)
+	result := us.UpdatePassword(ctx, userName, newPassword)
 
 	if result == 0 {
 		logger.Error("Failed to update password in database for user: "+userName, nil)
@@ -407,6 +497,18 @@ func (us *User_service) ChangePassword(ctx context.Context, userName, currentPas
 	return nil
 }
 
+// ValidatePassword checks the password against the policy of the tenant group of the user, returning a
+// *password_policy.PolicyError with every rule broken. User.TenantID is required; the password history
+// is only checked for users already saved
+func (us *User_service) ValidatePassword(ctx context.Context, User *model.User, password string) error {
+	return password_policy.Validate(ctx, us.dbp.GetDB(), User, password)
+}
+
+// PasswordExpired reports whether the password of the user is older than the maximum age of its policy
+func (us *User_service) PasswordExpired(ctx context.Context, User *model.User) (bool, error) {
+	return password_policy.Expired(ctx, us.dbp.GetDB(), User)
+}
+
 func (us *User_service) EmailExists(ctx context.Context, email string) (bool, error) {
 	query := "SELECT COUNT(*) FROM tb_user WHERE email = $1"
 	var count int
@@ -420,3 +522,40 @@ func (us *User_service) EmailExists(ctx context.Context, email string) (bool, er
 	}
 	return false, nil
 }
+
+// replaceMainRole moves the main role of the user in tb_user_role. The other roles stay
+// in the tenant; a user moved to another tenant leaves every role of the old tenant.
+func replaceMainRole(ctx context.Context, tx *sql.Tx, userID uuid.UUID, before, after permission.RoleBinding) error {
+	if before == after {
+		return nil
+	}
+
+	if before.TenantID != after.TenantID {
+		if err := role.ReplaceUserRoles(ctx, tx, userID, before.TenantID, nil); err != nil {
+			return err
+		}
+	}
+
+	current, err := role.TenantRoles(ctx, tx, userID, after.TenantID)
+	if err != nil {
+		return err
+	}
+
+	roles := []string{after.Role}
+	for _, r := range current {
+		if r != before.Role && r != after.Role {
+			roles = append(roles, r)
+		}
+	}
+
+	return role.ReplaceUserRoles(ctx, tx, userID, after.TenantID, roles)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
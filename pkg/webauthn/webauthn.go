@@ -0,0 +1,240 @@
+// Package webauthn runs the registration and authentication ceremonies of WebAuthn
+// (https://www.w3.org/TR/webauthn-2/) for passkey login. The responses are parsed and verified by
+// the protocol package of github.com/go-webauthn/webauthn; this package keeps the options sent to the
+// browser and the credentials in the shape the passkey service stores them. Attestation is not
+// requested (conveyance "none"): the credential is trusted because the user registers it while logged in.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+// Timeout is the time the browser gives the user to complete a ceremony
+const Timeout = 5 * time.Minute
+
+// COSE algorithms accepted for credentials (IANA COSE Algorithms registry)
+const (
+	AlgES256 = int(webauthncose.AlgES256)
+	AlgEdDSA = int(webauthncose.AlgEdDSA)
+	AlgRS256 = int(webauthncose.AlgRS256)
+)
+
+// SupportedAlgorithms are offered to the authenticators in the order of preference
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+var (
+	// ErrInvalidResponse means the response of the browser could not be parsed
+	ErrInvalidResponse = errors.New("invalid WebAuthn response")
+	// ErrVerification means a check of the ceremony failed: challenge, origin, relying party,
+	// user verification or signature. The error of the protocol package tells which.
+	ErrVerification = errors.New("WebAuthn verification failed")
+	// ErrSignCount means the counter did not increase: the authenticator may have been cloned
+	ErrSignCount = errors.New("WebAuthn signature counter did not increase")
+)
+
+var encoding = base64.RawURLEncoding
+
+// RelyingParty is this service as seen by the authenticators. ID is the domain the credentials
+// are bound to and Origins the web origins allowed to run the ceremonies.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// Credential is a registered public key credential
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE_Key of the credential
+	PublicKey  []byte
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+// UserEntity identifies the user to the authenticator; ID is the user handle returned at login
+type UserEntity struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// NewChallenge returns a random challenge, base64url encoded as it is sent to the browser
+func NewChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(challenge), nil
+}
+
+// CreationOptions builds the options of navigator.credentials.create. Passkeys are discoverable
+// credentials with user verification, so they replace the password and not only add a factor.
+func (rp *RelyingParty) CreationOptions(challenge string, user UserEntity, exclude []Credential) *CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return &CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User: UserEntityOptions{
+			ID:          encoding.EncodeToString(user.ID),
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds the options of navigator.credentials.get; without credentials the
+// browser offers the discoverable credentials of the relying party
+func (rp *RelyingParty) RequestOptions(challenge string, allow []Credential) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "required",
+	}
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create to the challenge
+// and returns the new credential (WebAuthn, section 7.1)
+func (rp *RelyingParty) VerifyRegistration(response *AttestationResponse, challenge string) (*Credential, error) {
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	if err := rejectCrossOrigin(&parsed.Response.CollectedClientData); err != nil {
+		return nil, err
+	}
+
+	// The attestation statement is not verified beyond its format, as none was requested
+	if _, err := parsed.Verify(challenge, true, true, rp.ID, rp.Origins, nil, protocol.TopOriginExplicitVerificationMode, nil, credentialParameters()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerification, describe(err))
+	}
+
+	authData := parsed.Response.AttestationObject.AuthData
+	if !bytes.Equal(parsed.RawID, authData.AttData.CredentialID) {
+		return nil, fmt.Errorf("%w: rawId does not match the credential", ErrInvalidResponse)
+	}
+
+	return &Credential{
+		ID:         authData.AttData.CredentialID,
+		PublicKey:  authData.AttData.CredentialPublicKey,
+		SignCount:  authData.Counter,
+		AAGUID:     authData.AttData.AAGUID,
+		Transports: response.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get with the registered credential
+// and returns the new signature counter (WebAuthn, section 7.2)
+func (rp *RelyingParty) VerifyAssertion(response *AssertionResponse, challenge string, credential *Credential) (uint32, error) {
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if !bytes.Equal(parsed.RawID, credential.ID) {
+		return 0, fmt.Errorf("%w: rawId does not match the credential", ErrInvalidResponse)
+	}
+
+	if err := rejectCrossOrigin(&parsed.Response.CollectedClientData); err != nil {
+		return 0, err
+	}
+
+	if err := parsed.Verify(challenge, rp.ID, rp.Origins, nil, protocol.TopOriginExplicitVerificationMode, "", true, true, credential.PublicKey); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrVerification, describe(err))
+	}
+
+	// Authenticators without a counter always send 0 (WebAuthn, section 6.1.1)
+	signCount := parsed.Response.AuthenticatorData.Counter
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		return 0, ErrSignCount
+	}
+
+	return signCount, nil
+}
+
+// ClientChallenge reads the challenge of a clientDataJSON, to find the ceremony it answers
+func ClientChallenge(clientDataJSON string) (string, error) {
+	raw, err := encoding.DecodeString(clientDataJSON)
+	if err != nil {
+		return "", fmt.Errorf("%w: clientDataJSON is not base64url", ErrInvalidResponse)
+	}
+
+	var clientData protocol.CollectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return "", fmt.Errorf("%w: clientDataJSON: %v", ErrInvalidResponse, err)
+	}
+	return clientData.Challenge, nil
+}
+
+// rejectCrossOrigin refuses the ceremonies run in an iframe of another site: the login pages
+// are served from the configured origins only
+func rejectCrossOrigin(clientData *protocol.CollectedClientData) error {
+	if clientData.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin ceremony from %s", ErrVerification, clientData.Origin)
+	}
+	return nil
+}
+
+// describe keeps the details of the protocol errors, whose message alone is generic
+func describe(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.DevInfo != "" {
+		return protocolErr.Details + " (" + protocolErr.DevInfo + ")"
+	}
+	return err.Error()
+}
+
+func credentialParameters() []protocol.CredentialParameter {
+	params := make([]protocol.CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, protocol.CredentialParameter{
+			Type:      protocol.PublicKeyCredentialType,
+			Algorithm: webauthncose.COSEAlgorithmIdentifier(alg),
+		})
+	}
+	return params
+}
+
+func descriptors(credentials []Credential) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		list = append(list, CredentialDescriptor{
+			Type:       "public-key",
+			ID:         encoding.EncodeToString(credential.ID),
+			Transports: credential.Transports,
+		})
+	}
+	return list
+}
@@ -0,0 +1,139 @@
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/katana-stuidio/access-control/pkg/webauthn"
+	"github.com/katana-stuidio/access-control/pkg/webauthn/webauthntest"
+)
+
+const testOrigin = "https://app.katana.com"
+
+func testRelyingParty() *webauthn.RelyingParty {
+	return &webauthn.RelyingParty{ID: "katana.com", Name: "Katana", Origins: []string{testOrigin}}
+}
+
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	challenge, _ := webauthn.NewChallenge()
+	options := rp.CreationOptions(challenge, webauthn.UserEntity{ID: []byte("user-1"), Name: "maria", DisplayName: "Maria"}, nil)
+
+	response, err := authenticator.Create(options)
+	if err != nil {
+		t.Fatalf("erro no autenticador: %v", err)
+	}
+
+	credential, err := rp.VerifyRegistration(response, challenge)
+	if err != nil {
+		t.Fatalf("registro rejeitado: %v", err)
+	}
+	return credential
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testOrigin)
+	authenticator.Counter = true
+
+	credential := register(t, rp, authenticator)
+	if len(credential.ID) == 0 || len(credential.PublicKey) == 0 {
+		t.Fatalf("credencial incompleta: %+v", credential)
+	}
+
+	challenge, _ := webauthn.NewChallenge()
+	response, err := authenticator.Get(rp.RequestOptions(challenge, []webauthn.Credential{*credential}))
+	if err != nil {
+		t.Fatalf("erro no autenticador: %v", err)
+	}
+
+	signCount, err := rp.VerifyAssertion(response, challenge, credential)
+	if err != nil {
+		t.Fatalf("asserção rejeitada: %v", err)
+	}
+	if signCount != 1 {
+		t.Errorf("esperado contador 1, mas obteve %d", signCount)
+	}
+
+	// A mesma asserção não pode ser repetida com o contador já gravado
+	credential.SignCount = signCount
+	if _, err := rp.VerifyAssertion(response, challenge, credential); !errors.Is(err, webauthn.ErrSignCount) {
+		t.Errorf("asserção repetida: esperado ErrSignCount, mas obteve %v", err)
+	}
+}
+
+func TestAssertionChecks(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testOrigin)
+	credential := register(t, rp, authenticator)
+
+	challenge, _ := webauthn.NewChallenge()
+	other, _ := webauthn.NewChallenge()
+
+	response, _ := authenticator.Get(rp.RequestOptions(challenge, nil))
+	if _, err := rp.VerifyAssertion(response, other, credential); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("outro desafio: esperado ErrVerification, mas obteve %v", err)
+	}
+
+	tampered := *response
+	tampered.Response.Signature = response.Response.AuthenticatorData
+	if _, err := rp.VerifyAssertion(&tampered, challenge, credential); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("assinatura inválida: esperado ErrVerification, mas obteve %v", err)
+	}
+
+	// Uma página de outro site não pode usar a credencial
+	phishing := webauthntest.New("https://katana.com.evil.io")
+	phishingCredential := register(t, &webauthn.RelyingParty{ID: "katana.com", Origins: []string{"https://katana.com.evil.io"}}, phishing)
+	response, _ = phishing.Get(rp.RequestOptions(challenge, nil))
+	if _, err := rp.VerifyAssertion(response, challenge, phishingCredential); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("outra origem: esperado ErrVerification, mas obteve %v", err)
+	}
+
+	// Outro relying party (rpId) não é aceito
+	otherRP := &webauthn.RelyingParty{ID: "outro.com", Origins: []string{testOrigin}}
+	response, _ = authenticator.Get(rp.RequestOptions(challenge, nil))
+	if _, err := otherRP.VerifyAssertion(response, challenge, credential); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("outro rpId: esperado ErrVerification, mas obteve %v", err)
+	}
+
+	// Passkeys substituem a senha: a verificação do usuário é obrigatória
+	authenticator.UserVerified = false
+	response, _ = authenticator.Get(rp.RequestOptions(challenge, nil))
+	if _, err := rp.VerifyAssertion(response, challenge, credential); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("sem verificação: esperado ErrVerification, mas obteve %v", err)
+	}
+}
+
+func TestRegistrationChecks(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(testOrigin)
+
+	challenge, _ := webauthn.NewChallenge()
+	options := rp.CreationOptions(challenge, webauthn.UserEntity{ID: []byte("user-1"), Name: "maria"}, nil)
+	response, _ := authenticator.Create(options)
+
+	// Resposta de login não serve como registro
+	assertion, _ := authenticator.Get(rp.RequestOptions(challenge, nil))
+	forged := *response
+	forged.Response.ClientDataJSON = assertion.Response.ClientDataJSON
+	if _, err := rp.VerifyRegistration(&forged, challenge); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("tipo webauthn.get: esperado ErrVerification, mas obteve %v", err)
+	}
+
+	// attestationObject que não é CBOR
+	broken := *response
+	broken.Response.AttestationObject = "AAAA"
+	if _, err := rp.VerifyRegistration(&broken, challenge); !errors.Is(err, webauthn.ErrInvalidResponse) {
+		t.Errorf("attestationObject inválido: esperado ErrInvalidResponse, mas obteve %v", err)
+	}
+
+	other, _ := webauthn.NewChallenge()
+	if _, err := rp.VerifyRegistration(response, other); !errors.Is(err, webauthn.ErrVerification) {
+		t.Errorf("outro desafio: esperado ErrVerification, mas obteve %v", err)
+	}
+
+	if got, err := webauthn.ClientChallenge(response.Response.ClientDataJSON); err != nil || got != challenge {
+		t.Errorf("esperado o desafio %s, mas obteve %s (%v)", challenge, got, err)
+	}
+}
@@ -0,0 +1,207 @@
+// Package webauthntest provides a software authenticator that answers the WebAuthn
+// ceremonies like a browser with a platform passkey, for tests of the relying party
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/katana-stuidio/access-control/pkg/webauthn"
+)
+
+var encoding = base64.RawURLEncoding
+
+// Authenticator keeps ES256 credentials in memory. Origin is written to the client data,
+// UserVerified and Counter change the answers to test the checks of the relying party.
+type Authenticator struct {
+	Origin       string
+	UserVerified bool
+	// Counter makes the authenticator increment the signature counter, as security keys do;
+	// without it the counter is always 0, as in synced passkeys
+	Counter bool
+
+	credentials []*credential
+}
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// New returns an authenticator of a browser at origin, with user verification
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin, UserVerified: true}
+}
+
+// Create answers navigator.credentials.create with a new discoverable credential
+func (a *Authenticator) Create(options *webauthn.CreationOptions) (*webauthn.AttestationResponse, error) {
+	for _, excluded := range options.ExcludeCredentials {
+		if a.find(options.RP.ID, excluded.ID) != nil {
+			return nil, errors.New("InvalidStateError: credential already registered")
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	userHandle, err := encoding.DecodeString(options.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &credential{id: id, rpID: options.RP.ID, userHandle: userHandle, key: key}
+	a.credentials = append(a.credentials, cred)
+
+	// aaguid | credentialIdLength | credentialId | credentialPublicKey
+	attested := make([]byte, 16, 16+2+len(id))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(id)))
+	attested = append(attested, id...)
+	publicKey, err := coseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	attested = append(attested, publicKey...)
+
+	authData := a.authenticatorData(cred, 0x40, attested)
+	attestationObject, err := webauthncbor.Marshal(&attestation{Format: "none", Statement: map[string]interface{}{}, AuthData: authData})
+	if err != nil {
+		return nil, err
+	}
+
+	clientData, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &webauthn.AttestationResponse{
+		ID:    encoding.EncodeToString(id),
+		RawID: encoding.EncodeToString(id),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = encoding.EncodeToString(clientData)
+	response.Response.AttestationObject = encoding.EncodeToString(attestationObject)
+	response.Response.Transports = []string{"internal"}
+	return response, nil
+}
+
+// Get answers navigator.credentials.get with the first allowed credential or,
+// when the options allow any, the first discoverable credential of the relying party
+func (a *Authenticator) Get(options *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	var cred *credential
+	if len(options.AllowCredentials) == 0 {
+		for _, candidate := range a.credentials {
+			if candidate.rpID == options.RPID {
+				cred = candidate
+				break
+			}
+		}
+	}
+	for _, allowed := range options.AllowCredentials {
+		if cred = a.find(options.RPID, allowed.ID); cred != nil {
+			break
+		}
+	}
+	if cred == nil {
+		return nil, errors.New("NotAllowedError: no credential for the relying party")
+	}
+
+	if a.Counter {
+		cred.signCount++
+	}
+
+	clientData, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authenticatorData(cred, 0, nil)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	response := &webauthn.AssertionResponse{
+		ID:    encoding.EncodeToString(cred.id),
+		RawID: encoding.EncodeToString(cred.id),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = encoding.EncodeToString(clientData)
+	response.Response.AuthenticatorData = encoding.EncodeToString(authData)
+	response.Response.Signature = encoding.EncodeToString(signature)
+	response.Response.UserHandle = encoding.EncodeToString(cred.userHandle)
+	return response, nil
+}
+
+func (a *Authenticator) find(rpID, id string) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpID == rpID && encoding.EncodeToString(cred.id) == id {
+			return cred
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+// authenticatorData is rpIdHash | flags | signCount | attested credential data
+func (a *Authenticator) authenticatorData(cred *credential, flags byte, attested []byte) []byte {
+	flags |= 0x01
+	if a.UserVerified {
+		flags |= 0x04
+	}
+
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, cred.signCount)
+	return append(data, attested...)
+}
+
+// attestation is the attestationObject of the conveyance "none"
+type attestation struct {
+	Format    string                 `cbor:"fmt"`
+	Statement map[string]interface{} `cbor:"attStmt"`
+	AuthData  []byte                 `cbor:"authData"`
+}
+
+// coseKey encodes the public key as a COSE_Key of kty EC2 and alg ES256
+func coseKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.PublicKey.X.FillBytes(x)
+	key.PublicKey.Y.FillBytes(y)
+
+	return webauthncbor.Marshal(&webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: x,
+		YCoord: y,
+	})
+}